@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/compliance"
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/system"
+	"github.com/klamhq/facter-oss/pkg/agent/remediation"
+	"github.com/klamhq/facter-oss/pkg/agent/sink"
+	"github.com/klamhq/facter-oss/pkg/agent/store"
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	complianceDataStream string
+	complianceTailoring  string
+
+	remediateFormat    string
+	remediateInput     string
+	remediateHostname  string
+	remediateOutput    string
+	remediateRules     []string
+	remediateSkipRules []string
+	remediateDryRun    bool
+
+	trendHostname string
+	trendLast     int
+	trendJSON     bool
+
+	complianceCmd = &cobra.Command{
+		Use:   "compliance",
+		Short: "Inspect the compliance configuration and results",
+	}
+
+	complianceProfilesCmd = &cobra.Command{
+		Use:   "profiles",
+		Short: "List the OpenSCAP profiles available on the host",
+		Long: `Profiles lists the XCCDF profiles of the OpenSCAP datastream used by the
+compliance collector, the configured one or the SCAP Security Guide datastream
+of the OS, and the profiles of the configured tailoring file. Their ids are the
+values of compliance.profile.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cfg options.RunOptions
+			if err := viper.Unmarshal(&cfg); err != nil {
+				logrus.Fatalf("Failed to unmarshal config: %v", err)
+			}
+			complianceCfg := cfg.Facter.Compliance
+			if complianceDataStream != "" {
+				complianceCfg.DataStream = complianceDataStream
+			}
+			if complianceTailoring != "" {
+				complianceCfg.Tailoring = complianceTailoring
+			}
+
+			host := system.GetSystem().Host
+			dataStreamFile := compliance.DataStream(&complianceCfg, &schema.Os{Name: host.Platform, Version: host.PlatformVersion})
+			if dataStreamFile == "" {
+				return fmt.Errorf("no OpenSCAP datastream known for %s %s, set compliance.dataStream or --datastream", host.Platform, host.PlatformVersion)
+			}
+			profiles, err := compliance.ListProfiles(dataStreamFile)
+			if err != nil {
+				return err
+			}
+			source := map[string]string{}
+			if complianceCfg.Tailoring != "" {
+				tailored, err := compliance.ListProfiles(complianceCfg.Tailoring)
+				if err != nil {
+					return err
+				}
+				for _, p := range tailored {
+					source[p.ID] = "tailoring, extends " + p.Extends
+				}
+				profiles = append(profiles, tailored...)
+			}
+
+			fmt.Fprintf(os.Stderr, "Profiles of %s\n", dataStreamFile)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tTITLE\tSOURCE")
+			for _, p := range profiles {
+				if source[p.ID] == "" {
+					source[p.ID] = "datastream"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", p.ID, p.Title, source[p.ID])
+			}
+			return w.Flush()
+		},
+	}
+
+	complianceRemediateCmd = &cobra.Command{
+		Use:   "remediate",
+		Short: "Export the fixes of the failing compliance rules",
+		Long: `Remediate writes a shell script or an Ansible playbook with the fixes of the
+failing rules of the last compliance run of the host, read from the local inventory
+store, or with --input from a full inventory exported by the file output or an
+OpenSCAP XCCDF results file. Rules are selected with --rule and --skip-rule, by
+full id or without the xccdf_org.ssgproject.content_rule_ prefix. With --dry-run,
+nothing is written and the diff with the current content of --output is printed.
+Facter never applies the fixes.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cfg options.RunOptions
+			if err := viper.Unmarshal(&cfg); err != nil {
+				logrus.Fatalf("Failed to unmarshal config: %v", err)
+			}
+			if !remediation.ValidFormat(remediateFormat) {
+				return fmt.Errorf("invalid remediation format %q, expected %s or %s", remediateFormat, remediation.FormatBash, remediation.FormatAnsible)
+			}
+
+			hostname, report, err := remediateSource(&cfg)
+			if err != nil {
+				return err
+			}
+			plan := remediation.NewPlan(hostname, report, remediateRules, remediateSkipRules)
+			bin, err := remediation.Generate(remediateFormat, plan)
+			if err != nil {
+				return err
+			}
+			logrus.Infof("%d failing rules to remediate, %d without automated fix", len(plan.Fixes), len(plan.Manual))
+
+			if remediateDryRun {
+				var current []byte
+				name := "remediation"
+				if remediateOutput != "" && remediateOutput != "-" {
+					name = remediateOutput
+					if current, err = os.ReadFile(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+						return err
+					}
+				}
+				diff, err := remediation.Diff(name, current, bin)
+				if err != nil {
+					return err
+				}
+				_, err = os.Stdout.WriteString(diff)
+				return err
+			}
+			if remediateOutput == "" || remediateOutput == "-" {
+				_, err = os.Stdout.Write(bin)
+				return err
+			}
+			mode := os.FileMode(0644)
+			if remediateFormat == remediation.FormatBash {
+				mode = 0755
+			}
+			return os.WriteFile(remediateOutput, bin, mode)
+		},
+	}
+
+	complianceTrendCmd = &cobra.Command{
+		Use:   "trend",
+		Short: "Show the history of the compliance score of the host",
+		Long: `Trend prints the compliance score of every run of the host kept in the local
+inventory store, oldest first, with the change since the previous run and the
+number of rules failing or passing since then. With --json, the history is
+printed with the ids of these rules.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cfg options.RunOptions
+			if err := viper.Unmarshal(&cfg); err != nil {
+				logrus.Fatalf("Failed to unmarshal config: %v", err)
+			}
+			hostname := trendHostname
+			if hostname == "" {
+				var err error
+				if hostname, err = os.Hostname(); err != nil {
+					return err
+				}
+			}
+			s, err := store.NewBoltInventoryStore(cfg.Facter.Store.Path)
+			if err != nil {
+				return fmt.Errorf("unable to open inventory store: %w", err)
+			}
+			defer s.Close()
+			history, err := s.ComplianceHistory(hostname)
+			if err != nil {
+				return err
+			}
+			if len(history) == 0 {
+				return fmt.Errorf("no compliance score of %s in %s, is compliance enabled ?", hostname, cfg.Facter.Store.Path)
+			}
+
+			first := 0
+			if trendLast > 0 && len(history) > trendLast {
+				first = len(history) - trendLast
+			}
+			if trendJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(history[first:])
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TIME\tPROFILE\tSCORE\tCHANGE\tPASSED\tFAILED\tNEWLY FAILING\tNEWLY PASSING")
+			for i := first; i < len(history); i++ {
+				score := history[i]
+				change := "-"
+				if i > 0 && history[i-1].Profile == score.Profile {
+					change = fmt.Sprintf("%+.2f", score.Score-history[i-1].Score)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\t%d\t%d\t%d\t%d\n", score.Time, score.Profile, score.Score, change,
+					score.Passed, score.Failed, len(score.NewlyFailing), len(score.NewlyPassing))
+			}
+			return w.Flush()
+		},
+	}
+)
+
+// remediateSource returns the host and the compliance report to remediate, read from a file or the store.
+func remediateSource(cfg *options.RunOptions) (string, *models.ComplianceReport, error) {
+	hostname := remediateHostname
+	if hostname == "" {
+		var err error
+		if hostname, err = os.Hostname(); err != nil {
+			return "", nil, err
+		}
+	}
+	if strings.EqualFold(filepath.Ext(remediateInput), ".xml") {
+		system := compliance.FixSystemShell
+		if remediateFormat == remediation.FormatAnsible {
+			system = compliance.FixSystemAnsible
+		}
+		report, err := compliance.ReadResults(remediateInput, system)
+		return hostname, report, err
+	}
+
+	var inv *schema.HostInventory
+	if remediateInput != "" {
+		var err error
+		if inv, _, err = sink.ReadExport(remediateInput); err != nil {
+			return "", nil, err
+		}
+	} else {
+		s, err := store.NewBoltInventoryStore(cfg.Facter.Store.Path)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to open inventory store: %w", err)
+		}
+		defer s.Close()
+		if inv, err = s.Get(hostname); err != nil {
+			return "", nil, fmt.Errorf("no inventory of %s in %s: %w", hostname, cfg.Facter.Store.Path, err)
+		}
+	}
+	if inv.ComplianceReport == nil {
+		return "", nil, fmt.Errorf("the inventory of %s has no compliance report, is compliance enabled ?", inv.Hostname)
+	}
+	return inv.Hostname, remediation.FromSchema(inv.ComplianceReport), nil
+}
+
+func init() {
+	complianceProfilesCmd.Flags().StringVar(&complianceDataStream, "datastream", "", "OpenSCAP datastream, overrides the configured one")
+	complianceProfilesCmd.Flags().StringVar(&complianceTailoring, "tailoring", "", "XCCDF tailoring file, overrides the configured one")
+	complianceRemediateCmd.Flags().StringVar(&remediateFormat, "format", remediation.FormatBash, "remediation format, bash or ansible")
+	complianceRemediateCmd.Flags().StringVar(&remediateInput, "input", "", "full inventory exported by the file output, or OpenSCAP XCCDF results file, to read instead of the store")
+	complianceRemediateCmd.Flags().StringVar(&remediateHostname, "hostname", "", "host to read from the store, the local one by default")
+	complianceRemediateCmd.Flags().StringVarP(&remediateOutput, "output", "o", "", "file to write the remediation to, the standard output by default")
+	complianceRemediateCmd.Flags().StringSliceVar(&remediateRules, "rule", nil, "rule to remediate, every failing rule by default, can be repeated")
+	complianceRemediateCmd.Flags().StringSliceVar(&remediateSkipRules, "skip-rule", nil, "failing rule not to remediate, can be repeated")
+	complianceRemediateCmd.Flags().BoolVar(&remediateDryRun, "dry-run", false, "print the diff with the current content of --output instead of writing it")
+	complianceTrendCmd.Flags().StringVar(&trendHostname, "hostname", "", "host to read from the store, the local one by default")
+	complianceTrendCmd.Flags().IntVar(&trendLast, "last", 0, "number of runs to show, every run by default")
+	complianceTrendCmd.Flags().BoolVar(&trendJSON, "json", false, "print the history as JSON")
+	complianceCmd.AddCommand(complianceProfilesCmd, complianceRemediateCmd, complianceTrendCmd)
+	rootCmd.AddCommand(complianceCmd)
+}
@@ -10,6 +10,7 @@ import (
 
 var (
 	cfgFile string
+	failOn  string
 	rootCmd = &cobra.Command{
 
 		Use:   "facter",
@@ -18,16 +19,32 @@ var (
 a system's hardware, operating system, and environment. It is commonly
 used in configuration management systems to provide data for making
 decisions about how to configure systems.`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			var cfg options.RunOptions
-			if err := viper.Unmarshal(&cfg); err != nil {
-				logrus.Fatalf("Failed to unmarshal config: %v", err)
-			}
-			return agent.Run(&cfg)
-		},
+		RunE: run,
+	}
+
+	runCmd = &cobra.Command{
+		Use:   "run",
+		Short: "Collect the system facts and send the inventory",
+		Long: `Run collects the system facts and sends the inventory to the configured output,
+as facter does without command. With --fail-on, or the failOn setting of the
+vulnerability risk policy, it exits with code 3 once the inventory is sent when
+the risk of the host reaches the given rating, e.g. to fail image builds. It
+fails as well when the risk could not be computed, e.g. without vulnerability scan.`,
+		RunE: run,
 	}
 )
 
+func run(cmd *cobra.Command, args []string) error {
+	var cfg options.RunOptions
+	if err := viper.Unmarshal(&cfg); err != nil {
+		logrus.Fatalf("Failed to unmarshal config: %v", err)
+	}
+	if failOn != "" {
+		cfg.Facter.Vulnerabilities.Policy.FailOn = failOn
+	}
+	return agent.Run(&cfg)
+}
+
 func Execute() error {
 	if err := rootCmd.Execute(); err != nil {
 		logrus.Error(err)
@@ -41,6 +58,10 @@ func init() {
 	rootCmd.SilenceUsage = true
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "path to facter config file")
 	viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+	for _, c := range []*cobra.Command{rootCmd, runCmd} {
+		c.Flags().StringVar(&failOn, "fail-on", "", "exit with code 3 when the vulnerability risk of the host reaches low, medium, high or critical")
+	}
+	rootCmd.AddCommand(runCmd)
 
 	cobra.OnInitialize(initConfig)
 }
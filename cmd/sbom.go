@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/klamhq/facter-oss/pkg/agent"
+	"github.com/klamhq/facter-oss/pkg/agent/sbom"
+	"github.com/klamhq/facter-oss/pkg/agent/sink"
+	"github.com/klamhq/facter-oss/pkg/agent/store"
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/klamhq/facter-oss/pkg/utils"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	sbomFormat    string
+	sbomOutput    string
+	sbomInput     string
+	sbomFromStore bool
+	sbomHostname  string
+	sbomImage     string
+
+	sbomCmd = &cobra.Command{
+		Use:   "sbom",
+		Short: "Export the software bill of materials of the host",
+		Long: `Sbom writes the packages of the host, with their licences and vulnerabilities,
+as a CycloneDX 1.5 or SPDX 2.3 JSON document. The packages are collected with the
+configured collectors, or read with --input from a full inventory exported by the
+file output, or with --store from the local inventory store. With --image, the
+document describes a docker image scanned by the images collector instead of the host.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cfg options.RunOptions
+			if err := viper.Unmarshal(&cfg); err != nil {
+				logrus.Fatalf("Failed to unmarshal config: %v", err)
+			}
+			if !sbom.ValidFormat(sbomFormat) {
+				return fmt.Errorf("invalid SBOM format %q, expected %s or %s", sbomFormat, sbom.FormatCycloneDX, sbom.FormatSPDX)
+			}
+			if sbomInput != "" && sbomFromStore {
+				return fmt.Errorf("--input and --store are mutually exclusive")
+			}
+
+			inv, ext, err := sbomSource(&cfg)
+			if err != nil {
+				return err
+			}
+			subject := sbom.FromInventory(inv, ext)
+			if sbomImage != "" {
+				var images []*models.ImageInventory
+				if ext != nil {
+					images = ext.Images
+				}
+				img := sbom.FindImage(images, sbomImage)
+				if img == nil {
+					return fmt.Errorf("image %s not found in the inventory, is applications.docker.images.scan enabled ?", sbomImage)
+				}
+				subject = sbom.FromImage(img, subject.ToolVersion)
+			}
+			bin, err := sbom.Generate(sbomFormat, subject)
+			if err != nil {
+				return err
+			}
+			if sbomOutput == "" || sbomOutput == "-" {
+				_, err = os.Stdout.Write(append(bin, '\n'))
+				return err
+			}
+			return os.WriteFile(sbomOutput, bin, 0644)
+		},
+	}
+)
+
+// sbomSource returns the inventory to export, read from an export or the store, or collected.
+func sbomSource(cfg *options.RunOptions) (*schema.HostInventory, *models.HostExtensions, error) {
+	switch {
+	case sbomInput != "":
+		return sink.ReadExport(sbomInput)
+	case sbomFromStore:
+		hostname := sbomHostname
+		if hostname == "" {
+			var err error
+			if hostname, err = os.Hostname(); err != nil {
+				return nil, nil, err
+			}
+		}
+		s, err := store.NewBoltInventoryStore(cfg.Facter.Store.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to open inventory store: %w", err)
+		}
+		defer s.Close()
+		inv, err := s.Get(hostname)
+		if err != nil {
+			return nil, nil, fmt.Errorf("no inventory of %s in %s: %w", hostname, cfg.Facter.Store.Path, err)
+		}
+		ext, err := s.GetExtensions(hostname)
+		if err != nil {
+			ext = nil
+		}
+		return inv, ext, nil
+	default:
+		level := logrus.InfoLevel
+		if cfg.Facter.Logs.DebugMode {
+			level = logrus.DebugLevel
+		}
+		var factory utils.LoggerFactory = &utils.DefaultLoggerFactory{}
+		logger := factory.New(level)
+		// The document may be written to the standard output
+		logger.SetOutput(os.Stderr)
+		return agent.Collect(cfg, logger)
+	}
+}
+
+func init() {
+	sbomCmd.Flags().StringVar(&sbomFormat, "format", sbom.FormatCycloneDX, "SBOM format, cyclonedx-json or spdx-json")
+	sbomCmd.Flags().StringVarP(&sbomOutput, "output", "o", "", "file to write the SBOM to, the standard output by default")
+	sbomCmd.Flags().StringVar(&sbomInput, "input", "", "full inventory exported by the file output to read instead of collecting")
+	sbomCmd.Flags().BoolVar(&sbomFromStore, "store", false, "read the last inventory of the local store instead of collecting")
+	sbomCmd.Flags().StringVar(&sbomHostname, "hostname", "", "host to read from the store, the local one by default")
+	sbomCmd.Flags().StringVar(&sbomImage, "image", "", "tag or identifier of a scanned docker image to describe instead of the host")
+	rootCmd.AddCommand(sbomCmd)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/vulnerability"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	vulndbDatabase string
+	vulndbReplace  bool
+
+	vulndbCmd = &cobra.Command{
+		Use:   "vulndb",
+		Short: "Manage the local vulnerability database",
+	}
+
+	vulndbImportCmd = &cobra.Command{
+		Use:   "import <file|directory|zip>...",
+		Short: "Load or refresh the vulnerability database from OSV files",
+		Long: `Import loads vulnerability records in the OSV format into the local database
+used to match the installed packages without Trivy. Sources are JSON files,
+directories of JSON files, or zip archives such as the per-ecosystem exports
+of https://osv.dev. Records already in the database are replaced by the
+imported ones with the same ID.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cfg options.RunOptions
+			if err := viper.Unmarshal(&cfg); err != nil {
+				logrus.Fatalf("Failed to unmarshal config: %v", err)
+			}
+			database := cfg.Facter.Vulnerabilities.Database
+			if vulndbDatabase != "" {
+				database = vulndbDatabase
+			}
+			if database == "" {
+				return fmt.Errorf("no vulnerability database configured, set facter.vulnerabilities.database or --database")
+			}
+
+			stats, err := vulnerability.ImportOSV(database, args, vulndbReplace)
+			if err != nil {
+				return err
+			}
+			logrus.Infof("%d vulnerabilities imported into %s, %d without supported ecosystem skipped", stats.Imported, database, stats.Skipped)
+			return nil
+		},
+	}
+)
+
+func init() {
+	vulndbImportCmd.Flags().StringVar(&vulndbDatabase, "database", "", "vulnerability database directory, overrides the configured one")
+	vulndbImportCmd.Flags().BoolVar(&vulndbReplace, "replace", false, "drop the records of the database before importing")
+	vulndbCmd.AddCommand(vulndbImportCmd)
+	rootCmd.AddCommand(vulndbCmd)
+}
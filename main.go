@@ -1,13 +1,21 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/klamhq/facter-oss/cmd"
+	"github.com/klamhq/facter-oss/pkg/agent"
 )
 
+// exitRiskThreshold is the exit code when the vulnerability risk policy fails, distinct from errors.
+const exitRiskThreshold = 3
+
 func main() {
 	err := cmd.Execute()
+	if errors.Is(err, agent.ErrRiskThreshold) {
+		os.Exit(exitRiskThreshold)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -3,9 +3,13 @@ package compliance
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/exec"
+	"sync"
 
 	"github.com/klamhq/facter-oss/pkg/agent/collectors/compliance"
 	"github.com/klamhq/facter-oss/pkg/agent/collectors/system"
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
 	"github.com/klamhq/facter-oss/pkg/utils"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
@@ -15,6 +19,9 @@ import (
 type ComplianceCollectorImpl struct {
 	log *logrus.Logger
 	cfg *options.ComplianceOptions
+
+	engineOnce sync.Once
+	engine     string
 }
 
 func New(log *logrus.Logger, cfg *options.ComplianceOptions) *ComplianceCollectorImpl {
@@ -25,7 +32,34 @@ func New(log *logrus.Logger, cfg *options.ComplianceOptions) *ComplianceCollecto
 	}
 }
 
+// Engine returns the configured engine. The auto engine resolves to OpenSCAP when oscap is installed
+// and a datastream exists for the OS, and to the native engine otherwise.
+func (c *ComplianceCollectorImpl) Engine() string {
+	c.engineOnce.Do(func() {
+		c.engine = c.cfg.Engine
+		if c.engine != "" && c.engine != compliance.EngineAuto {
+			return
+		}
+		c.engine = compliance.EngineNative
+		if _, err := exec.LookPath("oscap"); err != nil {
+			c.log.Info("OpenSCAP is not installed, using the native compliance engine")
+			return
+		}
+		host := system.GetSystem().Host
+		dataStreamFile := compliance.DataStream(c.cfg, &schema.Os{Name: host.Platform, Version: host.PlatformVersion})
+		if _, err := os.Stat(dataStreamFile); dataStreamFile == "" || err != nil {
+			c.log.Infof("No OpenSCAP datastream for %s %s, using the native compliance engine", host.Platform, host.PlatformVersion)
+			return
+		}
+		c.engine = compliance.EngineOpenSCAP
+	})
+	return c.engine
+}
+
 func (c *ComplianceCollectorImpl) CollectCompliance(ctx context.Context) (*schema.ComplianceReport, error) {
+	if engine := c.Engine(); engine != compliance.EngineOpenSCAP {
+		return nil, fmt.Errorf("unknown compliance engine %q, expected %s, %s or %s", engine, compliance.EngineOpenSCAP, compliance.EngineNative, compliance.EngineAuto)
+	}
 	if !utils.CheckBinInstalled(c.log, "oscap") {
 		c.log.Error("OpenSCAP is not installed, compliance report will not be generated, see here: https://github.com/ComplianceAsCode/content?tab=readme-ov-file#installation for installation instructions")
 		return nil, fmt.Errorf("openscap is not installed, compliance report will not be generated")
@@ -41,14 +75,33 @@ func (c *ComplianceCollectorImpl) CollectCompliance(ctx context.Context) (*schem
 	if err != nil {
 		return nil, err
 	}
+	return toSchema(openscapReport), nil
+}
+
+// EvaluateRules evaluates the configured rule packs on the host and its inventory.
+func (c *ComplianceCollectorImpl) EvaluateRules(ctx context.Context, inv *schema.HostInventory, packages []*models.Package) (*schema.ComplianceReport, error) {
+	engine, err := compliance.NewNativeEngine(c.log, c.cfg.Native.Packs, c.cfg.Native.RuleDirs)
+	if err != nil {
+		return nil, err
+	}
+	engine.Select(c.cfg.Rules, c.cfg.SkipRules)
+	c.log.Info("Evaluating compliance rules")
+	report, err := engine.Evaluate(ctx, &compliance.Facts{Inventory: inv, Packages: packages})
+	if err != nil {
+		return nil, err
+	}
+	return toSchema(report), nil
+}
+
+func toSchema(report *models.ComplianceReport) *schema.ComplianceReport {
 	complianceReport := &schema.ComplianceReport{}
 	complianceReport.Score = &schema.Score{}
-	complianceReport.RuleResults = make([]*schema.RuleCheckResult, 0, len(openscapReport.RuleResults))
-	complianceReport.Score.Maximum = openscapReport.Score.Maximum
-	complianceReport.Score.Value = openscapReport.Score.Value
-	complianceReport.Profile = openscapReport.Profile
+	complianceReport.RuleResults = make([]*schema.RuleCheckResult, 0, len(report.RuleResults))
+	complianceReport.Score.Maximum = report.Score.Maximum
+	complianceReport.Score.Value = report.Score.Value
+	complianceReport.Profile = report.Profile
 
-	for _, ruleResult := range openscapReport.RuleResults {
+	for _, ruleResult := range report.RuleResults {
 		ruleCheckResult := &schema.RuleCheckResult{
 			Id:          ruleResult.ID,
 			Title:       ruleResult.Title,
@@ -60,5 +113,5 @@ func (c *ComplianceCollectorImpl) CollectCompliance(ctx context.Context) (*schem
 		complianceReport.RuleResults = append(complianceReport.RuleResults, ruleCheckResult)
 	}
 
-	return complianceReport, nil
+	return complianceReport
 }
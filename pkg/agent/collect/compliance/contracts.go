@@ -3,9 +3,15 @@ package compliance
 import (
 	"context"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 )
 
 type ComplianceCollector interface {
+	// Engine returns the engine evaluating the compliance, openscap or native.
+	Engine() string
+	// CollectCompliance runs the OpenSCAP evaluation, concurrently with the other collectors.
 	CollectCompliance(ctx context.Context) (*schema.ComplianceReport, error)
+	// EvaluateRules runs the native rules, once the inventory they may check is collected.
+	EvaluateRules(ctx context.Context, inv *schema.HostInventory, packages []*models.Package) (*schema.ComplianceReport, error)
 }
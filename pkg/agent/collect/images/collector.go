@@ -0,0 +1,134 @@
+package images
+
+import (
+	"context"
+	"os"
+
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/image"
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/packages"
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/vulnerability"
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+)
+
+type ImagesCollectorImpl struct {
+	log      *logrus.Logger
+	cfg      *options.DockerImagesOptions
+	packages *options.PackagesOptions
+	vulns    *options.VulnerabilitiesOptions
+	// newClient connects to the docker daemon, replaced by tests
+	newClient func() (image.DockerAPI, error)
+}
+
+func New(log *logrus.Logger, cfg *options.DockerImagesOptions, pkgs *options.PackagesOptions, vulns *options.VulnerabilitiesOptions) *ImagesCollectorImpl {
+
+	return &ImagesCollectorImpl{
+		log:       log,
+		cfg:       cfg,
+		packages:  pkgs,
+		vulns:     vulns,
+		newClient: image.NewDockerClient,
+	}
+}
+
+// CollectImages unpacks the local docker images one at a time and reads their packages, matched with the
+// vulnerability scanners when vulnerabilities are enabled. An image failing to be scanned is returned with
+// its error, the other images are still scanned.
+func (c *ImagesCollectorImpl) CollectImages(ctx context.Context) ([]*models.ImageInventory, error) {
+	c.log.Info("Crafting docker images")
+	api, err := c.newClient()
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := image.ListImages(ctx, api, c.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	imgs := make([]*models.ImageInventory, 0, len(summaries))
+	matches := make([][]models.PackageVulnMatch, 0, len(summaries))
+	for _, summary := range summaries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		img := &models.ImageInventory{
+			ID:          summary.ID,
+			RepoTags:    summary.RepoTags,
+			RepoDigests: summary.RepoDigests,
+			Created:     summary.Created,
+		}
+		m, err := c.scanImage(ctx, api, img)
+		if err != nil {
+			c.log.WithError(err).WithField("image", img.Name()).Warn("unable to scan docker image")
+			img.Error = err.Error()
+		}
+		imgs = append(imgs, img)
+		matches = append(matches, m)
+	}
+
+	if c.vulns.Enabled {
+		if err := c.assess(imgs, matches); err != nil {
+			return imgs, err
+		}
+	}
+	c.log.Infof("%d docker images scanned", len(imgs))
+	return imgs, nil
+}
+
+// scanImage reads the packages of an image, and returns their vulnerabilities. The image is removed once read.
+func (c *ImagesCollectorImpl) scanImage(ctx context.Context, api image.DockerAPI, img *models.ImageInventory) ([]models.PackageVulnMatch, error) {
+	dir, err := os.MkdirTemp(c.cfg.TempDir, "facter-image-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	root, err := image.Unpack(ctx, c.log, api, c.cfg, img.ID, dir)
+	if err != nil {
+		return nil, err
+	}
+	distro := vulnerability.ReadDistro(root)
+	img.OSName, img.OSVersion = distro.ID, distro.VersionID
+
+	var languages *options.LanguagePackagesOptions
+	if c.packages != nil {
+		languages = &c.packages.Languages
+	}
+	img.Packages, err = packages.ReadRootPackages(ctx, c.log, root, languages)
+	if err != nil {
+		return nil, err
+	}
+	if !c.vulns.Enabled {
+		return nil, nil
+	}
+
+	scanners, err := vulnerability.NewScannersForRoot(c.log, c.vulns, root)
+	if err != nil {
+		return nil, err
+	}
+	return vulnerability.RunScanners(ctx, c.log, scanners, c.vulns.Strategy, img.Packages)
+}
+
+// assess enriches the vulnerabilities of every image from the feeds, loaded once, and rates them.
+// Nothing runs from an image itself, their reachability is left unknown.
+func (c *ImagesCollectorImpl) assess(imgs []*models.ImageInventory, matches [][]models.PackageVulnMatch) error {
+	ids := map[string]bool{}
+	for _, m := range matches {
+		for id := range vulnerability.VulnerabilityIDs(m) {
+			ids[id] = true
+		}
+	}
+	feeds, err := vulnerability.LoadFeeds(c.vulns.Enrichment, ids)
+	if err != nil {
+		return err
+	}
+	for i, img := range imgs {
+		if img.Error != "" {
+			continue
+		}
+		img.Vulnerabilities = vulnerability.Enrich(matches[i], feeds)
+		img.Risk = vulnerability.RatePolicy(img.Vulnerabilities, c.vulns.Policy)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package images
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	dockerimage "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/image"
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/vulnerability"
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const dpkgStatus = `Package: openssl
+Status: install ok installed
+Architecture: amd64
+Version: 3.0.11-1~deb12u2
+Description: Secure Sockets Layer toolkit
+
+`
+
+type fakeDocker struct {
+	saved map[string][]byte
+}
+
+func (f *fakeDocker) ImageList(context.Context, dockerimage.ListOptions) ([]dockerimage.Summary, error) {
+	return []dockerimage.Summary{
+		{ID: "sha256:app", RepoTags: []string{"app:1"}, Created: 200},
+		{ID: "sha256:broken", RepoTags: []string{"broken:1"}, Created: 100},
+	}, nil
+}
+
+func (f *fakeDocker) ImageInspect(context.Context, string, ...client.ImageInspectOption) (dockerimage.InspectResponse, error) {
+	return dockerimage.InspectResponse{}, errors.New("not implemented")
+}
+
+func (f *fakeDocker) ImageSave(_ context.Context, ids []string, _ ...client.ImageSaveOption) (io.ReadCloser, error) {
+	saved, ok := f.saved[ids[0]]
+	if !ok {
+		return nil, errors.New("no such image")
+	}
+	return io.NopCloser(bytes.NewReader(saved)), nil
+}
+
+func tarFiles(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, body := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(body))}))
+		_, err := tw.Write([]byte(body))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func savedImage(t *testing.T) []byte {
+	layer := tarFiles(t, map[string]string{
+		"etc/os-release":      "ID=debian\nVERSION_ID=\"12\"\n",
+		"var/lib/dpkg/status": dpkgStatus,
+	})
+	manifest, err := json.Marshal([]map[string]any{{"Config": "config.json", "Layers": []string{"layer.tar"}}})
+	assert.NoError(t, err)
+	return tarFiles(t, map[string]string{"layer.tar": string(layer), "manifest.json": string(manifest)})
+}
+
+func newTestCollector(t *testing.T, vulns *options.VulnerabilitiesOptions) *ImagesCollectorImpl {
+	c := New(logrus.New(), &options.DockerImagesOptions{Scan: true, TempDir: t.TempDir()}, &options.PackagesOptions{}, vulns)
+	c.newClient = func() (image.DockerAPI, error) {
+		return &fakeDocker{saved: map[string][]byte{"sha256:app": savedImage(t)}}, nil
+	}
+	return c
+}
+
+func TestCollectImages(t *testing.T) {
+	imgs, err := newTestCollector(t, &options.VulnerabilitiesOptions{}).CollectImages(context.Background())
+	assert.NoError(t, err)
+	if !assert.Len(t, imgs, 2) {
+		return
+	}
+
+	app := imgs[0]
+	assert.Equal(t, "sha256:app", app.ID)
+	assert.Equal(t, "debian", app.OSName)
+	assert.Equal(t, "12", app.OSVersion)
+	if assert.Len(t, app.Packages, 1) {
+		assert.Equal(t, "openssl", app.Packages[0].Name)
+		assert.Equal(t, models.EcosystemDeb, app.Packages[0].Ecosystem)
+	}
+	assert.Empty(t, app.Error)
+	assert.Nil(t, app.Risk)
+
+	// An image failing to be unpacked does not prevent the others from being scanned
+	assert.Equal(t, "sha256:broken", imgs[1].ID)
+	assert.Contains(t, imgs[1].Error, "no such image")
+}
+
+func TestCollectImages_Vulnerabilities(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "vulndb")
+	_, err := vulnerability.ImportOSV(db, []string{"../../collectors/vulnerability/testdata/osv/DEBIAN-CVE-2024-0727.json"}, false)
+	assert.NoError(t, err)
+
+	imgs, err := newTestCollector(t, &options.VulnerabilitiesOptions{Enabled: true, Database: db}).CollectImages(context.Background())
+	assert.NoError(t, err)
+	app := imgs[0]
+	if assert.Len(t, app.Vulnerabilities, 1) {
+		assert.Equal(t, "CVE-2024-0727", app.Vulnerabilities[0].ID)
+		assert.Equal(t, "3.0.13-1~deb12u1", app.Vulnerabilities[0].FixedVersion)
+		assert.Empty(t, app.Vulnerabilities[0].Reachability)
+	}
+	if assert.NotNil(t, app.Risk) {
+		assert.NotEmpty(t, app.Risk.Rating)
+	}
+	assert.Nil(t, imgs[1].Risk)
+}
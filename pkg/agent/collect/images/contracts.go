@@ -0,0 +1,11 @@
+package images
+
+import (
+	"context"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+type ImagesCollector interface {
+	CollectImages(ctx context.Context) ([]*models.ImageInventory, error)
+}
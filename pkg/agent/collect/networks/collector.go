@@ -8,6 +8,7 @@ import (
 	"github.com/klamhq/facter-oss/pkg/agent/collectors/external"
 	"github.com/klamhq/facter-oss/pkg/agent/collectors/firewall"
 	"github.com/klamhq/facter-oss/pkg/agent/collectors/network"
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
 	"github.com/klamhq/facter-oss/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -18,6 +19,8 @@ import (
 type NetworksCollectorImpl struct {
 	log *logrus.Logger
 	cfg *options.NetworksOptions
+	// sockets are the sockets listed by CollectNetworks for the connections, reused by the next CollectSockets
+	sockets []*models.Socket
 }
 
 func New(log *logrus.Logger, cfg *options.NetworksOptions) *NetworksCollectorImpl {
@@ -115,13 +118,63 @@ func (c *NetworksCollectorImpl) CollectNetworks(ctx context.Context) (*schema.Ne
 	return networks, nil
 }
 
+// CollectFirewall returns the configuration of the nftables, firewalld and ufw backends, the backend managing
+// the rules and the rules of iptables, ip6tables and nftables in a normalised representation.
+func (c *NetworksCollectorImpl) CollectFirewall(ctx context.Context) (*models.FirewallInventory, error) {
+	if !utils.IsRoot() {
+		return nil, fmt.Errorf("unable to fetch firewall config without root privileges")
+	}
+	c.log.Info("Crafting firewall backends")
+	inv := firewall.Collect(ctx, c.log)
+	c.log.Debugf("Firewall managed by %s", inv.Backend)
+	return inv, nil
+}
+
+// CollectSockets returns the tcp, udp and, when enabled, unix sockets of the host with their state and owner. The
+// sockets listed by the previous CollectNetworks are reused, the sockets are listed once per run.
+func (c *NetworksCollectorImpl) CollectSockets(ctx context.Context) ([]*models.Socket, error) {
+	c.log.Info("Crafting sockets")
+	sockets := c.sockets
+	c.sockets = nil
+	if sockets == nil {
+		var err error
+		if sockets, err = network.Sockets(c.log); err != nil {
+			return nil, err
+		}
+	}
+	if c.cfg.Connections.Unix {
+		return sockets, nil
+	}
+	inet := make([]*models.Socket, 0, len(sockets))
+	for _, socket := range sockets {
+		if socket.Family != models.SocketFamilyUnix {
+			inet = append(inet, socket)
+		}
+	}
+	return inet, nil
+}
+
+// CollectRouting returns the routing tables, the policy routing rules, the neighbours and, when enabled, the
+// network namespaces of the host.
+func (c *NetworksCollectorImpl) CollectRouting(ctx context.Context) (*models.RoutingInventory, error) {
+	c.log.Info("Crafting routing")
+	inv, err := network.Routing(c.log, c.cfg.Routing.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+	c.log.Debugf("%d routes, %d rules and %d neighbours parsed", len(inv.Routes), len(inv.Rules), len(inv.Neighbours))
+	return inv, nil
+}
+
 func (c *NetworksCollectorImpl) craftConnections(networks *schema.Network) error {
 	c.log.Info("Crafting connections")
-	connections, err := network.Connections(c.log)
+	sockets, err := network.Sockets(c.log)
 	if err != nil {
 		c.log.Errorf("Error during crafting connections %v", err)
+		return nil
 	}
-	networks.Connections = connections
+	c.sockets = sockets
+	networks.Connections = network.Connections(c.log, sockets)
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/sirupsen/logrus"
@@ -48,6 +49,44 @@ func TestCollectNetworksWithConnections(t *testing.T) {
 	assert.NotEmpty(t, res.Connections)
 }
 
+func TestCollectSockets(t *testing.T) {
+	cfg := options.RunOptions{}
+	c := New(logrus.New(), &cfg.Facter.Inventory.Networks)
+	res, err := c.CollectSockets(context.Background())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res)
+	for _, socket := range res {
+		assert.NotEqual(t, models.SocketFamilyUnix, socket.Family, "unix sockets are disabled")
+	}
+}
+
+func TestCollectSocketsReused(t *testing.T) {
+	cfg := options.RunOptions{}
+	cfg.Facter.Inventory.Networks.Connections.Enabled = true
+	c := New(logrus.New(), &cfg.Facter.Inventory.Networks)
+	_, err := c.CollectNetworks(context.Background())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, c.sockets, "listed for the connections")
+	c.sockets = []*models.Socket{{Family: models.SocketFamilyInet, Protocol: models.SocketProtocolTcp, LocalPort: 22}}
+
+	res, err := c.CollectSockets(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Socket{{Family: models.SocketFamilyInet, Protocol: models.SocketProtocolTcp, LocalPort: 22}}, res)
+	assert.Nil(t, c.sockets, "the next run lists them again")
+}
+
+func TestCollectRouting(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip()
+	}
+	cfg := options.RunOptions{}
+	c := New(logrus.New(), &cfg.Facter.Inventory.Networks)
+	res, err := c.CollectRouting(context.Background())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Routes)
+	assert.Empty(t, res.Namespaces, "namespaces are disabled")
+}
+
 func TestCraftFirewallFail(t *testing.T) {
 	if runtime.GOOS != "darwin" {
 		t.Skip()
@@ -3,9 +3,13 @@ package networks
 import (
 	"context"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 )
 
 type NetworksCollector interface {
 	CollectNetworks(ctx context.Context) (*schema.Network, error)
+	CollectFirewall(ctx context.Context) (*models.FirewallInventory, error)
+	CollectSockets(ctx context.Context) ([]*models.Socket, error)
+	CollectRouting(ctx context.Context) (*models.RoutingInventory, error)
 }
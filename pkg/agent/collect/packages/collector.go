@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/klamhq/facter-oss/pkg/agent/collectors/packages"
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
 	"github.com/sirupsen/logrus"
 
@@ -13,22 +14,52 @@ import (
 type PackagesCollectorImpl struct {
 	log *logrus.Logger
 	cfg *options.PackagesOptions
+	// root is the directory the package files are verified under.
+	root string
 }
 
 func New(log *logrus.Logger, cfg *options.PackagesOptions) *PackagesCollectorImpl {
 
 	return &PackagesCollectorImpl{
-		log: log,
-		cfg: cfg,
+		log:  log,
+		cfg:  cfg,
+		root: "/",
 	}
 }
-func (c *PackagesCollectorImpl) CollectPackages(ctx context.Context) ([]*schema.Package, error) {
+
+// CollectPackages returns the packages of every package manager found on the host,
+// followed by the packages of language ecosystems when enabled.
+// Packages are kept as models, the ecosystem they come from has no field in the schema.
+func (c *PackagesCollectorImpl) CollectPackages(ctx context.Context) ([]*models.Package, error) {
 	c.log.Info("Crafting packages")
 
-	items, err := packages.NewPackagesGrabber(ctx, c.log)
+	pkgs, err := packages.NewPackagesGrabber(ctx, c.log)
 	if err != nil {
-		return nil, err
+		if !c.cfg.Languages.Enabled {
+			return nil, err
+		}
+		c.log.WithError(err).Warn("unable to read system packages")
+	}
+
+	if c.cfg.Languages.Enabled {
+		c.log.Info("Crafting language packages")
+		langPkgs, langErr := packages.NewLanguagePackagesGrabber(ctx, c.log, &c.cfg.Languages)
+		if langErr != nil {
+			c.log.WithError(langErr).Error("unable to read language packages")
+		}
+		pkgs = append(pkgs, langPkgs...)
 	}
+	return pkgs, nil
+}
+
+// CollectIntegrity returns the package files which no longer match the checksums of the package database.
+func (c *PackagesCollectorImpl) CollectIntegrity(ctx context.Context) ([]*models.ModifiedFile, error) {
+	c.log.Info("Verifying package files")
+	return packages.VerifyPackageFiles(ctx, c.log, c.root, c.cfg.Integrity.ConfigFiles)
+}
+
+// ToSchema converts packages to their schema representation.
+func ToSchema(items []*models.Package) []*schema.Package {
 	pkgs := make([]*schema.Package, 0, len(items))
 	for _, pkg := range items {
 
@@ -43,6 +74,5 @@ func (c *PackagesCollectorImpl) CollectPackages(ctx context.Context) ([]*schema.
 		pkgs = append(pkgs, pkgProto)
 
 	}
-	return pkgs, nil
-
+	return pkgs
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -23,3 +24,38 @@ func TestCollectPackages(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, res)
 }
+
+func TestCollectIntegrity(t *testing.T) {
+	cfg := options.RunOptions{}
+	p := New(logrus.New(), &cfg.Facter.Inventory.Packages)
+	// The binary matches its md5sum, the README does not
+	p.root = "testdata/integrity"
+	res, err := p.CollectIntegrity(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, res, 1) {
+		assert.Equal(t, "/usr/share/doc/hello/README", res[0].Path)
+		assert.Equal(t, "hello", res[0].Package)
+		assert.Equal(t, models.FileChanged, res[0].Reason)
+		assert.Equal(t, "b1946ac92492d2347c6235b4d2611184", res[0].Expected)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err = p.CollectIntegrity(ctx)
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
+
+func TestToSchema(t *testing.T) {
+	items := []*models.Package{
+		{Name: "curl", Version: "7.88.1-10", Architecture: "amd64", Description: "command line tool", UpgradableVersion: "7.88.1-10+deb12u5", Ecosystem: models.EcosystemDeb},
+		{Name: "curl", Version: "8.5.0", IsUpToDate: true, Ecosystem: models.EcosystemHomebrew},
+	}
+	res := ToSchema(items)
+	assert.Len(t, res, 2)
+	assert.Equal(t, "curl", res[0].Name)
+	assert.Equal(t, "amd64", res[0].Architecture)
+	assert.Equal(t, "7.88.1-10+deb12u5", res[0].UpgradableVersion)
+	assert.False(t, res[0].IsUpToDate)
+	assert.True(t, res[1].IsUpToDate)
+}
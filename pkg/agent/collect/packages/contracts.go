@@ -3,9 +3,10 @@ package packages
 import (
 	"context"
 
-	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/klamhq/facter-oss/pkg/models"
 )
 
 type PackagesCollector interface {
-	CollectPackages(ctx context.Context) ([]*schema.Package, error)
+	CollectPackages(ctx context.Context) ([]*models.Package, error)
+	CollectIntegrity(ctx context.Context) ([]*models.ModifiedFile, error)
 }
@@ -2,11 +2,10 @@ package vulnerability
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/klamhq/facter-oss/pkg/agent/collectors/vulnerability"
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
-	"github.com/klamhq/facter-oss/pkg/utils"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/sirupsen/logrus"
 )
@@ -14,6 +13,10 @@ import (
 type VulnerabilityCollectorImpl struct {
 	log *logrus.Logger
 	cfg *options.VulnerabilitiesOptions
+	// report is the last report collected and matches the matches it was built from, which keep the ecosystem
+	// and the collected package of the matches the schema has no field for.
+	report  *schema.VulnerabilityReport
+	matches []models.PackageVulnMatch
 }
 
 func New(log *logrus.Logger, cfg *options.VulnerabilitiesOptions) *VulnerabilityCollectorImpl {
@@ -24,14 +27,43 @@ func New(log *logrus.Logger, cfg *options.VulnerabilitiesOptions) *Vulnerability
 	}
 }
 
-func (c *VulnerabilityCollectorImpl) CollectVulnerability(ctx context.Context, packages []*schema.Package) (*schema.VulnerabilityReport, error) {
-	if !utils.CheckBinInstalled(c.log, "trivy") {
-		c.log.Error("Trivy is not installed, see here: https://trivy.dev/latest/getting-started/installation/ for installation instructions")
-		return nil, fmt.Errorf("trivy is not installed, vulnerabilities report will not generated")
+// CollectVulnerability runs the configured scanners, following the configured strategy.
+func (c *VulnerabilityCollectorImpl) CollectVulnerability(ctx context.Context, packages []*models.Package) (*schema.VulnerabilityReport, error) {
+	scanners, err := vulnerability.NewScanners(c.log, c.cfg)
+	if err != nil {
+		return nil, err
 	}
 	c.log.Info("Crafting packages vulnerabilities")
+	packageVulnMatch, err := vulnerability.RunScanners(ctx, c.log, scanners, c.cfg.Strategy, packages)
+	if err != nil {
+		return nil, err
+	}
+	c.report, c.matches = toSchema(packageVulnMatch), packageVulnMatch
+	return c.report, nil
+}
+
+// AssessVulnerabilities enriches the vulnerabilities of the installed packages from the configured feeds,
+// marks their reachability from the processes and connections collected, and rates them with the risk policy.
+// The matches of a report collected by CollectVulnerability are used as they are, other reports are read back
+// from the schema, without ecosystem.
+func (c *VulnerabilityCollectorImpl) AssessVulnerabilities(report *schema.VulnerabilityReport, processes []*schema.Process, network *schema.Network) ([]*models.VulnerabilityFinding, *models.HostRisk, error) {
+	matches := c.matches
+	if report != c.report {
+		matches = fromSchema(report)
+	}
+	feeds, err := vulnerability.LoadFeeds(c.cfg.Enrichment, vulnerability.VulnerabilityIDs(matches))
+	if err != nil {
+		return nil, nil, err
+	}
+	findings := vulnerability.Enrich(matches, feeds)
+	vulnerability.AssessReachability(findings, processes, network)
+	risk := vulnerability.RatePolicy(findings, c.cfg.Policy)
+	c.log.WithField("risk", risk.Rating).Infof("%d vulnerabilities assessed", len(findings))
+	return findings, risk, nil
+}
+
+func toSchema(packageVulnMatch []models.PackageVulnMatch) *schema.VulnerabilityReport {
 	vulnerabilityReport := &schema.VulnerabilityReport{}
-	packageVulnMatch := vulnerability.RunTrivyScan(ctx, c.log, packages)
 	vulnerabilityReport.Matches = make([]*schema.PackageVulnMatch, 0, len(packageVulnMatch))
 	for _, match := range packageVulnMatch {
 		pkgVulnMatch := &schema.PackageVulnMatch{
@@ -51,5 +83,28 @@ func (c *VulnerabilityCollectorImpl) CollectVulnerability(ctx context.Context, p
 		}
 		vulnerabilityReport.Matches = append(vulnerabilityReport.Matches, pkgVulnMatch)
 	}
-	return vulnerabilityReport, nil
+	return vulnerabilityReport
+}
+
+func fromSchema(report *schema.VulnerabilityReport) []models.PackageVulnMatch {
+	matches := make([]models.PackageVulnMatch, 0, len(report.GetMatches()))
+	for _, match := range report.GetMatches() {
+		m := models.PackageVulnMatch{
+			PackageName:      match.GetPackageName(),
+			InstalledVersion: match.GetInstalledVersion(),
+			Matched:          match.GetMatched(),
+			Vulnerabilities:  make([]models.MatchedVuln, 0, len(match.GetVulnerabilities())),
+		}
+		for _, vuln := range match.GetVulnerabilities() {
+			m.Vulnerabilities = append(m.Vulnerabilities, models.MatchedVuln{
+				VulnerabilityId: vuln.GetVulnerabilityId(),
+				Severity:        vuln.GetSeverity(),
+				Title:           vuln.GetTitle(),
+				Description:     vuln.GetDescription(),
+				FixedVersion:    vuln.GetFixedVersion(),
+			})
+		}
+		matches = append(matches, m)
+	}
+	return matches
 }
@@ -1,9 +1,12 @@
 package vulnerability
 
 import (
+	"context"
 	"testing"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -13,3 +16,57 @@ func TestNew(t *testing.T) {
 	res := New(logrus.New(), &cfg.Facter.Vulnerabilities)
 	assert.NotNil(t, res)
 }
+
+func TestCollectVulnerability_MissingDatabase(t *testing.T) {
+	cfg := options.VulnerabilitiesOptions{Enabled: true, Database: "/nonexistent/vulndb"}
+	res, err := New(logrus.New(), &cfg).CollectVulnerability(context.Background(), nil)
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
+
+func TestAssessVulnerabilities(t *testing.T) {
+	cfg := options.VulnerabilitiesOptions{Enabled: true}
+	report := &schema.VulnerabilityReport{Matches: []*schema.PackageVulnMatch{
+		{PackageName: "liblzma5", InstalledVersion: "5.6.0-0.2", Matched: true, Vulnerabilities: []*schema.MatchedVuln{{VulnerabilityId: "CVE-2024-3094", Severity: "CRITICAL"}}},
+		{PackageName: "curl", InstalledVersion: "7.68.0", Vulnerabilities: []*schema.MatchedVuln{{VulnerabilityId: "CVE-2023-38545", Severity: "CRITICAL"}}},
+	}}
+	findings, risk, err := New(logrus.New(), &cfg).AssessVulnerabilities(report, nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, "liblzma5", findings[0].Package)
+	assert.Equal(t, "", findings[0].Reachability)
+	assert.Equal(t, models.RiskCritical, risk.Rating)
+
+	processes := []*schema.Process{{Pid: 812, Name: "sshd", Package: &schema.Package{Name: "liblzma5"}}}
+	findings, risk, err = New(logrus.New(), &cfg).AssessVulnerabilities(report, processes, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReachabilityRunning, findings[0].Reachability)
+	assert.Equal(t, 0, risk.Exposed)
+
+	cfg.Enrichment.Kev = "/nonexistent/kev.json"
+	_, _, err = New(logrus.New(), &cfg).AssessVulnerabilities(report, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestAssessVulnerabilities_CollectedMatches(t *testing.T) {
+	cfg := options.VulnerabilitiesOptions{Enabled: true}
+	c := New(logrus.New(), &cfg)
+	vuln := models.MatchedVuln{VulnerabilityId: "CVE-2023-32681", Severity: "MEDIUM"}
+	c.matches = []models.PackageVulnMatch{
+		{PackageName: "requests", InstalledVersion: "2.28.1", Ecosystem: models.EcosystemPypi, Matched: true, Vulnerabilities: []models.MatchedVuln{vuln}},
+		{PackageName: "requests", InstalledVersion: "2.28.1", Ecosystem: models.EcosystemDeb, Matched: true, Vulnerabilities: []models.MatchedVuln{vuln}},
+	}
+	c.report = toSchema(c.matches)
+
+	findings, _, err := c.AssessVulnerabilities(c.report, nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, findings, 2)
+	assert.Equal(t, models.EcosystemPypi, findings[0].Ecosystem)
+	assert.Equal(t, models.EcosystemDeb, findings[1].Ecosystem)
+	assert.NotEqual(t, findings[0].Key(), findings[1].Key())
+
+	// Another report is read back from the schema
+	findings, _, err = c.AssessVulnerabilities(toSchema(c.matches), nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, findings[0].Ecosystem)
+}
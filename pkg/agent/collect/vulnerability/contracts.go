@@ -3,9 +3,11 @@ package vulnerability
 import (
 	"context"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 )
 
 type VulnerabilityCollector interface {
-	CollectVulnerability(ctx context.Context, packages []*schema.Package) (*schema.VulnerabilityReport, error)
+	CollectVulnerability(ctx context.Context, packages []*models.Package) (*schema.VulnerabilityReport, error)
+	AssessVulnerabilities(report *schema.VulnerabilityReport, processes []*schema.Process, network *schema.Network) ([]*models.VulnerabilityFinding, *models.HostRisk, error)
 }
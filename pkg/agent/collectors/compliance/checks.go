@@ -0,0 +1,285 @@
+package compliance
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// XCCDF results of the rules, see https://csrc.nist.gov/pubs/ir/7275/r4/final.
+const (
+	ResultPass          = "pass"
+	ResultFail          = "fail"
+	ResultError         = "error"
+	ResultNotApplicable = "notapplicable"
+	ResultNotChecked    = "notchecked"
+)
+
+// evaluate returns the result of a check on the host.
+func (e *evaluation) evaluate(c *Check) string {
+	switch {
+	case len(c.All) > 0:
+		return e.all(c.All)
+	case len(c.Any) > 0:
+		return e.any(c.Any)
+	case c.FileContent != nil:
+		return e.fileContent(c.FileContent)
+	case c.FileMode != nil:
+		return e.fileMode(c.FileMode)
+	case c.Sysctl != nil:
+		return e.sysctl(c.Sysctl)
+	case c.KernelModule != nil:
+		return e.kernelModule(c.KernelModule)
+	case c.Service != nil:
+		return e.service(c.Service)
+	case c.Package != nil:
+		return e.pkg(c.Package)
+	case c.Fact != nil:
+		return e.fact(c.Fact)
+	}
+	return ResultError
+}
+
+// all fails when any check fails, checks not applicable are ignored.
+func (e *evaluation) all(checks []Check) string {
+	result := ResultNotApplicable
+	for i := range checks {
+		switch r := e.evaluate(&checks[i]); r {
+		case ResultFail:
+			return ResultFail
+		case ResultError, ResultNotChecked:
+			result = r
+		case ResultPass:
+			if result == ResultNotApplicable {
+				result = ResultPass
+			}
+		}
+	}
+	return result
+}
+
+// any passes when any check passes.
+func (e *evaluation) any(checks []Check) string {
+	result := ResultNotApplicable
+	for i := range checks {
+		switch r := e.evaluate(&checks[i]); r {
+		case ResultPass:
+			return ResultPass
+		case ResultError, ResultNotChecked:
+			result = r
+		case ResultFail:
+			if result == ResultNotApplicable {
+				result = ResultFail
+			}
+		}
+	}
+	return result
+}
+
+func orDefault(result, fallback string) string {
+	if result == "" {
+		return fallback
+	}
+	return result
+}
+
+// glob returns the files of the patterns under the root, paths are returned relative to the host.
+func (e *evaluation) glob(patterns []string) []string {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(e.path(pattern))
+		if err != nil {
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return files
+}
+
+func (e *evaluation) path(name string) string {
+	return filepath.Join(e.root, name)
+}
+
+func (e *evaluation) fileContent(c *FileContentCheck) string {
+	files := e.glob(c.Paths)
+	found, read := false, false
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		read = true
+		if c.re.Match(data) {
+			found = true
+			break
+		}
+	}
+	if !read {
+		return orDefault(c.Missing, ResultFail)
+	}
+	if found != c.Absent {
+		return ResultPass
+	}
+	return ResultFail
+}
+
+func (e *evaluation) fileMode(c *FileModeCheck) string {
+	files := e.glob(c.Paths)
+	if len(files) == 0 {
+		return orDefault(c.Missing, ResultFail)
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return ResultError
+		}
+		if c.Mode != "" && uint32(info.Mode().Perm())&^c.mode != 0 {
+			return ResultFail
+		}
+		st, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return ResultNotChecked
+		}
+		if c.Owner != "" && !e.sameID(c.Owner, st.Uid, "etc/passwd") {
+			return ResultFail
+		}
+		if c.Group != "" && !e.sameID(c.Group, st.Gid, "etc/group") {
+			return ResultFail
+		}
+	}
+	return ResultPass
+}
+
+// sameID reports whether a user or group, by name or id, has the id.
+func (e *evaluation) sameID(name string, id uint32, database string) bool {
+	if n, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(n) == id
+	}
+	f, err := os.Open(e.path(database))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) > 2 && fields[0] == name {
+			return fields[2] == strconv.FormatUint(uint64(id), 10)
+		}
+	}
+	return false
+}
+
+func (e *evaluation) sysctl(c *SysctlCheck) string {
+	data, err := os.ReadFile(e.path(filepath.Join("proc/sys", strings.ReplaceAll(c.Key, ".", "/"))))
+	if err != nil {
+		return orDefault(c.Missing, ResultNotApplicable)
+	}
+	// Multi-valued parameters are separated by tabs in /proc/sys
+	if strings.Join(strings.Fields(string(data)), " ") == strings.Join(strings.Fields(c.Value), " ") {
+		return ResultPass
+	}
+	return ResultFail
+}
+
+// modprobeDirs hold the modprobe configuration, see modprobe.d(5).
+var modprobeDirs = []string{"etc/modprobe.d", "run/modprobe.d", "usr/local/lib/modprobe.d", "usr/lib/modprobe.d", "lib/modprobe.d"}
+
+func (e *evaluation) kernelModule(c *KernelModuleCheck) string {
+	// Module names are written with underscores in /proc/modules
+	name := strings.ReplaceAll(c.Name, "-", "_")
+	if data, err := os.ReadFile(e.path("proc/modules")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if fields := strings.Fields(line); len(fields) > 0 && fields[0] == name {
+				return ResultFail
+			}
+		}
+	}
+	for _, dir := range modprobeDirs {
+		files, _ := filepath.Glob(filepath.Join(e.path(dir), "*.conf"))
+		for _, file := range files {
+			if modprobeDisables(file, name) {
+				return ResultPass
+			}
+		}
+	}
+	return ResultFail
+}
+
+// modprobeDisables reports whether a modprobe configuration file blacklists a module or
+// replaces its loading by a command doing nothing.
+func modprobeDisables(file, name string) bool {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.ReplaceAll(fields[1], "-", "_") != name {
+			continue
+		}
+		switch fields[0] {
+		case "blacklist":
+			return true
+		case "install":
+			if len(fields) > 2 && (strings.HasSuffix(fields[2], "/false") || strings.HasSuffix(fields[2], "/true")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// systemdUnitDirs hold the enablement links of the systemd units, the administrator ones first.
+var systemdUnitDirs = []string{"etc/systemd/system", "run/systemd/system", "usr/lib/systemd/system", "lib/systemd/system"}
+
+func (e *evaluation) service(c *ServiceCheck) string {
+	if e.serviceEnabled(c.Name) == c.Enabled {
+		return ResultPass
+	}
+	return ResultFail
+}
+
+// serviceEnabled looks up the systemd wants links of the unit, or the OpenRC runlevels. A masked unit is disabled.
+func (e *evaluation) serviceEnabled(name string) bool {
+	units := []string{name}
+	if !strings.Contains(name, ".") {
+		units = []string{name + ".service", name + ".socket"}
+	}
+	for _, unit := range units {
+		if target, err := os.Readlink(e.path(filepath.Join("etc/systemd/system", unit))); err == nil && target == "/dev/null" {
+			return false
+		}
+	}
+	for _, dir := range systemdUnitDirs {
+		for _, unit := range units {
+			links, _ := filepath.Glob(filepath.Join(e.path(dir), "*.wants", unit))
+			if len(links) > 0 {
+				return true
+			}
+		}
+	}
+	runlevels, _ := filepath.Glob(e.path(filepath.Join("etc/runlevels", "*", name)))
+	return len(runlevels) > 0
+}
+
+func (e *evaluation) pkg(c *PackageCheck) string {
+	if e.facts.Packages == nil {
+		return ResultNotChecked
+	}
+	installed := false
+	for _, pkg := range e.facts.Packages {
+		for _, name := range c.Names {
+			if pkg.Name == name {
+				installed = true
+			}
+		}
+	}
+	if installed == c.Installed {
+		return ResultPass
+	}
+	return ResultFail
+}
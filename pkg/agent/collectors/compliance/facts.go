@@ -0,0 +1,145 @@
+package compliance
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// inventoryDocument returns the inventory as the generic JSON document the fact paths are resolved in.
+func (e *evaluation) inventoryDocument() (any, error) {
+	if e.document != nil || e.facts.Inventory == nil {
+		return e.document, nil
+	}
+	bin, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(e.facts.Inventory)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bin, &e.document); err != nil {
+		return nil, err
+	}
+	return e.document, nil
+}
+
+func (e *evaluation) fact(c *FactCheck) string {
+	doc, err := e.inventoryDocument()
+	if err != nil {
+		e.logger.WithError(err).Debug("unable to read the inventory facts")
+		return ResultError
+	}
+	if doc == nil {
+		return ResultNotChecked
+	}
+	values := lookup(doc, strings.Split(c.Path, "."))
+
+	switch c.Op {
+	case "exists", "absent":
+		if (len(values) > 0) == (c.Op == "exists") {
+			return ResultPass
+		}
+		return ResultFail
+	case "count":
+		if strconv.Itoa(len(values)) == c.Value {
+			return ResultPass
+		}
+		return ResultFail
+	}
+	if len(values) == 0 {
+		return orDefault(c.Missing, ResultNotApplicable)
+	}
+	matched := 0
+	for _, value := range values {
+		if c.compare(value) {
+			matched++
+		}
+	}
+	if (c.Any && matched > 0) || (!c.Any && matched == len(values)) {
+		return ResultPass
+	}
+	return ResultFail
+}
+
+// compare applies the operator of the check to a value of the inventory.
+func (c *FactCheck) compare(value string) bool {
+	switch c.Op {
+	case "equals":
+		return value == c.Value
+	case "not_equals":
+		return value != c.Value
+	case "matches":
+		return c.re.MatchString(value)
+	case "not_matches":
+		return !c.re.MatchString(value)
+	}
+	v, err1 := strconv.ParseFloat(value, 64)
+	ref, err2 := strconv.ParseFloat(c.Value, 64)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	switch c.Op {
+	case "lt":
+		return v < ref
+	case "le":
+		return v <= ref
+	case "gt":
+		return v > ref
+	case "ge":
+		return v >= ref
+	}
+	return false
+}
+
+// lookup returns the scalar values at a path of the document. A segment name[] walks every element of a
+// list, name[field=value] only the elements whose field has the value.
+func lookup(node any, segments []string) []string {
+	if len(segments) == 0 {
+		switch v := node.(type) {
+		case nil, map[string]any:
+			return nil
+		case []any:
+			var values []string
+			for _, item := range v {
+				values = append(values, lookup(item, nil)...)
+			}
+			return values
+		case string:
+			return []string{v}
+		case float64:
+			return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+		default:
+			return []string{fmt.Sprint(v)}
+		}
+	}
+
+	name, filter, isList := strings.Cut(segments[0], "[")
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return nil
+	}
+	child, ok := obj[name]
+	if !ok {
+		return nil
+	}
+	if !isList {
+		return lookup(child, segments[1:])
+	}
+	items, ok := child.([]any)
+	if !ok {
+		return nil
+	}
+	field, want, hasFilter := strings.Cut(strings.TrimSuffix(filter, "]"), "=")
+	var values []string
+	for _, item := range items {
+		if hasFilter {
+			got := lookup(item, []string{field})
+			if len(got) != 1 || got[0] != want {
+				continue
+			}
+		}
+		values = append(values, lookup(item, segments[1:])...)
+	}
+	return values
+}
@@ -0,0 +1,127 @@
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/osrelease"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// Compliance engines.
+const (
+	EngineOpenSCAP = "openscap"
+	EngineNative   = "native"
+	EngineAuto     = "auto" // OpenSCAP when installed with a datastream for the OS, native otherwise
+)
+
+// NativeProfilePrefix prefixes the profile of the reports of the native engine, followed by the packs evaluated.
+const NativeProfilePrefix = "facter:"
+
+// Facts are the collected data the package and fact checks are evaluated on.
+type Facts struct {
+	Inventory *schema.HostInventory
+	// Packages of the host, nil when they were not collected.
+	Packages []*models.Package
+}
+
+// NativeEngine evaluates YAML rules on the host, without any external tool.
+type NativeEngine struct {
+	Logger  *logrus.Logger
+	Rules   []*Rule
+	Profile string
+	// Root is the filesystem the files are read from, / for the host.
+	Root string
+}
+
+// NewNativeEngine loads the bundled packs and the rule directories.
+func NewNativeEngine(logger *logrus.Logger, packs, ruleDirs []string) (*NativeEngine, error) {
+	rules, err := LoadRules(packs, ruleDirs)
+	if err != nil {
+		return nil, err
+	}
+	names := packs
+	if len(names) == 0 && len(ruleDirs) == 0 {
+		names = []string{DefaultPack}
+	}
+	if len(ruleDirs) > 0 {
+		names = append(slices.Clone(names), "custom")
+	}
+	return &NativeEngine{Logger: logger, Rules: rules, Profile: NativeProfilePrefix + strings.Join(names, "+"), Root: "/"}, nil
+}
+
+// Select keeps the rules of the ids, every rule when empty, except the skipped ones.
+func (n *NativeEngine) Select(ids, skip []string) {
+	n.Rules = slices.DeleteFunc(n.Rules, func(r *Rule) bool {
+		return (len(ids) > 0 && !slices.Contains(ids, r.ID)) || slices.Contains(skip, r.ID)
+	})
+}
+
+// evaluation holds the state of a run of the rules.
+type evaluation struct {
+	logger   *logrus.Logger
+	root     string
+	facts    *Facts
+	document any
+}
+
+// Evaluate runs the rules applicable to the platform of the host. The score is the percentage of the
+// rules passing among the rules passing or failing.
+func (n *NativeEngine) Evaluate(ctx context.Context, facts *Facts) (*models.ComplianceReport, error) {
+	if facts == nil {
+		facts = &Facts{}
+	}
+	e := &evaluation{logger: n.Logger, root: n.Root, facts: facts}
+	platforms := osrelease.IDs(osrelease.Read(n.Root))
+
+	report := &models.ComplianceReport{Profile: n.Profile, Score: models.Score{Maximum: "100"}}
+	passed, checked := 0, 0
+	for _, rule := range n.Rules {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result := ResultNotApplicable
+		if rule.applies(platforms) {
+			result = e.evaluate(&rule.Check)
+		}
+		switch result {
+		case ResultPass:
+			passed++
+			checked++
+		case ResultFail:
+			checked++
+		}
+		report.RuleResults = append(report.RuleResults, models.RuleCheckResult{
+			ID:          rule.ID,
+			Title:       rule.Title,
+			Description: strings.TrimSpace(rule.Description),
+			Result:      result,
+			Severity:    rule.Severity,
+			Fix:         rule.Fix,
+		})
+	}
+	score := 0.0
+	if checked > 0 {
+		score = 100 * float64(passed) / float64(checked)
+	}
+	report.Score.Value = fmt.Sprintf("%f", score)
+	n.Logger.WithField("profile", n.Profile).Infof("%d of %d compliance rules passed", passed, checked)
+	return report, nil
+}
+
+// applies reports whether the rule is evaluated on a host of the os-release ID and ID_LIKE values.
+func (r *Rule) applies(platforms []string) bool {
+	if len(r.Platforms) == 0 {
+		return true
+	}
+	for _, platform := range r.Platforms {
+		if slices.Contains(platforms, platform) {
+			return true
+		}
+	}
+	return false
+}
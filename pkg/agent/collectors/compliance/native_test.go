@@ -0,0 +1,189 @@
+package compliance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRootFile(t *testing.T, root, name, content string, mode os.FileMode) {
+	path := filepath.Join(root, name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), mode))
+	assert.NoError(t, os.Chmod(path, mode))
+}
+
+// testRoot returns a debian host, hardened for some of the bundled rules only.
+func testRoot(t *testing.T) string {
+	root := t.TempDir()
+	writeRootFile(t, root, "etc/os-release", "ID=debian\nVERSION_ID=\"12\"\n", 0o644)
+	writeRootFile(t, root, "etc/passwd", "root:x:0:0:root:/root:/bin/bash\n", 0o644)
+	writeRootFile(t, root, "etc/group", "root:x:0:\n", 0o666)
+	writeRootFile(t, root, "etc/ssh/sshd_config", "Include /etc/ssh/sshd_config.d/*.conf\nPermitRootLogin yes\nX11Forwarding yes\n", 0o600)
+	writeRootFile(t, root, "etc/ssh/sshd_config.d/hardening.conf", "MaxAuthTries 3\n", 0o600)
+	writeRootFile(t, root, "etc/modprobe.d/cramfs.conf", "install cramfs /bin/false\n", 0o644)
+	writeRootFile(t, root, "etc/modprobe.d/blacklist.conf", "blacklist jffs2\nblacklist hfs\n", 0o644)
+	writeRootFile(t, root, "proc/modules", "hfs 12288 0 - Live 0x0000000000000000\n", 0o444)
+	writeRootFile(t, root, "proc/sys/net/ipv4/ip_forward", "1\n", 0o644)
+	writeRootFile(t, root, "proc/sys/kernel/randomize_va_space", "2\n", 0o644)
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "etc/systemd/system/multi-user.target.wants"), 0o755))
+	assert.NoError(t, os.Symlink("/lib/systemd/system/chrony.service", filepath.Join(root, "etc/systemd/system/multi-user.target.wants/chrony.service")))
+	assert.NoError(t, os.Symlink("/lib/systemd/system/cups.service", filepath.Join(root, "etc/systemd/system/multi-user.target.wants/cups.service")))
+	assert.NoError(t, os.Symlink("/dev/null", filepath.Join(root, "etc/systemd/system/avahi-daemon.service")))
+	return root
+}
+
+func resultsByID(report *models.ComplianceReport) map[string]string {
+	results := map[string]string{}
+	for _, r := range report.RuleResults {
+		results[r.ID] = r.Result
+	}
+	return results
+}
+
+func TestLoadRules(t *testing.T) {
+	assert.ElementsMatch(t, []string{"cis-linux-l1", "cis-linux-l2"}, BundledPacks())
+
+	rules, err := LoadRules(nil, nil)
+	assert.NoError(t, err)
+	ids := map[string]bool{}
+	for _, rule := range rules {
+		assert.False(t, ids[rule.ID], "duplicated rule %s", rule.ID)
+		ids[rule.ID] = true
+		assert.NotEmpty(t, rule.Title, rule.ID)
+		assert.NotEmpty(t, rule.Severity, rule.ID)
+	}
+	assert.True(t, ids["sshd_disable_root_login"])
+
+	_, err = LoadRules([]string{"cis-windows"}, nil)
+	assert.ErrorContains(t, err, "cis-linux-l1")
+
+	// Custom rules replace the bundled rules of the same id
+	dir := t.TempDir()
+	writeRootFile(t, dir, "custom.yml", `
+name: custom
+rules:
+  - id: sshd_disable_root_login
+    title: Root login only with keys
+    severity: medium
+    check:
+      file_content: {paths: [/etc/ssh/sshd_config], pattern: '(?m)^PermitRootLogin prohibit-password$'}
+  - id: motd_present
+    title: A message of the day is set
+    severity: low
+    check:
+      file_mode: {paths: [/etc/motd], mode: "0644"}
+`, 0o644)
+	custom, err := LoadRules([]string{DefaultPack}, []string{dir})
+	assert.NoError(t, err)
+	assert.Len(t, custom, len(rules)+1)
+	for _, rule := range custom {
+		if rule.ID == "sshd_disable_root_login" {
+			assert.Equal(t, "Root login only with keys", rule.Title)
+		}
+	}
+
+	writeRootFile(t, dir, "invalid.yml", `
+rules:
+  - id: two_checks
+    check:
+      sysctl: {key: kernel.randomize_va_space, value: "2"}
+      service: {name: sshd, enabled: true}
+`, 0o644)
+	_, err = LoadRules(nil, []string{dir})
+	assert.ErrorContains(t, err, "two_checks")
+}
+
+func TestNativeEngine_Evaluate(t *testing.T) {
+	engine, err := NewNativeEngine(logrus.New(), nil, nil)
+	assert.NoError(t, err)
+	engine.Root = testRoot(t)
+	assert.Equal(t, "facter:cis-linux-l1", engine.Profile)
+
+	facts := &Facts{
+		Inventory: &schema.HostInventory{Users: []*schema.User{
+			{Username: "root", Uid: "0"},
+			{Username: "toor", Uid: "0"},
+			{Username: "alice", Uid: "1000"},
+		}},
+		Packages: []*models.Package{{Name: "inetutils-telnet", Version: "2.4-2"}, {Name: "apparmor", Version: "3.0.8-3"}},
+	}
+	report, err := engine.Evaluate(context.Background(), facts)
+	assert.NoError(t, err)
+	results := resultsByID(report)
+
+	assert.Equal(t, ResultPass, results["kernel_module_cramfs_disabled"])
+	assert.Equal(t, ResultPass, results["kernel_module_jffs2_disabled"])
+	assert.Equal(t, ResultFail, results["kernel_module_hfs_disabled"], "loaded")
+	assert.Equal(t, ResultFail, results["kernel_module_freevxfs_disabled"])
+
+	assert.Equal(t, ResultPass, results["file_permissions_etc_passwd"])
+	assert.Equal(t, ResultFail, results["file_permissions_etc_group"])
+	assert.Equal(t, ResultFail, results["file_permissions_etc_shadow"], "missing")
+	assert.Equal(t, ResultNotApplicable, results["file_permissions_crontab"])
+
+	assert.Equal(t, ResultFail, results["sysctl_net_ipv4_ip_forward"])
+	assert.Equal(t, ResultPass, results["sysctl_kernel_randomize_va_space"])
+	assert.Equal(t, ResultNotApplicable, results["sysctl_net_ipv4_tcp_syncookies"])
+
+	assert.Equal(t, ResultFail, results["sshd_disable_root_login"])
+	assert.Equal(t, ResultFail, results["sshd_disable_x11_forwarding"])
+	assert.Equal(t, ResultPass, results["sshd_disable_empty_passwords"])
+	assert.Equal(t, ResultPass, results["sshd_max_auth_tries"])
+
+	assert.Equal(t, ResultPass, results["service_time_synchronization_enabled"])
+	assert.Equal(t, ResultFail, results["service_cups_disabled"])
+	assert.Equal(t, ResultPass, results["service_avahi_daemon_disabled"], "masked")
+
+	assert.Equal(t, ResultFail, results["package_telnet_removed"])
+	assert.Equal(t, ResultPass, results["package_nis_removed"])
+	assert.Equal(t, ResultPass, results["package_apparmor_installed"])
+	assert.Equal(t, ResultNotApplicable, results["selinux_state_enforcing"], "debian host")
+
+	assert.Equal(t, ResultFail, results["accounts_no_uid_except_zero"])
+
+	assert.Equal(t, "100", report.Score.Maximum)
+	assert.NotEqual(t, "0.000000", report.Score.Value)
+
+	// Without collected packages nor inventory, their checks are not evaluated
+	report, err = engine.Evaluate(context.Background(), nil)
+	assert.NoError(t, err)
+	results = resultsByID(report)
+	assert.Equal(t, ResultNotChecked, results["package_telnet_removed"])
+	assert.Equal(t, ResultNotChecked, results["accounts_no_uid_except_zero"])
+
+	engine.Select([]string{"sshd_disable_root_login", "sshd_max_auth_tries", "package_nis_removed"}, []string{"package_nis_removed"})
+	report, err = engine.Evaluate(context.Background(), facts)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"sshd_disable_root_login": ResultFail, "sshd_max_auth_tries": ResultPass}, resultsByID(report))
+	assert.Equal(t, "50.000000", report.Score.Value)
+}
+
+func TestLookup(t *testing.T) {
+	doc := map[string]any{
+		"hostname": "web-01",
+		"users": []any{
+			map[string]any{"username": "root", "uid": "0", "can_become_root": true},
+			map[string]any{"username": "alice", "uid": "1000"},
+		},
+		"platform": map[string]any{"kernel": map[string]any{"release": "6.1.0"}, "uptime": float64(86400)},
+	}
+	assert.Equal(t, []string{"web-01"}, lookup(doc, []string{"hostname"}))
+	assert.Equal(t, []string{"root", "alice"}, lookup(doc, []string{"users[]", "username"}))
+	assert.Equal(t, []string{"root"}, lookup(doc, []string{"users[uid=0]", "username"}))
+	assert.Equal(t, []string{"root"}, lookup(doc, []string{"users[can_become_root=true]", "username"}))
+	assert.Equal(t, []string{"86400"}, lookup(doc, []string{"platform", "uptime"}))
+	assert.Empty(t, lookup(doc, []string{"platform", "os", "name"}))
+	assert.Empty(t, lookup(doc, []string{"platform"}))
+
+	check := &Check{Fact: &FactCheck{Path: "platform.uptime", Op: "ge", Value: "3600"}}
+	assert.NoError(t, check.compile())
+	assert.True(t, check.Fact.compare("86400"))
+	assert.False(t, check.Fact.compare("60"))
+}
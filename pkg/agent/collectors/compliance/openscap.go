@@ -3,11 +3,16 @@ package compliance
 import (
 	"bytes"
 	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"slices"
 	"strings"
-	"syscall"
 
 	"github.com/gocomply/scap/pkg/scap/models/cdf"
 	"github.com/gocomply/scap/pkg/scap/scap_document"
@@ -17,6 +22,15 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// Remediation systems of the XCCDF fixes.
+const (
+	FixSystemShell   = "urn:xccdf:fix:script:sh"
+	FixSystemAnsible = "urn:xccdf:fix:script:ansible"
+)
+
+// subPattern matches the references to the values of the benchmark in a fix.
+var subPattern = regexp.MustCompile(`<(?:\w+:)?sub\s+idref="([^"]*)"[^>]*/>`)
+
 // collectAllRules recursively collects all rules from groups and stores them in a map.
 // This is useful for quickly accessing rules by their ID.
 func collectAllRules(groups []cdf.GroupType, rules map[string]*cdf.RuleType) {
@@ -30,82 +44,62 @@ func collectAllRules(groups []cdf.GroupType, rules map[string]*cdf.RuleType) {
 	}
 }
 
-func GetDataStreamFile(osName, version string) string {
-	osName = strings.ToLower(osName)
-	// Rocky, Alma, RHEL : ssg-rl9-ds.xml, ssg-almalinux9-ds.xml
-	if osName == "rocky" || osName == "almalinux" || osName == "rhel" {
-		parts := strings.Split(version, ".")
-		major := parts[0]
-		prefix := map[string]string{
-			"rocky":     "rl",
-			"almalinux": "almalinux",
-			"rhel":      "rhel",
-		}[osName]
-		return fmt.Sprintf("/usr/share/xml/scap/ssg/content/ssg-%s%s-ds.xml", prefix, major)
-	}
-	// Ubuntu : ssg-ubuntu2404-ds.xml (YYMM sans point)
-	if osName == "ubuntu" {
-		versionNum := strings.ReplaceAll(version, ".", "")
-		return fmt.Sprintf("/usr/share/xml/scap/ssg/content/ssg-ubuntu%s-ds.xml", versionNum)
-	}
-	// Debian : ssg-debian12-ds.xml
-	if osName == "debian" {
-		parts := strings.Split(version, ".")
-		major := parts[0]
-		return fmt.Sprintf("/usr/share/xml/scap/ssg/content/ssg-debian%s-ds.xml", major)
-	}
-	// Fedora : ssg-fedora-ds.xml (rolling)
-	if osName == "fedora" {
-		return "/usr/share/xml/scap/ssg/content/ssg-fedora-ds.xml"
+// collectAllValues collects the active values of the groups, by id.
+func collectAllValues(groups []cdf.GroupType, values map[string]string) {
+	for _, group := range groups {
+		addValues(group.Value, values)
+		collectAllValues(group.Group, values)
 	}
-	return ""
 }
 
-// Oscap runs an OpenSCAP audit and collects the results.
-func Oscap(ctx context.Context, cfg *options.ComplianceOptions, operatingSystem *schema.Os, logger *logrus.Logger) (*models.ComplianceReport, error) {
-	dataStreamFile := GetDataStreamFile(operatingSystem.Name, operatingSystem.Version)
-	if cfg.Profile == "" {
-		logger.Warn("No OpenSCAP profile specified, using default 'xccdf_org.ssgproject.content_profile_cis'")
-		cfg.Profile = "xccdf_org.ssgproject.content_profile_cis"
-	}
-
-	if cfg.ResultFile == "" {
-		logger.Warn("No OpenSCAP results file specified, using default 'results.xml'")
-		cfg.ResultFile = "results.xml"
+// addValues adds the values without selector, or the first value, of the XCCDF values.
+func addValues(xccdfValues []cdf.ValueType, values map[string]string) {
+	for _, v := range xccdfValues {
+		for i, sel := range v.Value {
+			if i == 0 || sel.Selector == "" {
+				values[v.Id] = sel.Text
+			}
+			if sel.Selector == "" {
+				break
+			}
+		}
 	}
-	cmd := exec.CommandContext(ctx, "oscap", "xccdf", "eval", "--profile", cfg.Profile, "--results", cfg.ResultFile, dataStreamFile)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
-				code := status.ExitStatus()
-				if code == 1 {
-					return nil, fmt.Errorf("OpenSCAP command failed with exit code 1: %s", stderr.String())
-				}
-				if code == 2 {
+}
 
-				}
-			}
-		} else {
-			logger.Errorf("Error executing oscap command: %v", err)
-			return nil, err
+// fixScript returns the fix of the system with the references to the values resolved. It is empty when the rule
+// has no fix for the system, the rule is then remediated manually.
+func fixScript(fixes []cdf.FixType, system string, values map[string]string) string {
+	for _, fix := range fixes {
+		if fix.System != system {
+			continue
 		}
+		script := subPattern.ReplaceAllStringFunc(fix.InnerXml, func(sub string) string {
+			return values[subPattern.FindStringSubmatch(sub)[1]]
+		})
+		return html.UnescapeString(script)
 	}
-	// Read the OpenSCAP document from the results file
-	data, err := scap_document.ReadDocumentFromFile(cfg.ResultFile)
+	return ""
+}
+
+// ReadResults reads the rule results of an XCCDF results file written by oscap xccdf eval --results.
+// The fix of a rule is its fix for the system, e.g. FixSystemShell, empty when it has none.
+func ReadResults(file, fixSystem string) (*models.ComplianceReport, error) {
+	data, err := scap_document.ReadDocumentFromFile(file)
 	if err != nil {
-		logger.Errorf("Error reading OpenSCAP document: %v", err)
 		return nil, err
 	}
+	if data.Benchmark == nil {
+		return nil, fmt.Errorf("%s is not an XCCDF results file", file)
+	}
 
 	ruleMap := make(map[string]*cdf.RuleType)
 	for _, rule := range data.Rule {
 		ruleMap[rule.Id] = &rule
 	}
 	collectAllRules(data.Group, ruleMap)
+	values := make(map[string]string)
+	addValues(data.Value, values)
+	collectAllValues(data.Group, values)
 
 	report := models.ComplianceReport{}
 	for _, testResult := range data.TestResult {
@@ -117,7 +111,13 @@ func Oscap(ctx context.Context, cfg *options.ComplianceOptions, operatingSystem
 			}
 		}
 		report.Score = score
-		report.Profile = cfg.Profile
+		if testResult.Profile != nil {
+			report.Profile = testResult.Profile.Idref
+		}
+		// The values set by the profile
+		for _, v := range testResult.SetValue {
+			values[v.Idref] = v.Text
+		}
 
 		for _, ruleResult := range testResult.RuleResult {
 			if ruleResult.Result != "notselected" {
@@ -130,9 +130,7 @@ func Oscap(ctx context.Context, cfg *options.ComplianceOptions, operatingSystem
 					if len(rule.Description) > 0 {
 						description = rule.Description[0].InnerXml // ou .Value
 					}
-					if len(rule.Fix) > 0 {
-						fix = rule.Fix[0].InnerXml // ou .Value
-					}
+					fix = fixScript(rule.Fix, fixSystem, values)
 					report.RuleResults = append(report.RuleResults, models.RuleCheckResult{
 						ID:          ruleResult.Idref,
 						Title:       title,
@@ -152,11 +150,203 @@ func Oscap(ctx context.Context, cfg *options.ComplianceOptions, operatingSystem
 			}
 		}
 	}
+	return &report, nil
+}
+
+func GetDataStreamFile(osName, version string) string {
+	osName = strings.ToLower(osName)
+	// Rocky, Alma, RHEL : ssg-rl9-ds.xml, ssg-almalinux9-ds.xml
+	if osName == "rocky" || osName == "almalinux" || osName == "rhel" {
+		parts := strings.Split(version, ".")
+		major := parts[0]
+		prefix := map[string]string{
+			"rocky":     "rl",
+			"almalinux": "almalinux",
+			"rhel":      "rhel",
+		}[osName]
+		return fmt.Sprintf("/usr/share/xml/scap/ssg/content/ssg-%s%s-ds.xml", prefix, major)
+	}
+	// Ubuntu : ssg-ubuntu2404-ds.xml (YYMM sans point)
+	if osName == "ubuntu" {
+		versionNum := strings.ReplaceAll(version, ".", "")
+		return fmt.Sprintf("/usr/share/xml/scap/ssg/content/ssg-ubuntu%s-ds.xml", versionNum)
+	}
+	// Debian : ssg-debian12-ds.xml
+	if osName == "debian" {
+		parts := strings.Split(version, ".")
+		major := parts[0]
+		return fmt.Sprintf("/usr/share/xml/scap/ssg/content/ssg-debian%s-ds.xml", major)
+	}
+	// Fedora : ssg-fedora-ds.xml (rolling)
+	if osName == "fedora" {
+		return "/usr/share/xml/scap/ssg/content/ssg-fedora-ds.xml"
+	}
+	return ""
+}
+
+// DefaultProfile is the profile evaluated when none is configured, when the datastream provides it.
+const DefaultProfile = "xccdf_org.ssgproject.content_profile_cis"
+
+// DataStream returns the configured datastream, or the SCAP Security Guide datastream of the OS.
+func DataStream(cfg *options.ComplianceOptions, operatingSystem *schema.Os) string {
+	if cfg.DataStream != "" {
+		return cfg.DataStream
+	}
+	return GetDataStreamFile(operatingSystem.Name, operatingSystem.Version)
+}
+
+// Profile is an XCCDF profile of a datastream or a tailoring file.
+type Profile struct {
+	ID    string
+	Title string
+	// Extends is the profile customized by a profile of a tailoring file.
+	Extends string
+}
+
+// ListProfiles returns the XCCDF profiles declared in a datastream, a benchmark or a tailoring file.
+func ListProfiles(file string) ([]Profile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var profiles []Profile
+	decoder := xml.NewDecoder(f)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return profiles, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", file, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Profile" {
+			continue
+		}
+		var profile struct {
+			ID      string   `xml:"id,attr"`
+			Extends string   `xml:"extends,attr"`
+			Title   []string `xml:"title"`
+		}
+		if err := decoder.DecodeElement(&profile, &start); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", file, err)
+		}
+		p := Profile{ID: profile.ID, Extends: profile.Extends}
+		if len(profile.Title) > 0 {
+			p.Title = strings.Join(strings.Fields(profile.Title[0]), " ")
+		}
+		profiles = append(profiles, p)
+	}
+}
+
+// selectProfile returns the configured profile, checked against the profiles of the datastream and
+// the tailoring file. Without configured profile, it is the only profile of the tailoring file, or
+// the default profile when the datastream provides it.
+func selectProfile(cfg *options.ComplianceOptions, dataStreamFile string, logger *logrus.Logger) (string, error) {
+	profiles, err := ListProfiles(dataStreamFile)
+	if err != nil {
+		return "", err
+	}
+	var tailored []Profile
+	if cfg.Tailoring != "" {
+		if tailored, err = ListProfiles(cfg.Tailoring); err != nil {
+			return "", err
+		}
+		profiles = append(profiles, tailored...)
+	}
+
+	ids := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		ids = append(ids, p.ID)
+	}
+	profile := cfg.Profile
+	switch {
+	case profile != "":
+	case len(tailored) == 1:
+		profile = tailored[0].ID
+		logger.Infof("No OpenSCAP profile specified, using the tailored profile %s", profile)
+	default:
+		profile = DefaultProfile
+		logger.Warnf("No OpenSCAP profile specified, using %s", profile)
+	}
+	if !slices.Contains(ids, profile) {
+		return "", fmt.Errorf("OpenSCAP profile %s not found in %s, available profiles: %s", profile, dataStreamFile, strings.Join(ids, ", "))
+	}
+	return profile, nil
+}
 
-	logger.Infof("Removed OpenSCAP results file: %s", cfg.ResultFile)
-	err = os.Remove(cfg.ResultFile)
+// oscapArgs returns the arguments of the evaluation of a profile.
+func oscapArgs(cfg *options.ComplianceOptions, profile, resultFile, dataStreamFile string) []string {
+	args := []string{"xccdf", "eval", "--profile", profile, "--results", resultFile}
+	if cfg.Tailoring != "" {
+		args = append(args, "--tailoring-file", cfg.Tailoring)
+	}
+	for _, rule := range cfg.Rules {
+		args = append(args, "--rule", rule)
+	}
+	for _, rule := range cfg.SkipRules {
+		args = append(args, "--skip-rule", rule)
+	}
+	return append(args, dataStreamFile)
+}
+
+// oscapError returns the error of an evaluation. oscap exits with code 2 when a rule fails, the
+// results are written as when every rule passes.
+func oscapError(err error, stderr string) error {
+	var exitError *exec.ExitError
+	if err == nil {
+		return nil
+	}
+	if !errors.As(err, &exitError) {
+		return fmt.Errorf("error executing oscap: %w", err)
+	}
+	switch code := exitError.ExitCode(); code {
+	case 2:
+		return nil
+	case 1:
+		return fmt.Errorf("OpenSCAP evaluation failed: %s", strings.TrimSpace(stderr))
+	default:
+		return fmt.Errorf("OpenSCAP exited with code %d: %s", code, strings.TrimSpace(stderr))
+	}
+}
+
+// Oscap runs an OpenSCAP audit and collects the results.
+func Oscap(ctx context.Context, cfg *options.ComplianceOptions, operatingSystem *schema.Os, logger *logrus.Logger) (*models.ComplianceReport, error) {
+	dataStreamFile := DataStream(cfg, operatingSystem)
+	if dataStreamFile == "" {
+		return nil, fmt.Errorf("no OpenSCAP datastream known for %s %s, set compliance.dataStream", operatingSystem.Name, operatingSystem.Version)
+	}
+	profile, err := selectProfile(cfg, dataStreamFile, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	resultFile := cfg.ResultFile
+	if resultFile == "" {
+		logger.Warn("No OpenSCAP results file specified, using default 'results.xml'")
+		resultFile = "results.xml"
+	}
+	cmd := exec.CommandContext(ctx, "oscap", oscapArgs(cfg, profile, resultFile, dataStreamFile)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := oscapError(cmd.Run(), stderr.String()); err != nil {
+		logger.WithError(err).Error("OpenSCAP evaluation failed")
+		return nil, err
+	}
+	report, err := ReadResults(resultFile, FixSystemShell)
+	if err != nil {
+		logger.Errorf("Error reading OpenSCAP document: %v", err)
+		return nil, err
+	}
+	report.Profile = profile
+
+	logger.Infof("Removed OpenSCAP results file: %s", resultFile)
+	err = os.Remove(resultFile)
 	if err != nil {
 		logger.Errorf("Error removing OpenSCAP results file: %v", err)
 	}
-	return &report, nil
+	return report, nil
 }
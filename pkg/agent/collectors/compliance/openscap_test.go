@@ -0,0 +1,175 @@
+package compliance
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/options"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDataStream = `<?xml version="1.0" encoding="UTF-8"?>
+<ds:data-stream-collection xmlns:ds="http://scap.nist.gov/schema/scap/source/1.2" xmlns:xccdf-1.2="http://checklists.nist.gov/xccdf/1.2">
+  <ds:component id="scap_org.open-scap_comp_ssg-debian12-xccdf.xml">
+    <xccdf-1.2:Benchmark id="xccdf_org.ssgproject.content_benchmark_DEBIAN-12">
+      <xccdf-1.2:Profile id="xccdf_org.ssgproject.content_profile_cis_level1_server">
+        <xccdf-1.2:title xml:lang="en-US">CIS Debian Linux 12 Benchmark
+          for Level 1 - Server</xccdf-1.2:title>
+        <xccdf-1.2:select idref="xccdf_org.ssgproject.content_rule_sshd_disable_root_login" selected="true"/>
+      </xccdf-1.2:Profile>
+      <xccdf-1.2:Profile id="xccdf_org.ssgproject.content_profile_anssi_bp28_minimal">
+        <xccdf-1.2:title xml:lang="en-US">ANSSI-BP-028 (minimal)</xccdf-1.2:title>
+      </xccdf-1.2:Profile>
+    </xccdf-1.2:Benchmark>
+  </ds:component>
+</ds:data-stream-collection>
+`
+
+const testTailoring = `<?xml version="1.0" encoding="UTF-8"?>
+<xccdf-1.2:Tailoring xmlns:xccdf-1.2="http://checklists.nist.gov/xccdf/1.2" id="xccdf_scap-workbench_tailoring_default">
+  <xccdf-1.2:Profile id="xccdf_org.example_profile_cis_level1_server_customized" extends="xccdf_org.ssgproject.content_profile_cis_level1_server">
+    <xccdf-1.2:title>CIS Level 1 - Server [CUSTOMIZED]</xccdf-1.2:title>
+    <xccdf-1.2:select idref="xccdf_org.ssgproject.content_rule_sshd_disable_root_login" selected="false"/>
+  </xccdf-1.2:Profile>
+</xccdf-1.2:Tailoring>
+`
+
+func TestListProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeRootFile(t, dir, "ds.xml", testDataStream, 0o644)
+	writeRootFile(t, dir, "tailoring.xml", testTailoring, 0o644)
+
+	profiles, err := ListProfiles(filepath.Join(dir, "ds.xml"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Profile{
+		{ID: "xccdf_org.ssgproject.content_profile_cis_level1_server", Title: "CIS Debian Linux 12 Benchmark for Level 1 - Server"},
+		{ID: "xccdf_org.ssgproject.content_profile_anssi_bp28_minimal", Title: "ANSSI-BP-028 (minimal)"},
+	}, profiles)
+
+	profiles, err = ListProfiles(filepath.Join(dir, "tailoring.xml"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Profile{{
+		ID:      "xccdf_org.example_profile_cis_level1_server_customized",
+		Title:   "CIS Level 1 - Server [CUSTOMIZED]",
+		Extends: "xccdf_org.ssgproject.content_profile_cis_level1_server",
+	}}, profiles)
+
+	_, err = ListProfiles(filepath.Join(dir, "missing.xml"))
+	assert.Error(t, err)
+}
+
+func TestSelectProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeRootFile(t, dir, "ds.xml", testDataStream, 0o644)
+	writeRootFile(t, dir, "tailoring.xml", testTailoring, 0o644)
+	ds := filepath.Join(dir, "ds.xml")
+	logger := logrus.New()
+
+	profile, err := selectProfile(&options.ComplianceOptions{Profile: "xccdf_org.ssgproject.content_profile_anssi_bp28_minimal"}, ds, logger)
+	assert.NoError(t, err)
+	assert.Equal(t, "xccdf_org.ssgproject.content_profile_anssi_bp28_minimal", profile)
+
+	// The default profile is not in the datastream
+	_, err = selectProfile(&options.ComplianceOptions{}, ds, logger)
+	assert.ErrorContains(t, err, "xccdf_org.ssgproject.content_profile_cis_level1_server")
+
+	_, err = selectProfile(&options.ComplianceOptions{Profile: "xccdf_org.ssgproject.content_profile_stig"}, ds, logger)
+	assert.ErrorContains(t, err, "not found")
+
+	profile, err = selectProfile(&options.ComplianceOptions{Tailoring: filepath.Join(dir, "tailoring.xml")}, ds, logger)
+	assert.NoError(t, err)
+	assert.Equal(t, "xccdf_org.example_profile_cis_level1_server_customized", profile)
+}
+
+func TestOscapArgs(t *testing.T) {
+	cfg := &options.ComplianceOptions{
+		Tailoring: "/etc/facter/tailoring.xml",
+		Rules:     []string{"xccdf_org.ssgproject.content_rule_sshd_disable_root_login"},
+		SkipRules: []string{"xccdf_org.ssgproject.content_rule_partition_for_tmp"},
+	}
+	assert.Equal(t, []string{
+		"xccdf", "eval", "--profile", "cis", "--results", "/tmp/results.xml",
+		"--tailoring-file", "/etc/facter/tailoring.xml",
+		"--rule", "xccdf_org.ssgproject.content_rule_sshd_disable_root_login",
+		"--skip-rule", "xccdf_org.ssgproject.content_rule_partition_for_tmp",
+		"/usr/share/xml/scap/ssg/content/ssg-debian12-ds.xml",
+	}, oscapArgs(cfg, "cis", "/tmp/results.xml", "/usr/share/xml/scap/ssg/content/ssg-debian12-ds.xml"))
+
+	assert.Equal(t, "/srv/ds.xml", DataStream(&options.ComplianceOptions{DataStream: "/srv/ds.xml"}, &schema.Os{Name: "debian", Version: "12"}))
+	assert.Equal(t, "/usr/share/xml/scap/ssg/content/ssg-debian12-ds.xml", DataStream(&options.ComplianceOptions{}, &schema.Os{Name: "debian", Version: "12.5"}))
+}
+
+func TestOscapError(t *testing.T) {
+	exit := func(code string) error {
+		return exec.Command("sh", "-c", "exit "+code).Run()
+	}
+	assert.NoError(t, oscapError(nil, ""))
+	assert.NoError(t, oscapError(exit("2"), ""), "a rule failed")
+	assert.ErrorContains(t, oscapError(exit("1"), "OpenSCAP Error: no profile\n"), "no profile")
+	assert.ErrorContains(t, oscapError(exit("139"), ""), "code 139")
+	assert.Error(t, oscapError(exec.ErrNotFound, ""))
+}
+
+const testResults = `<?xml version="1.0" encoding="UTF-8"?>
+<Benchmark xmlns="http://checklists.nist.gov/xccdf/1.2" id="xccdf_org.ssgproject.content_benchmark_DEBIAN-12" resolved="1">
+  <Value id="xccdf_org.ssgproject.content_value_sshd_max_auth_tries_value" type="number">
+    <value selector="3">3</value>
+    <value>4</value>
+  </Value>
+  <Group id="xccdf_org.ssgproject.content_group_ssh">
+    <Value id="xccdf_org.ssgproject.content_value_sshd_idle_timeout_value" type="number">
+      <value selector="5_minutes">300</value>
+    </Value>
+    <Rule id="xccdf_org.ssgproject.content_rule_sshd_set_max_auth_tries" severity="medium">
+      <title>Set SSH authentication attempt limit</title>
+      <fix system="urn:xccdf:fix:script:sh">sshd_max_auth_tries_value='<sub idref="xccdf_org.ssgproject.content_value_sshd_max_auth_tries_value" use="legacy"/>'
+if grep -q "^MaxAuthTries" /etc/ssh/sshd_config &amp;&amp; [ -n "$sshd_max_auth_tries_value" ]; then echo ok; fi</fix>
+      <fix system="urn:xccdf:fix:script:ansible">- name: Set MaxAuthTries
+  lineinfile: {path: /etc/ssh/sshd_config, line: MaxAuthTries <sub idref="xccdf_org.ssgproject.content_value_sshd_max_auth_tries_value" use="legacy"/>}</fix>
+    </Rule>
+    <Rule id="xccdf_org.ssgproject.content_rule_sshd_set_idle_timeout" severity="medium">
+      <title>Set SSH Client Alive Interval</title>
+      <fix system="urn:xccdf:fix:script:ansible">- name: Set ClientAliveInterval
+  lineinfile: {path: /etc/ssh/sshd_config, line: ClientAliveInterval <sub idref="xccdf_org.ssgproject.content_value_sshd_idle_timeout_value"/>}</fix>
+    </Rule>
+    <Rule id="xccdf_org.ssgproject.content_rule_sshd_disable_root_login" severity="high">
+      <title>Disable SSH Root Login</title>
+    </Rule>
+  </Group>
+  <TestResult id="xccdf_org.open-scap_testresult_xccdf_org.ssgproject.content_profile_cis_level1_server">
+    <profile idref="xccdf_org.ssgproject.content_profile_cis_level1_server"/>
+    <set-value idref="xccdf_org.ssgproject.content_value_sshd_max_auth_tries_value">5</set-value>
+    <rule-result idref="xccdf_org.ssgproject.content_rule_sshd_set_max_auth_tries" severity="medium"><result>fail</result></rule-result>
+    <rule-result idref="xccdf_org.ssgproject.content_rule_sshd_set_idle_timeout" severity="medium"><result>fail</result></rule-result>
+    <rule-result idref="xccdf_org.ssgproject.content_rule_sshd_disable_root_login" severity="high"><result>pass</result></rule-result>
+    <rule-result idref="xccdf_org.ssgproject.content_rule_partition_for_tmp" severity="low"><result>notselected</result></rule-result>
+    <score system="urn:xccdf:scoring:default" maximum="100.000000">33.333332</score>
+  </TestResult>
+</Benchmark>
+`
+
+func TestReadResults(t *testing.T) {
+	dir := t.TempDir()
+	writeRootFile(t, dir, "results.xml", testResults, 0o644)
+	writeRootFile(t, dir, "ds.xml", testDataStream, 0o644)
+
+	report, err := ReadResults(filepath.Join(dir, "results.xml"), FixSystemShell)
+	assert.NoError(t, err)
+	assert.Equal(t, "xccdf_org.ssgproject.content_profile_cis_level1_server", report.Profile)
+	assert.Equal(t, "33.333332", report.Score.Value)
+	assert.Len(t, report.RuleResults, 3)
+	assert.Equal(t, "sshd_max_auth_tries_value='5'\nif grep -q \"^MaxAuthTries\" /etc/ssh/sshd_config && [ -n \"$sshd_max_auth_tries_value\" ]; then echo ok; fi", report.RuleResults[0].Fix, "value of the profile")
+	assert.Equal(t, "", report.RuleResults[1].Fix, "only an ansible fix, remediated manually")
+	assert.Equal(t, "", report.RuleResults[2].Fix)
+
+	report, err = ReadResults(filepath.Join(dir, "results.xml"), FixSystemAnsible)
+	assert.NoError(t, err)
+	assert.Equal(t, "- name: Set MaxAuthTries\n  lineinfile: {path: /etc/ssh/sshd_config, line: MaxAuthTries 5}", report.RuleResults[0].Fix)
+	assert.Equal(t, "- name: Set ClientAliveInterval\n  lineinfile: {path: /etc/ssh/sshd_config, line: ClientAliveInterval 300}", report.RuleResults[1].Fix, "first value")
+
+	_, err = ReadResults(filepath.Join(dir, "ds.xml"), FixSystemShell)
+	assert.ErrorContains(t, err, "not an XCCDF results file")
+}
@@ -0,0 +1,274 @@
+package compliance
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPack is the bundled rule pack evaluated when none is configured.
+const DefaultPack = "cis-linux-l1"
+
+//go:embed packs/*.yml
+var bundledPacks embed.FS
+
+// RulePack is a YAML file of rules evaluated by the native engine.
+type RulePack struct {
+	Name        string  `yaml:"name"`
+	Title       string  `yaml:"title"`
+	Description string  `yaml:"description"`
+	Rules       []*Rule `yaml:"rules"`
+}
+
+// Rule is a compliance check, evaluated on the hosts of its platforms.
+type Rule struct {
+	ID          string `yaml:"id"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Severity    string `yaml:"severity"` // low, medium or high
+	// Platforms are os-release ID or ID_LIKE values, the rule is not applicable elsewhere. Every platform when empty.
+	Platforms []string `yaml:"platforms"`
+	// Fix is a shell remediation of the rule, reported as is.
+	Fix   string `yaml:"fix"`
+	Check Check  `yaml:"check"`
+}
+
+// Check is either a combination of checks or a single one, exactly one field is set.
+type Check struct {
+	All          []Check            `yaml:"all"`
+	Any          []Check            `yaml:"any"`
+	FileContent  *FileContentCheck  `yaml:"file_content"`
+	FileMode     *FileModeCheck     `yaml:"file_mode"`
+	Sysctl       *SysctlCheck       `yaml:"sysctl"`
+	KernelModule *KernelModuleCheck `yaml:"kernel_module"`
+	Service      *ServiceCheck      `yaml:"service"`
+	Package      *PackageCheck      `yaml:"package"`
+	Fact         *FactCheck         `yaml:"fact"`
+}
+
+// FileContentCheck matches a regular expression against files, globs are allowed.
+type FileContentCheck struct {
+	Paths   []string `yaml:"paths"`
+	Pattern string   `yaml:"pattern"`
+	Absent  bool     `yaml:"absent"`  // Pass when no file matches, instead of when any file matches
+	Missing string   `yaml:"missing"` // Result when no file exists: fail by default, pass or notapplicable
+	re      *regexp.Regexp
+}
+
+// FileModeCheck checks the permissions and the ownership of files, globs are allowed.
+type FileModeCheck struct {
+	Paths   []string `yaml:"paths"`
+	Mode    string   `yaml:"mode"`  // Most permissive mode allowed, in octal, e.g. 0640
+	Owner   string   `yaml:"owner"` // User name or uid
+	Group   string   `yaml:"group"` // Group name or gid
+	Missing string   `yaml:"missing"`
+	mode    uint32
+}
+
+// SysctlCheck compares a kernel parameter, read from /proc/sys, with a value.
+type SysctlCheck struct {
+	Key     string `yaml:"key"`
+	Value   string `yaml:"value"`
+	Missing string `yaml:"missing"` // Result when the parameter does not exist: notapplicable by default
+}
+
+// KernelModuleCheck passes when a module is neither loaded nor loadable, disabled with install or blacklist.
+type KernelModuleCheck struct {
+	Name string `yaml:"name"`
+}
+
+// ServiceCheck checks whether a systemd unit or an OpenRC service is enabled.
+type ServiceCheck struct {
+	Name    string `yaml:"name"`
+	Enabled bool   `yaml:"enabled"`
+}
+
+// PackageCheck checks whether one of the packages is installed, from the packages collected.
+type PackageCheck struct {
+	Names     []string `yaml:"names"`
+	Installed bool     `yaml:"installed"`
+}
+
+// FactCheck compares the values found at a path of the inventory, written with its protobuf field names,
+// e.g. users[uid=0].username. Every value must satisfy the operator, or any of them when Any is set.
+type FactCheck struct {
+	Path    string `yaml:"path"`
+	Op      string `yaml:"op"` // exists, absent, equals, not_equals, matches, not_matches, lt, le, gt, ge, count
+	Value   string `yaml:"value"`
+	Any     bool   `yaml:"any"`
+	Missing string `yaml:"missing"` // Result when the path has no value: notapplicable by default
+	re      *regexp.Regexp
+}
+
+// factOps are the operators of the fact checks.
+var factOps = map[string]bool{
+	"exists": true, "absent": true, "equals": true, "not_equals": true, "matches": true, "not_matches": true,
+	"lt": true, "le": true, "gt": true, "ge": true, "count": true,
+}
+
+// BundledPacks returns the names of the rule packs shipped with facter.
+func BundledPacks() []string {
+	entries, _ := fs.ReadDir(bundledPacks, "packs")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".yml"))
+	}
+	return names
+}
+
+// LoadRules returns the rules of the bundled packs, followed by the rules of the YAML files of the
+// directories. A rule replaces the rule of the same id loaded before.
+func LoadRules(packs []string, dirs []string) ([]*Rule, error) {
+	if len(packs) == 0 && len(dirs) == 0 {
+		packs = []string{DefaultPack}
+	}
+	var loaded []*RulePack
+	for _, name := range packs {
+		data, err := bundledPacks.ReadFile(path.Join("packs", name+".yml"))
+		if err != nil {
+			return nil, fmt.Errorf("unknown rule pack %q, bundled packs are %s", name, strings.Join(BundledPacks(), ", "))
+		}
+		pack, err := parseRulePack(data, name)
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, pack)
+	}
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.y*ml"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			pack, err := parseRulePack(data, file)
+			if err != nil {
+				return nil, err
+			}
+			loaded = append(loaded, pack)
+		}
+	}
+
+	var rules []*Rule
+	index := map[string]int{}
+	for _, pack := range loaded {
+		for _, rule := range pack.Rules {
+			if i, ok := index[rule.ID]; ok {
+				rules[i] = rule
+				continue
+			}
+			index[rule.ID] = len(rules)
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func parseRulePack(data []byte, source string) (*RulePack, error) {
+	pack := &RulePack{}
+	if err := yaml.Unmarshal(data, pack); err != nil {
+		return nil, fmt.Errorf("unable to parse rule pack %s: %w", source, err)
+	}
+	for _, rule := range pack.Rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule pack %s: rule without id", source)
+		}
+		if err := rule.Check.compile(); err != nil {
+			return nil, fmt.Errorf("rule pack %s: rule %s: %w", source, rule.ID, err)
+		}
+	}
+	return pack, nil
+}
+
+// compile validates a check and prepares its regular expressions.
+func (c *Check) compile() error {
+	set := 0
+	for _, isSet := range []bool{
+		len(c.All) > 0, len(c.Any) > 0, c.FileContent != nil, c.FileMode != nil, c.Sysctl != nil,
+		c.KernelModule != nil, c.Service != nil, c.Package != nil, c.Fact != nil,
+	} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("a check must have exactly one of all, any, file_content, file_mode, sysctl, kernel_module, service, package or fact")
+	}
+
+	for i := range c.All {
+		if err := c.All[i].compile(); err != nil {
+			return err
+		}
+	}
+	for i := range c.Any {
+		if err := c.Any[i].compile(); err != nil {
+			return err
+		}
+	}
+	var err error
+	switch {
+	case c.FileContent != nil:
+		if len(c.FileContent.Paths) == 0 {
+			return fmt.Errorf("file_content without paths")
+		}
+		if c.FileContent.re, err = regexp.Compile(c.FileContent.Pattern); err != nil {
+			return fmt.Errorf("file_content pattern: %w", err)
+		}
+		return validMissing(c.FileContent.Missing)
+	case c.FileMode != nil:
+		if len(c.FileMode.Paths) == 0 {
+			return fmt.Errorf("file_mode without paths")
+		}
+		if c.FileMode.Mode != "" {
+			if _, err := fmt.Sscanf(c.FileMode.Mode, "%o", &c.FileMode.mode); err != nil {
+				return fmt.Errorf("file_mode mode %q is not octal", c.FileMode.Mode)
+			}
+		}
+		return validMissing(c.FileMode.Missing)
+	case c.Sysctl != nil:
+		if c.Sysctl.Key == "" {
+			return fmt.Errorf("sysctl without key")
+		}
+		return validMissing(c.Sysctl.Missing)
+	case c.KernelModule != nil && c.KernelModule.Name == "":
+		return fmt.Errorf("kernel_module without name")
+	case c.Service != nil && c.Service.Name == "":
+		return fmt.Errorf("service without name")
+	case c.Package != nil && len(c.Package.Names) == 0:
+		return fmt.Errorf("package without names")
+	case c.Fact != nil:
+		if c.Fact.Path == "" {
+			return fmt.Errorf("fact without path")
+		}
+		if !factOps[c.Fact.Op] {
+			return fmt.Errorf("unknown fact operator %q", c.Fact.Op)
+		}
+		if c.Fact.Op == "matches" || c.Fact.Op == "not_matches" {
+			if c.Fact.re, err = regexp.Compile(c.Fact.Value); err != nil {
+				return fmt.Errorf("fact pattern: %w", err)
+			}
+		}
+		return validMissing(c.Fact.Missing)
+	}
+	return nil
+}
+
+func validMissing(result string) error {
+	switch result {
+	case "", ResultPass, ResultFail, ResultNotApplicable:
+		return nil
+	}
+	return fmt.Errorf("invalid missing result %q, expected pass, fail or notapplicable", result)
+}
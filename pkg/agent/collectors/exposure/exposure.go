@@ -0,0 +1,202 @@
+package exposure
+
+import (
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+)
+
+// levelOrder ranks the exposure levels, the highest one of the addresses of a socket is kept.
+var levelOrder = map[string]int{
+	models.ExposureLoopback: 0,
+	models.ExposureLan:      1,
+	models.ExposurePublic:   2,
+}
+
+// privateNets are the networks which are not routed on the internet.
+var privateNets = parseNets(
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "169.254.0.0/16",
+	"fc00::/7", "fe80::/10",
+)
+
+// hostAddress is a non-loopback address of the host and the interface holding it.
+type hostAddress struct {
+	ip     net.IP
+	iface  string
+	public bool
+}
+
+// Analyse returns the exposure of the listening sockets of the network. A socket bound to a wildcard address is
+// reachable on every address of the host, each address being public or on a private network. The input rules of
+// the firewall then lower the exposure when they drop the packets of the internet or of the private networks.
+// A socket listened by several processes is reported once.
+func Analyse(network *schema.Network, processes []*schema.Process, firewall *models.FirewallInventory) []*models.Exposure {
+	byPid := make(map[int64]*schema.Process, len(processes))
+	for _, process := range processes {
+		byPid[process.GetPid()] = process
+	}
+	filter := newFilter(firewall)
+	addresses := hostAddresses(network.GetInterfaces())
+
+	seen := map[string]bool{}
+	var exposures []*models.Exposure
+	for _, conn := range network.GetConnections() {
+		if conn.GetState() != schema.State_STATE_LISTENING {
+			continue
+		}
+		exposure := &models.Exposure{
+			Protocol: protocolName(conn.GetProtocol()),
+			Address:  conn.GetLocal().GetIp().GetAddr(),
+			Port:     conn.GetLocal().GetPort(),
+		}
+		if exposure.Address == "" {
+			exposure.Address = "*"
+		}
+		socket := exposure.Protocol + "/" + net.JoinHostPort(exposure.Address, strconv.FormatUint(uint64(exposure.Port), 10))
+		if seen[socket] {
+			continue
+		}
+		seen[socket] = true
+
+		exposure.Level, exposure.Filtered = filter.level(exposure, addresses)
+		if process := conn.GetProcess(); process.GetPid() != 0 {
+			exposure.Pid = process.GetPid()
+			exposure.Process = process.GetName()
+			exposure.Package = packageName(process)
+			if known := byPid[process.GetPid()]; known != nil {
+				exposure.User = known.GetUsername()
+				if exposure.Package == "" {
+					exposure.Package = packageName(known)
+				}
+				if exposure.Process == "" {
+					exposure.Process = known.GetName()
+				}
+			}
+		}
+		exposures = append(exposures, exposure)
+	}
+	sort.Slice(exposures, func(i, j int) bool {
+		a, b := exposures[i], exposures[j]
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Address < b.Address
+	})
+	return exposures
+}
+
+// level returns the exposure level of a socket, and whether the firewall lowered the level of its bind address.
+func (f *filter) level(exposure *models.Exposure, addresses []hostAddress) (string, bool) {
+	bind := net.ParseIP(exposure.Address)
+	if bind != nil && bind.IsLoopback() {
+		return models.ExposureLoopback, false
+	}
+	var candidates []hostAddress
+	switch {
+	case bind == nil:
+		// Wildcard written * or an unparsable address
+		candidates = addresses
+	case bind.IsUnspecified():
+		for _, addr := range addresses {
+			// A socket bound to :: also accepts the IPv4 connections, unless bindv6only is set
+			if bind.To4() == nil || addr.ip.To4() != nil {
+				candidates = append(candidates, addr)
+			}
+		}
+	default:
+		for _, addr := range addresses {
+			if addr.ip.Equal(bind) {
+				candidates = append(candidates, addr)
+			}
+		}
+		if len(candidates) == 0 {
+			candidates = append(candidates, hostAddress{ip: bind, public: isPublic(bind)})
+		}
+	}
+
+	bindLevel, level := models.ExposureLoopback, models.ExposureLoopback
+	for _, addr := range candidates {
+		scopes := []string{models.ExposureLan}
+		if addr.public {
+			scopes = []string{models.ExposurePublic, models.ExposureLan}
+		}
+		bindLevel = maxLevel(bindLevel, scopes[0])
+		for _, scope := range scopes {
+			p := &packet{protocol: exposure.Protocol, destination: addr.ip, port: exposure.Port, iface: addr.iface, sources: scope}
+			if f.verdict(p) != models.FirewallActionDrop {
+				level = maxLevel(level, scope)
+				break
+			}
+		}
+	}
+	return level, levelOrder[level] < levelOrder[bindLevel]
+}
+
+// hostAddresses returns the non-loopback addresses of the interfaces.
+func hostAddresses(interfaces []*schema.Interface) []hostAddress {
+	var addresses []hostAddress
+	for _, iface := range interfaces {
+		for _, ip := range iface.GetIps() {
+			parsed := net.ParseIP(ip.GetAddr())
+			if parsed == nil || parsed.IsLoopback() {
+				continue
+			}
+			addresses = append(addresses, hostAddress{ip: parsed, iface: iface.GetName(), public: isPublic(parsed)})
+		}
+	}
+	return addresses
+}
+
+// isPublic reports whether an address is routed on the internet.
+func isPublic(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() {
+		return false
+	}
+	for _, private := range privateNets {
+		if private.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func maxLevel(a, b string) string {
+	if levelOrder[b] > levelOrder[a] {
+		return b
+	}
+	return a
+}
+
+func protocolName(protocol schema.Protocol) string {
+	if protocol == schema.Protocol_PROTOCOL_TCP {
+		return "tcp"
+	}
+	return "udp"
+}
+
+// packageName returns the package of the executable of a process, empty when it is unknown.
+func packageName(process *schema.Process) string {
+	name := process.GetPackage().GetName()
+	if name == "unknown" {
+		return ""
+	}
+	return name
+}
+
+func parseNets(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
@@ -0,0 +1,142 @@
+package exposure
+
+import (
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func listening(protocol schema.Protocol, addr string, port uint32, pid int64, name, pkg string) *schema.ConnectionState {
+	return &schema.ConnectionState{
+		Protocol: protocol,
+		State:    schema.State_STATE_LISTENING,
+		Local:    &schema.IpPort{Ip: &schema.Ip{Addr: addr}, Port: port},
+		Process:  &schema.Process{Pid: pid, Name: name, Package: &schema.Package{Name: pkg}},
+	}
+}
+
+func testNetwork() *schema.Network {
+	return &schema.Network{
+		Interfaces: []*schema.Interface{
+			{Name: "eth0", Ips: []*schema.Ip{{Addr: "192.168.1.10", Cidr: "192.168.1.0/24"}, {Addr: "fe80::1", Cidr: "fe80::/64"}}},
+			{Name: "eth1", Ips: []*schema.Ip{{Addr: "203.0.113.10", Cidr: "203.0.113.0/24"}}},
+		},
+		Connections: []*schema.ConnectionState{
+			listening(schema.Protocol_PROTOCOL_TCP, "0.0.0.0", 22, 100, "sshd", "openssh-server"),
+			listening(schema.Protocol_PROTOCOL_TCP, "127.0.0.1", 5432, 200, "postgres", "postgresql-15"),
+			listening(schema.Protocol_PROTOCOL_TCP, "::", 80, 300, "nginx", "unknown"),
+			listening(schema.Protocol_PROTOCOL_TCP, "::", 80, 301, "nginx", "unknown"),
+			listening(schema.Protocol_PROTOCOL_TCP, "192.168.1.10", 6379, 400, "redis-server", "redis-server"),
+			listening(schema.Protocol_PROTOCOL_UDP_UNSPECIFIED, "0.0.0.0", 53, 500, "dnsmasq", "dnsmasq-base"),
+			{
+				Protocol: schema.Protocol_PROTOCOL_TCP,
+				State:    schema.State_STATE_ESTABLISHED,
+				Local:    &schema.IpPort{Ip: &schema.Ip{Addr: "192.168.1.10"}, Port: 22},
+				Remote:   &schema.IpPort{Ip: &schema.Ip{Addr: "192.168.1.20"}, Port: 51000},
+			},
+		},
+	}
+}
+
+func levels(exposures []*models.Exposure) map[string]string {
+	result := map[string]string{}
+	for _, e := range exposures {
+		result[e.Protocol+"/"+e.Address] = e.Level
+		if e.Filtered {
+			result[e.Protocol+"/"+e.Address] += " filtered"
+		}
+	}
+	return result
+}
+
+func TestAnalyse(t *testing.T) {
+	processes := []*schema.Process{{Pid: 300, Name: "nginx", Username: "www-data", Package: &schema.Package{Name: "nginx-core"}}}
+	exposures := Analyse(testNetwork(), processes, nil)
+	assert.Len(t, exposures, 5, "established connections and duplicated sockets are skipped")
+
+	assert.Equal(t, &models.Exposure{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Level: models.ExposurePublic, Process: "sshd", Pid: 100, Package: "openssh-server"}, exposures[0])
+	assert.Equal(t, "udp", exposures[1].Protocol)
+	assert.Equal(t, models.ExposurePublic, exposures[1].Level)
+	assert.Equal(t, &models.Exposure{Protocol: "tcp", Address: "::", Port: 80, Level: models.ExposurePublic, Process: "nginx", Pid: 300, User: "www-data", Package: "nginx-core"}, exposures[2])
+	assert.Equal(t, models.ExposureLoopback, exposures[3].Level)
+	assert.Equal(t, uint32(5432), exposures[3].Port)
+	assert.Equal(t, models.ExposureLan, exposures[4].Level)
+
+	network := testNetwork()
+	network.Interfaces = network.Interfaces[:1]
+	assert.Equal(t, models.ExposureLan, Analyse(network, nil, nil)[0].Level, "private addresses only")
+}
+
+func TestAnalyse_Iptables(t *testing.T) {
+	filter := func(chain string, position int, rule models.FirewallRule) *models.FirewallRule {
+		rule.Origin, rule.Family, rule.Table, rule.Chain, rule.Position = "iptables", models.FirewallFamilyIPv4, "filter", chain, position
+		return &rule
+	}
+	firewall := &models.FirewallInventory{
+		Chains: []*models.FirewallChain{
+			{Origin: "iptables", Family: models.FirewallFamilyIPv4, Table: "filter", Name: "INPUT", Hook: "input", Policy: "drop"},
+			{Origin: "iptables", Family: models.FirewallFamilyIPv4, Table: "filter", Name: "services"},
+			{Origin: "ip6tables", Family: models.FirewallFamilyIPv6, Table: "filter", Name: "INPUT", Hook: "input", Policy: "accept"},
+		},
+		Rules: []*models.FirewallRule{
+			filter("INPUT", 1, models.FirewallRule{States: []string{"related", "established"}, Action: models.FirewallActionAccept}),
+			filter("INPUT", 2, models.FirewallRule{InInterface: "lo", Action: models.FirewallActionAccept}),
+			filter("INPUT", 3, models.FirewallRule{SourceAddresses: []string{"198.51.100.7"}, Action: models.FirewallActionDrop}),
+			filter("INPUT", 4, models.FirewallRule{Action: models.FirewallActionJump, Target: "services"}),
+			filter("services", 1, models.FirewallRule{Protocol: "tcp", SourceAddresses: []string{"10.0.0.0/8", "192.168.0.0/16"}, DestinationPorts: []string{"22"}, Action: models.FirewallActionAccept}),
+			filter("services", 2, models.FirewallRule{Protocol: "tcp", DestinationPorts: []string{"80", "443"}, Action: models.FirewallActionAccept}),
+			filter("services", 3, models.FirewallRule{Protocol: "udp", DestinationPorts: []string{"!53"}, Action: models.FirewallActionAccept}),
+		},
+	}
+	assert.Equal(t, map[string]string{
+		"tcp/0.0.0.0":      "lan filtered",
+		"udp/0.0.0.0":      "loopback filtered",
+		"tcp/::":           "public",
+		"tcp/127.0.0.1":    "loopback",
+		"tcp/192.168.1.10": "loopback filtered",
+	}, levels(Analyse(testNetwork(), nil, firewall)))
+}
+
+func TestAnalyse_Nftables(t *testing.T) {
+	firewall := &models.FirewallInventory{
+		Nftables: &models.NftRuleset{Tables: []*models.NftTable{{Family: "inet", Name: "filter", Sets: []*models.NftSet{
+			{Name: "admins", Type: "ipv4_addr", Elements: []string{"10.0.0.0/8"}},
+		}}}},
+		Chains: []*models.FirewallChain{{Origin: "nftables", Family: models.FirewallFamilyInet, Table: "filter", Name: "input", Hook: "input", Policy: "drop"}},
+		Rules: []*models.FirewallRule{
+			{Origin: "nftables", Family: models.FirewallFamilyInet, Table: "filter", Chain: "input", Position: 1, States: []string{"!invalid"}, Protocol: "tcp",
+				DestinationPorts: []string{"80-443"}, Action: models.FirewallActionAccept},
+			{Origin: "nftables", Family: models.FirewallFamilyIPv4, Table: "filter", Chain: "input", Position: 2, Protocol: "tcp",
+				SourceAddresses: []string{"@admins"}, DestinationPorts: []string{"22", "6379"}, Action: models.FirewallActionAccept},
+			{Origin: "nftables", Family: models.FirewallFamilyInet, Table: "filter", Chain: "input", Position: 3, InInterface: "eth*", Protocol: "udp",
+				Action: models.FirewallActionAccept},
+		},
+	}
+	assert.Equal(t, map[string]string{
+		"tcp/0.0.0.0":      "lan filtered",
+		"udp/0.0.0.0":      "public",
+		"tcp/::":           "public",
+		"tcp/127.0.0.1":    "loopback",
+		"tcp/192.168.1.10": "lan",
+	}, levels(Analyse(testNetwork(), nil, firewall)))
+}
+
+func TestMatchValues(t *testing.T) {
+	port := func(v string) match { return portMatch(v, 22) }
+	assert.Equal(t, matchAll, matchValues(nil, port))
+	assert.Equal(t, matchAll, matchValues([]string{"80", "20-25"}, port))
+	assert.Equal(t, matchNone, matchValues([]string{"!22"}, port))
+	assert.Equal(t, matchAll, matchValues([]string{"!53"}, port))
+	assert.Equal(t, matchSome, matchValues([]string{"ssh"}, port), "service names are not resolved")
+
+	public := &packet{destination: []byte{203, 0, 113, 10}, sources: models.ExposurePublic}
+	lan := &packet{destination: []byte{203, 0, 113, 10}, sources: models.ExposureLan}
+	assert.Equal(t, matchAll, sourceMatch("0.0.0.0/0", public))
+	assert.Equal(t, matchNone, sourceMatch("10.0.0.0/8", public))
+	assert.Equal(t, matchSome, sourceMatch("10.1.0.0/16", lan))
+	assert.Equal(t, matchSome, sourceMatch("198.51.100.0/24", public))
+	assert.Equal(t, matchNone, sourceMatch("198.51.100.0/24", lan))
+	assert.Equal(t, matchNone, sourceMatch("2001:db8::/32", public), "other family")
+}
@@ -0,0 +1,336 @@
+package exposure
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// maxJumps bounds the chains followed by jump and goto, against loops.
+const maxJumps = 16
+
+// verdictReturn is the verdict of a chain which returned to its caller or reached its end.
+const verdictReturn = "return"
+
+// match is the outcome of a criterion for a class of packets: it matches none, some or all of them.
+type match int
+
+const (
+	matchNone match = iota
+	matchSome
+	matchAll
+)
+
+// packet is the class of packets sent by the hosts of the internet, or of the private networks, to a socket.
+type packet struct {
+	protocol    string
+	destination net.IP
+	port        uint32
+	iface       string
+	// sources is the exposure level of the senders, public or lan.
+	sources string
+}
+
+func (p *packet) family() string {
+	if p.destination.To4() != nil {
+		return models.FirewallFamilyIPv4
+	}
+	return models.FirewallFamilyIPv6
+}
+
+// filter evaluates the normalised input rules of the firewall.
+type filter struct {
+	inputs []*models.FirewallChain
+	rules  map[string][]*models.FirewallRule
+	// sets are the elements of the nftables sets, by table and name.
+	sets map[string][]string
+}
+
+func newFilter(firewall *models.FirewallInventory) *filter {
+	f := &filter{rules: map[string][]*models.FirewallRule{}, sets: map[string][]string{}}
+	if firewall == nil {
+		return f
+	}
+	for _, chain := range firewall.Chains {
+		// The input rules of iptables are in the filter table, all the nftables chains on the input hook filter
+		if chain.Hook == "input" && (chain.Origin == "nftables" || chain.Table == "filter") {
+			f.inputs = append(f.inputs, chain)
+		}
+	}
+	for _, rule := range firewall.Rules {
+		key := chainKey(rule.Origin, rule.Table, rule.Chain)
+		f.rules[key] = append(f.rules[key], rule)
+	}
+	if firewall.Nftables != nil {
+		for _, table := range firewall.Nftables.Tables {
+			for _, set := range table.Sets {
+				if set.Map == "" {
+					f.sets[table.Name+" "+set.Name] = append(f.sets[table.Name+" "+set.Name], set.Elements...)
+				}
+			}
+		}
+	}
+	return f
+}
+
+func chainKey(origin, table, chain string) string {
+	return origin + " " + table + " " + chain
+}
+
+// verdict returns drop when every input chain of the family of the packet may drop it, that is when none
+// accepts some packets of the class, empty without input chain for the family.
+func (f *filter) verdict(p *packet) string {
+	verdict := ""
+	for _, chain := range f.inputs {
+		if !sameFamily(chain.Family, p.family()) {
+			continue
+		}
+		v := f.evaluate(chain.Origin, chain.Table, chain.Name, p, 0)
+		if v == "" || v == verdictReturn {
+			v = models.FirewallActionAccept
+			if strings.EqualFold(chain.Policy, models.FirewallActionDrop) {
+				v = models.FirewallActionDrop
+			}
+		}
+		if v == models.FirewallActionDrop {
+			return v
+		}
+		verdict = v
+	}
+	return verdict
+}
+
+// evaluate runs the rules of a chain. A rule matching some packets of the class accepts them, a rule has to
+// match all of them to drop the class or return from the chain.
+func (f *filter) evaluate(origin, table, chain string, p *packet, jumps int) string {
+	if jumps > maxJumps {
+		return ""
+	}
+	for _, rule := range f.rules[chainKey(origin, table, chain)] {
+		if !sameFamily(rule.Family, p.family()) {
+			continue
+		}
+		m := f.match(rule, p)
+		if m == matchNone {
+			continue
+		}
+		switch rule.Action {
+		case models.FirewallActionAccept:
+			return models.FirewallActionAccept
+		case models.FirewallActionDrop, models.FirewallActionReject:
+			if m == matchAll {
+				return models.FirewallActionDrop
+			}
+		case models.FirewallActionReturn:
+			if m == matchAll {
+				return verdictReturn
+			}
+		case models.FirewallActionJump, models.FirewallActionGoto:
+			v := f.evaluate(origin, table, rule.Target, p, jumps+1)
+			switch {
+			case v == models.FirewallActionAccept:
+				return v
+			case v == models.FirewallActionDrop && m == matchAll:
+				return v
+			case rule.Action == models.FirewallActionGoto && m == matchAll:
+				// The end of the chain of a goto returns to the caller of the current chain
+				return verdictReturn
+			}
+		}
+	}
+	return ""
+}
+
+// match returns whether a rule matches none, some or all the packets of the class.
+func (f *filter) match(rule *models.FirewallRule, p *packet) match {
+	if rule.OutInterface != "" {
+		// Input packets have no output interface
+		return matchNone
+	}
+	result := matchAll
+	for _, m := range []match{
+		matchValues(splitValues(rule.Protocol), func(v string) match { return protocolMatch(v, p.protocol) }),
+		f.matchSet(rule, rule.SourceAddresses, func(v string) match { return sourceMatch(v, p) }),
+		f.matchSet(rule, rule.DestinationAddresses, func(v string) match { return addressMatch(v, p.destination) }),
+		f.matchSet(rule, rule.DestinationPorts, func(v string) match { return portMatch(v, p.port) }),
+		matchValues(rule.SourcePorts, func(string) match { return matchSome }),
+		matchValues(splitValues(rule.InInterface), func(v string) match { return interfaceMatch(v, p.iface) }),
+		// The connections to a listening socket are new
+		matchValues(rule.States, func(v string) match { return boolMatch(v == "new") }),
+	} {
+		result = min(result, m)
+	}
+	return result
+}
+
+// matchSet is matchValues resolving the nftables sets, @name, of the values.
+func (f *filter) matchSet(rule *models.FirewallRule, values []string, one func(string) match) match {
+	return matchValues(values, func(v string) match {
+		if !strings.HasPrefix(v, "@") {
+			return one(v)
+		}
+		elements, ok := f.sets[rule.Table+" "+v[1:]]
+		if !ok {
+			return matchSome
+		}
+		return matchValues(elements, one)
+	})
+}
+
+// matchValues matches a list of values, one of the values has to match and none of the values prefixed
+// with ! has to. An empty list matches everything.
+func matchValues(values []string, one func(string) match) match {
+	result, positive := matchNone, false
+	var excluded []string
+	for _, v := range values {
+		if strings.HasPrefix(v, "!") {
+			excluded = append(excluded, v[1:])
+			continue
+		}
+		positive = true
+		result = max(result, one(v))
+	}
+	if !positive {
+		result = matchAll
+	}
+	for _, v := range excluded {
+		switch one(v) {
+		case matchAll:
+			return matchNone
+		case matchSome:
+			result = min(result, matchSome)
+		}
+	}
+	return result
+}
+
+func splitValues(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func sameFamily(family, packetFamily string) bool {
+	return family == models.FirewallFamilyInet || family == packetFamily
+}
+
+func boolMatch(ok bool) match {
+	if ok {
+		return matchAll
+	}
+	return matchNone
+}
+
+var protocolNumbers = map[string]string{"6": "tcp", "17": "udp"}
+
+func protocolMatch(value, protocol string) match {
+	value = strings.ToLower(value)
+	if name, ok := protocolNumbers[value]; ok {
+		value = name
+	}
+	return boolMatch(value == "all" || value == protocol)
+}
+
+// sourceMatch tells whether an address, network or range of a rule matches the hosts of the internet or of
+// the private networks sending the packets.
+func sourceMatch(value string, p *packet) match {
+	if _, _, ok := strings.Cut(value, "-"); ok {
+		return matchSome
+	}
+	n := parseNet(value)
+	if n == nil {
+		return matchSome
+	}
+	if (n.IP.To4() != nil) != (p.family() == models.FirewallFamilyIPv4) {
+		return matchNone
+	}
+	if ones, _ := n.Mask.Size(); ones == 0 {
+		return matchAll
+	}
+	inside, overlaps := false, false
+	for _, private := range privateNets {
+		if private.Contains(n.IP) && prefixLen(private) <= prefixLen(n) {
+			inside = true
+		}
+		if n.Contains(private.IP) && prefixLen(n) <= prefixLen(private) {
+			overlaps = true
+		}
+	}
+	switch {
+	case p.sources == models.ExposureLan && (inside || overlaps):
+		return matchSome
+	case p.sources == models.ExposurePublic && !inside:
+		return matchSome
+	}
+	return matchNone
+}
+
+// addressMatch tells whether an address, network or range of a rule holds the address.
+func addressMatch(value string, ip net.IP) match {
+	if from, to, ok := strings.Cut(value, "-"); ok {
+		low, high := net.ParseIP(from), net.ParseIP(to)
+		if low == nil || high == nil {
+			return matchSome
+		}
+		return boolMatch(bytes.Compare(ip.To16(), low.To16()) >= 0 && bytes.Compare(ip.To16(), high.To16()) <= 0)
+	}
+	n := parseNet(value)
+	if n == nil {
+		return matchSome
+	}
+	return boolMatch(n.Contains(ip))
+}
+
+// portMatch tells whether a port or range of ports of a rule holds the port, service names are not resolved.
+func portMatch(value string, port uint32) match {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		to = from
+	}
+	low, err1 := strconv.ParseUint(from, 10, 16)
+	high, err2 := strconv.ParseUint(to, 10, 16)
+	if err1 != nil || err2 != nil {
+		return matchSome
+	}
+	return boolMatch(uint64(port) >= low && uint64(port) <= high)
+}
+
+// interfaceMatch tells whether an interface of a rule, possibly ending with the + or * wildcard, is the
+// interface of the packet.
+func interfaceMatch(value, iface string) match {
+	if iface == "" {
+		return matchSome
+	}
+	value = strings.Trim(value, `"`)
+	if prefix, ok := strings.CutSuffix(value, "+"); ok {
+		return boolMatch(strings.HasPrefix(iface, prefix))
+	}
+	if prefix, ok := strings.CutSuffix(value, "*"); ok {
+		return boolMatch(strings.HasPrefix(iface, prefix))
+	}
+	return boolMatch(value == iface)
+}
+
+// parseNet parses a network, or an address as a network of a single address.
+func parseNet(value string) *net.IPNet {
+	if _, n, err := net.ParseCIDR(value); err == nil {
+		return n
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil
+	}
+	if ip.To4() != nil {
+		return &net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+func prefixLen(n *net.IPNet) int {
+	ones, _ := n.Mask.Size()
+	return ones
+}
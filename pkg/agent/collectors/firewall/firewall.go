@@ -0,0 +1,142 @@
+package firewall
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// runCommand runs a firewall tool and returns its standard output. It is a variable to be replaced in tests.
+var runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("%w: %s", err, msg)
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+// lookPath reports whether a firewall tool is installed. It is a variable to be replaced in tests.
+var lookPath = func(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// IptablesMode returns the mode of the iptables command, nf_tables or legacy, empty without iptables.
+func IptablesMode(ctx context.Context) string {
+	out, err := runCommand(ctx, "iptables", "-V")
+	if err != nil {
+		return ""
+	}
+	// iptables v1.8.9 (nf_tables)
+	if _, mode, ok := strings.Cut(strings.TrimSpace(string(out)), "("); ok {
+		return strings.TrimSuffix(mode, ")")
+	}
+	return "legacy"
+}
+
+// Collect reads the configuration of each firewall backend installed on the host and finds the one managing
+// the rules. A backend which cannot be read is logged and skipped.
+func Collect(ctx context.Context, logger *logrus.Logger) *models.FirewallInventory {
+	inv := &models.FirewallInventory{}
+	if lookPath("nft") {
+		inv.Backends = append(inv.Backends, models.FirewallBackendNftables)
+		if err := collectNftables(ctx, inv); err != nil {
+			logger.WithError(err).Warn("Unable to read the nftables ruleset")
+		}
+	}
+	mode := IptablesMode(ctx)
+	switch mode {
+	case "":
+	case "nf_tables":
+		inv.Backends = append(inv.Backends, models.FirewallBackendIptablesNft)
+	default:
+		inv.Backends = append(inv.Backends, models.FirewallBackendIptablesLegacy)
+	}
+	if lookPath("firewall-cmd") {
+		inv.Backends = append(inv.Backends, models.FirewallBackendFirewalld)
+		state, err := GetFirewalldState(ctx)
+		if err != nil {
+			logger.WithError(err).Warn("Unable to read the firewalld configuration")
+		}
+		inv.Firewalld = state
+	}
+	if lookPath("ufw") {
+		inv.Backends = append(inv.Backends, models.FirewallBackendUfw)
+		status, err := GetUfwStatus(ctx)
+		if err != nil {
+			logger.WithError(err).Warn("Unable to read the ufw status")
+		}
+		inv.Ufw = status
+	}
+	if mode != "" {
+		for _, origin := range []string{OriginIptables, OriginIp6tables} {
+			chains, rules, err := GetIptablesRules(ctx, origin)
+			if err != nil {
+				logger.WithError(err).Warnf("Unable to read the %s rules", origin)
+				continue
+			}
+			inv.Chains = append(inv.Chains, chains...)
+			inv.Rules = append(inv.Rules, rules...)
+		}
+	}
+	inv.Backend = Backend(inv, mode)
+	return inv
+}
+
+// collectNftables reads the nftables ruleset, as is and normalised, it needs the root privileges.
+func collectNftables(ctx context.Context, inv *models.FirewallInventory) error {
+	out, err := runCommand(ctx, "nft", "-j", "list", "ruleset")
+	if err != nil {
+		return err
+	}
+	doc, err := parseNftDocument(out)
+	if err != nil {
+		return err
+	}
+	inv.Nftables = newNftRuleset(doc)
+	chains, rules := nftFirewallRules(doc, inv.Nftables)
+	inv.Chains = append(inv.Chains, chains...)
+	inv.Rules = append(inv.Rules, rules...)
+	return nil
+}
+
+// Backend returns the backend managing the rules. firewalld and ufw are frontends and win when active,
+// then nftables when it holds tables of its own, then iptables.
+func Backend(inv *models.FirewallInventory, iptablesMode string) string {
+	if inv.Firewalld != nil && inv.Firewalld.Running {
+		return models.FirewallBackendFirewalld
+	}
+	if inv.Ufw != nil && inv.Ufw.Active {
+		return models.FirewallBackendUfw
+	}
+	iptablesNft := false
+	if inv.Nftables != nil {
+		for _, table := range inv.Nftables.Tables {
+			if !isIptablesNftTable(table) {
+				return models.FirewallBackendNftables
+			}
+			iptablesNft = true
+		}
+	}
+	switch {
+	case iptablesNft:
+		return models.FirewallBackendIptablesNft
+	case iptablesMode != "" && iptablesMode != "nf_tables":
+		return models.FirewallBackendIptablesLegacy
+	}
+	return models.FirewallBackendNone
+}
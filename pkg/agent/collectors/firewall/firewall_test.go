@@ -0,0 +1,236 @@
+package firewall
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testNftRuleset = `{"nftables": [
+  {"metainfo": {"version": "1.0.6", "release_name": "Lester Gooch #5", "json_schema_version": 1}},
+  {"table": {"family": "inet", "name": "filter", "handle": 1}},
+  {"chain": {"family": "inet", "table": "filter", "name": "input", "handle": 1, "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}},
+  {"chain": {"family": "inet", "table": "filter", "name": "services", "handle": 2}},
+  {"set": {"family": "inet", "name": "admins", "table": "filter", "type": "ipv4_addr", "handle": 3, "flags": ["interval"],
+    "elem": [{"prefix": {"addr": "10.0.0.0", "len": 8}}, "192.168.1.10", {"range": ["172.16.0.1", "172.16.0.9"]}]}},
+  {"map": {"family": "inet", "name": "ports", "table": "filter", "type": "inet_service", "handle": 4, "map": "verdict",
+    "elem": [[22, {"jump": {"target": "services"}}]]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 5, "expr": [
+    {"match": {"op": "in", "left": {"ct": {"key": "state"}}, "right": ["related", "established"]}},
+    {"counter": {"packets": 1520, "bytes": 184023}},
+    {"accept": null}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 6, "comment": "ssh from admins", "expr": [
+    {"match": {"op": "==", "left": {"payload": {"protocol": "ip", "field": "saddr"}}, "right": "@admins"}},
+    {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": 22}},
+    {"jump": {"target": "services"}}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 7, "expr": [
+    {"match": {"op": "!=", "left": {"meta": {"key": "iifname"}}, "right": "lo"}},
+    {"limit": {"rate": 5, "per": "minute", "burst": 10}},
+    {"log": {"prefix": "dropped: "}},
+    {"reject": {"type": "icmpx", "expr": "admin-prohibited"}}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "services", "handle": 8, "expr": [{"accept": null}]}},
+  {"table": {"family": "ip", "name": "nat", "handle": 2}},
+  {"chain": {"family": "ip", "table": "nat", "name": "POSTROUTING", "handle": 1, "type": "nat", "hook": "postrouting", "prio": 100, "policy": "accept"}},
+  {"rule": {"family": "ip", "table": "nat", "chain": "POSTROUTING", "handle": 2, "expr": [
+    {"match": {"op": "==", "left": {"payload": {"protocol": "ip", "field": "saddr"}}, "right": {"prefix": {"addr": "172.17.0.0", "len": 16}}}},
+    {"xt": {"type": "match", "name": "comment"}},
+    {"masquerade": null}]}}
+]}`
+
+func TestParseNftRuleset(t *testing.T) {
+	ruleset, err := ParseNftRuleset([]byte(testNftRuleset))
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.6", ruleset.Version)
+	assert.Len(t, ruleset.Tables, 2)
+
+	filter := ruleset.Tables[0]
+	assert.Equal(t, "inet", filter.Family)
+	assert.Len(t, filter.Chains, 2)
+	input := filter.Chains[0]
+	assert.Equal(t, "filter", input.Type)
+	assert.Equal(t, "input", input.Hook)
+	assert.Equal(t, 0, *input.Priority)
+	assert.Equal(t, "drop", input.Policy)
+	assert.Nil(t, filter.Chains[1].Priority, "regular chain")
+
+	assert.Equal(t, []*models.NftRule{
+		{Handle: 5, Expr: "ct state { established, related } counter accept", Verdict: "accept"},
+		{Handle: 6, Expr: "ip saddr @admins tcp dport 22 jump services", Verdict: "jump services", Comment: "ssh from admins"},
+		{Handle: 7, Expr: `meta iifname != lo limit rate 5/minute burst 10 packets log prefix "dropped: " reject with icmpx admin-prohibited`,
+			Verdict: "reject with icmpx admin-prohibited"},
+	}, input.Rules)
+
+	assert.Equal(t, []*models.NftSet{
+		{Name: "admins", Type: "ipv4_addr", Flags: []string{"interval"}, Elements: []string{"10.0.0.0/8", "192.168.1.10", "172.16.0.1-172.16.0.9"}},
+		{Name: "ports", Type: "inet_service", Map: "verdict", Elements: []string{"22 : jump services"}},
+	}, filter.Sets)
+
+	nat := ruleset.Tables[1]
+	assert.Equal(t, "ip saddr 172.17.0.0/16 xt match comment masquerade", nat.Chains[0].Rules[0].Expr)
+	assert.False(t, isIptablesNftTable(filter))
+	assert.True(t, isIptablesNftTable(nat))
+
+	_, err = ParseNftRuleset([]byte("table inet filter {"))
+	assert.Error(t, err)
+}
+
+func TestNftFirewallRules(t *testing.T) {
+	doc, err := parseNftDocument([]byte(testNftRuleset))
+	assert.NoError(t, err)
+	chains, rules := nftFirewallRules(doc, newNftRuleset(doc))
+	assert.Equal(t, []*models.FirewallChain{
+		{Origin: OriginNftables, Family: models.FirewallFamilyInet, Table: "filter", Name: "input", Hook: "input", Policy: "drop"},
+		{Origin: OriginNftables, Family: models.FirewallFamilyInet, Table: "filter", Name: "services"},
+	}, chains, "the iptables-nft nat table is skipped")
+
+	assert.Len(t, rules, 4)
+	assert.Equal(t, &models.FirewallRule{
+		Origin: OriginNftables, Family: models.FirewallFamilyInet, Table: "filter", Chain: "input", Position: 1,
+		States: []string{"related", "established"}, Action: models.FirewallActionAccept, Packets: 1520, Bytes: 184023,
+	}, rules[0])
+	assert.Equal(t, &models.FirewallRule{
+		Origin: OriginNftables, Family: models.FirewallFamilyIPv4, Table: "filter", Chain: "input", Position: 2,
+		Protocol: "tcp", SourceAddresses: []string{"@admins"}, DestinationPorts: []string{"22"},
+		Action: models.FirewallActionJump, Target: "services", Comment: "ssh from admins",
+	}, rules[1])
+	assert.Equal(t, "!lo", rules[2].InInterface)
+	assert.Equal(t, models.FirewallActionReject, rules[2].Action)
+	assert.Equal(t, 1, rules[3].Position)
+}
+
+const testFirewalldZones = `block
+  target: %%REJECT%%
+  icmp-block-inversion: no
+  interfaces:
+  sources:
+  services:
+  ports:
+  masquerade: no
+
+public (default, active)
+  target: default
+  icmp-block-inversion: no
+  interfaces: eth0 eth1
+  sources:
+  services: dhcpv6-client ssh
+  ports: 8080/tcp 60000-61000/udp
+  protocols:
+  forward: yes
+  masquerade: yes
+  forward-ports:
+	port=80:proto=tcp:toport=8080:toaddr=
+  source-ports:
+  icmp-blocks:
+  rich rules:
+	rule family="ipv4" source address="10.0.0.0/8" service name="postgresql" accept
+	rule family="ipv4" source address="192.0.2.1" drop
+
+trusted (active)
+  target: ACCEPT
+  sources: 10.1.0.0/16
+`
+
+func TestParseFirewalldZones(t *testing.T) {
+	zones := ParseFirewalldZones([]byte(testFirewalldZones))
+	assert.Len(t, zones, 3)
+	assert.Equal(t, &models.FirewalldZone{Name: "block", Target: "%%REJECT%%"}, zones[0])
+	assert.Equal(t, &models.FirewalldZone{
+		Name:         "public",
+		Active:       true,
+		Target:       "default",
+		Interfaces:   []string{"eth0", "eth1"},
+		Services:     []string{"dhcpv6-client", "ssh"},
+		Ports:        []string{"8080/tcp", "60000-61000/udp"},
+		Masquerade:   true,
+		ForwardPorts: []string{"port=80:proto=tcp:toport=8080:toaddr="},
+		RichRules: []string{
+			`rule family="ipv4" source address="10.0.0.0/8" service name="postgresql" accept`,
+			`rule family="ipv4" source address="192.0.2.1" drop`,
+		},
+	}, zones[1])
+	assert.Equal(t, []string{"10.1.0.0/16"}, zones[2].Sources)
+	assert.True(t, zones[2].Active)
+}
+
+const testUfwStatus = `Status: active
+Logging: on (low)
+Default: deny (incoming), allow (outgoing), disabled (routed)
+New profiles: skip
+
+To                         Action      From
+--                         ------      ----
+22/tcp                     ALLOW IN    Anywhere                   # ssh
+80,443/tcp (Nginx Full)    ALLOW IN    Anywhere
+Anywhere on eth1           DENY IN     203.0.113.0/24
+22/tcp (v6)                ALLOW IN    Anywhere (v6)              # ssh
+
+`
+
+func TestParseUfwStatus(t *testing.T) {
+	status := ParseUfwStatus([]byte(testUfwStatus))
+	assert.True(t, status.Active)
+	assert.Equal(t, "on (low)", status.Logging)
+	assert.Equal(t, "deny", status.DefaultIncoming)
+	assert.Equal(t, "allow", status.DefaultOutgoing)
+	assert.Equal(t, "disabled", status.DefaultRouted)
+	assert.Equal(t, []*models.UfwRule{
+		{To: "22/tcp", Action: "ALLOW", Direction: "IN", From: "Anywhere", Comment: "ssh"},
+		{To: "80,443/tcp (Nginx Full)", Action: "ALLOW", Direction: "IN", From: "Anywhere"},
+		{To: "Anywhere on eth1", Action: "DENY", Direction: "IN", From: "203.0.113.0/24"},
+		{To: "22/tcp", Action: "ALLOW", Direction: "IN", From: "Anywhere", IPv6: true, Comment: "ssh"},
+	}, status.Rules)
+
+	assert.Equal(t, &models.UfwStatus{}, ParseUfwStatus([]byte("Status: inactive\n")))
+}
+
+func TestCollect(t *testing.T) {
+	defer func(run func(context.Context, string, ...string) ([]byte, error), look func(string) bool) {
+		runCommand, lookPath = run, look
+	}(runCommand, lookPath)
+	outputs := map[string]string{
+		"nft -j list ruleset":             testNftRuleset,
+		"iptables -V":                     "iptables v1.8.9 (nf_tables)\n",
+		"firewall-cmd --state":            "running\n",
+		"firewall-cmd --get-default-zone": "public\n",
+		"firewall-cmd --list-all-zones":   testFirewalldZones,
+		"iptables-save -c":                testIptablesSave,
+	}
+	runCommand = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		out, ok := outputs[strings.Join(append([]string{name}, args...), " ")]
+		if !ok {
+			return nil, errors.New("not installed")
+		}
+		return []byte(out), nil
+	}
+	lookPath = func(name string) bool { return name != "ufw" }
+
+	inv := Collect(context.Background(), logrus.New())
+	assert.Equal(t, models.FirewallBackendFirewalld, inv.Backend)
+	assert.Equal(t, []string{models.FirewallBackendNftables, models.FirewallBackendIptablesNft, models.FirewallBackendFirewalld}, inv.Backends)
+	assert.Len(t, inv.Nftables.Tables, 2)
+	assert.Equal(t, "public", inv.Firewalld.DefaultZone)
+	assert.Len(t, inv.Firewalld.Zones, 2, "active and default zones")
+	assert.Nil(t, inv.Ufw)
+	assert.Len(t, inv.Chains, 8, "nftables and iptables chains")
+	assert.Len(t, inv.Rules, 12, "nftables and iptables rules, ip6tables-save is not installed")
+
+	outputs["firewall-cmd --state"] = "not running\n"
+	inv = Collect(context.Background(), logrus.New())
+	assert.Equal(t, models.FirewallBackendNftables, inv.Backend)
+	assert.False(t, inv.Firewalld.Running)
+	assert.Empty(t, inv.Firewalld.Zones)
+}
+
+func TestBackend(t *testing.T) {
+	compat := &models.NftRuleset{Tables: []*models.NftTable{{Family: "ip", Name: "filter", Chains: []*models.NftChain{{Name: "INPUT", Hook: "input"}}}}}
+	assert.Equal(t, models.FirewallBackendUfw, Backend(&models.FirewallInventory{Nftables: compat, Ufw: &models.UfwStatus{Active: true}}, "nf_tables"))
+	assert.Equal(t, models.FirewallBackendIptablesNft, Backend(&models.FirewallInventory{Nftables: compat, Ufw: &models.UfwStatus{}}, "nf_tables"))
+	assert.Equal(t, models.FirewallBackendIptablesLegacy, Backend(&models.FirewallInventory{Nftables: &models.NftRuleset{}}, "legacy"))
+	assert.Equal(t, models.FirewallBackendNone, Backend(&models.FirewallInventory{}, "nf_tables"))
+	assert.Equal(t, models.FirewallBackendNone, Backend(&models.FirewallInventory{}, ""))
+}
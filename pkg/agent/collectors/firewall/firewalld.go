@@ -0,0 +1,109 @@
+package firewall
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// GetFirewalldState returns the runtime configuration of firewalld, the zones are only listed when it runs.
+func GetFirewalldState(ctx context.Context) (*models.FirewalldState, error) {
+	state := &models.FirewalldState{}
+	// firewall-cmd exits with 252 when firewalld is not running
+	out, err := runCommand(ctx, "firewall-cmd", "--state")
+	if err != nil || strings.TrimSpace(string(out)) != "running" {
+		return state, nil
+	}
+	state.Running = true
+
+	out, err = runCommand(ctx, "firewall-cmd", "--get-default-zone")
+	if err != nil {
+		return nil, err
+	}
+	state.DefaultZone = strings.TrimSpace(string(out))
+
+	out, err = runCommand(ctx, "firewall-cmd", "--list-all-zones")
+	if err != nil {
+		return nil, err
+	}
+	for _, zone := range ParseFirewalldZones(out) {
+		if zone.Active || zone.Name == state.DefaultZone {
+			state.Zones = append(state.Zones, zone)
+		}
+	}
+	return state, nil
+}
+
+// ParseFirewalldZones reads the output of firewall-cmd --list-all-zones, or --list-all for a single zone.
+//
+//	public (default, active)
+//	  target: default
+//	  interfaces: eth0
+//	  services: dhcpv6-client ssh
+//	  rich rules:
+//		rule family="ipv4" source address="10.0.0.0/8" accept
+func ParseFirewalldZones(data []byte) []*models.FirewalldZone {
+	var (
+		zones []*models.FirewalldZone
+		zone  *models.FirewalldZone
+		key   string
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		switch {
+		case !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t"):
+			name, flags, _ := strings.Cut(line, " ")
+			zone = &models.FirewalldZone{Name: name, Active: strings.Contains(flags, "active")}
+			zones = append(zones, zone)
+			key = ""
+		case zone == nil:
+			continue
+		case strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    "):
+			// Continuation of a multi-line value, one item per line
+			addFirewalldValue(zone, key, []string{strings.TrimSpace(line)})
+		default:
+			var value string
+			key, value, _ = strings.Cut(strings.TrimSpace(line), ":")
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if key == "rich rules" {
+				addFirewalldValue(zone, key, []string{value})
+			} else {
+				addFirewalldValue(zone, key, strings.Fields(value))
+			}
+		}
+	}
+	return zones
+}
+
+func addFirewalldValue(zone *models.FirewalldZone, key string, values []string) {
+	switch key {
+	case "target":
+		zone.Target = values[0]
+	case "interfaces":
+		zone.Interfaces = append(zone.Interfaces, values...)
+	case "sources":
+		zone.Sources = append(zone.Sources, values...)
+	case "services":
+		zone.Services = append(zone.Services, values...)
+	case "ports":
+		zone.Ports = append(zone.Ports, values...)
+	case "protocols":
+		zone.Protocols = append(zone.Protocols, values...)
+	case "masquerade":
+		zone.Masquerade = values[0] == "yes"
+	case "forward-ports":
+		zone.ForwardPorts = append(zone.ForwardPorts, values...)
+	case "rich rules":
+		zone.RichRules = append(zone.RichRules, values...)
+	}
+}
@@ -0,0 +1,234 @@
+package firewall
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// Tools the normalised chains and rules are read from.
+const (
+	OriginIptables  = "iptables"
+	OriginIp6tables = "ip6tables"
+	OriginNftables  = "nftables"
+)
+
+// iptablesHooks are the hooks of the built-in chains of iptables.
+var iptablesHooks = map[string]string{
+	"PREROUTING":  "prerouting",
+	"INPUT":       "input",
+	"FORWARD":     "forward",
+	"OUTPUT":      "output",
+	"POSTROUTING": "postrouting",
+}
+
+// iptablesActions are the standard targets of iptables and their normalised action.
+var iptablesActions = map[string]string{
+	"ACCEPT":     models.FirewallActionAccept,
+	"DROP":       models.FirewallActionDrop,
+	"REJECT":     models.FirewallActionReject,
+	"RETURN":     models.FirewallActionReturn,
+	"LOG":        models.FirewallActionLog,
+	"NFLOG":      models.FirewallActionLog,
+	"QUEUE":      models.FirewallActionQueue,
+	"NFQUEUE":    models.FirewallActionQueue,
+	"MASQUERADE": models.FirewallActionMasquerade,
+	"SNAT":       models.FirewallActionSnat,
+	"DNAT":       models.FirewallActionDnat,
+	"REDIRECT":   models.FirewallActionRedirect,
+}
+
+// GetIptablesRules returns the normalised chains and rules of iptables, or of ip6tables, with their counters.
+func GetIptablesRules(ctx context.Context, origin string) ([]*models.FirewallChain, []*models.FirewallRule, error) {
+	out, err := runCommand(ctx, origin+"-save", "-c")
+	if err != nil {
+		return nil, nil, err
+	}
+	chains, rules := ParseIptablesSave(out, origin)
+	return chains, rules, nil
+}
+
+// ParseIptablesSave reads the output of iptables-save -c or ip6tables-save -c.
+//
+//	*filter
+//	:INPUT DROP [120:8400]
+//	:DOCKER - [0:0]
+//	[100:2000] -A INPUT -i lo -j ACCEPT
+//	COMMIT
+func ParseIptablesSave(data []byte, origin string) ([]*models.FirewallChain, []*models.FirewallRule) {
+	family := models.FirewallFamilyIPv4
+	if origin == OriginIp6tables {
+		family = models.FirewallFamilyIPv6
+	}
+	var (
+		chains    []*models.FirewallChain
+		rules     []*models.FirewallRule
+		table     string
+		declared  = map[string]bool{}
+		positions = map[string]int{}
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line == "COMMIT" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+			declared = map[string]bool{}
+			positions = map[string]int{}
+		case strings.HasPrefix(line, ":"):
+			fields := strings.Fields(line[1:])
+			if len(fields) < 2 {
+				continue
+			}
+			chain := &models.FirewallChain{Origin: origin, Family: family, Table: table, Name: fields[0], Hook: iptablesHooks[fields[0]]}
+			if fields[1] != "-" {
+				chain.Policy = strings.ToLower(fields[1])
+			}
+			if len(fields) > 2 {
+				chain.Packets, chain.Bytes = parseIptablesCounters(fields[2])
+			}
+			declared[chain.Name] = true
+			chains = append(chains, chain)
+		default:
+			rule := &models.FirewallRule{Origin: origin, Family: family, Table: table}
+			if strings.HasPrefix(line, "[") {
+				counters, rest, _ := strings.Cut(line, " ")
+				rule.Packets, rule.Bytes = parseIptablesCounters(counters)
+				line = rest
+			}
+			parseIptablesRule(rule, splitIptablesArgs(line), declared)
+			if rule.Chain == "" {
+				continue
+			}
+			positions[rule.Chain]++
+			rule.Position = positions[rule.Chain]
+			rules = append(rules, rule)
+		}
+	}
+	return chains, rules
+}
+
+// parseIptablesRule fills the rule from the arguments of an -A command, the unknown matches are ignored.
+func parseIptablesRule(rule *models.FirewallRule, args []string, declared map[string]bool) {
+	negate := false
+	for i := 0; i < len(args); i++ {
+		next := func() string {
+			if i+1 >= len(args) {
+				return ""
+			}
+			i++
+			if negate {
+				return "!" + args[i]
+			}
+			return args[i]
+		}
+		switch args[i] {
+		case "!":
+			negate = true
+			continue
+		case "-A", "--append":
+			rule.Chain = next()
+		case "-c", "--set-counters":
+			packets, bytes := next(), next()
+			rule.Packets, _ = strconv.ParseUint(packets, 10, 64)
+			rule.Bytes, _ = strconv.ParseUint(bytes, 10, 64)
+		case "-p", "--protocol":
+			rule.Protocol = next()
+		case "-s", "--source", "--src-range":
+			rule.SourceAddresses = splitIptablesList(next(), negate)
+		case "-d", "--destination", "--dst-range":
+			rule.DestinationAddresses = splitIptablesList(next(), negate)
+		case "-i", "--in-interface":
+			rule.InInterface = next()
+		case "-o", "--out-interface":
+			rule.OutInterface = next()
+		case "--sport", "--source-port", "--sports", "--source-ports":
+			rule.SourcePorts = splitIptablesPorts(next(), negate)
+		case "--dport", "--destination-port", "--dports", "--destination-ports":
+			rule.DestinationPorts = splitIptablesPorts(next(), negate)
+		case "--ctstate", "--state":
+			rule.States = splitIptablesList(strings.ToLower(next()), negate)
+		case "--comment":
+			rule.Comment = next()
+		case "-j", "--jump":
+			target := next()
+			switch action, ok := iptablesActions[target]; {
+			case ok:
+				rule.Action = action
+			case declared[target]:
+				rule.Action, rule.Target = models.FirewallActionJump, target
+			default:
+				rule.Action = strings.ToLower(target)
+			}
+		case "-g", "--goto":
+			rule.Action, rule.Target = models.FirewallActionGoto, next()
+		case "--to-destination", "--to-source", "--to-ports":
+			rule.Target = next()
+		}
+		negate = false
+	}
+}
+
+// splitIptablesList splits a comma separated list, each value is excluded when the list is negated.
+func splitIptablesList(value string, negate bool) []string {
+	values := strings.Split(strings.TrimPrefix(value, "!"), ",")
+	if negate {
+		for i := range values {
+			values[i] = "!" + values[i]
+		}
+	}
+	return values
+}
+
+// splitIptablesPorts splits a list of ports, whose ranges are written 1000:2000 by iptables.
+func splitIptablesPorts(value string, negate bool) []string {
+	return splitIptablesList(strings.ReplaceAll(value, ":", "-"), negate)
+}
+
+// parseIptablesCounters reads counters written [packets:bytes].
+func parseIptablesCounters(value string) (uint64, uint64) {
+	packets, bytes, _ := strings.Cut(strings.Trim(value, "[]"), ":")
+	p, _ := strconv.ParseUint(packets, 10, 64)
+	b, _ := strconv.ParseUint(bytes, 10, 64)
+	return p, b
+}
+
+// splitIptablesArgs splits a rule into its arguments, iptables-save quotes the values holding spaces.
+func splitIptablesArgs(line string) []string {
+	var (
+		args    []string
+		current strings.Builder
+		quoted  bool
+		started bool
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && quoted && i+1 < len(line):
+			i++
+			current.WriteByte(line[i])
+		case c == '"':
+			quoted = !quoted
+			started = true
+		case c == ' ' && !quoted:
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteByte(c)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
@@ -0,0 +1,66 @@
+package firewall
+
+import (
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const testIptablesSave = `# Generated by iptables-save v1.8.9 (nf_tables) on Mon Oct 19 08:00:00 2026
+*filter
+:INPUT DROP [120:8400]
+:FORWARD DROP [0:0]
+:OUTPUT ACCEPT [310:42000]
+:DOCKER - [0:0]
+[1520:184023] -A INPUT -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+[12:720] -A INPUT -i lo -j ACCEPT
+[3:180] -A INPUT -s 10.0.0.0/8 -p tcp -m multiport --dports 22,8000:8080 -m comment --comment "admin \"ssh\" access" -j ACCEPT
+[0:0] ! -s 192.168.0.0/16 -A INPUT -p udp -m udp ! --dport 53 -j LOG --log-prefix "udp: "
+[0:0] -A FORWARD -o docker0 -j DOCKER
+[0:0] -A DOCKER -d 172.17.0.2/32 ! -i docker0 -o docker0 -p tcp -m tcp --dport 80 -j ACCEPT
+COMMIT
+*nat
+:PREROUTING ACCEPT [0:0]
+:POSTROUTING ACCEPT [0:0]
+-A PREROUTING -p tcp --dport 8443 -j DNAT --to-destination 172.17.0.2:443
+-A POSTROUTING -s 172.17.0.0/16 ! -o docker0 -j MASQUERADE
+COMMIT
+`
+
+func TestParseIptablesSave(t *testing.T) {
+	chains, rules := ParseIptablesSave([]byte(testIptablesSave), OriginIptables)
+	assert.Len(t, chains, 6)
+	assert.Equal(t, &models.FirewallChain{
+		Origin: OriginIptables, Family: models.FirewallFamilyIPv4, Table: "filter", Name: "INPUT", Hook: "input", Policy: "drop", Packets: 120, Bytes: 8400,
+	}, chains[0])
+	assert.Equal(t, &models.FirewallChain{Origin: OriginIptables, Family: models.FirewallFamilyIPv4, Table: "filter", Name: "DOCKER"}, chains[3])
+
+	assert.Len(t, rules, 8)
+	assert.Equal(t, &models.FirewallRule{
+		Origin: OriginIptables, Family: models.FirewallFamilyIPv4, Table: "filter", Chain: "INPUT", Position: 1,
+		States: []string{"related", "established"}, Action: models.FirewallActionAccept, Packets: 1520, Bytes: 184023,
+	}, rules[0])
+	assert.Equal(t, &models.FirewallRule{
+		Origin: OriginIptables, Family: models.FirewallFamilyIPv4, Table: "filter", Chain: "INPUT", Position: 3,
+		Protocol: "tcp", SourceAddresses: []string{"10.0.0.0/8"}, DestinationPorts: []string{"22", "8000-8080"},
+		Comment: `admin "ssh" access`, Action: models.FirewallActionAccept, Packets: 3, Bytes: 180,
+	}, rules[2])
+	assert.Equal(t, []string{"!192.168.0.0/16"}, rules[3].SourceAddresses)
+	assert.Equal(t, []string{"!53"}, rules[3].DestinationPorts)
+	assert.Equal(t, models.FirewallActionLog, rules[3].Action)
+	assert.Equal(t, models.FirewallActionJump, rules[4].Action)
+	assert.Equal(t, "DOCKER", rules[4].Target)
+	assert.Equal(t, 1, rules[5].Position, "position in the DOCKER chain")
+	assert.Equal(t, "!docker0", rules[5].InInterface)
+
+	assert.Equal(t, "nat", rules[6].Table)
+	assert.Equal(t, models.FirewallActionDnat, rules[6].Action)
+	assert.Equal(t, "172.17.0.2:443", rules[6].Target)
+	assert.Equal(t, models.FirewallActionMasquerade, rules[7].Action)
+
+	chains, rules = ParseIptablesSave([]byte("*filter\n:INPUT ACCEPT [0:0]\n-A INPUT -s 2001:db8::/32 -p ipv6-icmp -j ACCEPT\nCOMMIT\n"), OriginIp6tables)
+	assert.Equal(t, models.FirewallFamilyIPv6, chains[0].Family)
+	assert.Equal(t, models.FirewallFamilyIPv6, rules[0].Family)
+	assert.Equal(t, []string{"2001:db8::/32"}, rules[0].SourceAddresses)
+}
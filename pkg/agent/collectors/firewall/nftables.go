@@ -0,0 +1,503 @@
+package firewall
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// nftDocument is the output of nft -j list ruleset, a list of objects holding a single typed member.
+type nftDocument struct {
+	Nftables []nftObject `json:"nftables"`
+}
+
+type nftObject struct {
+	Metainfo *struct {
+		Version string `json:"version"`
+	} `json:"metainfo,omitempty"`
+	Table *nftTable `json:"table,omitempty"`
+	Chain *nftChain `json:"chain,omitempty"`
+	Rule  *nftRule  `json:"rule,omitempty"`
+	Set   *nftSet   `json:"set,omitempty"`
+	Map   *nftSet   `json:"map,omitempty"`
+}
+
+type nftTable struct {
+	Family string `json:"family"`
+	Name   string `json:"name"`
+}
+
+type nftChain struct {
+	Family string `json:"family"`
+	Table  string `json:"table"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Hook   string `json:"hook"`
+	Prio   *int   `json:"prio"`
+	Policy string `json:"policy"`
+}
+
+type nftRule struct {
+	Family  string           `json:"family"`
+	Table   string           `json:"table"`
+	Chain   string           `json:"chain"`
+	Handle  int              `json:"handle"`
+	Comment string           `json:"comment"`
+	Expr    []map[string]any `json:"expr"`
+}
+
+type nftSet struct {
+	Family string `json:"family"`
+	Table  string `json:"table"`
+	Name   string `json:"name"`
+	// Type is a string, or a list of strings for concatenations.
+	Type  any      `json:"type"`
+	Map   any      `json:"map"`
+	Flags []string `json:"flags"`
+	Elem  []any    `json:"elem"`
+}
+
+// nftVerdicts are the statements ending the evaluation of a rule, or moving it to another chain.
+var nftVerdicts = map[string]bool{
+	"accept": true, "drop": true, "reject": true, "return": true, "continue": true, "queue": true,
+	"jump": true, "goto": true, "masquerade": true, "snat": true, "dnat": true, "redirect": true,
+}
+
+// ParseNftRuleset reads the JSON output of nft -j list ruleset.
+func ParseNftRuleset(data []byte) (*models.NftRuleset, error) {
+	doc, err := parseNftDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	return newNftRuleset(doc), nil
+}
+
+func parseNftDocument(data []byte) (*nftDocument, error) {
+	var doc nftDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid nftables ruleset: %w", err)
+	}
+	return &doc, nil
+}
+
+func newNftRuleset(doc *nftDocument) *models.NftRuleset {
+	ruleset := &models.NftRuleset{}
+	tables := map[string]*models.NftTable{}
+	chains := map[string]*models.NftChain{}
+	for _, obj := range doc.Nftables {
+		switch {
+		case obj.Metainfo != nil:
+			ruleset.Version = obj.Metainfo.Version
+		case obj.Table != nil:
+			table := &models.NftTable{Family: obj.Table.Family, Name: obj.Table.Name}
+			tables[obj.Table.Family+" "+obj.Table.Name] = table
+			ruleset.Tables = append(ruleset.Tables, table)
+		case obj.Chain != nil:
+			table := tables[obj.Chain.Family+" "+obj.Chain.Table]
+			if table == nil {
+				continue
+			}
+			chain := &models.NftChain{
+				Name:     obj.Chain.Name,
+				Type:     obj.Chain.Type,
+				Hook:     obj.Chain.Hook,
+				Priority: obj.Chain.Prio,
+				Policy:   obj.Chain.Policy,
+			}
+			chains[obj.Chain.Family+" "+obj.Chain.Table+" "+obj.Chain.Name] = chain
+			table.Chains = append(table.Chains, chain)
+		case obj.Rule != nil:
+			chain := chains[obj.Rule.Family+" "+obj.Rule.Table+" "+obj.Rule.Chain]
+			if chain == nil {
+				continue
+			}
+			chain.Rules = append(chain.Rules, newNftRule(obj.Rule))
+		case obj.Set != nil || obj.Map != nil:
+			set := obj.Set
+			if set == nil {
+				set = obj.Map
+			}
+			table := tables[set.Family+" "+set.Table]
+			if table == nil {
+				continue
+			}
+			table.Sets = append(table.Sets, newNftSet(set))
+		}
+	}
+	return ruleset
+}
+
+func newNftRule(r *nftRule) *models.NftRule {
+	rule := &models.NftRule{Handle: r.Handle, Comment: r.Comment}
+	var statements []string
+	for _, stmt := range r.Expr {
+		text, verdict := nftStatement(stmt)
+		if text != "" {
+			statements = append(statements, text)
+		}
+		if verdict {
+			rule.Verdict = text
+		}
+	}
+	rule.Expr = strings.Join(statements, " ")
+	return rule
+}
+
+func newNftSet(s *nftSet) *models.NftSet {
+	set := &models.NftSet{Name: s.Name, Type: nftType(s.Type), Map: nftType(s.Map), Flags: s.Flags}
+	for _, elem := range s.Elem {
+		// The elements of a map are [key, value] pairs
+		if pair, ok := elem.([]any); ok && len(pair) == 2 && s.Map != nil {
+			set.Elements = append(set.Elements, nftExpr(pair[0])+" : "+nftExpr(pair[1]))
+			continue
+		}
+		set.Elements = append(set.Elements, nftExpr(elem))
+	}
+	return set
+}
+
+// nftType returns the type of a set, the types of a concatenation are joined by a dot.
+func nftType(t any) string {
+	switch v := t.(type) {
+	case string:
+		return v
+	case []any:
+		var types []string
+		for _, e := range v {
+			types = append(types, fmt.Sprint(e))
+		}
+		return strings.Join(types, " . ")
+	}
+	return ""
+}
+
+// nftStatement renders a statement of a rule in the nft syntax, and reports whether it is a verdict.
+// Counters are rendered without their values, which change on every run.
+func nftStatement(stmt map[string]any) (string, bool) {
+	for name, arg := range stmt {
+		args, _ := arg.(map[string]any)
+		switch name {
+		case "match":
+			left, right := nftExpr(args["left"]), nftExpr(args["right"])
+			if op, _ := args["op"].(string); op != "" && op != "==" && op != "in" {
+				return left + " " + op + " " + right, false
+			}
+			return left + " " + right, false
+		case "counter":
+			if named, ok := arg.(string); ok {
+				return "counter name " + named, false
+			}
+			return "counter", false
+		case "jump", "goto":
+			return name + " " + nftExpr(args["target"]), true
+		case "reject":
+			text := "reject"
+			if t, ok := args["type"].(string); ok {
+				text += " with " + t
+				if e, ok := args["expr"]; ok {
+					text += " " + nftExpr(e)
+				}
+			}
+			return text, true
+		case "snat", "dnat", "masquerade", "redirect":
+			text := name
+			if addr, ok := args["addr"]; ok {
+				text += " to " + nftExpr(addr)
+				if port, ok := args["port"]; ok {
+					text += ":" + nftExpr(port)
+				}
+			} else if port, ok := args["port"]; ok {
+				text += " to :" + nftExpr(port)
+			}
+			return text, true
+		case "log":
+			text := "log"
+			if prefix, ok := args["prefix"].(string); ok {
+				text += " prefix " + strconv.Quote(prefix)
+			}
+			return text, false
+		case "limit":
+			text := fmt.Sprintf("limit rate %s/%s", nftExpr(args["rate"]), nftExpr(args["per"]))
+			if burst, ok := args["burst"]; ok {
+				text += " burst " + nftExpr(burst) + " packets"
+			}
+			return text, false
+		case "mangle":
+			return nftExpr(args["key"]) + " set " + nftExpr(args["value"]), false
+		case "xt":
+			// Statement of a rule added through iptables-nft
+			return fmt.Sprintf("xt %s %s", nftExpr(args["type"]), nftExpr(args["name"])), false
+		}
+		return name, nftVerdicts[name]
+	}
+	return "", false
+}
+
+// nftExpr renders an expression of a statement in the nft syntax.
+func nftExpr(e any) string {
+	switch v := e.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		return nftSetExpr(v)
+	case map[string]any:
+		for name, arg := range v {
+			args, _ := arg.(map[string]any)
+			switch name {
+			case "payload":
+				if base, ok := args["base"].(string); ok {
+					return fmt.Sprintf("@%s,%s,%s", base, nftExpr(args["offset"]), nftExpr(args["len"]))
+				}
+				return nftExpr(args["protocol"]) + " " + nftExpr(args["field"])
+			case "meta":
+				return "meta " + nftExpr(args["key"])
+			case "ct":
+				if dir, ok := args["dir"].(string); ok {
+					return "ct " + dir + " " + nftExpr(args["key"])
+				}
+				return "ct " + nftExpr(args["key"])
+			case "fib":
+				var flags []string
+				if list, ok := args["flags"].([]any); ok {
+					for _, f := range list {
+						flags = append(flags, nftExpr(f))
+					}
+				}
+				return "fib " + strings.Join(flags, " . ") + " " + nftExpr(args["result"])
+			case "prefix":
+				return nftExpr(args["addr"]) + "/" + nftExpr(args["len"])
+			case "range":
+				if bounds, ok := arg.([]any); ok && len(bounds) == 2 {
+					return nftExpr(bounds[0]) + "-" + nftExpr(bounds[1])
+				}
+			case "set":
+				if elems, ok := arg.([]any); ok {
+					return nftSetExpr(elems)
+				}
+				return nftExpr(arg)
+			case "concat":
+				if elems, ok := arg.([]any); ok {
+					var parts []string
+					for _, elem := range elems {
+						parts = append(parts, nftExpr(elem))
+					}
+					return strings.Join(parts, " . ")
+				}
+			case "elem":
+				return nftExpr(args["val"])
+			case "map":
+				return nftExpr(args["key"]) + " map " + nftExpr(args["data"])
+			case "&", "|", "^", "<<", ">>":
+				if ops, ok := arg.([]any); ok && len(ops) == 2 {
+					return nftExpr(ops[0]) + " " + name + " " + nftExpr(ops[1])
+				}
+			default:
+				// Verdicts used as map values
+				if nftVerdicts[name] {
+					if target, ok := args["target"]; ok {
+						return name + " " + nftExpr(target)
+					}
+					return name
+				}
+			}
+			return name
+		}
+	}
+	return fmt.Sprint(e)
+}
+
+// nftSetExpr renders an anonymous set, its elements are sorted to keep the rendering stable.
+func nftSetExpr(elems []any) string {
+	var parts []string
+	for _, elem := range elems {
+		parts = append(parts, nftExpr(elem))
+	}
+	sort.Strings(parts)
+	return "{ " + strings.Join(parts, ", ") + " }"
+}
+
+// isIptablesNftTable reports whether a table has been created by iptables-nft, whose tables are ip or ip6
+// tables named after the iptables ones, with the upper case iptables chains.
+func isIptablesNftTable(table *models.NftTable) bool {
+	if table.Family != "ip" && table.Family != "ip6" {
+		return false
+	}
+	switch table.Name {
+	case "filter", "nat", "mangle", "raw", "security":
+	default:
+		return false
+	}
+	for _, chain := range table.Chains {
+		if chain.Hook != "" && chain.Name != strings.ToUpper(chain.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// nftFirewallRules returns the normalised chains and rules of the ruleset. The tables of iptables-nft are
+// skipped, their rules are read through iptables-save which knows their matches.
+func nftFirewallRules(doc *nftDocument, ruleset *models.NftRuleset) ([]*models.FirewallChain, []*models.FirewallRule) {
+	compat := map[string]bool{}
+	for _, table := range ruleset.Tables {
+		if isIptablesNftTable(table) {
+			compat[table.Family+" "+table.Name] = true
+		}
+	}
+	var (
+		chains    []*models.FirewallChain
+		rules     []*models.FirewallRule
+		positions = map[string]int{}
+	)
+	for _, obj := range doc.Nftables {
+		switch {
+		case obj.Chain != nil && !compat[obj.Chain.Family+" "+obj.Chain.Table]:
+			chains = append(chains, &models.FirewallChain{
+				Origin: OriginNftables,
+				Family: nftFamily(obj.Chain.Family),
+				Table:  obj.Chain.Table,
+				Name:   obj.Chain.Name,
+				Hook:   obj.Chain.Hook,
+				Policy: obj.Chain.Policy,
+			})
+		case obj.Rule != nil && !compat[obj.Rule.Family+" "+obj.Rule.Table]:
+			key := obj.Rule.Family + " " + obj.Rule.Table + " " + obj.Rule.Chain
+			positions[key]++
+			rules = append(rules, newNftFirewallRule(obj.Rule, positions[key]))
+		}
+	}
+	return chains, rules
+}
+
+// nftFamily returns the normalised family of a table, the families other than ip, ip6 and inet are kept.
+func nftFamily(family string) string {
+	switch family {
+	case "ip":
+		return models.FirewallFamilyIPv4
+	case "ip6":
+		return models.FirewallFamilyIPv6
+	}
+	return family
+}
+
+func newNftFirewallRule(r *nftRule, position int) *models.FirewallRule {
+	rule := &models.FirewallRule{
+		Origin:   OriginNftables,
+		Family:   nftFamily(r.Family),
+		Table:    r.Table,
+		Chain:    r.Chain,
+		Position: position,
+		Comment:  r.Comment,
+	}
+	logged := false
+	for _, stmt := range r.Expr {
+		for name, arg := range stmt {
+			args, _ := arg.(map[string]any)
+			switch name {
+			case "match":
+				nftMatch(rule, args)
+			case "counter":
+				packets, _ := args["packets"].(float64)
+				bytes, _ := args["bytes"].(float64)
+				rule.Packets, rule.Bytes = uint64(packets), uint64(bytes)
+			case "accept", "drop", "reject", "return", "queue", "masquerade":
+				rule.Action = name
+			case "jump", "goto":
+				rule.Action, rule.Target = name, nftExpr(args["target"])
+			case "snat", "dnat", "redirect":
+				rule.Action = name
+				rule.Target = nftExpr(args["addr"])
+				if port, ok := args["port"]; ok {
+					rule.Target += ":" + nftExpr(port)
+				}
+			case "log":
+				logged = true
+			}
+		}
+	}
+	if rule.Action == "" && logged {
+		rule.Action = models.FirewallActionLog
+	}
+	return rule
+}
+
+// nftMatch fills the criteria of the rule matched by a match statement, the other matches are ignored.
+func nftMatch(rule *models.FirewallRule, args map[string]any) {
+	op, _ := args["op"].(string)
+	values := nftValues(args["right"], op == "!=")
+	left, _ := args["left"].(map[string]any)
+	if payload, ok := left["payload"].(map[string]any); ok {
+		protocol, field := nftExpr(payload["protocol"]), nftExpr(payload["field"])
+		switch field {
+		case "saddr":
+			rule.SourceAddresses = values
+		case "daddr":
+			rule.DestinationAddresses = values
+		case "protocol", "nexthdr":
+			rule.Protocol = strings.Join(values, ",")
+		case "sport":
+			rule.SourcePorts = values
+		case "dport":
+			rule.DestinationPorts = values
+		}
+		switch protocol {
+		case "ip", "ip6":
+			if rule.Family == models.FirewallFamilyInet {
+				rule.Family = nftFamily(protocol)
+			}
+		case "tcp", "udp", "sctp", "dccp", "icmp", "icmpv6":
+			if rule.Protocol == "" {
+				rule.Protocol = protocol
+			}
+		}
+		return
+	}
+	if meta, ok := left["meta"].(map[string]any); ok {
+		switch nftExpr(meta["key"]) {
+		case "l4proto":
+			rule.Protocol = strings.Join(values, ",")
+		case "nfproto":
+			if rule.Family == models.FirewallFamilyInet && len(values) == 1 {
+				rule.Family = values[0]
+			}
+		case "iifname", "iif":
+			rule.InInterface = strings.Join(values, ",")
+		case "oifname", "oif":
+			rule.OutInterface = strings.Join(values, ",")
+		}
+		return
+	}
+	if ct, ok := left["ct"].(map[string]any); ok && nftExpr(ct["key"]) == "state" {
+		rule.States = values
+	}
+}
+
+// nftValues returns the values of the right side of a match, a set gives one value by element.
+func nftValues(right any, negate bool) []string {
+	elems, ok := right.([]any)
+	if set, isSet := right.(map[string]any); isSet {
+		elems, ok = set["set"].([]any)
+	}
+	if !ok {
+		elems = []any{right}
+	}
+	values := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		value := nftExpr(elem)
+		if negate {
+			value = "!" + value
+		}
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,95 @@
+package firewall
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// GetUfwStatus returns the status of ufw, it needs the root privileges.
+func GetUfwStatus(ctx context.Context) (*models.UfwStatus, error) {
+	out, err := runCommand(ctx, "ufw", "status", "verbose")
+	if err != nil {
+		return nil, err
+	}
+	return ParseUfwStatus(out), nil
+}
+
+// ParseUfwStatus reads the output of ufw status verbose. The rules are a table whose columns are
+// found from the position of the To, Action and From headers.
+//
+//	Status: active
+//	Default: deny (incoming), allow (outgoing), disabled (routed)
+//
+//	To                         Action      From
+//	--                         ------      ----
+//	22/tcp                     ALLOW IN    Anywhere                   # ssh
+//	22/tcp (v6)                ALLOW IN    Anywhere (v6)
+func ParseUfwStatus(data []byte) *models.UfwStatus {
+	status := &models.UfwStatus{}
+	actionCol, fromCol := -1, -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "Status:"):
+			status.Active = strings.TrimSpace(strings.TrimPrefix(line, "Status:")) == "active"
+		case strings.HasPrefix(line, "Logging:"):
+			status.Logging = strings.TrimSpace(strings.TrimPrefix(line, "Logging:"))
+		case strings.HasPrefix(line, "Default:"):
+			for _, policy := range strings.Split(strings.TrimPrefix(line, "Default:"), ",") {
+				action, direction, _ := strings.Cut(strings.TrimSpace(policy), " ")
+				switch strings.Trim(direction, "()") {
+				case "incoming":
+					status.DefaultIncoming = action
+				case "outgoing":
+					status.DefaultOutgoing = action
+				case "routed":
+					status.DefaultRouted = action
+				}
+			}
+		case strings.HasPrefix(line, "To ") && strings.Contains(line, "Action"):
+			actionCol, fromCol = strings.Index(line, "Action"), strings.Index(line, "From")
+		case actionCol < 0 || strings.HasPrefix(line, "--") || strings.TrimSpace(line) == "":
+			continue
+		default:
+			status.Rules = append(status.Rules, parseUfwRule(line, actionCol, fromCol))
+		}
+	}
+	return status
+}
+
+func parseUfwRule(line string, actionCol, fromCol int) *models.UfwRule {
+	rule := &models.UfwRule{}
+	if before, comment, ok := strings.Cut(line, " # "); ok {
+		line, rule.Comment = before, strings.TrimSpace(comment)
+	}
+	column := func(start, end int) string {
+		if start >= len(line) {
+			return ""
+		}
+		if end < 0 || end > len(line) {
+			end = len(line)
+		}
+		return strings.TrimSpace(line[start:end])
+	}
+	rule.To = column(0, actionCol)
+	rule.From = column(fromCol, -1)
+	action := strings.Fields(column(actionCol, fromCol))
+	if len(action) > 0 {
+		rule.Action = action[0]
+	}
+	if len(action) > 1 {
+		rule.Direction = action[1]
+	}
+	for _, field := range []*string{&rule.To, &rule.From} {
+		if strings.HasSuffix(*field, " (v6)") {
+			*field = strings.TrimSuffix(*field, " (v6)")
+			rule.IPv6 = true
+		}
+	}
+	return rule
+}
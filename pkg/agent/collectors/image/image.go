@@ -0,0 +1,167 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+)
+
+// Sources of the image filesystems.
+const (
+	SourceExport  = "export"  // Images saved through the docker API
+	SourceStorage = "storage" // Layers read from the overlay2 storage of the daemon
+)
+
+// DockerAPI is the part of the docker client used to unpack the images.
+type DockerAPI interface {
+	ImageList(ctx context.Context, options image.ListOptions) ([]image.Summary, error)
+	ImageInspect(ctx context.Context, imageID string, inspectOpts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ImageSave(ctx context.Context, imageIDs []string, saveOpts ...client.ImageSaveOption) (io.ReadCloser, error)
+}
+
+// NewDockerClient returns a docker client configured from the environment (DOCKER_HOST, etc ...).
+func NewDockerClient() (DockerAPI, error) {
+	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+}
+
+// ListImages returns the images to scan, the most recent first. Untagged images are skipped unless configured.
+func ListImages(ctx context.Context, api DockerAPI, cfg *options.DockerImagesOptions) ([]image.Summary, error) {
+	imgs, err := api.ImageList(ctx, image.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list docker images: %w", err)
+	}
+	selected := make([]image.Summary, 0, len(imgs))
+	for _, img := range imgs {
+		if !cfg.Dangling && !tagged(img.RepoTags) {
+			continue
+		}
+		selected = append(selected, img)
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].Created > selected[j].Created })
+	if cfg.MaxImages > 0 && len(selected) > cfg.MaxImages {
+		selected = selected[:cfg.MaxImages]
+	}
+	return selected, nil
+}
+
+func tagged(tags []string) bool {
+	for _, tag := range tags {
+		if tag != "<none>:<none>" {
+			return true
+		}
+	}
+	return false
+}
+
+// Unpack writes the filesystem of an image below dir and returns its root.
+// The caller removes dir once the filesystem has been read.
+func Unpack(ctx context.Context, logger *logrus.Logger, api DockerAPI, cfg *options.DockerImagesOptions, id, dir string) (string, error) {
+	root := filepath.Join(dir, "rootfs")
+	t, err := newTree(logger, root)
+	if err != nil {
+		return "", err
+	}
+	switch cfg.Source {
+	case "", SourceExport:
+		err = unpackExport(ctx, t, api, id, filepath.Join(dir, "save"))
+	case SourceStorage:
+		err = unpackStorage(ctx, t, api, id, cfg.DataRoot)
+	default:
+		return "", fmt.Errorf("unknown image source %q, expected %s or %s", cfg.Source, SourceExport, SourceStorage)
+	}
+	if err != nil {
+		return "", err
+	}
+	return root, nil
+}
+
+// saveManifest is an entry of the manifest.json written by docker save.
+type saveManifest struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+// unpackExport saves the image through the docker API, then applies its layers in order.
+func unpackExport(ctx context.Context, t *tree, api DockerAPI, id, saveDir string) error {
+	rc, err := api.ImageSave(ctx, []string{id})
+	if err != nil {
+		return fmt.Errorf("unable to save image: %w", err)
+	}
+	save, err := newTree(t.logger, saveDir)
+	if err != nil {
+		rc.Close()
+		return err
+	}
+	err = save.extract(rc)
+	rc.Close()
+	// The saved archive is as large as the image, it is not kept once unpacked
+	defer os.RemoveAll(saveDir)
+	if err != nil {
+		return err
+	}
+
+	bin, err := os.ReadFile(filepath.Join(saveDir, "manifest.json"))
+	if err != nil {
+		return fmt.Errorf("unable to read image manifest: %w", err)
+	}
+	var manifests []saveManifest
+	if err := json.Unmarshal(bin, &manifests); err != nil {
+		return fmt.Errorf("unable to parse image manifest: %w", err)
+	}
+	if len(manifests) != 1 {
+		return fmt.Errorf("%d images in the saved archive, expected one", len(manifests))
+	}
+	for _, layer := range manifests[0].Layers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		path, ok := save.path(layer)
+		if !ok {
+			return fmt.Errorf("invalid layer path %s", layer)
+		}
+		if err := applyLayerFile(t, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyLayerFile(t *tree, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return t.applyLayer(f)
+}
+
+// unpackStorage copies the layer directories of the overlay2 storage, the lowest first.
+func unpackStorage(ctx context.Context, t *tree, api DockerAPI, id, dataRoot string) error {
+	inspect, err := api.ImageInspect(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to inspect image: %w", err)
+	}
+	layers, err := overlayLayers(inspect.GraphDriver.Name, inspect.GraphDriver.Data, dataRoot)
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := t.applyOverlay(layer); err != nil {
+			return fmt.Errorf("unable to read layer %s: %w", layer, err)
+		}
+	}
+	return nil
+}
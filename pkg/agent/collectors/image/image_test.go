@@ -0,0 +1,246 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type entry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+func writeTar(t *testing.T, entries []entry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0o644, Size: int64(len(e.body)), Linkname: e.linkname}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0o755
+		}
+		assert.NoError(t, tw.WriteHeader(hdr))
+		if e.body != "" {
+			_, err := tw.Write([]byte(e.body))
+			assert.NoError(t, err)
+		}
+	}
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+type fakeDocker struct {
+	images  []image.Summary
+	saved   []byte
+	inspect image.InspectResponse
+}
+
+func (f *fakeDocker) ImageList(context.Context, image.ListOptions) ([]image.Summary, error) {
+	return f.images, nil
+}
+
+func (f *fakeDocker) ImageInspect(context.Context, string, ...client.ImageInspectOption) (image.InspectResponse, error) {
+	return f.inspect, nil
+}
+
+func (f *fakeDocker) ImageSave(context.Context, []string, ...client.ImageSaveOption) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(f.saved)), nil
+}
+
+// savedImage returns a docker save archive of two layers, the upper one gzipped and holding whiteouts.
+func savedImage(t *testing.T) []byte {
+	base := writeTar(t, []entry{
+		{name: "etc/", typeflag: tar.TypeDir},
+		{name: "etc/os-release", typeflag: tar.TypeReg, body: "ID=debian\nVERSION_ID=\"12\"\n"},
+		{name: "etc/old.conf", typeflag: tar.TypeReg, body: "old"},
+		{name: "etc/hostname", typeflag: tar.TypeLink, linkname: "etc/old.conf"},
+		{name: "opt/app/a.txt", typeflag: tar.TypeReg, body: "a"},
+		{name: "usr/bin/tool", typeflag: tar.TypeReg, body: "tool"},
+		{name: "bin", typeflag: tar.TypeSymlink, linkname: "/usr/bin"},
+		{name: "etc/passwd-link", typeflag: tar.TypeSymlink, linkname: "../../../../etc/passwd"},
+		{name: "../../escape.txt", typeflag: tar.TypeReg, body: "inside"},
+	})
+	upper := gzipped(t, writeTar(t, []entry{
+		{name: "etc/.wh.old.conf", typeflag: tar.TypeReg},
+		{name: "opt/app/", typeflag: tar.TypeDir},
+		{name: "opt/app/b.txt", typeflag: tar.TypeReg, body: "b"},
+		{name: "opt/app/.wh..wh..opq", typeflag: tar.TypeReg},
+		{name: "bin/injected", typeflag: tar.TypeReg, body: "outside"},
+	}))
+	manifest, err := json.Marshal([]saveManifest{{Config: "blobs/sha256/cfg", RepoTags: []string{"app:1"}, Layers: []string{"blobs/sha256/base", "blobs/sha256/upper"}}})
+	assert.NoError(t, err)
+	return writeTar(t, []entry{
+		{name: "blobs/sha256/base", typeflag: tar.TypeReg, body: string(base)},
+		{name: "blobs/sha256/upper", typeflag: tar.TypeReg, body: string(upper)},
+		{name: "manifest.json", typeflag: tar.TypeReg, body: string(manifest)},
+	})
+}
+
+func TestUnpack_Export(t *testing.T) {
+	dir := t.TempDir()
+	api := &fakeDocker{saved: savedImage(t)}
+	root, err := Unpack(context.Background(), logrus.New(), api, &options.DockerImagesOptions{Source: SourceExport}, "sha256:1234", dir)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "rootfs"), root)
+
+	read := func(name string) string {
+		bin, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			return ""
+		}
+		return string(bin)
+	}
+	assert.Contains(t, read("etc/os-release"), "ID=debian")
+	assert.Equal(t, "inside", read("escape.txt"))
+
+	// Whiteouts of the upper layer, the hard link to the removed file remains
+	assert.NoFileExists(t, filepath.Join(root, "etc/old.conf"))
+	assert.Equal(t, "old", read("etc/hostname"))
+	assert.NoFileExists(t, filepath.Join(root, "opt/app/a.txt"))
+	assert.Equal(t, "b", read("opt/app/b.txt"))
+
+	// Absolute links are kept inside the image, links escaping it and entries below links are skipped
+	target, err := os.Readlink(filepath.Join(root, "bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "usr/bin"), target)
+	assert.Equal(t, "tool", read("bin/tool"))
+	_, err = os.Lstat(filepath.Join(root, "etc/passwd-link"))
+	assert.True(t, os.IsNotExist(err))
+	assert.NoFileExists(t, filepath.Join(root, "usr/bin/injected"))
+
+	// The saved archive is removed once unpacked
+	assert.NoDirExists(t, filepath.Join(dir, "save"))
+}
+
+func TestApplyLayer_SymlinkChain(t *testing.T) {
+	// The parent of the root holds the files of the host
+	host := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(host, "etc"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(host, "etc/os-release"), []byte("ID=host\n"), 0o644))
+	root := filepath.Join(host, "rootfs")
+	tr, err := newTree(logrus.New(), root)
+	assert.NoError(t, err)
+
+	layer := writeTar(t, []entry{
+		{name: "usr/lib/os-release", typeflag: tar.TypeReg, body: "ID=debian\n"},
+		{name: "etc/", typeflag: tar.TypeDir},
+		{name: "etc/os-release", typeflag: tar.TypeSymlink, linkname: "../usr/lib/os-release"},
+		// d/l is the root, a would be the parent of the root: lexically d, really the host
+		{name: "d/", typeflag: tar.TypeDir},
+		{name: "d/l", typeflag: tar.TypeSymlink, linkname: ".."},
+		{name: "a", typeflag: tar.TypeSymlink, linkname: "d/l/.."},
+		{name: "x", typeflag: tar.TypeSymlink, linkname: "a/.."},
+		{name: "hostetc", typeflag: tar.TypeSymlink, linkname: "a/etc"},
+	})
+	assert.NoError(t, tr.applyLayer(bytes.NewReader(layer)))
+	// A link of an upper layer created below the links written before
+	assert.NoError(t, tr.applyLayer(bytes.NewReader(writeTar(t, []entry{
+		{name: "a", typeflag: tar.TypeSymlink, linkname: "d/l/../.."},
+	}))))
+
+	bin, err := os.ReadFile(filepath.Join(root, "etc/os-release"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ID=debian\n", string(bin))
+	target, err := os.Readlink(filepath.Join(root, "d/l"))
+	assert.NoError(t, err)
+	assert.Equal(t, root, target)
+	_, err = os.Lstat(filepath.Join(root, "a"))
+	assert.True(t, os.IsNotExist(err))
+	target, err = os.Readlink(filepath.Join(root, "x"))
+	assert.NoError(t, err)
+	assert.Equal(t, root, target)
+	_, err = os.ReadFile(filepath.Join(root, "hostetc/os-release"))
+	assert.Error(t, err, "the os-release of the host is not reachable")
+}
+
+func TestUnpack_UnknownSource(t *testing.T) {
+	_, err := Unpack(context.Background(), logrus.New(), &fakeDocker{}, &options.DockerImagesOptions{Source: "registry"}, "sha256:1234", t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestListImages(t *testing.T) {
+	api := &fakeDocker{images: []image.Summary{
+		{ID: "sha256:old", RepoTags: []string{"app:1"}, Created: 100},
+		{ID: "sha256:dangling", RepoTags: []string{"<none>:<none>"}, Created: 300},
+		{ID: "sha256:new", RepoTags: []string{"app:2"}, Created: 200},
+	}}
+
+	imgs, err := ListImages(context.Background(), api, &options.DockerImagesOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, imgs, 2) {
+		assert.Equal(t, "sha256:new", imgs[0].ID)
+		assert.Equal(t, "sha256:old", imgs[1].ID)
+	}
+
+	imgs, err = ListImages(context.Background(), api, &options.DockerImagesOptions{Dangling: true, MaxImages: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, imgs, 2) {
+		assert.Equal(t, "sha256:dangling", imgs[0].ID)
+		assert.Equal(t, "sha256:new", imgs[1].ID)
+	}
+}
+
+func TestOverlayLayers(t *testing.T) {
+	data := map[string]string{
+		"LowerDir": "/var/lib/docker/overlay2/l2/diff:/var/lib/docker/overlay2/l1/diff",
+		"UpperDir": "/var/lib/docker/overlay2/top/diff",
+	}
+	layers, err := overlayLayers("overlay2", data, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"/var/lib/docker/overlay2/l1/diff",
+		"/var/lib/docker/overlay2/l2/diff",
+		"/var/lib/docker/overlay2/top/diff",
+	}, layers)
+
+	layers, err = overlayLayers("overlay2", data, "/host/docker")
+	assert.NoError(t, err)
+	assert.Equal(t, "/host/docker/overlay2/l1/diff", layers[0])
+
+	_, err = overlayLayers("btrfs", data, "")
+	assert.ErrorContains(t, err, "use the export source")
+}
+
+func TestApplyOverlay(t *testing.T) {
+	lower, upper := t.TempDir(), t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(lower, "etc"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(lower, "etc/os-release"), []byte("ID=alpine\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(lower, "etc/motd"), []byte("lower"), 0o644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(upper, "etc"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(upper, "etc/motd"), []byte("upper"), 0o644))
+	assert.NoError(t, os.Symlink("/etc/motd", filepath.Join(upper, "motd")))
+
+	root := filepath.Join(t.TempDir(), "rootfs")
+	tr, err := newTree(logrus.New(), root)
+	assert.NoError(t, err)
+	assert.NoError(t, tr.applyOverlay(lower))
+	assert.NoError(t, tr.applyOverlay(upper))
+
+	bin, err := os.ReadFile(filepath.Join(root, "motd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "upper", string(bin))
+	assert.FileExists(t, filepath.Join(root, "etc/os-release"))
+}
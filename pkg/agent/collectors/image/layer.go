@@ -0,0 +1,95 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// applyLayer applies a layer archive, optionally gzipped, on top of the tree.
+func (t *tree) applyLayer(r io.Reader) error {
+	t.startLayer()
+	tr, err := layerReader(r)
+	if err != nil {
+		return err
+	}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read layer: %w", err)
+		}
+		if err := t.applyEntry(tr, hdr); err != nil {
+			return fmt.Errorf("unable to apply %s: %w", hdr.Name, err)
+		}
+	}
+}
+
+// extract writes every entry of an archive, such as the one written by docker save, without whiteouts.
+func (t *tree) extract(r io.Reader) error {
+	t.startLayer()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read archive: %w", err)
+		}
+		if err := t.writeEntry(tr, hdr); err != nil {
+			return fmt.Errorf("unable to extract %s: %w", hdr.Name, err)
+		}
+	}
+}
+
+func layerReader(r io.Reader) (*tar.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return tar.NewReader(zr), nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		return nil, errUnsupported("zstd compressed layer")
+	}
+	return tar.NewReader(br), nil
+}
+
+func (t *tree) applyEntry(tr *tar.Reader, hdr *tar.Header) error {
+	if ok, err := t.whiteout(hdr.Name); ok || err != nil {
+		return err
+	}
+	return t.writeEntry(tr, hdr)
+}
+
+func (t *tree) writeEntry(tr *tar.Reader, hdr *tar.Header) error {
+	mode := os.FileMode(hdr.Mode).Perm()
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return t.mkdir(hdr.Name, mode)
+	case tar.TypeReg:
+		return t.writeFile(hdr.Name, mode, tr)
+	case tar.TypeSymlink:
+		return t.symlink(hdr.Name, hdr.Linkname)
+	case tar.TypeLink:
+		return t.link(hdr.Name, hdr.Linkname)
+	}
+	// Devices, fifos and the extended headers are not needed to read the packages
+	return nil
+}
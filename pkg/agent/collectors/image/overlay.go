@@ -0,0 +1,103 @@
+package image
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// overlayOpaqueXattr marks the directories hiding the entries of the lower layers.
+const overlayOpaqueXattr = "trusted.overlay.opaque"
+
+// overlayLayers returns the layer directories of an overlay2 image, the lowest first. The paths reported by
+// the daemon are moved below dataRoot when set, for a data root mounted elsewhere.
+func overlayLayers(driver string, data map[string]string, dataRoot string) ([]string, error) {
+	if driver != "overlay2" {
+		return nil, errUnsupported("storage driver %s, only overlay2 is read, use the export source", driver)
+	}
+	var dirs []string
+	if upper := data["UpperDir"]; upper != "" {
+		dirs = append(dirs, upper)
+	}
+	if lower := data["LowerDir"]; lower != "" {
+		// Lower directories are listed from the top
+		dirs = append(dirs, strings.Split(lower, ":")...)
+	}
+	if len(dirs) == 0 {
+		return nil, errUnsupported("no layer directory reported by the daemon")
+	}
+
+	layers := make([]string, 0, len(dirs))
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		if dataRoot != "" {
+			if _, rel, found := strings.Cut(dir, "/overlay2/"); found {
+				dir = filepath.Join(dataRoot, "overlay2", rel)
+			}
+		}
+		layers = append(layers, dir)
+	}
+	return layers, nil
+}
+
+// applyOverlay applies a layer directory of the overlay2 storage on top of the tree. Whiteouts are
+// character devices 0/0, opaque directories carry the trusted.overlay.opaque extended attribute.
+func (t *tree) applyOverlay(dir string) error {
+	t.startLayer()
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil || name == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			if err := t.mkdir(name, mode); err != nil {
+				return err
+			}
+			if opaque(path) {
+				return t.clear(name)
+			}
+		case mode.IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return t.writeFile(name, mode, f)
+		case mode&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return t.symlink(name, target)
+		case mode&os.ModeCharDevice != 0 && isWhiteoutDevice(info):
+			return t.remove(name)
+		}
+		return nil
+	})
+}
+
+func opaque(path string) bool {
+	value := make([]byte, 1)
+	n, err := unix.Lgetxattr(path, overlayOpaqueXattr, value)
+	return err == nil && n == 1 && value[0] == 'y'
+}
+
+func isWhiteoutDevice(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*unix.Stat_t)
+	if !ok {
+		return false
+	}
+	return uint64(st.Rdev) == 0
+}
@@ -0,0 +1,257 @@
+package image
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Whiteout markers of the OCI image layers, see https://github.com/opencontainers/image-spec/blob/main/layer.md#whiteouts.
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// maxSymlinkHops bounds the resolution of the link targets, as the kernel does with ELOOP.
+const maxSymlinkHops = 40
+
+// tree writes the entries of the image layers under its root. Nothing written can escape the root:
+// entries below a symbolic link are skipped, link targets are resolved through the links of the tree and
+// written as absolute paths under the root, and the targets going above the root are skipped. As the written
+// links never hold "..", the links of the upper layers cannot turn them into an escape.
+// Ownership, special bits and devices are dropped.
+type tree struct {
+	root   string
+	logger *logrus.Logger
+	// layer records the entries of the layer being applied, spared by the opaque whiteouts of the same layer.
+	layer map[string]bool
+}
+
+func newTree(logger *logrus.Logger, root string) (*tree, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &tree{root: filepath.Clean(root), logger: logger}, nil
+}
+
+// startLayer resets the entries of the layer being applied.
+func (t *tree) startLayer() {
+	t.layer = map[string]bool{}
+}
+
+// path returns the path of an entry of the image under the root, false when the entry must be skipped.
+func (t *tree) path(name string) (string, bool) {
+	clean := filepath.Clean("/" + name)
+	if clean == "/" {
+		return "", false
+	}
+	// Parents resolved through a link could be outside of the root
+	dir := t.root
+	for _, part := range strings.Split(filepath.Dir(clean), "/")[1:] {
+		if part == "" {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if err != nil {
+			break
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			t.logger.WithField("path", clean).Debug("entry below a symbolic link skipped")
+			return "", false
+		}
+	}
+	return filepath.Join(t.root, clean), true
+}
+
+// prepare returns the path of a new entry, removing the entry it replaces and creating its parents.
+func (t *tree) prepare(name string) (string, bool, error) {
+	path, ok := t.path(name)
+	if !ok {
+		return "", false, nil
+	}
+	t.layer[path] = true
+	if _, err := os.Lstat(path); err == nil {
+		if err := os.RemoveAll(path); err != nil {
+			return "", false, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", false, err
+	}
+	return path, true, nil
+}
+
+func (t *tree) mkdir(name string, mode os.FileMode) error {
+	path, ok := t.path(name)
+	if !ok {
+		return nil
+	}
+	t.layer[path] = true
+	if info, err := os.Lstat(path); err == nil {
+		if info.IsDir() {
+			return os.Chmod(path, dirMode(mode))
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(path, dirMode(mode)); err != nil {
+		return err
+	}
+	return os.Chmod(path, dirMode(mode))
+}
+
+func (t *tree) writeFile(name string, mode os.FileMode, r io.Reader) error {
+	path, ok, err := t.prepare(name)
+	if err != nil || !ok {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (t *tree) symlink(name, target string) error {
+	path, ok := t.path(name)
+	if !ok {
+		return nil
+	}
+	resolved, ok := t.resolve(filepath.Dir(path), target)
+	if !ok {
+		t.logger.WithField("path", name).Debugf("symbolic link to %s outside of the image skipped", target)
+		return nil
+	}
+	path, ok, err := t.prepare(name)
+	if err != nil || !ok {
+		return err
+	}
+	return os.Symlink(resolved, path)
+}
+
+// resolve returns the path under the root a link target refers to, from the directory of the link and from
+// the root for an absolute target. The links of the tree met on the way are followed, the missing entries are
+// joined as they are. It is false when the target goes above the root or has too many links.
+func (t *tree) resolve(dir, target string) (string, bool) {
+	current := dir
+	if filepath.IsAbs(target) {
+		current = t.root
+	}
+	parts := strings.Split(target, "/")
+	for hops := 0; len(parts) > 0; {
+		part := parts[0]
+		parts = parts[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if current == t.root {
+				return "", false
+			}
+			current = filepath.Dir(current)
+			continue
+		}
+		next := filepath.Join(current, part)
+		link, err := os.Readlink(next)
+		if err != nil {
+			current = next
+			continue
+		}
+		if hops++; hops > maxSymlinkHops {
+			return "", false
+		}
+		// The links of the tree are absolute paths under the root
+		rel, err := filepath.Rel(t.root, link)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			return "", false
+		}
+		current = t.root
+		parts = append(strings.Split(rel, "/"), parts...)
+	}
+	return current, true
+}
+
+// link creates a hard link to an entry written before, as the image layers reference their own entries.
+func (t *tree) link(name, target string) error {
+	source, ok := t.path(target)
+	if !ok {
+		return nil
+	}
+	info, err := os.Lstat(source)
+	if err != nil || !info.Mode().IsRegular() {
+		t.logger.WithField("path", name).Debugf("hard link to missing %s skipped", target)
+		return nil
+	}
+	path, ok, err := t.prepare(name)
+	if err != nil || !ok {
+		return err
+	}
+	return os.Link(source, path)
+}
+
+// remove deletes an entry hidden by a whiteout of the upper layer.
+func (t *tree) remove(name string) error {
+	path, ok := t.path(name)
+	if !ok {
+		return nil
+	}
+	return os.RemoveAll(path)
+}
+
+// clear deletes the entries of a directory from the lower layers, hidden by an opaque whiteout.
+func (t *tree) clear(dir string) error {
+	path, ok := t.path(dir + "/" + whiteoutOpaque)
+	if !ok {
+		return nil
+	}
+	path = filepath.Dir(path)
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		child := filepath.Join(path, entry.Name())
+		if t.layer[child] {
+			continue
+		}
+		if err := os.RemoveAll(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// whiteout applies the entry when it is a whiteout marker, and reports whether it was one.
+func (t *tree) whiteout(name string) (bool, error) {
+	dir, base := filepath.Split(filepath.Clean("/" + name))
+	switch {
+	case base == whiteoutOpaque:
+		return true, t.clear(dir)
+	case strings.HasPrefix(base, whiteoutPrefix):
+		return true, t.remove(dir + strings.TrimPrefix(base, whiteoutPrefix))
+	}
+	return false, nil
+}
+
+// dirMode keeps the directories writable and traversable, to fill and remove them.
+func dirMode(mode os.FileMode) os.FileMode {
+	return mode.Perm() | 0o700
+}
+
+// errUnsupported reports an image which cannot be unpacked.
+func errUnsupported(format string, args ...any) error {
+	return fmt.Errorf("unsupported image: "+format, args...)
+}
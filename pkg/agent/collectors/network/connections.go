@@ -6,39 +6,37 @@ import (
 	"github.com/klamhq/facter-oss/pkg/agent/collectors/packages"
 	"github.com/klamhq/facter-oss/pkg/models"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
-	"github.com/shirou/gopsutil/net"
 	pproc "github.com/shirou/gopsutil/process"
 	"github.com/sirupsen/logrus"
 )
 
-// Connections return all connections in protobuf schema
-func Connections(logger *logrus.Logger) ([]*schema.ConnectionState, error) {
-	cnx, err := getConnections(logger)
-	if err != nil {
-		return nil, err
-	}
+// Connections return the listening and established tcp and udp connections of the sockets in protobuf schema
+func Connections(logger *logrus.Logger, sockets []*models.Socket) []*schema.ConnectionState {
+	cnx := getConnections(logger, sockets)
 	connections := make([]*schema.ConnectionState, 0, len(cnx))
-	var state schema.State
 	for _, c := range cnx {
-		if c.State == "ESTABLISHED" {
-			state = schema.State_STATE_ESTABLISHED
-		} else {
-			state = schema.State_STATE_LISTENING
+		protocol := schema.Protocol_PROTOCOL_TCP
+		if c.Protocol == models.SocketProtocolUdp {
+			protocol = schema.Protocol_PROTOCOL_UDP_UNSPECIFIED
+		}
+		version := "4"
+		if c.Family == models.SocketFamilyInet6 {
+			version = "6"
 		}
 		connection := schema.ConnectionState{
-			Protocol: schema.Protocol_PROTOCOL_TCP,
-			State:    state,
+			Protocol: protocol,
+			State:    connectionState(c.Protocol, c.State, c.LocalPort),
 			Local: &schema.IpPort{
 				Ip: &schema.Ip{
 					Addr:    c.LocalIp,
-					Version: "4",
+					Version: version,
 				},
 				Port: c.LocalPort,
 			},
 			Remote: &schema.IpPort{
 				Ip: &schema.Ip{
 					Addr:    c.RemoteIp,
-					Version: "4",
+					Version: version,
 				},
 				Port: c.RemotePort,
 			},
@@ -52,42 +50,55 @@ func Connections(logger *logrus.Logger) ([]*schema.ConnectionState, error) {
 		}
 		connections = append(connections, &connection)
 	}
-	return connections, nil
+	return connections
 }
 
-// GetConnections get listen and established tcp connections and get if possible the associated package, no available for darwin
-func getConnections(logger *logrus.Logger) ([]models.Connections, error) {
-	conns, err := net.Connections("all")
-	if err != nil {
-		logger.Errorf("Error during fetching connections: %v", err)
-		return nil, err
+// connectionState returns the state of a socket in the schema: a listening tcp socket and a udp socket bound to
+// a port without peer are listening, a connected socket is established.
+func connectionState(protocol, state string, localPort uint32) schema.State {
+	switch {
+	case state == models.SocketStateListen:
+		return schema.State_STATE_LISTENING
+	case protocol == models.SocketProtocolUdp && state == models.SocketStateUnconn && localPort != 0:
+		return schema.State_STATE_LISTENING
+	case state == models.SocketStateEstablished:
+		return schema.State_STATE_ESTABLISHED
 	}
+	return schema.State_STATE_UNKNOWN_UNSPECIFIED
+}
 
-	results := make([]models.Connections, 0, len(conns))
+// GetConnections get listening and established tcp and udp connections of the sockets and get if possible the
+// associated package
+func getConnections(logger *logrus.Logger, sockets []*models.Socket) []models.Connections {
+	results := make([]models.Connections, 0, len(sockets))
 	pkgExtract, err := packages.NewPackageExtractor(logger)
 	if err != nil {
 		logger.Errorf("Unable to extract package from exe path: %v", err)
 	}
-	for _, c := range conns {
-		if c.Status != "LISTEN" && c.Status != "ESTABLISHED" {
+	for _, s := range sockets {
+		if s.Family == models.SocketFamilyUnix || connectionState(s.Protocol, s.State, s.LocalPort) == schema.State_STATE_UNKNOWN_UNSPECIFIED {
 			continue
 		}
 
 		info := models.Connections{
-			Protocol:   c.Type,
-			LocalIp:    c.Laddr.IP,
-			LocalPort:  c.Laddr.Port,
-			RemoteIp:   c.Raddr.IP,
-			RemotePort: c.Raddr.Port,
-			State:      c.Status,
-			Pid:        c.Pid,
+			Family:      s.Family,
+			Protocol:    s.Protocol,
+			LocalIp:     s.LocalAddress,
+			LocalPort:   s.LocalPort,
+			RemoteIp:    s.RemoteAddress,
+			RemotePort:  s.RemotePort,
+			State:       s.State,
+			Pid:         s.Pid,
+			ProcessName: s.Process,
 		}
 
-		if c.Pid != 0 {
-			if proc, err := pproc.NewProcess(c.Pid); err == nil {
-				if name, err := proc.Name(); err == nil {
+		if s.Pid != 0 {
+			if proc, err := pproc.NewProcess(s.Pid); err == nil {
+				if name, err := proc.Name(); err == nil && info.ProcessName == "" {
 					namePart := strings.Fields(name)
-					info.ProcessName = namePart[0]
+					if len(namePart) > 0 {
+						info.ProcessName = namePart[0]
+					}
 				}
 				if pkgExtract != nil {
 					if exe, err := proc.Exe(); err == nil {
@@ -100,5 +111,5 @@ func getConnections(logger *logrus.Logger) ([]models.Connections, error) {
 
 		results = append(results, info)
 	}
-	return results, nil
+	return results
 }
@@ -8,13 +8,13 @@ import (
 )
 
 func TestConnections(t *testing.T) {
-	conn, err := Connections(logrus.New())
+	sockets, err := Sockets(logrus.New())
 	assert.NoError(t, err)
-	assert.NotEmpty(t, conn)
+	assert.NotEmpty(t, Connections(logrus.New(), sockets))
 }
 
 func TestGetConnections(t *testing.T) {
-	conn, err := getConnections(logrus.New())
+	sockets, err := Sockets(logrus.New())
 	assert.NoError(t, err)
-	assert.NotEmpty(t, conn)
+	assert.NotEmpty(t, getConnections(logrus.New(), sockets))
 }
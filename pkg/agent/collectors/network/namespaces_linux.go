@@ -0,0 +1,166 @@
+package network
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// netnsDirs are the directories of the namespaces named by ip netns. It is a variable to be replaced in tests.
+var netnsDirs = []string{"/run/netns", "/var/run/netns"}
+
+// Namespaces returns the network namespaces of the processes and the named namespaces. The namespace of the host,
+// the one of the init process or of facter when init cannot be read, comes first, then the named namespaces.
+// The interfaces of a namespace are read from /proc/<pid>/net/dev of its lowest pid, a named namespace without
+// process is entered to list them, which needs the root privileges.
+func Namespaces(logger *logrus.Logger, root string) []*models.NetworkNamespace {
+	host, ok := namespaceInode(filepath.Join(root, "1", "ns", "net"))
+	if !ok {
+		host, _ = namespaceInode(filepath.Join(root, "self", "ns", "net"))
+	}
+	byInode := map[uint64]*models.NetworkNamespace{}
+	paths := map[uint64]string{}
+	namespace := func(inode uint64) *models.NetworkNamespace {
+		ns, ok := byInode[inode]
+		if !ok {
+			ns = &models.NetworkNamespace{Inode: inode, Host: inode == host}
+			byInode[inode] = ns
+		}
+		return ns
+	}
+
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		pid, err := strconv.ParseInt(entry.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		inode, ok := namespaceInode(filepath.Join(root, entry.Name(), "ns", "net"))
+		if !ok {
+			continue
+		}
+		ns := namespace(inode)
+		ns.Processes++
+		if ns.Pid == 0 || int32(pid) < ns.Pid {
+			ns.Pid = int32(pid)
+		}
+	}
+	for _, dir := range netnsDirs {
+		files, _ := os.ReadDir(dir)
+		for _, file := range files {
+			path := filepath.Join(dir, file.Name())
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			stat, ok := info.Sys().(*syscall.Stat_t)
+			if !ok {
+				continue
+			}
+			ns := namespace(stat.Ino)
+			if ns.Name == "" {
+				ns.Name = file.Name()
+				paths[stat.Ino] = path
+			}
+		}
+	}
+
+	namespaces := make([]*models.NetworkNamespace, 0, len(byInode))
+	for inode, ns := range byInode {
+		if ns.Pid != 0 {
+			if data, err := os.ReadFile(filepath.Join(root, strconv.Itoa(int(ns.Pid)), "net", "dev")); err == nil {
+				ns.Interfaces = ParseProcNetDev(data)
+			}
+		} else if path, ok := paths[inode]; ok {
+			ifaces, err := namespaceInterfaces(path)
+			if err != nil {
+				logger.WithError(err).Debugf("Unable to list the interfaces of the network namespace %s", ns.Name)
+			}
+			ns.Interfaces = ifaces
+		}
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		a, b := namespaces[i], namespaces[j]
+		if a.Host != b.Host {
+			return a.Host
+		}
+		if a.Name != b.Name {
+			// The named namespaces first
+			return b.Name == "" || (a.Name != "" && a.Name < b.Name)
+		}
+		return a.Inode < b.Inode
+	})
+	return namespaces
+}
+
+// namespaceInode returns the inode of a namespace link of procfs, such as net:[4026531840].
+func namespaceInode(path string) (uint64, bool) {
+	link, err := os.Readlink(path)
+	if err != nil {
+		return 0, false
+	}
+	value, ok := strings.CutPrefix(link, "net:[")
+	if !ok {
+		return 0, false
+	}
+	inode, err := strconv.ParseUint(strings.TrimSuffix(value, "]"), 10, 64)
+	return inode, err == nil
+}
+
+// namespaceInterfaces enters a network namespace on a dedicated thread to list its interfaces. The thread is
+// discarded when it cannot return to its namespace.
+func namespaceInterfaces(path string) ([]string, error) {
+	type result struct {
+		names []string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		runtime.LockOSThread()
+		names, restored, err := listNamespaceInterfaces(path)
+		if restored {
+			runtime.UnlockOSThread()
+		}
+		done <- result{names, err}
+	}()
+	r := <-done
+	return r.names, r.err
+}
+
+func listNamespaceInterfaces(path string) (names []string, restored bool, err error) {
+	current, err := unix.Open("/proc/thread-self/ns/net", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, true, err
+	}
+	defer unix.Close(current)
+	target, err := unix.Open(path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, true, err
+	}
+	defer unix.Close(target)
+	if err := unix.Setns(target, unix.CLONE_NEWNET); err != nil {
+		return nil, true, err
+	}
+	ifaces, err := net.Interfaces()
+	if restoreErr := unix.Setns(current, unix.CLONE_NEWNET); restoreErr != nil {
+		return nil, false, restoreErr
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	sort.Strings(names)
+	return names, true, nil
+}
@@ -0,0 +1,268 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// routeTables are the names of the reserved routing tables, the other tables are named by number.
+var routeTables = map[uint32]string{253: "default", 254: "main", 255: "local"}
+
+// routeTypes are the names of the types of route, by RTN_* value.
+var routeTypes = map[uint8]string{
+	1: "unicast", 2: "local", 3: "broadcast", 4: "anycast", 5: "multicast",
+	6: "blackhole", 7: "unreachable", 8: "prohibit", 9: "throw", 10: "nat",
+}
+
+// routeProtocols are the names of the origins of a route, by RTPROT_* value as in /etc/iproute2/rt_protos.
+var routeProtocols = map[uint8]string{
+	1: "redirect", 2: "kernel", 3: "boot", 4: "static", 9: "ra", 11: "zebra", 12: "bird", 16: "dhcp",
+	186: "bgp", 187: "isis", 188: "ospf", 189: "rip",
+}
+
+// routeScopes are the names of the scopes of a route, by RT_SCOPE_* value.
+var routeScopes = map[uint8]string{0: "universe", 200: "site", 253: "link", 254: "host", 255: "nowhere"}
+
+// ruleActions are the names of the actions of a policy routing rule, by FR_ACT_* value.
+var ruleActions = map[uint8]string{1: "lookup", 2: "goto", 3: "nop", 6: "blackhole", 7: "unreachable", 8: "prohibit"}
+
+// neighbourStates are the names of the states of a neighbour, by NUD_* flag.
+var neighbourStates = map[uint16]string{
+	0x01: "INCOMPLETE", 0x02: "REACHABLE", 0x04: "STALE", 0x08: "DELAY", 0x10: "PROBE", 0x20: "FAILED", 0x80: "PERMANENT",
+}
+
+// Flags of the routes of /proc/net/route and /proc/net/ipv6_route.
+const (
+	rtfUp      = 0x0001
+	rtfGateway = 0x0002
+	rtfReject  = 0x0200
+	rtfCache   = 0x01000000
+	rtfLocal   = 0x80000000
+)
+
+// Flags of the entries of /proc/net/arp.
+const (
+	atfComplete = 0x02
+	atfPerm     = 0x04
+)
+
+func tableName(table uint32) string {
+	if name, ok := routeTables[table]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(table), 10)
+}
+
+func nameOrNumber[K uint8 | uint16](names map[K]string, value K) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+// readProcRoutes reads the main IPv4 routing table, the IPv6 routes and the ARP table of /proc/net. The policy
+// routing rules and the IPv6 neighbours are not exposed there.
+func readProcRoutes(root string) (*models.RoutingInventory, error) {
+	inv := &models.RoutingInventory{}
+	for _, file := range []struct {
+		name  string
+		parse func([]byte, *models.RoutingInventory) error
+	}{
+		{"route", func(data []byte, inv *models.RoutingInventory) error {
+			routes, err := ParseProcNetRoute(data)
+			inv.Routes = append(inv.Routes, routes...)
+			return err
+		}},
+		{"ipv6_route", func(data []byte, inv *models.RoutingInventory) error {
+			routes, err := ParseProcNetIpv6Route(data)
+			inv.Routes = append(inv.Routes, routes...)
+			return err
+		}},
+		{"arp", func(data []byte, inv *models.RoutingInventory) error {
+			neighbours, err := ParseProcNetArp(data)
+			inv.Neighbours = append(inv.Neighbours, neighbours...)
+			return err
+		}},
+	} {
+		data, err := os.ReadFile(filepath.Join(root, "net", file.name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := file.parse(data, inv); err != nil {
+			return nil, fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+	return inv, nil
+}
+
+// ParseProcNetRoute parses /proc/net/route, the main IPv4 routing table. Its addresses are written as
+// hexadecimal words of 32 bits holding the bytes of the address in network order.
+func ParseProcNetRoute(data []byte) ([]*models.Route, error) {
+	var routes []*models.Route
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] == "Iface" {
+			continue
+		}
+		var values [5]uint64
+		for i, field := range []string{fields[1], fields[2], fields[3], fields[6], fields[7]} {
+			base := 16
+			if i == 3 {
+				base = 10
+			}
+			value, err := strconv.ParseUint(field, base, 32)
+			if err != nil {
+				return nil, fmt.Errorf("route %q: %w", scanner.Text(), err)
+			}
+			values[i] = value
+		}
+		destination, gateway, flags, metric, mask := values[0], values[1], values[2], values[3], values[4]
+		if flags&rtfUp == 0 {
+			continue
+		}
+		ones, _ := net.IPMask(procIPv4(mask)).Size()
+		route := &models.Route{
+			Family:      models.SocketFamilyInet,
+			Table:       "main",
+			Type:        "unicast",
+			Destination: fmt.Sprintf("%s/%d", procIPv4(destination), ones),
+			Interface:   fields[0],
+			Metric:      uint32(metric),
+			Scope:       "link",
+			Default:     ones == 0,
+		}
+		if flags&rtfGateway != 0 {
+			route.Gateway = procIPv4(gateway).String()
+			route.Scope = "universe"
+		}
+		if flags&rtfReject != 0 {
+			route.Type, route.Interface, route.Default = "unreachable", "", false
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// ParseProcNetIpv6Route parses /proc/net/ipv6_route, which holds the routes of every table without their table.
+// The routes cached by the kernel and the routes which are not up, such as the null entry, are skipped.
+func ParseProcNetIpv6Route(data []byte) ([]*models.Route, error) {
+	var routes []*models.Route
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		destination, err1 := hex.DecodeString(fields[0])
+		source, err2 := hex.DecodeString(fields[2])
+		gateway, err3 := hex.DecodeString(fields[4])
+		destinationLen, err4 := strconv.ParseUint(fields[1], 16, 8)
+		sourceLen, err5 := strconv.ParseUint(fields[3], 16, 8)
+		metric, err6 := strconv.ParseUint(fields[5], 16, 32)
+		flags, err7 := strconv.ParseUint(fields[8], 16, 32)
+		for _, err := range []error{err1, err2, err3, err4, err5, err6, err7} {
+			if err != nil {
+				return nil, fmt.Errorf("route %q: %w", scanner.Text(), err)
+			}
+		}
+		if len(destination) != net.IPv6len || len(source) != net.IPv6len || len(gateway) != net.IPv6len {
+			return nil, fmt.Errorf("route %q: invalid address", scanner.Text())
+		}
+		if flags&rtfUp == 0 || flags&rtfCache != 0 {
+			continue
+		}
+		route := &models.Route{
+			Family:      models.SocketFamilyInet6,
+			Type:        "unicast",
+			Destination: fmt.Sprintf("%s/%d", net.IP(destination), destinationLen),
+			Interface:   fields[9],
+			Metric:      uint32(metric),
+			Default:     destinationLen == 0,
+		}
+		if sourceLen != 0 {
+			route.Source = fmt.Sprintf("%s/%d", net.IP(source), sourceLen)
+		}
+		if flags&rtfGateway != 0 {
+			route.Gateway = net.IP(gateway).String()
+		}
+		switch {
+		case flags&rtfLocal != 0:
+			route.Type, route.Table, route.Default = "local", "local", false
+		case flags&rtfReject != 0:
+			route.Type, route.Interface, route.Default = "unreachable", "", false
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// ParseProcNetArp parses /proc/net/arp. It does not hold the state of the neighbours, a complete entry is
+// reported REACHABLE.
+func ParseProcNetArp(data []byte) ([]*models.Neighbour, error) {
+	var neighbours []*models.Neighbour
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[0] == "IP" {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("flags %q: %w", fields[2], err)
+		}
+		neighbour := &models.Neighbour{
+			Family:    models.SocketFamilyInet,
+			Address:   fields[0],
+			Interface: fields[5],
+			State:     "INCOMPLETE",
+		}
+		switch {
+		case flags&atfPerm != 0:
+			neighbour.State = "PERMANENT"
+		case flags&atfComplete != 0:
+			neighbour.State = "REACHABLE"
+		}
+		if flags&atfComplete != 0 {
+			neighbour.LinkAddress = fields[3]
+		}
+		neighbours = append(neighbours, neighbour)
+	}
+	return neighbours, scanner.Err()
+}
+
+// ParseProcNetDev returns the sorted names of the interfaces of /proc/net/dev.
+func ParseProcNetDev(data []byte) []string {
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name, _, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.Contains(name, "|") {
+			continue
+		}
+		names = append(names, strings.TrimSpace(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// procIPv4 returns the address of a hexadecimal word of /proc/net/route.
+func procIPv4(value uint64) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.NativeEndian.PutUint32(ip, uint32(value))
+	return ip
+}
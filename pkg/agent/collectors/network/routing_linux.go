@@ -0,0 +1,229 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// sizeofFibRuleHdr is the size of the fib_rule_hdr of the policy routing rules, which is not defined by x/sys/unix.
+const sizeofFibRuleHdr = 12
+
+// Routing returns the routes of every table, the policy routing rules and the neighbours of the host from netlink,
+// or from /proc/net when netlink is not available, and the network namespaces when asked.
+func Routing(logger *logrus.Logger, namespaces bool) (*models.RoutingInventory, error) {
+	inv, err := routeNetlink()
+	if err != nil {
+		logger.WithError(err).Debug("Unable to dump the routes with netlink, reading /proc/net")
+		inv, err = readProcRoutes(procRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if namespaces {
+		inv.Namespaces = Namespaces(logger, procRoot)
+	}
+	return inv, nil
+}
+
+// routeNetlink dumps the IPv4 and IPv6 routes, rules and neighbours with rtnetlink.
+func routeNetlink() (*models.RoutingInventory, error) {
+	ifNames := map[int32]string{}
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			ifNames[int32(iface.Index)] = iface.Name
+		}
+	}
+	inv := &models.RoutingInventory{}
+	for _, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+		header := make([]byte, unix.SizeofRtMsg)
+		header[0] = family
+		msgs, err := netlinkDump(unix.NETLINK_ROUTE, unix.RTM_GETROUTE, header)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			if route := parseRouteMsg(msg, ifNames); route != nil {
+				inv.Routes = append(inv.Routes, route)
+			}
+		}
+
+		header = make([]byte, sizeofFibRuleHdr)
+		header[0] = family
+		if msgs, err = netlinkDump(unix.NETLINK_ROUTE, unix.RTM_GETRULE, header); err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			if rule := parseRuleMsg(msg); rule != nil {
+				inv.Rules = append(inv.Rules, rule)
+			}
+		}
+
+		header = make([]byte, unix.SizeofNdMsg)
+		header[0] = family
+		if msgs, err = netlinkDump(unix.NETLINK_ROUTE, unix.RTM_GETNEIGH, header); err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			if neighbour := parseNeighMsg(msg, ifNames); neighbour != nil {
+				inv.Neighbours = append(inv.Neighbours, neighbour)
+			}
+		}
+	}
+	return inv, nil
+}
+
+// parseRouteMsg parses a rtmsg and its attributes, nil for a route cached by the kernel.
+func parseRouteMsg(data []byte, ifNames map[int32]string) *models.Route {
+	if len(data) < unix.SizeofRtMsg || binary.NativeEndian.Uint32(data[8:12])&unix.RTM_F_CLONED != 0 {
+		return nil
+	}
+	family, destinationLen, sourceLen := data[0], data[1], data[2]
+	attrs := parseAttributes(data[unix.SizeofRtMsg:])
+	route := &models.Route{
+		Family:   netlinkFamily(family),
+		Table:    tableName(uint32(data[4])),
+		Type:     nameOrNumber(routeTypes, data[7]),
+		Protocol: nameOrNumber(routeProtocols, data[5]),
+		Scope:    nameOrNumber(routeScopes, data[6]),
+	}
+	if table, ok := attrs[unix.RTA_TABLE]; ok && len(table) >= 4 {
+		route.Table = tableName(binary.NativeEndian.Uint32(table))
+	}
+	destination := net.IP(attrs[unix.RTA_DST])
+	if destination == nil {
+		destination = net.IPv4zero.To4()
+		if family == unix.AF_INET6 {
+			destination = net.IPv6zero
+		}
+	}
+	route.Destination = fmt.Sprintf("%s/%d", destination, destinationLen)
+	if source, ok := attrs[unix.RTA_SRC]; ok {
+		route.Source = fmt.Sprintf("%s/%d", net.IP(source), sourceLen)
+	}
+	if gateway, ok := attrs[unix.RTA_GATEWAY]; ok {
+		route.Gateway = net.IP(gateway).String()
+	}
+	if oif, ok := attrs[unix.RTA_OIF]; ok && len(oif) >= 4 {
+		route.Interface = interfaceName(ifNames, int32(binary.NativeEndian.Uint32(oif)))
+	}
+	if source, ok := attrs[unix.RTA_PREFSRC]; ok {
+		route.PreferredSource = net.IP(source).String()
+	}
+	if metric, ok := attrs[unix.RTA_PRIORITY]; ok && len(metric) >= 4 {
+		route.Metric = binary.NativeEndian.Uint32(metric)
+	}
+	multipath := attrs[unix.RTA_MULTIPATH]
+	for len(multipath) >= unix.SizeofRtNexthop {
+		length := int(binary.NativeEndian.Uint16(multipath[0:2]))
+		if length < unix.SizeofRtNexthop || length > len(multipath) {
+			break
+		}
+		nexthop := &models.RouteNexthop{
+			Interface: interfaceName(ifNames, int32(binary.NativeEndian.Uint32(multipath[4:8]))),
+			Weight:    uint32(multipath[3]) + 1,
+		}
+		if gateway, ok := parseAttributes(multipath[unix.SizeofRtNexthop:length])[unix.RTA_GATEWAY]; ok {
+			nexthop.Gateway = net.IP(gateway).String()
+		}
+		route.Nexthops = append(route.Nexthops, nexthop)
+		multipath = multipath[(length+unix.NLMSG_ALIGNTO-1)&^(unix.NLMSG_ALIGNTO-1):]
+	}
+	route.Default = destinationLen == 0 && route.Type == "unicast" && route.Table != "local"
+	return route
+}
+
+// parseRuleMsg parses a fib_rule_hdr and its attributes.
+func parseRuleMsg(data []byte) *models.RoutingRule {
+	if len(data) < sizeofFibRuleHdr {
+		return nil
+	}
+	attrs := parseAttributes(data[sizeofFibRuleHdr:])
+	rule := &models.RoutingRule{
+		Family: netlinkFamily(data[0]),
+		Not:    binary.NativeEndian.Uint32(data[8:12])&unix.FIB_RULE_INVERT != 0,
+		Action: nameOrNumber(ruleActions, data[7]),
+	}
+	if data[7] == unix.FR_ACT_TO_TBL {
+		rule.Table = tableName(uint32(data[4]))
+		if table, ok := attrs[unix.FRA_TABLE]; ok && len(table) >= 4 {
+			rule.Table = tableName(binary.NativeEndian.Uint32(table))
+		}
+	}
+	if priority, ok := attrs[unix.FRA_PRIORITY]; ok && len(priority) >= 4 {
+		rule.Priority = binary.NativeEndian.Uint32(priority)
+	}
+	if source, ok := attrs[unix.FRA_SRC]; ok {
+		rule.From = fmt.Sprintf("%s/%d", net.IP(source), data[2])
+	}
+	if destination, ok := attrs[unix.FRA_DST]; ok {
+		rule.To = fmt.Sprintf("%s/%d", net.IP(destination), data[1])
+	}
+	if iif, ok := attrs[unix.FRA_IIFNAME]; ok {
+		rule.InInterface = strings.TrimRight(string(iif), "\x00")
+	}
+	if oif, ok := attrs[unix.FRA_OIFNAME]; ok {
+		rule.OutInterface = strings.TrimRight(string(oif), "\x00")
+	}
+	if mark, ok := attrs[unix.FRA_FWMARK]; ok && len(mark) >= 4 {
+		rule.Fwmark = fmt.Sprintf("0x%x", binary.NativeEndian.Uint32(mark))
+		if mask, ok := attrs[unix.FRA_FWMASK]; ok && len(mask) >= 4 && binary.NativeEndian.Uint32(mask) != 0xffffffff {
+			rule.Fwmark += fmt.Sprintf("/0x%x", binary.NativeEndian.Uint32(mask))
+		}
+	}
+	if target, ok := attrs[unix.FRA_GOTO]; ok && len(target) >= 4 {
+		rule.Goto = binary.NativeEndian.Uint32(target)
+	}
+	return rule
+}
+
+// parseNeighMsg parses a ndmsg and its attributes, nil for the entries without ARP nor NDP resolution, such as
+// the multicast and loopback ones.
+func parseNeighMsg(data []byte, ifNames map[int32]string) *models.Neighbour {
+	if len(data) < unix.SizeofNdMsg {
+		return nil
+	}
+	state := binary.NativeEndian.Uint16(data[8:10])
+	if state == 0 || state&unix.NUD_NOARP != 0 {
+		return nil
+	}
+	attrs := parseAttributes(data[unix.SizeofNdMsg:])
+	address, ok := attrs[unix.NDA_DST]
+	if !ok {
+		return nil
+	}
+	neighbour := &models.Neighbour{
+		Family:    netlinkFamily(data[0]),
+		Address:   net.IP(address).String(),
+		Interface: interfaceName(ifNames, int32(binary.NativeEndian.Uint32(data[4:8]))),
+		State:     nameOrNumber(neighbourStates, state),
+		Router:    data[10]&unix.NTF_ROUTER != 0,
+	}
+	if lladdr, ok := attrs[unix.NDA_LLADDR]; ok {
+		neighbour.LinkAddress = net.HardwareAddr(lladdr).String()
+	}
+	return neighbour
+}
+
+func netlinkFamily(family uint8) string {
+	if family == unix.AF_INET6 {
+		return models.SocketFamilyInet6
+	}
+	return models.SocketFamilyInet
+}
+
+// interfaceName returns the name of an interface, its index when it is unknown.
+func interfaceName(ifNames map[int32]string, index int32) string {
+	if name, ok := ifNames[index]; ok {
+		return name
+	}
+	if index == 0 {
+		return ""
+	}
+	return fmt.Sprintf("if%d", index)
+}
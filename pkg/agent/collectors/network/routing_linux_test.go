@@ -0,0 +1,118 @@
+package network
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func u32(v uint32) []byte { return binary.NativeEndian.AppendUint32(nil, v) }
+
+func TestParseRouteMsg(t *testing.T) {
+	ifNames := map[int32]string{1: "lo", 2: "eth0", 3: "eth1"}
+	msg := []byte{unix.AF_INET, 0, 0, 0, unix.RT_TABLE_MAIN, unix.RTPROT_DHCP, unix.RT_SCOPE_UNIVERSE, unix.RTN_UNICAST, 0, 0, 0, 0}
+	msg = append(msg, attribute(unix.RTA_TABLE, u32(unix.RT_TABLE_MAIN))...)
+	msg = append(msg, attribute(unix.RTA_PRIORITY, u32(100))...)
+	msg = append(msg, attribute(unix.RTA_GATEWAY, net.ParseIP("192.168.1.1").To4())...)
+	msg = append(msg, attribute(unix.RTA_OIF, u32(2))...)
+	assert.Equal(t, &models.Route{Family: "inet", Table: "main", Type: "unicast", Destination: "0.0.0.0/0", Gateway: "192.168.1.1",
+		Interface: "eth0", Metric: 100, Protocol: "dhcp", Scope: "universe", Default: true}, parseRouteMsg(msg, ifNames))
+
+	msg = []byte{unix.AF_INET, 24, 0, 0, 0, unix.RTPROT_STATIC, unix.RT_SCOPE_UNIVERSE, unix.RTN_UNICAST, 0, 0, 0, 0}
+	msg = append(msg, attribute(unix.RTA_TABLE, u32(100))...)
+	msg = append(msg, attribute(unix.RTA_DST, net.ParseIP("10.8.0.0").To4())...)
+	var nexthops []byte
+	for _, nh := range []struct {
+		ifindex uint32
+		hops    uint8
+		gateway string
+	}{{2, 0, "192.168.1.1"}, {3, 2, "192.168.2.1"}} {
+		gateway := attribute(unix.RTA_GATEWAY, net.ParseIP(nh.gateway).To4())
+		nexthop := binary.NativeEndian.AppendUint16(nil, uint16(unix.SizeofRtNexthop+len(gateway)))
+		nexthop = append(nexthop, 0, nh.hops)
+		nexthop = append(nexthop, u32(nh.ifindex)...)
+		nexthops = append(nexthops, append(nexthop, gateway...)...)
+	}
+	msg = append(msg, attribute(unix.RTA_MULTIPATH, nexthops)...)
+	assert.Equal(t, &models.Route{Family: "inet", Table: "100", Type: "unicast", Destination: "10.8.0.0/24", Protocol: "static", Scope: "universe",
+		Nexthops: []*models.RouteNexthop{{Gateway: "192.168.1.1", Interface: "eth0", Weight: 1}, {Gateway: "192.168.2.1", Interface: "eth1", Weight: 3}}},
+		parseRouteMsg(msg, ifNames))
+
+	msg = []byte{unix.AF_INET6, 128, 0, 0, unix.RT_TABLE_LOCAL, unix.RTPROT_KERNEL, unix.RT_SCOPE_UNIVERSE, unix.RTN_LOCAL, 0, 0, 0, 0}
+	msg = append(msg, attribute(unix.RTA_DST, net.ParseIP("fd00::2"))...)
+	msg = append(msg, attribute(unix.RTA_OIF, u32(9))...)
+	assert.Equal(t, &models.Route{Family: "inet6", Table: "local", Type: "local", Destination: "fd00::2/128", Interface: "if9", Protocol: "kernel", Scope: "universe"},
+		parseRouteMsg(msg, ifNames), "unknown interfaces are named by index")
+
+	binary.NativeEndian.PutUint32(msg[8:12], unix.RTM_F_CLONED)
+	assert.Nil(t, parseRouteMsg(msg, ifNames), "cached routes are skipped")
+}
+
+func TestParseRuleMsg(t *testing.T) {
+	msg := []byte{unix.AF_INET, 0, 16, 0, 0, 0, 0, unix.FR_ACT_TO_TBL, 0, 0, 0, 0}
+	msg = append(msg, attribute(unix.FRA_PRIORITY, u32(100))...)
+	msg = append(msg, attribute(unix.FRA_TABLE, u32(1000))...)
+	msg = append(msg, attribute(unix.FRA_SRC, net.ParseIP("10.1.0.0").To4())...)
+	msg = append(msg, attribute(unix.FRA_IIFNAME, []byte("wg0\x00"))...)
+	msg = append(msg, attribute(unix.FRA_FWMARK, u32(0x1))...)
+	msg = append(msg, attribute(unix.FRA_FWMASK, u32(0xff))...)
+	assert.Equal(t, &models.RoutingRule{Family: "inet", Priority: 100, From: "10.1.0.0/16", InInterface: "wg0", Fwmark: "0x1/0xff", Action: "lookup", Table: "1000"},
+		parseRuleMsg(msg))
+
+	msg = []byte{unix.AF_INET6, 0, 0, 0, 0, 0, 0, unix.FR_ACT_UNREACHABLE, unix.FIB_RULE_INVERT, 0, 0, 0}
+	msg = append(msg, attribute(unix.FRA_PRIORITY, u32(200))...)
+	msg = append(msg, attribute(unix.FRA_FWMARK, u32(0x2))...)
+	msg = append(msg, attribute(unix.FRA_FWMASK, u32(0xffffffff))...)
+	assert.Equal(t, &models.RoutingRule{Family: "inet6", Priority: 200, Not: true, Fwmark: "0x2", Action: "unreachable"}, parseRuleMsg(msg))
+}
+
+func TestParseNeighMsg(t *testing.T) {
+	ifNames := map[int32]string{2: "eth0"}
+	header := func(family uint8, state uint16, flags uint8) []byte {
+		msg := []byte{family, 0, 0, 0}
+		msg = append(msg, u32(2)...)
+		msg = binary.NativeEndian.AppendUint16(msg, state)
+		return append(msg, flags, 0)
+	}
+	msg := header(unix.AF_INET6, unix.NUD_STALE, unix.NTF_ROUTER)
+	msg = append(msg, attribute(unix.NDA_DST, net.ParseIP("fe80::1"))...)
+	msg = append(msg, attribute(unix.NDA_LLADDR, []byte{0x52, 0x54, 0, 0x12, 0x34, 0x56})...)
+	assert.Equal(t, &models.Neighbour{Family: "inet6", Address: "fe80::1", LinkAddress: "52:54:00:12:34:56", Interface: "eth0", State: "STALE", Router: true},
+		parseNeighMsg(msg, ifNames))
+
+	msg = header(unix.AF_INET, unix.NUD_NOARP, 0)
+	msg = append(msg, attribute(unix.NDA_DST, net.ParseIP("224.0.0.251").To4())...)
+	assert.Nil(t, parseNeighMsg(msg, ifNames), "entries without resolution are skipped")
+}
+
+func TestNamespaces(t *testing.T) {
+	root, netns := t.TempDir(), t.TempDir()
+	previous := netnsDirs
+	netnsDirs = []string{netns}
+	defer func() { netnsDirs = previous }()
+
+	for pid, inode := range map[string]string{"1": "4026531840", "57": "4026531840", "812": "4026532300", "900": "4026532300"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, pid, "ns"), 0o755))
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, pid, "net"), 0o755))
+		assert.NoError(t, os.Symlink("net:["+inode+"]", filepath.Join(root, pid, "ns", "net")))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "1", "net", "dev"), []byte("  eth0: 0 0\n    lo: 0 0\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "812", "net", "dev"), []byte("  eth0: 0 0\n    lo: 0 0\n"), 0o644))
+	// A named namespace without process, which is not a namespace in the test
+	assert.NoError(t, os.WriteFile(filepath.Join(netns, "blue"), nil, 0o644))
+
+	namespaces := Namespaces(logrus.New(), root)
+	assert.Len(t, namespaces, 3)
+	assert.Equal(t, &models.NetworkNamespace{Inode: 4026531840, Host: true, Pid: 1, Processes: 2, Interfaces: []string{"eth0", "lo"}}, namespaces[0])
+	assert.Equal(t, "blue", namespaces[1].Name)
+	assert.Zero(t, namespaces[1].Processes)
+	assert.Empty(t, namespaces[1].Interfaces)
+	assert.Equal(t, &models.NetworkNamespace{Inode: 4026532300, Pid: 812, Processes: 2, Interfaces: []string{"eth0", "lo"}}, namespaces[2])
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package network
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Routing is only available on Linux, the routes are read from netlink or /proc/net.
+func Routing(logger *logrus.Logger, namespaces bool) (*models.RoutingInventory, error) {
+	return nil, fmt.Errorf("routing inventory is not supported on %s", runtime.GOOS)
+}
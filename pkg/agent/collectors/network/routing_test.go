@@ -0,0 +1,72 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcNetRoute(t *testing.T) {
+	data := []byte(`Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0101A8C0	0003	0	0	100	00000000	0	0	0
+eth0	0001A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+wg0	0000000A	00000000	0001	0	0	0	000000FF	0	0	0
+*	0000FEA9	00000000	0201	0	0	0	0000FFFF	0	0	0
+eth1	0002A8C0	00000000	0000	0	0	0	00FFFFFF	0	0	0
+`)
+	routes, err := ParseProcNetRoute(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Route{
+		{Family: "inet", Table: "main", Type: "unicast", Destination: "0.0.0.0/0", Gateway: "192.168.1.1", Interface: "eth0", Metric: 100, Scope: "universe", Default: true},
+		{Family: "inet", Table: "main", Type: "unicast", Destination: "192.168.1.0/24", Interface: "eth0", Metric: 100, Scope: "link"},
+		{Family: "inet", Table: "main", Type: "unicast", Destination: "10.0.0.0/8", Interface: "wg0", Scope: "link"},
+		{Family: "inet", Table: "main", Type: "unreachable", Destination: "169.254.0.0/16", Scope: "link"},
+	}, routes, "the routes which are not up are skipped")
+
+	_, err = ParseProcNetRoute([]byte("eth0	0000000Z	00000000	0001	0	0	0	00000000	0	0	0"))
+	assert.Error(t, err)
+}
+
+func TestParseProcNetIpv6Route(t *testing.T) {
+	data := []byte(`fd000000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     eth0
+00000000000000000000000000000001 80 00000000000000000000000000000000 00 00000000000000000000000000000000 00000000 00000002 00000000 80200001       lo
+20010db8000000000000000000000000 20 00000000000000000000000000000000 00 00000000000000000000000000000000 00000400 00000001 00000000 00000201       lo
+20010db8000000000000000000000009 80 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000000 00000001 00000000 01000003     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 00000000000000000000000000000000 ffffffff 00000001 00000000 00200200       lo
+`)
+	routes, err := ParseProcNetIpv6Route(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Route{
+		{Family: "inet6", Type: "unicast", Destination: "fd00::/64", Interface: "eth0", Metric: 256},
+		{Family: "inet6", Type: "unicast", Destination: "::/0", Gateway: "fe80::1", Interface: "eth0", Metric: 1024, Default: true},
+		{Family: "inet6", Table: "local", Type: "local", Destination: "::1/128", Interface: "lo"},
+		{Family: "inet6", Type: "unreachable", Destination: "2001:db8::/32", Metric: 1024},
+	}, routes, "cached routes and the null entry are skipped")
+}
+
+func TestParseProcNetArp(t *testing.T) {
+	data := []byte(`IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         52:54:00:12:34:56     *        eth0
+192.168.1.77     0x1         0x0         00:00:00:00:00:00     *        eth0
+192.168.1.5      0x1         0x6         52:54:00:ab:cd:ef     *        eth0
+`)
+	neighbours, err := ParseProcNetArp(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Neighbour{
+		{Family: "inet", Address: "192.168.1.1", LinkAddress: "52:54:00:12:34:56", Interface: "eth0", State: "REACHABLE"},
+		{Family: "inet", Address: "192.168.1.77", Interface: "eth0", State: "INCOMPLETE"},
+		{Family: "inet", Address: "192.168.1.5", LinkAddress: "52:54:00:ab:cd:ef", Interface: "eth0", State: "PERMANENT"},
+	}, neighbours)
+}
+
+func TestParseProcNetDev(t *testing.T) {
+	data := []byte(`Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+  eth0: 1024 10 0 0 0 0 0 0 2048 20 0 0 0 0 0 0
+    lo: 512 5 0 0 0 0 0 0 512 5 0 0 0 0 0 0
+veth1a2b3c4:    0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+`)
+	assert.Equal(t, []string{"eth0", "lo", "veth1a2b3c4"}, ParseProcNetDev(data))
+}
@@ -0,0 +1,123 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"golang.org/x/sys/unix"
+)
+
+// Sizes of the sock_diag structures, which are not defined by x/sys/unix.
+const (
+	sizeofInetDiagReqV2 = 56
+	sizeofInetDiagMsg   = 72
+	sizeofUnixDiagReq   = 24
+	sizeofUnixDiagMsg   = 16
+)
+
+// Flags and attributes of the unix sock_diag requests and replies.
+const (
+	udiagShowName = 0x01
+	udiagShowUid  = 0x40
+
+	unixDiagName = 0
+	unixDiagUid  = 7
+)
+
+// sockDiag dumps the inet sockets of the tcp and udp protocols and the unix sockets with the sock_diag netlink
+// protocol, the owners of the sockets are not resolved.
+func sockDiag() ([]*models.Socket, error) {
+	var sockets []*models.Socket
+	for _, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+		for _, protocol := range []uint8{unix.IPPROTO_TCP, unix.IPPROTO_UDP} {
+			msgs, err := netlinkDump(unix.NETLINK_SOCK_DIAG, unix.SOCK_DIAG_BY_FAMILY, inetDiagRequest(family, protocol))
+			if err != nil {
+				return nil, err
+			}
+			for _, msg := range msgs {
+				socket, err := parseInetDiagMsg(msg, protocol)
+				if err != nil {
+					return nil, err
+				}
+				sockets = append(sockets, socket)
+			}
+		}
+	}
+	msgs, err := netlinkDump(unix.NETLINK_SOCK_DIAG, unix.SOCK_DIAG_BY_FAMILY, unixDiagRequest())
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		socket, err := parseUnixDiagMsg(msg)
+		if err != nil {
+			return nil, err
+		}
+		sockets = append(sockets, socket)
+	}
+	return sockets, nil
+}
+
+// inetDiagRequest returns an inet_diag_req_v2 of the sockets of a family and a protocol, in every state.
+func inetDiagRequest(family, protocol uint8) []byte {
+	req := make([]byte, sizeofInetDiagReqV2)
+	req[0] = family
+	req[1] = protocol
+	binary.NativeEndian.PutUint32(req[4:8], 0xffffffff)
+	return req
+}
+
+// unixDiagRequest returns a unix_diag_req of the unix sockets in every state, with their path and owner.
+func unixDiagRequest() []byte {
+	req := make([]byte, sizeofUnixDiagReq)
+	req[0] = unix.AF_UNIX
+	binary.NativeEndian.PutUint32(req[4:8], 0xffffffff)
+	binary.NativeEndian.PutUint32(req[12:16], udiagShowName|udiagShowUid)
+	return req
+}
+
+// parseInetDiagMsg parses an inet_diag_msg, the ports and addresses of its socket id are in network order.
+func parseInetDiagMsg(data []byte, protocol uint8) (*models.Socket, error) {
+	if len(data) < sizeofInetDiagMsg {
+		return nil, fmt.Errorf("inet_diag_msg of %d bytes", len(data))
+	}
+	socket := &models.Socket{Family: models.SocketFamilyInet, Protocol: models.SocketProtocolTcp}
+	size := net.IPv4len
+	if data[0] == unix.AF_INET6 {
+		socket.Family, size = models.SocketFamilyInet6, net.IPv6len
+	}
+	if protocol == unix.IPPROTO_UDP {
+		socket.Protocol = models.SocketProtocolUdp
+	}
+	socket.State = socketState(socket.Protocol, data[1])
+	socket.LocalAddress = net.IP(data[8 : 8+size]).String()
+	socket.LocalPort = uint32(binary.BigEndian.Uint16(data[4:6]))
+	setRemote(socket, net.IP(data[24:24+size]), uint32(binary.BigEndian.Uint16(data[6:8])))
+	uid := binary.NativeEndian.Uint32(data[64:68])
+	socket.UID = &uid
+	socket.Inode = uint64(binary.NativeEndian.Uint32(data[68:72]))
+	return socket, nil
+}
+
+// parseUnixDiagMsg parses a unix_diag_msg and its name and uid attributes.
+func parseUnixDiagMsg(data []byte) (*models.Socket, error) {
+	if len(data) < sizeofUnixDiagMsg {
+		return nil, fmt.Errorf("unix_diag_msg of %d bytes", len(data))
+	}
+	socket := &models.Socket{
+		Family:   models.SocketFamilyUnix,
+		Protocol: unixSocketTypes[uint64(data[1])],
+		Inode:    uint64(binary.NativeEndian.Uint32(data[4:8])),
+	}
+	socket.State = socketState(socket.Protocol, data[2])
+	attrs := parseAttributes(data[sizeofUnixDiagMsg:])
+	if name, ok := attrs[unixDiagName]; ok {
+		socket.Path = unixPath(string(name))
+	}
+	if uid, ok := attrs[unixDiagUid]; ok && len(uid) >= 4 {
+		value := binary.NativeEndian.Uint32(uid)
+		socket.UID = &value
+	}
+	return socket, nil
+}
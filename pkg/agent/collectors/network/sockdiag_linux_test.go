@@ -0,0 +1,67 @@
+package network
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseInetDiagMsg(t *testing.T) {
+	msg := make([]byte, sizeofInetDiagMsg)
+	msg[0], msg[1] = unix.AF_INET6, 1
+	binary.BigEndian.PutUint16(msg[4:6], 443)
+	binary.BigEndian.PutUint16(msg[6:8], 51000)
+	copy(msg[8:24], []byte{0x20, 0x01, 0x0d, 0xb8, 15: 1})
+	copy(msg[24:40], []byte{0x20, 0x01, 0x0d, 0xb8, 15: 2})
+	binary.NativeEndian.PutUint32(msg[64:68], 33)
+	binary.NativeEndian.PutUint32(msg[68:72], 4242)
+
+	socket, err := parseInetDiagMsg(msg, unix.IPPROTO_TCP)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Socket{Family: "inet6", Protocol: "tcp", State: "ESTABLISHED", LocalAddress: "2001:db8::1", LocalPort: 443,
+		RemoteAddress: "2001:db8::2", RemotePort: 51000, UID: uid(33), Inode: 4242}, socket)
+
+	msg = make([]byte, sizeofInetDiagMsg)
+	msg[0], msg[1] = unix.AF_INET, 7
+	binary.BigEndian.PutUint16(msg[4:6], 53)
+	copy(msg[8:12], []byte{127, 0, 0, 53})
+	socket, err = parseInetDiagMsg(msg, unix.IPPROTO_UDP)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Socket{Family: "inet", Protocol: "udp", State: "UNCONN", LocalAddress: "127.0.0.53", LocalPort: 53, UID: uid(0)}, socket)
+
+	_, err = parseInetDiagMsg(msg[:40], unix.IPPROTO_UDP)
+	assert.Error(t, err)
+}
+
+// attribute encodes a netlink attribute, padded to 4 bytes.
+func attribute(typ uint16, value []byte) []byte {
+	attr := make([]byte, unix.SizeofRtAttr, 16)
+	binary.NativeEndian.PutUint16(attr[0:2], uint16(unix.SizeofRtAttr+len(value)))
+	binary.NativeEndian.PutUint16(attr[2:4], typ)
+	attr = append(attr, value...)
+	for len(attr)%4 != 0 {
+		attr = append(attr, 0)
+	}
+	return attr
+}
+
+func TestParseUnixDiagMsg(t *testing.T) {
+	msg := make([]byte, sizeofUnixDiagMsg)
+	msg[0], msg[1], msg[2] = unix.AF_UNIX, 1, 10
+	binary.NativeEndian.PutUint32(msg[4:8], 15320)
+	msg = append(msg, attribute(unixDiagName, []byte("\x00/org/kernel/udev"))...)
+	msg = append(msg, attribute(unixDiagUid, binary.NativeEndian.AppendUint32(nil, 1000))...)
+
+	socket, err := parseUnixDiagMsg(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Socket{Family: "unix", Protocol: "stream", State: "LISTEN", Path: "@/org/kernel/udev", UID: uid(1000), Inode: 15320}, socket)
+
+	msg = make([]byte, sizeofUnixDiagMsg)
+	msg[1], msg[2] = 2, 7
+	socket, err = parseUnixDiagMsg(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Socket{Family: "unix", Protocol: "dgram", State: "UNCONN"}, socket, "no uid before Linux 5.3")
+}
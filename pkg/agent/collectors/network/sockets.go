@@ -0,0 +1,313 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// procRoot is the mount point of procfs. It is a variable to be replaced in tests.
+var procRoot = "/proc"
+
+// tcpStates are the socket states of the kernel, by number. TCP_CLOSE is UNCONN for the udp and unix sockets.
+var tcpStates = map[uint8]string{
+	1:  models.SocketStateEstablished,
+	2:  models.SocketStateSynSent,
+	3:  models.SocketStateSynRecv,
+	4:  models.SocketStateFinWait1,
+	5:  models.SocketStateFinWait2,
+	6:  models.SocketStateTimeWait,
+	7:  models.SocketStateClose,
+	8:  models.SocketStateCloseWait,
+	9:  models.SocketStateLastAck,
+	10: models.SocketStateListen,
+	11: models.SocketStateClosing,
+	12: models.SocketStateSynRecv,
+}
+
+// unixSocketTypes are the protocols of the unix sockets, by socket type.
+var unixSocketTypes = map[uint64]string{
+	1: models.SocketProtocolStream,
+	2: models.SocketProtocolDgram,
+	5: models.SocketProtocolSeqpacket,
+}
+
+// unixSocketStates are the states of /proc/net/unix, by socket_state of the kernel.
+var unixSocketStates = map[uint64]string{
+	1: models.SocketStateUnconn,
+	2: models.SocketStateSynSent,
+	3: models.SocketStateEstablished,
+	4: models.SocketStateClosing,
+}
+
+// soAcceptCon is the flag of the listening unix sockets in /proc/net/unix.
+const soAcceptCon = 0x10000
+
+// Sockets returns the tcp, udp and unix sockets of the host with the process owning them, sorted by family,
+// protocol and local address. Without the sock_diag netlink protocol, the sockets are read from /proc/net.
+func Sockets(logger *logrus.Logger) ([]*models.Socket, error) {
+	sockets, err := collectSockets(logger)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(sockets, func(i, j int) bool {
+		a, b := sockets[i], sockets[j]
+		if a.Family != b.Family {
+			return a.Family < b.Family
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.LocalPort != b.LocalPort {
+			return a.LocalPort < b.LocalPort
+		}
+		if a.LocalAddress != b.LocalAddress {
+			return a.LocalAddress < b.LocalAddress
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Inode < b.Inode
+	})
+	return sockets, nil
+}
+
+// readProcNet reads the sockets of /proc/net, a file missing when a protocol is disabled is skipped.
+func readProcNet(root string) ([]*models.Socket, error) {
+	var sockets []*models.Socket
+	for _, file := range []struct{ name, family, protocol string }{
+		{"tcp", models.SocketFamilyInet, models.SocketProtocolTcp},
+		{"tcp6", models.SocketFamilyInet6, models.SocketProtocolTcp},
+		{"udp", models.SocketFamilyInet, models.SocketProtocolUdp},
+		{"udp6", models.SocketFamilyInet6, models.SocketProtocolUdp},
+		{"unix", models.SocketFamilyUnix, ""},
+	} {
+		data, err := os.ReadFile(filepath.Join(root, "net", file.name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var parsed []*models.Socket
+		if file.family == models.SocketFamilyUnix {
+			parsed, err = ParseProcNetUnix(data)
+		} else {
+			parsed, err = ParseProcNetInet(data, file.family, file.protocol)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.name, err)
+		}
+		sockets = append(sockets, parsed...)
+	}
+	return sockets, nil
+}
+
+// ParseProcNetInet parses /proc/net/tcp, tcp6, udp or udp6. The addresses are written as hexadecimal words of
+// 32 bits holding the bytes of the address in network order.
+func ParseProcNetInet(data []byte, family, protocol string) ([]*models.Socket, error) {
+	var sockets []*models.Socket
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[0] == "sl" {
+			continue
+		}
+		local, localPort, err := parseProcAddress(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		remote, remotePort, err := parseProcAddress(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("state %q: %w", fields[3], err)
+		}
+		uid, err := strconv.ParseUint(fields[7], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("uid %q: %w", fields[7], err)
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("inode %q: %w", fields[9], err)
+		}
+		owner := uint32(uid)
+		socket := &models.Socket{
+			Family:       family,
+			Protocol:     protocol,
+			State:        socketState(protocol, uint8(state)),
+			LocalAddress: local.String(),
+			LocalPort:    localPort,
+			UID:          &owner,
+			Inode:        inode,
+		}
+		setRemote(socket, remote, remotePort)
+		sockets = append(sockets, socket)
+	}
+	return sockets, scanner.Err()
+}
+
+// ParseProcNetUnix parses /proc/net/unix, which does not hold the owner of the sockets.
+func ParseProcNetUnix(data []byte) ([]*models.Socket, error) {
+	var sockets []*models.Socket
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 || fields[0] == "Num" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("flags %q: %w", fields[3], err)
+		}
+		socketType, err := strconv.ParseUint(fields[4], 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("type %q: %w", fields[4], err)
+		}
+		state, err := strconv.ParseUint(fields[5], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("state %q: %w", fields[5], err)
+		}
+		inode, err := strconv.ParseUint(fields[6], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("inode %q: %w", fields[6], err)
+		}
+		socket := &models.Socket{
+			Family:   models.SocketFamilyUnix,
+			Protocol: unixSocketTypes[socketType],
+			State:    unixSocketStates[state],
+			Path:     strings.Join(fields[7:], " "),
+			Inode:    inode,
+		}
+		if flags&soAcceptCon != 0 {
+			socket.State = models.SocketStateListen
+		}
+		sockets = append(sockets, socket)
+	}
+	return sockets, scanner.Err()
+}
+
+// parseProcAddress parses an address and a port of /proc/net, such as 0100007F:0016.
+func parseProcAddress(value string) (net.IP, uint32, error) {
+	addr, port, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, 0, fmt.Errorf("address %q", value)
+	}
+	raw, err := hex.DecodeString(addr)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("address %q", value)
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.NativeEndian.PutUint32(ip[i:], binary.BigEndian.Uint32(raw[i:]))
+	}
+	p, err := strconv.ParseUint(port, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("port %q: %w", value, err)
+	}
+	return ip, uint32(p), nil
+}
+
+// socketState returns the name of a state of the kernel for a protocol.
+func socketState(protocol string, state uint8) string {
+	name, ok := tcpStates[state]
+	if !ok {
+		return ""
+	}
+	if name == models.SocketStateClose && protocol != models.SocketProtocolTcp {
+		return models.SocketStateUnconn
+	}
+	return name
+}
+
+// setRemote sets the remote address of a connected socket.
+func setRemote(socket *models.Socket, ip net.IP, port uint32) {
+	if ip.IsUnspecified() && port == 0 {
+		return
+	}
+	socket.RemoteAddress = ip.String()
+	socket.RemotePort = port
+}
+
+// unixPath returns the path of a unix socket, the name of an abstract socket starts with a NUL byte written @.
+func unixPath(name string) string {
+	name = strings.TrimRight(name, "\x00")
+	if strings.HasPrefix(name, "\x00") {
+		return "@" + name[1:]
+	}
+	return name
+}
+
+// resolveOwners sets the process owning each socket, from the file descriptors of the processes of procfs.
+func resolveOwners(sockets []*models.Socket, root string) {
+	owners := socketOwners(root)
+	names := map[int32]string{}
+	for _, socket := range sockets {
+		pid, ok := owners[socket.Inode]
+		if !ok {
+			continue
+		}
+		socket.Pid = pid
+		name, ok := names[pid]
+		if !ok {
+			if comm, err := os.ReadFile(filepath.Join(root, strconv.Itoa(int(pid)), "comm")); err == nil {
+				name = strings.TrimSpace(string(comm))
+			}
+			names[pid] = name
+		}
+		socket.Process = name
+	}
+}
+
+// socketOwners returns the process holding each socket, by inode. A socket shared by several processes, such as
+// the listening socket of a preforking server, is owned by the lowest pid. The processes whose file descriptors
+// cannot be read are skipped.
+func socketOwners(root string) map[uint64]int32 {
+	owners := map[uint64]int32{}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return owners
+	}
+	for _, entry := range entries {
+		pid, err := strconv.ParseInt(entry.Name(), 10, 32)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name(), "fd")
+		fds, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			value, ok := strings.CutPrefix(link, "socket:[")
+			if !ok {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(value, "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if owner, ok := owners[inode]; !ok || int32(pid) < owner {
+				owners[inode] = int32(pid)
+			}
+		}
+	}
+	return owners
+}
@@ -0,0 +1,21 @@
+package network
+
+import (
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// collectSockets dumps the sockets with sock_diag, or reads /proc/net when the protocol is not available, such
+// as in a container without the netlink diag modules, then resolves their owners.
+func collectSockets(logger *logrus.Logger) ([]*models.Socket, error) {
+	sockets, err := sockDiag()
+	if err != nil {
+		logger.WithError(err).Debug("Unable to dump the sockets with sock_diag, reading /proc/net")
+		sockets, err = readProcNet(procRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	resolveOwners(sockets, procRoot)
+	return sockets, nil
+}
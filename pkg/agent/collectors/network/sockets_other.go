@@ -0,0 +1,62 @@
+//go:build !linux
+
+package network
+
+import (
+	"syscall"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/shirou/gopsutil/net"
+	pproc "github.com/shirou/gopsutil/process"
+	"github.com/sirupsen/logrus"
+)
+
+// collectSockets lists the sockets with gopsutil, the systems without procfs do not expose sock_diag either.
+func collectSockets(logger *logrus.Logger) ([]*models.Socket, error) {
+	conns, err := net.Connections("all")
+	if err != nil {
+		return nil, err
+	}
+	sockets := make([]*models.Socket, 0, len(conns))
+	names := map[int32]string{}
+	for _, c := range conns {
+		socket := &models.Socket{
+			Family:       models.SocketFamilyInet,
+			Protocol:     models.SocketProtocolTcp,
+			State:        c.Status,
+			LocalAddress: c.Laddr.IP,
+			LocalPort:    c.Laddr.Port,
+			Pid:          c.Pid,
+		}
+		switch c.Family {
+		case syscall.AF_INET6:
+			socket.Family = models.SocketFamilyInet6
+		case syscall.AF_UNIX:
+			socket.Family, socket.Protocol, socket.Path = models.SocketFamilyUnix, unixSocketTypes[uint64(c.Type)], c.Laddr.IP
+			socket.LocalAddress = ""
+		}
+		if c.Type == syscall.SOCK_DGRAM && socket.Family != models.SocketFamilyUnix {
+			socket.Protocol = models.SocketProtocolUdp
+		}
+		if socket.Family != models.SocketFamilyUnix && (c.Raddr.IP != "" || c.Raddr.Port != 0) {
+			socket.RemoteAddress, socket.RemotePort = c.Raddr.IP, c.Raddr.Port
+		}
+		if socket.State == "" || socket.State == "NONE" {
+			socket.State = models.SocketStateUnconn
+			if socket.RemoteAddress != "" {
+				socket.State = models.SocketStateEstablished
+			}
+		}
+		if c.Pid != 0 {
+			if _, ok := names[c.Pid]; !ok {
+				if proc, err := pproc.NewProcess(c.Pid); err == nil {
+					names[c.Pid], _ = proc.Name()
+				}
+			}
+			socket.Process = names[c.Pid]
+		}
+		sockets = append(sockets, socket)
+	}
+	logger.Debugf("%d sockets listed", len(sockets))
+	return sockets, nil
+}
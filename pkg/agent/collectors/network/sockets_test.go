@@ -0,0 +1,102 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func uid(v uint32) *uint32 { return &v }
+
+func TestParseProcNetInet(t *testing.T) {
+	tcp := []byte(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21345 1 0000000000000000 100 0 0 10 0
+   1: 0A01A8C0:0016 1401A8C0:C738 01 00000000:00000000 02:0009C4D4 00000000     0        0 43210 4 0000000000000000 20 4 31 10 -1
+   2: 0100007F:1538 0100007F:A2F0 06 00000000:00000000 03:00001234 00000000     0        0 0 3 0000000000000000
+`)
+	sockets, err := ParseProcNetInet(tcp, models.SocketFamilyInet, models.SocketProtocolTcp)
+	assert.NoError(t, err)
+	assert.Len(t, sockets, 3)
+	assert.Equal(t, &models.Socket{Family: "inet", Protocol: "tcp", State: "LISTEN", LocalAddress: "0.0.0.0", LocalPort: 22, UID: uid(0), Inode: 21345}, sockets[0])
+	assert.Equal(t, &models.Socket{Family: "inet", Protocol: "tcp", State: "ESTABLISHED", LocalAddress: "192.168.1.10", LocalPort: 22,
+		RemoteAddress: "192.168.1.20", RemotePort: 51000, UID: uid(0), Inode: 43210}, sockets[1])
+	assert.Equal(t, "TIME_WAIT", sockets[2].State)
+
+	_, err = ParseProcNetInet([]byte("   0: 0100007:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1"), models.SocketFamilyInet, models.SocketProtocolTcp)
+	assert.Error(t, err)
+
+	udp6 := []byte(`  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  120: 00000000000000000000000000000000:0035 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000   101        0 31337 2 0000000000000000 0
+  121: 00000000000000000000000001000000:0202 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000   101        0 31338 2 0000000000000000 0
+  122: 0000000000000000FFFF00000A01A8C0:E5C4 0000000000000000FFFF000008080808:0035 01 00000000:00000000 00:00000000 00000000   101        0 31339 2 0000000000000000 0
+`)
+	sockets, err = ParseProcNetInet(udp6, models.SocketFamilyInet6, models.SocketProtocolUdp)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Socket{Family: "inet6", Protocol: "udp", State: "UNCONN", LocalAddress: "::", LocalPort: 53, UID: uid(101), Inode: 31337}, sockets[0])
+	assert.Equal(t, "::1", sockets[1].LocalAddress)
+	assert.Equal(t, "ESTABLISHED", sockets[2].State)
+	assert.Equal(t, "192.168.1.10", sockets[2].LocalAddress, "IPv4-mapped address")
+	assert.Equal(t, "8.8.8.8", sockets[2].RemoteAddress)
+	assert.Equal(t, uint32(53), sockets[2].RemotePort)
+}
+
+func TestParseProcNetUnix(t *testing.T) {
+	data := []byte(`Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01 15320 /run/systemd/private
+0000000000000000: 00000002 00000000 00000000 0002 01 15321 @/org/kernel/udev/udevd
+0000000000000000: 00000003 00000000 00000000 0001 03 18002
+0000000000000000: 00000002 00000000 00010000 0005 01 18003 /run/my app.sock
+`)
+	sockets, err := ParseProcNetUnix(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Socket{
+		{Family: "unix", Protocol: "stream", State: "LISTEN", Path: "/run/systemd/private", Inode: 15320},
+		{Family: "unix", Protocol: "dgram", State: "UNCONN", Path: "@/org/kernel/udev/udevd", Inode: 15321},
+		{Family: "unix", Protocol: "stream", State: "ESTABLISHED", Inode: 18002},
+		{Family: "unix", Protocol: "seqpacket", State: "LISTEN", Path: "/run/my app.sock", Inode: 18003},
+	}, sockets)
+}
+
+func TestReadProcNet(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "net"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "net", "tcp"), []byte(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21345 1 0000000000000000 100 0 0 10 0
+`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "net", "unix"), []byte(`Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01 15320 /run/systemd/private
+`), 0o644))
+	for pid, inodes := range map[string][]string{"1": {"15320"}, "812": {"21345"}, "813": {"21345"}} {
+		fd := filepath.Join(root, pid, "fd")
+		assert.NoError(t, os.MkdirAll(fd, 0o755))
+		assert.NoError(t, os.Symlink("/dev/null", filepath.Join(fd, "0")))
+		for i, inode := range inodes {
+			assert.NoError(t, os.Symlink("socket:["+inode+"]", filepath.Join(fd, string(rune('3'+i)))))
+		}
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "813", "comm"), []byte("sshd\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "812", "comm"), []byte("sshd\n"), 0o644))
+
+	sockets, err := readProcNet(root)
+	assert.NoError(t, err, "missing protocols are skipped")
+	assert.Len(t, sockets, 2)
+	resolveOwners(sockets, root)
+	assert.Equal(t, int32(812), sockets[0].Pid, "the lowest pid owns a shared socket")
+	assert.Equal(t, "sshd", sockets[0].Process)
+	assert.Equal(t, int32(1), sockets[1].Pid)
+	assert.Equal(t, "", sockets[1].Process)
+}
+
+func TestConnectionState(t *testing.T) {
+	assert.Equal(t, schema.State_STATE_LISTENING, connectionState("tcp", "LISTEN", 22))
+	assert.Equal(t, schema.State_STATE_ESTABLISHED, connectionState("tcp", "ESTABLISHED", 22))
+	assert.Equal(t, schema.State_STATE_UNKNOWN_UNSPECIFIED, connectionState("tcp", "TIME_WAIT", 22))
+	assert.Equal(t, schema.State_STATE_UNKNOWN_UNSPECIFIED, connectionState("tcp", "CLOSE", 22))
+	assert.Equal(t, schema.State_STATE_LISTENING, connectionState("udp", "UNCONN", 53))
+	assert.Equal(t, schema.State_STATE_ESTABLISHED, connectionState("udp", "ESTABLISHED", 40000))
+	assert.Equal(t, schema.State_STATE_UNKNOWN_UNSPECIFIED, connectionState("udp", "UNCONN", 0))
+}
@@ -0,0 +1,107 @@
+package packages
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// apkInstalledFile is the location of the apk database, relative to the root path.
+const apkInstalledFile = "lib/apk/db/installed"
+
+// ReadApkInstalled reads the apk database found under root and returns installed packages.
+func ReadApkInstalled(root string) ([]*models.Package, error) {
+	f, err := os.Open(filepath.Join(root, apkInstalledFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open apk database: %w", err)
+	}
+	defer f.Close()
+	return ParseApkDatabase(f)
+}
+
+// ParseApkDatabase parses an apk database, both the installed database and APKINDEX use this format.
+// Each package is a set of "K:value" lines, packages are separated by an empty line.
+// See https://wiki.alpinelinux.org/wiki/Apk_spec
+func ParseApkDatabase(r io.Reader) ([]*models.Package, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	pkgs := []*models.Package{}
+	var pkg *models.Package
+	dir := ""
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if pkg != nil && pkg.Name != "" {
+				pkgs = append(pkgs, pkg)
+			}
+			pkg = nil
+			dir = ""
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		if pkg == nil {
+			pkg = &models.Package{}
+		}
+		value := line[2:]
+		switch line[0] {
+		case 'P':
+			pkg.Name = value
+		case 'V':
+			pkg.Version = value
+		case 'A':
+			pkg.Architecture = value
+		case 'T':
+			pkg.Description = value
+		case 'o':
+			pkg.SourcePackage = value
+		case 'm':
+			pkg.Maintainer = value
+		case 'L':
+			pkg.License = value
+		case 'I':
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil {
+				pkg.InstalledSize = size
+			}
+		case 'F':
+			dir = value
+		case 'R':
+			pkg.Files = append(pkg.Files, "/"+path.Join(dir, value))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to parse apk database: %w", err)
+	}
+	if pkg != nil && pkg.Name != "" {
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// apkOwnerFromWhoOwns extracts the package name from `apk info --who-owns` output,
+// e.g. "/bin/busybox is owned by busybox-1.36.1-r15".
+func apkOwnerFromWhoOwns(out string) string {
+	_, nameVersion, found := strings.Cut(strings.TrimSpace(out), " is owned by ")
+	if !found {
+		return ""
+	}
+	// name-version-rREV, the name itself can contain dashes
+	for range 2 {
+		i := strings.LastIndex(nameVersion, "-")
+		if i <= 0 {
+			return ""
+		}
+		nameVersion = nameVersion[:i]
+	}
+	return nameVersion
+}
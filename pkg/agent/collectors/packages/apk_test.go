@@ -0,0 +1,65 @@
+package packages
+
+import (
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const apkTestRoot = "testdata/apk"
+
+func TestReadApkInstalled(t *testing.T) {
+	pkgs, err := ReadApkInstalled(apkTestRoot)
+	assert.NoError(t, err)
+	assert.Len(t, pkgs, 4)
+
+	busybox := pkgs[1]
+	assert.Equal(t, "busybox", busybox.Name)
+	assert.Equal(t, "1.36.1-r15", busybox.Version)
+	assert.Equal(t, "x86_64", busybox.Architecture)
+	assert.Equal(t, "Size optimized toolbox of many common UNIX utilities", busybox.Description)
+	assert.Equal(t, "busybox", busybox.SourcePackage)
+	assert.Equal(t, "GPL-2.0-only", busybox.License)
+	assert.Equal(t, int64(962560), busybox.InstalledSize)
+	assert.Equal(t, []string{"/bin/busybox", "/etc/securetty", "/etc/udhcpd.conf"}, busybox.Files)
+
+	binsh := pkgs[2]
+	assert.Equal(t, "busybox-binsh", binsh.Name)
+	assert.Equal(t, "busybox", binsh.SourcePackage)
+	assert.Equal(t, []string{"/bin/sh"}, binsh.Files)
+}
+
+func TestReadApkInstalled_Missing(t *testing.T) {
+	_, err := ReadApkInstalled("testdata/does-not-exist")
+	assert.Error(t, err)
+}
+
+func TestGetApkUpgradableMap(t *testing.T) {
+	pkgs, err := ReadApkInstalled(apkTestRoot)
+	assert.NoError(t, err)
+
+	upgrades, err := GetApkUpgradableMap(apkTestRoot, pkgs)
+	assert.NoError(t, err)
+	// musl is up to date, curl is not installed and the openssl rc is older than the installed release
+	assert.Equal(t, map[string]string{
+		"busybox": "1.36.1-r19",
+		"openssl": "3.1.4-r6",
+	}, upgrades)
+
+	pkgs = addUpgradablePackage(pkgs, upgrades, models.EcosystemApk)
+	assert.True(t, pkgs[0].IsUpToDate)
+	assert.False(t, pkgs[1].IsUpToDate)
+	assert.Equal(t, "1.36.1-r19", pkgs[1].UpgradableVersion)
+}
+
+func TestGetApkUpgradableMap_NoCache(t *testing.T) {
+	_, err := GetApkUpgradableMap("testdata/dpkg", nil)
+	assert.Error(t, err)
+}
+
+func TestApkOwnerFromWhoOwns(t *testing.T) {
+	assert.Equal(t, "busybox", apkOwnerFromWhoOwns("/bin/busybox is owned by busybox-1.36.1-r15\n"))
+	assert.Equal(t, "py3-setuptools", apkOwnerFromWhoOwns("/usr/lib/x is owned by py3-setuptools-68.2.2-r0"))
+	assert.Equal(t, "", apkOwnerFromWhoOwns("ERROR: /nope: Could not find owner package"))
+}
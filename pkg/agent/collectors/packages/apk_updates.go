@@ -0,0 +1,103 @@
+package packages
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/versions"
+)
+
+// apkIndexCacheGlobs are the locations of the cached repository indexes, relative to the root path.
+// They are refreshed by `apk update`, facter never downloads them.
+var apkIndexCacheGlobs = []string{
+	"var/cache/apk/APKINDEX.*.tar.gz",
+	"etc/apk/cache/APKINDEX.*.tar.gz",
+}
+
+// GetApkUpgradableMap returns the newest version available in the local APKINDEX cache
+// for each installed package that has a more recent version.
+func GetApkUpgradableMap(root string, installed []*models.Package) (map[string]string, error) {
+	available, err := readApkIndexCache(root)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]string{}
+	for _, pkg := range installed {
+		candidate, ok := available[pkg.Name]
+		if ok && versions.CompareApk(candidate, pkg.Version) > 0 {
+			result[pkg.Name] = candidate
+		}
+	}
+	return result, nil
+}
+
+// readApkIndexCache returns the newest version of each package found in the cached indexes.
+func readApkIndexCache(root string) (map[string]string, error) {
+	seen := map[string]bool{}
+	newest := map[string]string{}
+	for _, pattern := range apkIndexCacheGlobs {
+		files, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			// /etc/apk/cache is usually a symlink to /var/cache/apk
+			if real, err := filepath.EvalSymlinks(file); err == nil {
+				file = real
+			}
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			pkgs, err := readApkIndexArchive(file)
+			if err != nil {
+				return nil, err
+			}
+			for _, pkg := range pkgs {
+				if current, ok := newest[pkg.Name]; !ok || versions.CompareApk(pkg.Version, current) > 0 {
+					newest[pkg.Name] = pkg.Version
+				}
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no APKINDEX found in apk cache, run `apk update` first")
+	}
+	return newest, nil
+}
+
+// readApkIndexArchive extracts the packages listed in an APKINDEX.tar.gz archive.
+// The archive is made of a signature and an index gzip streams, read as a single tar stream.
+func readApkIndexArchive(file string) ([]*models.Package, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", file, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("no APKINDEX entry in %s", file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", file, err)
+		}
+		if hdr.Name == "APKINDEX" {
+			return ParseApkDatabase(tr)
+		}
+	}
+}
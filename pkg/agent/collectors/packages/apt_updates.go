@@ -8,27 +8,38 @@ import (
 	"github.com/klamhq/facter-oss/pkg/utils"
 )
 
-var aptLineRE = regexp.MustCompile(`^(?P<name>[^\s]+)/[^\s]+\s+(?:(?:\d+:)?)(?P<version>[^\s]+)\s+[^\s]+\s+\[upgradable from: (?:(?:\d+:)?)(?P<from_version>[^\]]+)\]$`)
+// aptUpgradeRE captures the suites an upgrade is available from and its full version, epoch included,
+// e.g. "openssl/jammy-updates,jammy-security 3.0.2-0ubuntu1.15 amd64 [upgradable from: 3.0.2-0ubuntu1.14]".
+var aptUpgradeRE = regexp.MustCompile(`^([^\s/]+)/([^\s]+)\s+([^\s]+)\s+[^\s]+\s+\[upgradable from: [^\]]+\]$`)
 
-func parseAptUpgradableOutput(output []byte) map[string]string {
-	lines := strings.Split(string(output), "\n")
-	result := map[string]string{}
-	for _, line := range lines {
-		match := aptLineRE.FindStringSubmatch(line)
+// parseAptUpgrades returns the upgradable version of each package and the classification of the upgrade.
+// An upgrade is a security update when it is available from a "-security" pocket, apt has no advisory IDs.
+func parseAptUpgrades(output []byte) (map[string]string, map[string]*UpgradeClass) {
+	upgradable := map[string]string{}
+	classes := map[string]*UpgradeClass{}
+	for _, line := range strings.Split(string(output), "\n") {
+		match := aptUpgradeRE.FindStringSubmatch(strings.TrimSpace(line))
 		if match == nil {
 			continue
 		}
 		name := match[1]
-		version := match[2]
-		result[name] = version
+		upgradable[name] = match[3]
+		for _, suite := range strings.Split(match[2], ",") {
+			if strings.HasSuffix(suite, "-security") {
+				classes[name] = &UpgradeClass{Security: true}
+				break
+			}
+		}
 	}
-	return result
+	return upgradable, classes
 }
 
-func GetAptUpgradableMap(ctx context.Context) (map[string]string, error) {
+// GetAptUpgrades returns the upgradable packages with their full version and which of them are security updates.
+func GetAptUpgrades(ctx context.Context) (map[string]string, map[string]*UpgradeClass, error) {
 	output, err := utils.RunCmd(ctx, "apt", "list", "--upgradable")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return parseAptUpgradableOutput(output), nil
+	upgradable, classes := parseAptUpgrades(output)
+	return upgradable, classes, nil
 }
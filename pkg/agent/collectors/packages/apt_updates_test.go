@@ -3,13 +3,14 @@ package packages
 import (
 	"context"
 	"errors"
+	"os"
 	"reflect"
 	"testing"
 
 	"github.com/klamhq/facter-oss/pkg/utils"
 )
 
-func TestParseAptUpgradableOutput(t *testing.T) {
+func TestParseAptUpgrades_Lines(t *testing.T) {
 	cases := []struct {
 		name   string
 		input  string
@@ -23,7 +24,7 @@ func TestParseAptUpgradableOutput(t *testing.T) {
 		{
 			name:   "with epoch",
 			input:  `mypkg/now 2:4.5.6-1 amd64 [upgradable from: 2:4.5.5-1]`,
-			expect: map[string]string{"mypkg": "4.5.6-1"}, // note: regex captures version after optional epoch
+			expect: map[string]string{"mypkg": "2:4.5.6-1"},
 		},
 		{
 			name:   "no matches",
@@ -48,7 +49,7 @@ libok/now 1.0.1 amd64 [upgradable from: 1.0.0]
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			got := parseAptUpgradableOutput([]byte(c.input))
+			got, _ := parseAptUpgrades([]byte(c.input))
 			if !reflect.DeepEqual(got, c.expect) {
 				t.Fatalf("unexpected result\ngot:  %#v\nwant: %#v", got, c.expect)
 			}
@@ -56,7 +57,7 @@ libok/now 1.0.1 amd64 [upgradable from: 1.0.0]
 	}
 }
 
-func TestGetAptUpgradableMap_RunCmdMock(t *testing.T) {
+func TestGetAptUpgrades_RunCmdMock(t *testing.T) {
 	orig := utils.RunCmd
 	defer func() { utils.RunCmd = orig }() // restore after test
 
@@ -68,7 +69,7 @@ func TestGetAptUpgradableMap_RunCmdMock(t *testing.T) {
 		}
 		return []byte("libfoo/now 1.2.3-1 amd64 [upgradable from: 1.2.2-1]\n"), nil
 	}
-	m, err := GetAptUpgradableMap(context.Background())
+	m, _, err := GetAptUpgrades(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -80,8 +81,34 @@ func TestGetAptUpgradableMap_RunCmdMock(t *testing.T) {
 	utils.RunCmd = func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return nil, errors.New("command failed")
 	}
-	_, err = GetAptUpgradableMap(context.Background())
+	_, _, err = GetAptUpgrades(context.Background())
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestParseAptUpgrades(t *testing.T) {
+	out, err := os.ReadFile("testdata/updates/apt-list-upgradable.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upgradable, classes := parseAptUpgrades(out)
+
+	wantUpgradable := map[string]string{
+		"libssl3":  "3.0.2-0ubuntu1.15",
+		"openssl":  "3.0.2-0ubuntu1.15",
+		"tzdata":   "2024a-0ubuntu0.22.04",
+		"vim-tiny": "2:8.2.3995-1ubuntu2.15",
+	}
+	if !reflect.DeepEqual(upgradable, wantUpgradable) {
+		t.Fatalf("unexpected upgradable\ngot:  %#v\nwant: %#v", upgradable, wantUpgradable)
+	}
+	for _, name := range []string{"libssl3", "openssl", "vim-tiny"} {
+		if classes[name] == nil || !classes[name].Security {
+			t.Errorf("expected %s to be a security update", name)
+		}
+	}
+	if _, ok := classes["tzdata"]; ok {
+		t.Errorf("tzdata is not a security update")
+	}
+}
@@ -0,0 +1,143 @@
+package packages
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// dpkgStatusFile is the location of the dpkg database, relative to the root path.
+const dpkgStatusFile = "var/lib/dpkg/status"
+
+// dpkgInstalledStates lists the dpkg states for which package files are present on disk.
+// "not-installed" and "config-files" entries are ignored, as `dpkg -l` does with `ii` filtering.
+var dpkgInstalledStates = map[string]bool{
+	"installed":        true,
+	"half-installed":   true,
+	"unpacked":         true,
+	"half-configured":  true,
+	"triggers-awaited": true,
+	"triggers-pending": true,
+}
+
+// ReadDpkgStatus reads the dpkg database found under root and returns installed packages.
+// root is usually "/", but can point to a mounted or extracted filesystem.
+func ReadDpkgStatus(root string) ([]*models.Package, error) {
+	f, err := os.Open(filepath.Join(root, dpkgStatusFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open dpkg database: %w", err)
+	}
+	defer f.Close()
+	return ParseDpkgStatus(f)
+}
+
+// ParseDpkgStatus parses the content of a dpkg status file.
+func ParseDpkgStatus(r io.Reader) ([]*models.Package, error) {
+	pkgs := []*models.Package{}
+	err := parseDpkgStanzas(r, func(fields map[string]string) {
+		if pkg := dpkgStanzaToPackage(fields); pkg != nil {
+			pkgs = append(pkgs, pkg)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// parseDpkgStanzas calls fn with the fields of each stanza of a dpkg database.
+// Each stanza is a set of RFC 822 like fields separated by an empty line.
+func parseDpkgStanzas(r io.Reader, fn func(fields map[string]string)) error {
+	scanner := bufio.NewScanner(r)
+	// Some Description or Conffiles fields can be quite long.
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	fields := map[string]string{}
+	lastField := ""
+
+	flush := func() {
+		if len(fields) > 0 {
+			fn(fields)
+		}
+		fields = map[string]string{}
+		lastField = ""
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		// Continuation of the previous field (multi-line Description, Conffiles, ...)
+		if line[0] == ' ' || line[0] == '\t' {
+			if lastField != "" {
+				fields[lastField] += "\n" + line
+			}
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		lastField = key
+		fields[key] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to parse dpkg database: %w", err)
+	}
+	flush()
+	return nil
+}
+
+// dpkgStanzaToPackage converts a parsed stanza into a package, nil is returned for non installed packages.
+func dpkgStanzaToPackage(fields map[string]string) *models.Package {
+	name := fields["Package"]
+	if name == "" {
+		return nil
+	}
+	status := fields["Status"]
+	statusFlags := strings.Fields(status)
+	if len(statusFlags) != 3 || !dpkgInstalledStates[statusFlags[2]] {
+		return nil
+	}
+
+	pkg := &models.Package{
+		Name:          name,
+		Version:       fields["Version"],
+		Architecture:  fields["Architecture"],
+		Description:   parseDpkgDescription(fields["Description"]),
+		Maintainer:    fields["Maintainer"],
+		Status:        status,
+		SourcePackage: name,
+	}
+	// Source can contain the source version when it differs, e.g. "glibc (2.36-9)"
+	if source := strings.Fields(fields["Source"]); len(source) > 0 {
+		pkg.SourcePackage = source[0]
+	}
+	// Installed-Size is expressed in KiB
+	if size, err := strconv.ParseInt(fields["Installed-Size"], 10, 64); err == nil {
+		pkg.InstalledSize = size * 1024
+	}
+	return pkg
+}
+
+// parseDpkgDescription rebuilds the full description from the synopsis and extended description.
+// Extended lines are prefixed by a space and a line containing a single "." stands for an empty line.
+func parseDpkgDescription(raw string) string {
+	lines := strings.Split(raw, "\n")
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimPrefix(strings.TrimPrefix(lines[i], " "), "\t")
+		if line == "." {
+			line = ""
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,75 @@
+package packages
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const dpkgTestRoot = "testdata/dpkg"
+
+func TestReadDpkgStatus(t *testing.T) {
+	pkgs, err := ReadDpkgStatus(dpkgTestRoot)
+	assert.NoError(t, err)
+	// vim-tiny only has config files left and must be ignored
+	assert.Len(t, pkgs, 6)
+
+	tzdata := pkgs[0]
+	assert.Equal(t, "tzdata", tzdata.Name)
+	assert.Equal(t, "2024a-0+deb12u1", tzdata.Version)
+	assert.Equal(t, "all", tzdata.Architecture)
+	assert.Equal(t, "tzdata", tzdata.SourcePackage)
+	assert.Equal(t, "GNU Libc Maintainers <debian-glibc@lists.debian.org>", tzdata.Maintainer)
+	assert.Equal(t, int64(2413*1024), tzdata.InstalledSize)
+	assert.Equal(t, "install ok installed", tzdata.Status)
+	assert.True(t, strings.HasPrefix(tzdata.Description, "time zone and daylight-saving time data\nThis package contains"))
+	assert.Contains(t, tzdata.Description, "globe.\n\nIt is updated")
+}
+
+func TestReadDpkgStatus_Multiarch(t *testing.T) {
+	pkgs, err := ReadDpkgStatus(dpkgTestRoot)
+	assert.NoError(t, err)
+
+	archs := []string{}
+	for _, pkg := range pkgs {
+		if pkg.Name == "libc6" {
+			archs = append(archs, pkg.Architecture)
+			assert.Equal(t, "glibc", pkg.SourcePackage)
+		}
+	}
+	assert.ElementsMatch(t, []string{"amd64", "i386"}, archs)
+}
+
+func TestReadDpkgStatus_EpochAndSourceVersion(t *testing.T) {
+	pkgs, err := ReadDpkgStatus(dpkgTestRoot)
+	assert.NoError(t, err)
+
+	byName := map[string]string{}
+	sources := map[string]string{}
+	for _, pkg := range pkgs {
+		byName[pkg.Name] = pkg.Version
+		sources[pkg.Name] = pkg.SourcePackage
+	}
+	assert.Equal(t, "1:9.2p1-2+deb12u2", byName["openssh-client"])
+	assert.Equal(t, "openssh", sources["openssh-client"])
+	assert.Equal(t, "libgcrypt20", sources["libgcrypt20"])
+	assert.Equal(t, "7.2-1", byName["nano"])
+	assert.NotContains(t, byName, "vim-tiny")
+}
+
+func TestReadDpkgStatus_MissingDatabase(t *testing.T) {
+	pkgs, err := ReadDpkgStatus(t.TempDir())
+	assert.Error(t, err)
+	assert.Nil(t, pkgs)
+}
+
+func TestParseDpkgStatus_NoTrailingNewline(t *testing.T) {
+	input := "Package: foo\nStatus: install ok installed\nVersion: 1.0\nArchitecture: arm64\nDescription: foo tool"
+	pkgs, err := ParseDpkgStatus(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Len(t, pkgs, 1)
+	assert.Equal(t, "foo", pkgs[0].Name)
+	assert.Equal(t, "arm64", pkgs[0].Architecture)
+	assert.Equal(t, "foo tool", pkgs[0].Description)
+}
@@ -0,0 +1,120 @@
+package packages
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// flatpakSystemInstallation is the system wide flatpak installation, relative to the root path.
+const flatpakSystemInstallation = "var/lib/flatpak"
+
+// flatpakUserInstallation is the per user flatpak installation, relative to the home directory.
+const flatpakUserInstallation = ".local/share/flatpak"
+
+// flatpakMetainfo holds the fields of the AppStream metainfo file used for the inventory.
+type flatpakMetainfo struct {
+	Summary  []string `xml:"summary"`
+	License  string   `xml:"project_license"`
+	Releases []struct {
+		Version string `xml:"version,attr"`
+	} `xml:"releases>release"`
+}
+
+// ReadFlatpaks returns the applications and runtimes of the system installation under root
+// and of the user installations of the given home directories.
+func ReadFlatpaks(root string, homes []string) ([]*models.Package, error) {
+	installations := []string{filepath.Join(root, flatpakSystemInstallation)}
+	for _, home := range homes {
+		installations = append(installations, filepath.Join(root, home, flatpakUserInstallation))
+	}
+
+	pkgs := []*models.Package{}
+	found := false
+	for _, installation := range installations {
+		if _, err := os.Stat(installation); err != nil {
+			continue
+		}
+		found = true
+		for _, kind := range []string{"app", "runtime"} {
+			pkgs = append(pkgs, readFlatpakRefs(installation, kind)...)
+		}
+	}
+	if !found {
+		return nil, os.ErrNotExist
+	}
+	return pkgs, nil
+}
+
+// readFlatpakRefs reads the deployed refs of an installation, laid out as <kind>/<id>/<arch>/<branch>/active.
+func readFlatpakRefs(installation, kind string) []*models.Package {
+	pkgs := []*models.Package{}
+	refs, _ := filepath.Glob(filepath.Join(installation, kind, "*", "*", "*", "active"))
+	for _, active := range refs {
+		commit, err := os.Readlink(active)
+		if err != nil {
+			continue
+		}
+		branchDir := filepath.Dir(active)
+		archDir := filepath.Dir(branchDir)
+		id := filepath.Base(filepath.Dir(archDir))
+		arch := filepath.Base(archDir)
+		branch := filepath.Base(branchDir)
+
+		pkg := &models.Package{
+			Name:         id,
+			Architecture: arch,
+			Channel:      branch,
+			Revision:     filepath.Base(commit),
+			Origin:       flatpakOrigin(installation, kind, id, arch, branch),
+			Ecosystem:    models.EcosystemFlatpak,
+			Manager:      "flatpak",
+			Location:     branchDir,
+		}
+		if meta := readFlatpakMetainfo(active, id); meta != nil {
+			if len(meta.Releases) > 0 {
+				// Releases are listed from the newest to the oldest
+				pkg.Version = meta.Releases[0].Version
+			}
+			if len(meta.Summary) > 0 {
+				pkg.Description = meta.Summary[0]
+			}
+			pkg.License = meta.License
+		}
+		if pkg.Version == "" {
+			// Runtimes rarely ship a metainfo file, their branch is their version
+			pkg.Version = branch
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs
+}
+
+// flatpakOrigin returns the remote a ref has been pulled from, found in the local repository refs.
+func flatpakOrigin(installation, kind, id, arch, branch string) string {
+	remotes, _ := filepath.Glob(filepath.Join(installation, "repo", "refs", "remotes", "*", kind, id, arch, branch))
+	if len(remotes) == 0 {
+		return ""
+	}
+	return filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(remotes[0])))))
+}
+
+func readFlatpakMetainfo(deploy, id string) *flatpakMetainfo {
+	for _, file := range []string{
+		filepath.Join(deploy, "files", "share", "metainfo", id+".metainfo.xml"),
+		filepath.Join(deploy, "files", "share", "appdata", id+".appdata.xml"),
+	} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var meta flatpakMetainfo
+		if err := xml.Unmarshal(data, &meta); err != nil {
+			continue
+		}
+		return &meta
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package packages
+
+import (
+	"context"
+	"crypto"
+	_ "crypto/md5" // Digest algorithms of the package databases
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/packages/rpmdb"
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// dpkgDiversionsFile lists the files moved aside by dpkg-divert, relative to the root path.
+const dpkgDiversionsFile = "var/lib/dpkg/diversions"
+
+// rpmDigestHashes maps the digest algorithms of the rpm database to their hash.
+var rpmDigestHashes = map[int64]crypto.Hash{
+	rpmdb.DigestAlgoMD5:    crypto.MD5,
+	rpmdb.DigestAlgoSHA1:   crypto.SHA1,
+	rpmdb.DigestAlgoSHA256: crypto.SHA256,
+	rpmdb.DigestAlgoSHA384: crypto.SHA384,
+	rpmdb.DigestAlgoSHA512: crypto.SHA512,
+}
+
+// expectedFile is a file as recorded by a package database.
+type expectedFile struct {
+	path      string
+	digest    string
+	hash      crypto.Hash
+	mode      fs.FileMode // Zero when the database does not record it
+	config    bool
+	missingOK bool
+}
+
+// VerifyPackageFiles compares the files installed under root with the checksums of the dpkg and
+// rpm databases, and returns the changed, missing and permission-modified files.
+// Configuration files are only verified when configFiles is set.
+func VerifyPackageFiles(ctx context.Context, logger *logrus.Logger, root string, configFiles bool) ([]*models.ModifiedFile, error) {
+	modified := []*models.ModifiedFile{}
+	verified := false
+
+	if pkgs, err := readDpkgExpectedFiles(root); err == nil {
+		verified = true
+		names := sortedKeys(pkgs)
+		for _, name := range names {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			modified = append(modified, verifyFiles(root, name, models.EcosystemDeb, pkgs[name], configFiles)...)
+		}
+	} else {
+		logger.WithError(err).Debug("no dpkg database to verify")
+	}
+
+	if infos, err := rpmdb.ListPackages(root); err == nil {
+		verified = true
+		for _, info := range infos {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			modified = append(modified, verifyFiles(root, info.Name, models.EcosystemRpm, rpmExpectedFiles(info), configFiles)...)
+		}
+	} else {
+		logger.WithError(err).Debug("no rpm database to verify")
+	}
+
+	if !verified {
+		return nil, fmt.Errorf("no package database with file checksums found")
+	}
+	logger.Debugf("%d modified package files found", len(modified))
+	return modified, nil
+}
+
+// readDpkgExpectedFiles reads the files of each installed deb package: the <package>[:<arch>].md5sums files
+// list regular files, configuration files and their checksum are listed in the Conffiles field of the status.
+// dpkg does not record file permissions.
+func readDpkgExpectedFiles(root string) (map[string][]expectedFile, error) {
+	f, err := os.Open(filepath.Join(root, dpkgStatusFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open dpkg database: %w", err)
+	}
+	defer f.Close()
+
+	pkgs := map[string][]expectedFile{}
+	err = parseDpkgStanzas(f, func(fields map[string]string) {
+		if dpkgStanzaToPackage(fields) == nil {
+			return
+		}
+		name := fields["Package"]
+		if _, ok := pkgs[name]; !ok {
+			pkgs[name] = []expectedFile{}
+		}
+		for _, line := range strings.Split(fields["Conffiles"], "\n") {
+			// "/etc/ssh/ssh_config 4c9f4d1c7d5e6c2f3c1c5b4a8f2e9d10 [obsolete|remove-on-upgrade]"
+			conffile := strings.Fields(line)
+			if len(conffile) < 2 || len(conffile) > 2 && conffile[2] == "obsolete" {
+				continue
+			}
+			pkgs[name] = append(pkgs[name], expectedFile{path: conffile[0], digest: conffile[1], hash: crypto.MD5, config: true})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sums, _ := filepath.Glob(filepath.Join(root, dpkgInfoDir, "*.md5sums"))
+	for _, sum := range sums {
+		name := strings.TrimSuffix(filepath.Base(sum), ".md5sums")
+		name, _, _ = strings.Cut(name, ":")
+		if _, installed := pkgs[name]; !installed {
+			continue
+		}
+		for _, line := range readLines(sum) {
+			// "<md5>  <path relative to the root>"
+			digest, path, found := strings.Cut(line, " ")
+			if !found {
+				continue
+			}
+			pkgs[name] = append(pkgs[name], expectedFile{path: "/" + strings.TrimSpace(path), digest: digest, hash: crypto.MD5})
+		}
+	}
+
+	applyDpkgDiversions(root, pkgs)
+	return pkgs, nil
+}
+
+// applyDpkgDiversions points the files diverted by another package to the path they have been moved to.
+// The diversions file is made of "from", "to" and "package" lines.
+func applyDpkgDiversions(root string, pkgs map[string][]expectedFile) {
+	lines := readLines(filepath.Join(root, dpkgDiversionsFile))
+	for i := 0; i+2 < len(lines); i += 3 {
+		from, to, by := lines[i], lines[i+1], lines[i+2]
+		for name, files := range pkgs {
+			if name == by {
+				continue
+			}
+			for j := range files {
+				if files[j].path == from {
+					files[j].path = to
+				}
+			}
+		}
+	}
+}
+
+// rpmExpectedFiles returns the files of an rpm package, ghost files are not installed by the package.
+func rpmExpectedFiles(info *rpmdb.PackageInfo) []expectedFile {
+	hash, ok := rpmDigestHashes[info.DigestAlgo]
+	files := make([]expectedFile, 0, len(info.FileEntries))
+	for _, entry := range info.FileEntries {
+		if entry.Flags&rpmdb.FileFlagGhost != 0 {
+			continue
+		}
+		file := expectedFile{
+			path:      entry.Path,
+			mode:      unixModeToFileMode(entry.Mode),
+			config:    entry.Flags&rpmdb.FileFlagConfig != 0,
+			missingOK: entry.Flags&rpmdb.FileFlagMissingOK != 0,
+		}
+		if ok {
+			file.digest = entry.Digest
+			file.hash = hash
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// verifyFiles checks the existence, permissions and checksum of the files of a package.
+func verifyFiles(root, name, ecosystem string, files []expectedFile, configFiles bool) []*models.ModifiedFile {
+	modified := []*models.ModifiedFile{}
+	report := func(file expectedFile, reason, expected, actual string) {
+		modified = append(modified, &models.ModifiedFile{
+			Path:      file.path,
+			Package:   name,
+			Ecosystem: ecosystem,
+			Reason:    reason,
+			Expected:  expected,
+			Actual:    actual,
+			Config:    file.config,
+		})
+	}
+
+	for _, file := range files {
+		if file.config && !configFiles {
+			continue
+		}
+		fi, err := os.Lstat(filepath.Join(root, file.path))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && !file.missingOK {
+				report(file, models.FileMissing, "", "")
+			}
+			continue
+		}
+		if file.mode != 0 && permissionBits(fi.Mode()) != permissionBits(file.mode) {
+			report(file, models.FilePermissions, formatMode(file.mode), formatMode(fi.Mode()))
+		}
+		// Digests are only recorded for regular files
+		if file.digest == "" || !fi.Mode().IsRegular() {
+			continue
+		}
+		digest, err := fileDigest(filepath.Join(root, file.path), file.hash)
+		if err != nil {
+			continue
+		}
+		if !strings.EqualFold(digest, file.digest) {
+			report(file, models.FileChanged, file.digest, digest)
+		}
+	}
+	return modified
+}
+
+func fileDigest(path string, hash crypto.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if !hash.Available() {
+		return "", fmt.Errorf("unsupported digest algorithm %v", hash)
+	}
+	h := hash.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// unixModeToFileMode converts the permission bits of a st_mode, as stored by rpm, to a FileMode.
+func unixModeToFileMode(mode int64) fs.FileMode {
+	m := fs.FileMode(mode & 0o777)
+	if mode&0o4000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if mode&0o2000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if mode&0o1000 != 0 {
+		m |= fs.ModeSticky
+	}
+	return m
+}
+
+// permissionBits keeps the permission, setuid, setgid and sticky bits of a mode.
+func permissionBits(mode fs.FileMode) fs.FileMode {
+	return mode & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+}
+
+// formatMode formats permission bits in octal, as chmod takes them.
+func formatMode(mode fs.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return fmt.Sprintf("%04o", bits)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package packages
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/packages/rpmdb"
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPackageFiles_Dpkg(t *testing.T) {
+	modified, err := VerifyPackageFiles(context.Background(), logrus.New(), "testdata/integrity", false)
+	assert.NoError(t, err)
+
+	// ls is diverted to ls.real, which is unchanged, and the configuration file is skipped
+	assert.Equal(t, []*models.ModifiedFile{
+		{
+			Path:      "/usr/bin/cat",
+			Package:   "coreutils",
+			Ecosystem: models.EcosystemDeb,
+			Reason:    models.FileChanged,
+			Expected:  "c4d6d23b462c511295ee86b7630aebfe",
+			Actual:    "11e1f79544d45b3c1ac286f3ab502795",
+		},
+		{
+			Path:      "/usr/bin/dir",
+			Package:   "coreutils",
+			Ecosystem: models.EcosystemDeb,
+			Reason:    models.FileMissing,
+		},
+	}, modified)
+}
+
+func TestVerifyPackageFiles_DpkgConfigFiles(t *testing.T) {
+	modified, err := VerifyPackageFiles(context.Background(), logrus.New(), "testdata/integrity", true)
+	assert.NoError(t, err)
+	assert.Len(t, modified, 3)
+
+	conffile := modified[2]
+	assert.Equal(t, "/etc/ssh/ssh_config", conffile.Path)
+	assert.Equal(t, "openssh-client", conffile.Package)
+	assert.Equal(t, models.FileChanged, conffile.Reason)
+	assert.True(t, conffile.Config)
+}
+
+func TestVerifyPackageFiles_Rpm(t *testing.T) {
+	// Files of the fixture database are not installed, the configuration file is skipped
+	modified, err := VerifyPackageFiles(context.Background(), logrus.New(), "rpmdb/testdata/sqlite", false)
+	assert.NoError(t, err)
+
+	paths := []string{}
+	for _, file := range modified {
+		assert.Equal(t, models.FileMissing, file.Reason)
+		assert.Equal(t, models.EcosystemRpm, file.Ecosystem)
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, "/usr/bin/bash")
+	assert.Contains(t, paths, "/usr/share/doc/bash/README")
+	assert.NotContains(t, paths, "/etc/skel/.bashrc")
+}
+
+func TestVerifyPackageFiles_NoDatabase(t *testing.T) {
+	_, err := VerifyPackageFiles(context.Background(), logrus.New(), "testdata/snap", false)
+	assert.Error(t, err)
+}
+
+func TestVerifyFiles_RpmEntries(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "usr/bin"), 0o755))
+	for _, name := range []string{"sudo", "httpd", "apachectl"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(root, "usr/bin", name), []byte("binary\n"), 0o755))
+	}
+	assert.NoError(t, os.Chmod(filepath.Join(root, "usr/bin/sudo"), 0o755))
+
+	// sha256 of "binary\n"
+	digest := "58eaf5a78d580f5dbd49d31a5b733094169b31bfdf49055b74bcac2877d8f58c"
+
+	info := &rpmdb.PackageInfo{
+		Name:       "httpd",
+		DigestAlgo: rpmdb.DigestAlgoSHA256,
+		FileEntries: []rpmdb.FileEntry{
+			// setuid bit dropped
+			{Path: "/usr/bin/sudo", Digest: digest, Mode: 0o104755},
+			{Path: "/usr/bin/httpd", Digest: digest, Mode: 0o100755},
+			{Path: "/usr/bin/apachectl", Digest: "00" + digest[2:], Mode: 0o100755},
+			{Path: "/run/httpd.pid", Mode: 0o100644, Flags: rpmdb.FileFlagGhost},
+			{Path: "/etc/httpd/conf.d/local.conf", Mode: 0o100644, Flags: rpmdb.FileFlagConfig | rpmdb.FileFlagMissingOK},
+		},
+	}
+	modified := verifyFiles(root, info.Name, models.EcosystemRpm, rpmExpectedFiles(info), true)
+	if assert.Len(t, modified, 2) {
+		assert.Equal(t, "/usr/bin/sudo", modified[0].Path)
+		assert.Equal(t, models.FilePermissions, modified[0].Reason)
+		assert.Equal(t, "4755", modified[0].Expected)
+		assert.Equal(t, "0755", modified[0].Actual)
+		assert.Equal(t, "/usr/bin/apachectl", modified[1].Path)
+		assert.Equal(t, models.FileChanged, modified[1].Reason)
+	}
+}
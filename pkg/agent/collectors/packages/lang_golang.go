@@ -0,0 +1,95 @@
+package packages
+
+import (
+	"debug/buildinfo"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// readGoBinary returns the main module and the dependencies embedded in a go binary by the go toolchain.
+// Binaries built without module support, or not built with go, return an error.
+func readGoBinary(path, location string) ([]*models.Package, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pkgs := []*models.Package{}
+	newPackage := func(mod, version string) *models.Package {
+		return &models.Package{
+			Name:      mod,
+			Version:   version,
+			Ecosystem: models.EcosystemGolang,
+			Manager:   "go",
+			Location:  location,
+		}
+	}
+	if info.Main.Path != "" {
+		pkgs = append(pkgs, newPackage(info.Main.Path, info.Main.Version))
+	}
+	for _, dep := range info.Deps {
+		// A replaced module is the one really compiled in the binary
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		pkgs = append(pkgs, newPackage(dep.Path, dep.Version))
+	}
+	// The standard library is vulnerable as any module, its version is the toolchain one
+	pkgs = append(pkgs, newPackage("stdlib", strings.TrimPrefix(info.GoVersion, "go")))
+	return pkgs, nil
+}
+
+// scanGoBinariesDir reads the go binaries found directly in dir, typically a PATH entry.
+func (s *languageScanner) scanGoBinariesDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if pkgs, err := readGoBinary(path, path); err == nil {
+			s.add(pkgs...)
+		}
+	}
+}
+
+// scanGoProcesses reads the executable of every running process, binaries outside PATH and deleted ones are found this way.
+func (s *languageScanner) scanGoProcesses(procPath string) {
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !isNumeric(entry.Name()) {
+			continue
+		}
+		exe := filepath.Join(procPath, entry.Name(), "exe")
+		target, err := os.Readlink(exe)
+		if err != nil || seen[target] {
+			continue
+		}
+		seen[target] = true
+		// Reading through /proc/<pid>/exe works even when the file has been replaced on disk
+		if pkgs, err := readGoBinary(exe, strings.TrimSuffix(target, " (deleted)")); err == nil {
+			s.add(pkgs...)
+		}
+	}
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
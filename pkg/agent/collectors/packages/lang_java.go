@@ -0,0 +1,114 @@
+package packages
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// maxNestedArchiveSize bounds the memory used to open archives embedded in another one, e.g. spring boot jars.
+const maxNestedArchiveSize = 64 << 20
+
+func isJavaArchive(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jar", ".war", ".ear":
+		return true
+	}
+	return false
+}
+
+// readJavaArchive returns the maven artifacts described by the META-INF/maven/**/pom.properties files of
+// the archive, and of the archives it embeds (WEB-INF/lib, BOOT-INF/lib, ...).
+func readJavaArchive(file string) ([]*models.Package, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readJavaZip(&r.Reader, file, true)
+}
+
+func readJavaZip(r *zip.Reader, location string, nested bool) ([]*models.Package, error) {
+	pkgs := []*models.Package{}
+	for _, f := range r.File {
+		switch {
+		case strings.HasPrefix(f.Name, "META-INF/maven/") && path.Base(f.Name) == "pom.properties":
+			pkg, err := readPomProperties(f, location)
+			if err != nil {
+				return pkgs, err
+			}
+			if pkg != nil {
+				pkgs = append(pkgs, pkg)
+			}
+		case nested && isJavaArchive(f.Name):
+			if f.UncompressedSize64 > maxNestedArchiveSize {
+				continue
+			}
+			inner, err := openNestedZip(f)
+			if err != nil {
+				continue
+			}
+			// Archives are only unpacked one level deep
+			innerPkgs, _ := readJavaZip(inner, location+"!/"+f.Name, false)
+			pkgs = append(pkgs, innerPkgs...)
+		}
+	}
+	return pkgs, nil
+}
+
+func openNestedZip(f *zip.File) (*zip.Reader, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxNestedArchiveSize))
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// readPomProperties parses the java properties file written by maven in every artifact.
+func readPomProperties(f *zip.File, location string) (*models.Package, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	props := map[string]string{}
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			key, value, found = strings.Cut(line, ":")
+		}
+		if found {
+			props[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", f.Name, err)
+	}
+	if props["groupId"] == "" || props["artifactId"] == "" || props["version"] == "" {
+		return nil, nil
+	}
+	return &models.Package{
+		Name:      props["groupId"] + ":" + props["artifactId"],
+		Version:   props["version"],
+		Ecosystem: models.EcosystemMaven,
+		Manager:   "maven",
+		Location:  location,
+	}, nil
+}
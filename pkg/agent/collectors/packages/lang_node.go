@@ -0,0 +1,69 @@
+package packages
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// isNodeModulesPackage returns true for node_modules/<name>/package.json and node_modules/@scope/<name>/package.json.
+func isNodeModulesPackage(path string) bool {
+	parent := filepath.Dir(filepath.Dir(path))
+	if filepath.Base(parent) == "node_modules" {
+		return true
+	}
+	return strings.HasPrefix(filepath.Base(parent), "@") && filepath.Base(filepath.Dir(parent)) == "node_modules"
+}
+
+// nodePackageJSON holds the fields of package.json used for the inventory.
+// License is either a SPDX expression or, in old packages, an object with a type.
+type nodePackageJSON struct {
+	Name        string          `json:"name"`
+	Version     string          `json:"version"`
+	Description string          `json:"description"`
+	License     json.RawMessage `json:"license"`
+	Author      json.RawMessage `json:"author"`
+}
+
+// readNodePackage reads the package.json of an installed node module.
+func readNodePackage(path string) *models.Package {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var manifest nodePackageJSON
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.Name == "" || manifest.Version == "" {
+		return nil
+	}
+	return &models.Package{
+		Name:        manifest.Name,
+		Version:     manifest.Version,
+		Description: manifest.Description,
+		License:     nodeStringOrField(manifest.License, "type"),
+		Maintainer:  nodeStringOrField(manifest.Author, "name"),
+		Ecosystem:   models.EcosystemNpm,
+		Manager:     "npm",
+		Location:    filepath.Dir(path),
+	}
+}
+
+// nodeStringOrField decodes a value which is either a string or an object holding the string in field.
+func nodeStringOrField(raw json.RawMessage, field string) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		if value, ok := obj[field].(string); ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package packages
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// readPythonMetadataDir reads a *.dist-info (wheel) or *.egg-info (setuptools) directory.
+func readPythonMetadataDir(dir string) *models.Package {
+	for _, name := range []string{"METADATA", "PKG-INFO"} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		return parsePythonMetadata(f, dir)
+	}
+	return nil
+}
+
+// readPythonMetadataFile reads a *.egg-info file, older setuptools write PKG-INFO as a single file.
+func readPythonMetadataFile(path string) *models.Package {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	return parsePythonMetadata(f, path)
+}
+
+// parsePythonMetadata parses the email-header like core metadata format, only the headers are read.
+// See https://packaging.python.org/en/latest/specifications/core-metadata/
+func parsePythonMetadata(r io.Reader, location string) *models.Package {
+	pkg := &models.Package{
+		Ecosystem: models.EcosystemPypi,
+		Manager:   "pip",
+		Location:  location,
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// The description body follows the headers
+			break
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			pkg.Name = value
+		case "Version":
+			pkg.Version = value
+		case "Summary":
+			pkg.Description = value
+		case "License":
+			pkg.License = value
+		case "Author":
+			pkg.Maintainer = value
+		case "Maintainer":
+			if value != "" {
+				pkg.Maintainer = value
+			}
+		}
+	}
+	if pkg.Name == "" || pkg.Version == "" {
+		return nil
+	}
+	return pkg
+}
@@ -0,0 +1,58 @@
+package packages
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// Installed gem specifications are ruby files generated by rubygems, e.g.
+//
+//	s.name = "rake".freeze
+//	s.version = "13.0.6"
+//	s.licenses = ["MIT".freeze]
+var (
+	gemNameRE     = regexp.MustCompile(`(?m)^\s*s\.name\s*=\s*"([^"]+)"`)
+	gemVersionRE  = regexp.MustCompile(`(?m)^\s*s\.version\s*=\s*"([^"]+)"`)
+	gemSummaryRE  = regexp.MustCompile(`(?m)^\s*s\.summary\s*=\s*"([^"]+)"`)
+	gemLicensesRE = regexp.MustCompile(`(?m)^\s*s\.licenses\s*=\s*\[([^\]]*)\]`)
+	gemAuthorsRE  = regexp.MustCompile(`(?m)^\s*s\.authors\s*=\s*\[\s*"([^"]+)"`)
+	gemQuotedRE   = regexp.MustCompile(`"([^"]+)"`)
+)
+
+// readGemSpec reads a gem specification from a rubygems "specifications" directory.
+func readGemSpec(path string) *models.Package {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	pkg := &models.Package{
+		Name:        firstSubmatch(gemNameRE, data),
+		Version:     firstSubmatch(gemVersionRE, data),
+		Description: firstSubmatch(gemSummaryRE, data),
+		Maintainer:  firstSubmatch(gemAuthorsRE, data),
+		Ecosystem:   models.EcosystemGem,
+		Manager:     "gem",
+		Location:    path,
+	}
+	if licenses := gemLicensesRE.FindSubmatch(data); licenses != nil {
+		names := []string{}
+		for _, m := range gemQuotedRE.FindAllSubmatch(licenses[1], -1) {
+			names = append(names, string(m[1]))
+		}
+		pkg.License = strings.Join(names, " OR ")
+	}
+	if pkg.Name == "" || pkg.Version == "" {
+		return nil
+	}
+	return pkg
+}
+
+func firstSubmatch(re *regexp.Regexp, data []byte) string {
+	if m := re.FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package packages
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultLanguageRoots are searched when no root is configured.
+var defaultLanguageRoots = []string{"/usr/lib", "/usr/local/lib", "/opt", "/srv", "/home", "/root"}
+
+const defaultLanguageMaxDepth = 8
+
+// languageSkipDirs are pseudo or volatile filesystems never worth walking.
+var languageSkipDirs = map[string]bool{
+	"/proc": true,
+	"/sys":  true,
+	"/dev":  true,
+	"/run":  true,
+}
+
+// languageScanner walks the search roots and collects the packages of language ecosystems.
+type languageScanner struct {
+	logger     *logrus.Logger
+	maxDepth   int
+	ecosystems map[string]bool
+	pkgs       []*models.Package
+	seen       map[string]bool
+}
+
+func newLanguageScanner(logger *logrus.Logger, cfg *options.LanguagePackagesOptions) *languageScanner {
+	s := &languageScanner{
+		logger:   logger,
+		maxDepth: cfg.MaxDepth,
+		seen:     map[string]bool{},
+	}
+	if s.maxDepth <= 0 {
+		s.maxDepth = defaultLanguageMaxDepth
+	}
+	if len(cfg.Ecosystems) > 0 {
+		s.ecosystems = map[string]bool{}
+		for _, ecosystem := range cfg.Ecosystems {
+			s.ecosystems[ecosystem] = true
+		}
+	}
+	return s
+}
+
+// NewLanguagePackagesGrabber returns packages of language ecosystems: python, node, ruby, go binaries and java archives.
+func NewLanguagePackagesGrabber(ctx context.Context, logger *logrus.Logger, cfg *options.LanguagePackagesOptions) ([]*models.Package, error) {
+	s := newLanguageScanner(logger, cfg)
+
+	roots := cfg.Roots
+	if len(roots) == 0 {
+		roots = defaultLanguageRoots
+	}
+	for _, root := range roots {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		s.scanRoot(ctx, root)
+	}
+
+	if s.enabled(models.EcosystemGolang) {
+		for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+			s.scanGoBinariesDir(dir)
+		}
+		if cfg.Processes {
+			s.scanGoProcesses("/proc")
+		}
+	}
+
+	logger.Debugf("%d language packages found", len(s.pkgs))
+	return s.pkgs, nil
+}
+
+func (s *languageScanner) enabled(ecosystem string) bool {
+	return s.ecosystems == nil || s.ecosystems[ecosystem]
+}
+
+// add records packages not seen yet, the same package is often found in several places
+// and only the first location is kept.
+func (s *languageScanner) add(pkgs ...*models.Package) {
+	for _, pkg := range pkgs {
+		if pkg == nil || pkg.Name == "" || !s.enabled(pkg.Ecosystem) {
+			continue
+		}
+		key := pkg.Key()
+		if s.seen[key] {
+			continue
+		}
+		s.seen[key] = true
+		s.pkgs = append(s.pkgs, pkg)
+	}
+}
+
+// scanRoot walks root up to the maximum depth, symbolic links are not followed.
+func (s *languageScanner) scanRoot(ctx context.Context, root string) {
+	root = filepath.Clean(root)
+	rootDepth := strings.Count(root, string(filepath.Separator))
+
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped, the walk goes on
+			s.logger.WithError(err).Debugf("unable to walk %s", path)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		name := d.Name()
+
+		if d.IsDir() {
+			if languageSkipDirs[path] || name == ".git" {
+				return filepath.SkipDir
+			}
+			if strings.Count(path, string(filepath.Separator))-rootDepth > s.maxDepth {
+				return filepath.SkipDir
+			}
+			if strings.HasSuffix(name, ".dist-info") || strings.HasSuffix(name, ".egg-info") {
+				s.add(readPythonMetadataDir(path))
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		switch {
+		case strings.HasSuffix(name, ".egg-info"):
+			s.add(readPythonMetadataFile(path))
+		case name == "package.json" && isNodeModulesPackage(path):
+			s.add(readNodePackage(path))
+		case strings.HasSuffix(name, ".gemspec") && filepath.Base(filepath.Dir(path)) == "specifications":
+			s.add(readGemSpec(path))
+		case isJavaArchive(name):
+			if s.enabled(models.EcosystemMaven) {
+				pkgs, err := readJavaArchive(path)
+				if err != nil {
+					s.logger.WithError(err).Debugf("unable to read java archive %s", path)
+				}
+				s.add(pkgs...)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,129 @@
+package packages
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const languageTestRoot = "testdata/lang"
+
+func languagePackagesByKey(pkgs []*models.Package) map[string]*models.Package {
+	byKey := map[string]*models.Package{}
+	for _, pkg := range pkgs {
+		byKey[pkg.Key()] = pkg
+	}
+	return byKey
+}
+
+func TestNewLanguagePackagesGrabber(t *testing.T) {
+	t.Setenv("PATH", "")
+	cfg := &options.LanguagePackagesOptions{Roots: []string{languageTestRoot}, MaxDepth: 8}
+	pkgs, err := NewLanguagePackagesGrabber(context.Background(), logrus.New(), cfg)
+	assert.NoError(t, err)
+
+	byKey := languagePackagesByKey(pkgs)
+	expected := []string{
+		"pypi/requests@2.31.0",
+		"pypi/PyYAML@6.0.1",
+		"pypi/six@1.16.0",
+		"npm/lodash@4.17.21",
+		"npm/@babel/core@7.23.9",
+		"npm/semver@6.3.1",
+		"gem/rake@13.0.6",
+		"gem/nokogiri@1.15.4",
+		"maven/org.apache.logging.log4j:log4j-core@2.14.1",
+		"maven/com.example:demo@0.0.1-SNAPSHOT",
+		"maven/com.fasterxml.jackson.core:jackson-databind@2.13.0",
+	}
+	for _, key := range expected {
+		assert.Contains(t, byKey, key)
+	}
+	assert.Len(t, pkgs, len(expected))
+
+	// package.json outside of node_modules and nodes below the maximum depth are ignored
+	assert.NotContains(t, byKey, "npm/web@1.0.0")
+	assert.NotContains(t, byKey, "npm/lodash-test-fixture@0.0.0")
+	assert.NotContains(t, byKey, "npm/leftpad@0.0.1")
+
+	requests := byKey["pypi/requests@2.31.0"]
+	assert.Equal(t, "Python HTTP for Humans.", requests.Description)
+	assert.Equal(t, "Apache 2.0", requests.License)
+	assert.Equal(t, "pip", requests.Manager)
+	assert.Equal(t, "testdata/lang/usr/lib/python3/dist-packages/requests-2.31.0.dist-info", requests.Location)
+
+	babel := byKey["npm/@babel/core@7.23.9"]
+	assert.Equal(t, "MIT", babel.License)
+	assert.Equal(t, "The Babel Team", babel.Maintainer)
+
+	rake := byKey["gem/rake@13.0.6"]
+	assert.Equal(t, "MIT", rake.License)
+	assert.Equal(t, "Hiroshi SHIBATA", rake.Maintainer)
+	assert.Equal(t, "MIT OR Apache-2.0", byKey["gem/nokogiri@1.15.4"].License)
+
+	jackson := byKey["maven/com.fasterxml.jackson.core:jackson-databind@2.13.0"]
+	assert.Equal(t, "testdata/lang/opt/app/lib/demo.jar!/BOOT-INF/lib/jackson-databind-2.13.0.jar", jackson.Location)
+}
+
+func TestNewLanguagePackagesGrabber_DepthAndEcosystems(t *testing.T) {
+	t.Setenv("PATH", "")
+	cfg := &options.LanguagePackagesOptions{Roots: []string{languageTestRoot}, MaxDepth: 20, Ecosystems: []string{models.EcosystemNpm}}
+	pkgs, err := NewLanguagePackagesGrabber(context.Background(), logrus.New(), cfg)
+	assert.NoError(t, err)
+
+	byKey := languagePackagesByKey(pkgs)
+	assert.Contains(t, byKey, "npm/leftpad@0.0.1")
+	for _, pkg := range pkgs {
+		assert.Equal(t, models.EcosystemNpm, pkg.Ecosystem)
+	}
+}
+
+func TestNewLanguagePackagesGrabber_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewLanguagePackagesGrabber(ctx, logrus.New(), &options.LanguagePackagesOptions{Roots: []string{languageTestRoot}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestReadGoBinary(t *testing.T) {
+	// The test binary is a go binary embedding its dependencies
+	exe, err := os.Executable()
+	assert.NoError(t, err)
+
+	pkgs, err := readGoBinary(exe, exe)
+	assert.NoError(t, err)
+	byName := map[string]*models.Package{}
+	for _, pkg := range pkgs {
+		assert.Equal(t, models.EcosystemGolang, pkg.Ecosystem)
+		assert.Equal(t, exe, pkg.Location)
+		byName[pkg.Name] = pkg
+	}
+	assert.Contains(t, byName, "github.com/stretchr/testify")
+	assert.Contains(t, byName, "stdlib")
+	assert.NotEmpty(t, byName["stdlib"].Version)
+
+	_, err = readGoBinary("testdata/lang/srv/web/package.json", "")
+	assert.Error(t, err)
+}
+
+func TestScanGoProcesses(t *testing.T) {
+	if _, err := os.Stat("/proc/self/exe"); err != nil {
+		t.Skip("procfs not available")
+	}
+	s := newLanguageScanner(logrus.New(), &options.LanguagePackagesOptions{})
+	s.scanGoProcesses("/proc")
+	assert.Contains(t, languagePackagesByKey(s.pkgs), "golang/stdlib@"+goVersionOf(t))
+}
+
+func goVersionOf(t *testing.T) string {
+	exe, err := os.Executable()
+	assert.NoError(t, err)
+	pkgs, err := readGoBinary(exe, exe)
+	assert.NoError(t, err)
+	return pkgs[len(pkgs)-1].Version
+}
@@ -0,0 +1,214 @@
+package packages
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/packages/rpmdb"
+	"github.com/sirupsen/logrus"
+)
+
+// dpkgInfoDir holds the file lists of deb packages, relative to the root path.
+const dpkgInfoDir = "var/lib/dpkg/info"
+
+// pacmanLocalDir holds the local database of pacman, relative to the root path.
+const pacmanLocalDir = "var/lib/pacman/local"
+
+// maxSymlinkHops bounds symbolic link resolution, as the kernel does with ELOOP.
+const maxSymlinkHops = 40
+
+// usrMergedDirs are the top level directories moved under /usr by the /usr merge.
+// Depending on the distribution and its age, package databases record either path.
+var usrMergedDirs = []string{"/bin/", "/sbin/", "/lib/", "/lib32/", "/lib64/", "/libx32/"}
+
+// FileOwners is an index of the files installed by the package managers, built once
+// from their databases and used to find which package an executable belongs to.
+type FileOwners struct {
+	root   string
+	owners map[string]string
+}
+
+var (
+	sharedFileOwners     *FileOwners
+	sharedFileOwnersOnce sync.Once
+	// sharedPathPkgCache is the package of the executables looked up by the extractors of every collector.
+	sharedPathPkgCache = newBinPathPackageAssociation()
+)
+
+// SharedFileOwners returns the file ownership index of the running system.
+// It is built on first use and shared by every collector of the run.
+func SharedFileOwners(logger *logrus.Logger) *FileOwners {
+	sharedFileOwnersOnce.Do(func() {
+		sharedFileOwners = BuildFileOwners(logger, "/")
+	})
+	return sharedFileOwners
+}
+
+// BuildFileOwners indexes the files of the dpkg, rpm, pacman and apk databases found under root.
+func BuildFileOwners(logger *logrus.Logger, root string) *FileOwners {
+	start := time.Now()
+	o := &FileOwners{root: root, owners: map[string]string{}}
+
+	o.indexDpkg(root)
+	if infos, err := rpmdb.ListPackages(root); err == nil {
+		for _, info := range infos {
+			o.add(info.Name, info.Files...)
+		}
+	} else {
+		logger.WithError(err).Debug("no rpm database to index")
+	}
+	o.indexPacman(root)
+	if pkgs, err := ReadApkInstalled(root); err == nil {
+		for _, pkg := range pkgs {
+			o.add(pkg.Name, pkg.Files...)
+		}
+	}
+
+	logger.WithFields(logrus.Fields{"elapsed": time.Since(start).Round(time.Millisecond)}).Debugf("%d files indexed by owning package", len(o.owners))
+	return o
+}
+
+// Len returns the number of files indexed.
+func (o *FileOwners) Len() int {
+	if o == nil {
+		return 0
+	}
+	return len(o.owners)
+}
+
+// Owner returns the name of the package owning path, or an empty string when no package does.
+// Symbolic links are followed and both sides of the /usr merge are looked up.
+func (o *FileOwners) Owner(path string) string {
+	if o.Len() == 0 {
+		return ""
+	}
+	// Executables replaced since the process started are reported as deleted by /proc
+	path = filepath.Clean(strings.TrimSuffix(path, " (deleted)"))
+
+	candidates := []string{path}
+	if resolved := o.resolve(path); resolved != path {
+		candidates = append(candidates, resolved)
+	}
+	for _, candidate := range candidates {
+		for _, alias := range append([]string{candidate}, usrMergeAliases(candidate)...) {
+			if owner, ok := o.owners[alias]; ok {
+				return owner
+			}
+		}
+	}
+	return ""
+}
+
+// add records the files of a package, the first package seen keeps shared paths such as directories.
+func (o *FileOwners) add(pkg string, files ...string) {
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		file = filepath.Clean("/" + file)
+		if _, ok := o.owners[file]; !ok {
+			o.owners[file] = pkg
+		}
+	}
+}
+
+// resolve follows the symbolic links of path within root.
+func (o *FileOwners) resolve(path string) string {
+	for range maxSymlinkHops {
+		target, err := os.Readlink(filepath.Join(o.root, path))
+		if err != nil {
+			return path
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = filepath.Clean(target)
+	}
+	return path
+}
+
+// usrMergeAliases returns the other paths a file can be known as on a /usr merged system.
+func usrMergeAliases(path string) []string {
+	aliases := []string{}
+	for _, dir := range usrMergedDirs {
+		if strings.HasPrefix(path, dir) {
+			aliases = append(aliases, "/usr"+path)
+		} else if strings.HasPrefix(path, "/usr"+dir) {
+			aliases = append(aliases, strings.TrimPrefix(path, "/usr"))
+		}
+	}
+	// Arch and recent Fedora releases also merged sbin into bin
+	for _, alias := range append([]string{path}, aliases...) {
+		if strings.HasPrefix(alias, "/usr/sbin/") {
+			aliases = append(aliases, "/usr/bin/"+strings.TrimPrefix(alias, "/usr/sbin/"))
+		}
+	}
+	return aliases
+}
+
+// indexDpkg reads the <package>[:<arch>].list files of dpkg, one path per line.
+func (o *FileOwners) indexDpkg(root string) {
+	lists, _ := filepath.Glob(filepath.Join(root, dpkgInfoDir, "*.list"))
+	sort.Strings(lists)
+	for _, list := range lists {
+		name := strings.TrimSuffix(filepath.Base(list), ".list")
+		name, _, _ = strings.Cut(name, ":")
+		o.add(name, readLines(list)...)
+	}
+}
+
+// indexPacman reads the %FILES% section of the files entry of each local package.
+// Paths are relative to the root and directories end with a slash.
+func (o *FileOwners) indexPacman(root string) {
+	entries, _ := filepath.Glob(filepath.Join(root, pacmanLocalDir, "*", "files"))
+	sort.Strings(entries)
+	for _, entry := range entries {
+		name := pacmanPackageName(filepath.Dir(entry))
+		if name == "" {
+			continue
+		}
+		inFiles := false
+		for _, line := range readLines(entry) {
+			switch {
+			case strings.HasPrefix(line, "%"):
+				inFiles = line == "%FILES%"
+			case inFiles:
+				o.add(name, line)
+			}
+		}
+	}
+}
+
+// pacmanPackageName reads the %NAME% of a local package entry, the directory name also holds the version.
+func pacmanPackageName(entry string) string {
+	lines := readLines(filepath.Join(entry, "desc"))
+	for i, line := range lines {
+		if line == "%NAME%" && i+1 < len(lines) {
+			return lines[i+1]
+		}
+	}
+	return ""
+}
+
+// readLines returns the non empty lines of a file, or nil when it cannot be read.
+func readLines(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,92 @@
+package packages
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileOwners_Dpkg(t *testing.T) {
+	owners := BuildFileOwners(logrus.New(), "testdata/owners")
+
+	assert.Equal(t, "bash", owners.Owner("/bin/bash"))
+	assert.Equal(t, "libc6", owners.Owner("/lib/x86_64-linux-gnu/libc.so.6"))
+	assert.Equal(t, "vim", owners.Owner("/usr/bin/vim.basic (deleted)"))
+	assert.Empty(t, owners.Owner("/usr/bin/unknown"))
+}
+
+func TestFileOwners_UsrMerge(t *testing.T) {
+	owners := BuildFileOwners(logrus.New(), "testdata/owners")
+
+	// Recorded as /bin/bash by dpkg, executed from /usr/bin/bash
+	assert.Equal(t, "bash", owners.Owner("/usr/bin/bash"))
+	assert.Equal(t, "libc6", owners.Owner("/usr/lib/x86_64-linux-gnu/libc.so.6"))
+	assert.Equal(t, "libc6", owners.Owner("/sbin/ldconfig"))
+	// Recorded as /usr/bin/sshd by pacman, where sbin is merged into bin
+	assert.Equal(t, "openssh", owners.Owner("/usr/sbin/sshd"))
+}
+
+func TestFileOwners_Symlinks(t *testing.T) {
+	owners := BuildFileOwners(logrus.New(), "testdata/owners")
+
+	// Absolute links through the alternatives system
+	assert.Equal(t, "vim", owners.Owner("/usr/bin/editor"))
+	// Relative link
+	assert.Equal(t, "python3.11-minimal", owners.Owner("/usr/bin/python3"))
+}
+
+func TestFileOwners_Pacman(t *testing.T) {
+	owners := BuildFileOwners(logrus.New(), "testdata/owners")
+
+	assert.Equal(t, "openssh", owners.Owner("/usr/bin/sshd"))
+	assert.Equal(t, "openssh", owners.Owner("/etc/ssh/sshd_config"))
+}
+
+func TestFileOwners_Rpm(t *testing.T) {
+	owners := BuildFileOwners(logrus.New(), "rpmdb/testdata/sqlite")
+
+	assert.Equal(t, "bash", owners.Owner("/usr/bin/bash"))
+	assert.Equal(t, "bash", owners.Owner("/bin/bash"))
+	assert.Equal(t, "python3-libs", owners.Owner("/usr/lib64/libpython3.9.so.1.0"))
+}
+
+func TestFileOwners_Apk(t *testing.T) {
+	owners := BuildFileOwners(logrus.New(), "testdata/apk")
+
+	assert.Equal(t, "busybox", owners.Owner("/bin/busybox"))
+}
+
+func TestFileOwners_Empty(t *testing.T) {
+	var owners *FileOwners
+	assert.Zero(t, owners.Len())
+	assert.Empty(t, owners.Owner("/bin/bash"))
+}
+
+func TestGetPackage_FromFileOwners(t *testing.T) {
+	execCommand = mockExecCommand
+	defer func() { execCommand = originalExecCommand }()
+
+	p := &PackageExtractor{
+		Bin:  "dpkg",
+		Args: "-S",
+		PostFn: func(in string) string {
+			name, _, _ := strings.Cut(in, ":")
+			return name
+		},
+		logger:       logrus.New(),
+		PathPkgCache: newBinPathPackageAssociation(),
+		Owners:       BuildFileOwners(logrus.New(), "testdata/owners"),
+	}
+	assert.Equal(t, "bash", p.GetPackage("/usr/bin/bash"))
+	// Unknown to the index, the command is not run
+	assert.Equal(t, "unknown", p.GetPackage("/usr/sbin/apache2"))
+
+	// Without index, the command is run
+	p.Owners = BuildFileOwners(logrus.New(), t.TempDir())
+	assert.Equal(t, "apache2", p.GetPackage("/opt/apache2/bin/httpd"))
+
+	p.Bin = ""
+	assert.Equal(t, "unknown", p.GetPackage("/usr/bin/other"))
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/host"
@@ -13,10 +14,30 @@ import (
 
 // binPathPackageAssociation act as a cache.
 // Each time we want too fetch corresponding package from a binary, it's took 200ms.
-// So this cache should speedup the process.
+// So this cache should speedup the process. It is shared by the collectors and safe for concurrent use.
 // Example
 // path(/usr/bin/apache) -> string(Apache)
-type binPathPackageAssociation map[string]string
+type binPathPackageAssociation struct {
+	mu       sync.Mutex
+	packages map[string]string
+}
+
+func newBinPathPackageAssociation() *binPathPackageAssociation {
+	return &binPathPackageAssociation{packages: map[string]string{}}
+}
+
+func (a *binPathPackageAssociation) get(path string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pkg, ok := a.packages[path]
+	return pkg, ok
+}
+
+func (a *binPathPackageAssociation) set(path, pkg string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.packages[path] = pkg
+}
 
 var execCommand = exec.Command
 
@@ -26,14 +47,16 @@ type PackageExtractor struct {
 	PostFn func(string) string
 	logger *logrus.Logger
 	// Cache layer, association between binary path and installed package.
-	PathPkgCache binPathPackageAssociation
+	PathPkgCache *binPathPackageAssociation
+	// Owners is looked up first, the package manager command is only run when no index could be built.
+	Owners *FileOwners
 }
 
 func NewPackageExtractor(logger *logrus.Logger) (*PackageExtractor, error) {
 	hostInfo, _ := host.Info()
-	pkgExtract := &PackageExtractor{logger: logger, PathPkgCache: make(binPathPackageAssociation)}
+	pkgExtract := &PackageExtractor{logger: logger, PathPkgCache: sharedPathPkgCache, Owners: SharedFileOwners(logger)}
 	switch os := hostInfo.Platform; os {
-	case "ubuntu":
+	case "ubuntu", "debian", "linuxmint":
 		{
 			pkgExtract.Bin = "dpkg"
 			pkgExtract.Args = "-S"
@@ -46,30 +69,7 @@ func NewPackageExtractor(logger *logrus.Logger) (*PackageExtractor, error) {
 			}
 
 		}
-	case "debian":
-	case "linuxmint":
-		{
-			pkgExtract.Bin = "dpkg"
-			pkgExtract.Args = "-S"
-			pkgExtract.PostFn = func(in string) string {
-				arr := strings.Split(string(in), ":")
-				if len(arr) == 2 {
-					return arr[0]
-				}
-				return ""
-			}
-
-		}
-	case "fedora":
-	case "rocky":
-		{
-			pkgExtract.Bin = "rpm"
-			pkgExtract.Args = "-qf"
-			pkgExtract.PostFn = func(str string) string {
-				return str
-			}
-		}
-	case "centos":
+	case "fedora", "rocky", "centos":
 		{
 			pkgExtract.Bin = "rpm"
 			pkgExtract.Args = "-qf"
@@ -85,8 +85,17 @@ func NewPackageExtractor(logger *logrus.Logger) (*PackageExtractor, error) {
 				return str
 			}
 		}
+	case "alpine":
+		{
+			pkgExtract.Bin = "apk"
+			pkgExtract.Args = "info --who-owns"
+			pkgExtract.PostFn = apkOwnerFromWhoOwns
+		}
 	default:
-		return nil, fmt.Errorf("no package extractor for %s system", os)
+		// The index alone is enough when the system has a package database it can read
+		if pkgExtract.Owners.Len() == 0 {
+			return nil, fmt.Errorf("no package extractor for %s system", os)
+		}
 	}
 	return pkgExtract, nil
 }
@@ -95,13 +104,24 @@ func (p *PackageExtractor) GetPackage(exe string) string {
 	start := time.Now()
 	var pkgName string
 	// Return cached value
-	if pkgName, inCache := p.PathPkgCache[exe]; inCache {
+	if pkgName, inCache := p.PathPkgCache.get(exe); inCache {
 		p.logger.WithFields(logrus.Fields{"package": pkgName}).Debugf("cached value returned")
 		return pkgName
 	}
+	if owner := p.Owners.Owner(exe); owner != "" {
+		p.PathPkgCache.set(exe, owner)
+		return owner
+	}
+	// The index knows every packaged file, a file it misses, e.g. in a container or under /opt, is not packaged
+	if p.Bin == "" || p.Owners.Len() > 0 {
+		p.PathPkgCache.set(exe, "unknown")
+		return "unknown"
+	}
 
 	logPath := strings.Join([]string{p.Bin, p.Args, exe}, " ")
-	cmd := execCommand(p.Bin, p.Args, exe)
+	// Args can hold a sub-command and its flags, e.g. "info --who-owns"
+	args := append(strings.Fields(p.Args), exe)
+	cmd := execCommand(p.Bin, args...)
 	cmd.Env = os.Environ()
 	cmd.Env = append(cmd.Env, "LANG=C")
 	var rawOutput []byte
@@ -116,7 +136,7 @@ func (p *PackageExtractor) GetPackage(exe string) string {
 		}
 	}
 
-	p.PathPkgCache[exe] = pkgName
+	p.PathPkgCache.set(exe, pkgName)
 
 	elapsed := time.Since(start).Round(time.Microsecond)
 	p.logger.WithFields(logrus.Fields{"elapsed": elapsed, "cmd": logPath}).Debugf("fetching package for exe %s", exe)
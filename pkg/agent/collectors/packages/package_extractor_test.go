@@ -47,6 +47,14 @@ func mockExecCommand(command string, args ...string) *exec.Cmd {
 				body = `printf 'coolpackage\n'`
 			}
 		}
+	case "apk":
+		if len(args) >= 2 && args[0] == "info" && args[1] == "--who-owns" {
+			if strings.Contains(exe, "non/existent") {
+				body = `echo "ERROR: ` + exe + `: Could not find owner package" 1>&2; exit 1`
+			} else {
+				body = `printf '` + exe + ` is owned by busybox-binsh-1.36.1-r15\n'`
+			}
+		}
 	}
 
 	return originalExecCommand("sh", "-c", body)
@@ -68,7 +76,7 @@ func TestGetPackage_Dpkg_BasedOnString_NoFilesystem(t *testing.T) {
 			return ""
 		},
 		logger:       logger,
-		PathPkgCache: make(binPathPackageAssociation),
+		PathPkgCache: newBinPathPackageAssociation(),
 	}
 
 	pkg := p.GetPackage("/usr/bin/apache")
@@ -97,7 +105,7 @@ func TestGetPackage_Rpm_BasedOnString_NoFilesystem(t *testing.T) {
 		Args:         "-qf",
 		PostFn:       func(s string) string { return strings.TrimSpace(s) },
 		logger:       logger,
-		PathPkgCache: make(binPathPackageAssociation),
+		PathPkgCache: newBinPathPackageAssociation(),
 	}
 
 	pkg := p.GetPackage("/usr/sbin/httpd")
@@ -116,7 +124,7 @@ func TestGetPackage_Pacman_BasedOnString_NoFilesystem(t *testing.T) {
 		Args:         "-Qoq",
 		PostFn:       func(s string) string { return strings.TrimSpace(s) },
 		logger:       logger,
-		PathPkgCache: make(binPathPackageAssociation),
+		PathPkgCache: newBinPathPackageAssociation(),
 	}
 
 	pkg := p.GetPackage("/usr/bin/coolbinary")
@@ -125,6 +133,30 @@ func TestGetPackage_Pacman_BasedOnString_NoFilesystem(t *testing.T) {
 	}
 }
 
+func TestGetPackage_Apk_BasedOnString_NoFilesystem(t *testing.T) {
+	execCommand = mockExecCommand
+	defer func() { execCommand = originalExecCommand }()
+
+	logger := logrus.New()
+	p := &PackageExtractor{
+		Bin:          "apk",
+		Args:         "info --who-owns",
+		PostFn:       apkOwnerFromWhoOwns,
+		logger:       logger,
+		PathPkgCache: newBinPathPackageAssociation(),
+	}
+
+	pkg := p.GetPackage("/bin/sh")
+	if pkg != "busybox-binsh" {
+		t.Fatalf("expected apk package 'busybox-binsh', got %q", pkg)
+	}
+
+	pkgErr := p.GetPackage("/non/existent")
+	if pkgErr != "unknown" {
+		t.Fatalf("expected 'unknown' for /non/existent, got %q", pkgErr)
+	}
+}
+
 func TestGetPackage_CommandError_ReturnsUnknown_BasedOnString(t *testing.T) {
 	execCommand = mockExecCommand
 	defer func() { execCommand = originalExecCommand }()
@@ -141,7 +173,7 @@ func TestGetPackage_CommandError_ReturnsUnknown_BasedOnString(t *testing.T) {
 			return ""
 		},
 		logger:       logger,
-		PathPkgCache: make(binPathPackageAssociation),
+		PathPkgCache: newBinPathPackageAssociation(),
 	}
 
 	pkg := p.GetPackage("/non/existent")
@@ -172,7 +204,7 @@ func TestCacheSpeedQuick(t *testing.T) {
 			return ""
 		},
 		logger:       logger,
-		PathPkgCache: make(binPathPackageAssociation),
+		PathPkgCache: newBinPathPackageAssociation(),
 	}
 
 	start := time.Now()
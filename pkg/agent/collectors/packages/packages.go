@@ -5,38 +5,87 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/packages/rpmdb"
 	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/utils"
+	"github.com/klamhq/facter-oss/pkg/versions"
 	"github.com/sirupsen/logrus"
 )
 
-// NewPackagesGrabber return correct PackageGrabber instance for current system
+// packageSource associates a package extract with the ecosystem and the manager it reads.
+type packageSource struct {
+	ecosystem string
+	manager   string
+	extract   func(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error)
+}
+
+// registeredSources lists every package extract, when adding new package extract, mind to update this array.
+// A source is skipped when a previous one already returned packages for the same ecosystem.
+var registeredSources = []packageSource{
+	{ecosystem: models.EcosystemDeb, manager: "dpkg", extract: NewPackageDebConfig},
+	// apt is only a fallback when the dpkg database cannot be read
+	{ecosystem: models.EcosystemDeb, manager: "apt", extract: NewPackageAptConfig},
+	{ecosystem: models.EcosystemRpm, manager: "rpm", extract: NewPackageRpmConfig},
+	{ecosystem: models.EcosystemApk, manager: "apk", extract: NewPackageApkConfig},
+	{ecosystem: models.EcosystemAlpm, manager: "pacman", extract: NewPackagePacConfig},
+	{ecosystem: models.EcosystemHomebrew, manager: "homebrew", extract: NewPackageHomebrewConfig},
+	{ecosystem: models.EcosystemSnap, manager: "snapd", extract: NewPackageSnapConfig},
+	{ecosystem: models.EcosystemFlatpak, manager: "flatpak", extract: NewPackageFlatpakConfig},
+}
+
+// NewPackagesGrabber return packages of every package manager usable on current system.
+// Each package is tagged with the ecosystem and the manager it comes from.
 func NewPackagesGrabber(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error) {
-	// When adding new package extract, mind to update this array.
-	registeredExtract := []func(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error){
-		NewPackageRpmConfig,
-		NewPackageDebConfig,
-		NewPackagePacConfig,
-		NewPackageHomebrewConfig,
-		NewPackageAptConfig,
-	}
-	for _, fn := range registeredExtract {
+	return grabPackages(ctx, logger, registeredSources)
+}
+
+func grabPackages(ctx context.Context, logger *logrus.Logger, sources []packageSource) ([]*models.Package, error) {
+	result := []*models.Package{}
+	collected := map[string]bool{}
+	seen := map[string]bool{}
 
-		foundPkgs, err := fn(ctx, logger)
+	for _, source := range sources {
+		if collected[source.ecosystem] {
+			continue
+		}
+		foundPkgs, err := source.extract(ctx, logger)
 		if err != nil {
-			logger.Error("Error executing command: ", err)
-			logger.WithError(err).Debug("unable to read packages list")
+			logger.WithError(err).WithField("manager", source.manager).Debug("unable to read packages list")
 			continue
 		}
-		return foundPkgs, nil
+		if len(foundPkgs) == 0 {
+			continue
+		}
+		collected[source.ecosystem] = true
+		logger.WithField("manager", source.manager).Debugf("%d packages found", len(foundPkgs))
+
+		for _, pkg := range foundPkgs {
+			if pkg.Ecosystem == "" {
+				pkg.Ecosystem = source.ecosystem
+			}
+			if pkg.Manager == "" {
+				pkg.Manager = source.manager
+			}
+			key := pkg.Key()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, pkg)
+		}
 	}
 
-	return nil, fmt.Errorf("unable to find a package extract usable on this system")
+	if len(collected) == 0 {
+		return nil, fmt.Errorf("unable to find a package extract usable on this system")
+	}
+	return result, nil
 }
 
 type PackageExtractorInterface interface {
@@ -150,9 +199,11 @@ func (e *PackageParserConfig) IsApplicable() bool {
 type PackageParser interface {
 }
 
-func addUpgradablePackage(pkgs []*models.Package, upgradableMap map[string]string) []*models.Package {
+// addUpgradablePackage sets the upgradable version of packages, a candidate which is not newer
+// than the installed version, e.g. from a stale repository cache, leaves the package up to date.
+func addUpgradablePackage(pkgs []*models.Package, upgradableMap map[string]string, ecosystem string) []*models.Package {
 	for i, pkg := range pkgs {
-		if upgradeVer, ok := upgradableMap[pkg.Name]; ok {
+		if upgradeVer, ok := upgradableMap[pkg.Name]; ok && versions.LessThan(ecosystem, pkg.Version, upgradeVer) {
 			pkgs[i].UpgradableVersion = upgradeVer
 			pkgs[i].IsUpToDate = false
 
@@ -163,6 +214,25 @@ func addUpgradablePackage(pkgs []*models.Package, upgradableMap map[string]strin
 	return pkgs
 }
 
+// UpgradeClass tells whether an available upgrade is a security update and which advisories it fixes.
+type UpgradeClass struct {
+	Security   bool
+	Advisories []models.Advisory
+}
+
+// classifyUpgrades records the classification of the upgrade of packages which are not up to date.
+func classifyUpgrades(pkgs []*models.Package, classes map[string]*UpgradeClass) []*models.Package {
+	for _, pkg := range pkgs {
+		class, ok := classes[pkg.Name]
+		if !ok || pkg.IsUpToDate {
+			continue
+		}
+		pkg.SecurityUpdate = class.Security
+		pkg.Advisories = class.Advisories
+	}
+	return pkgs
+}
+
 // NewPackageHomebrewConfig provide a configuration for homebrew based package system
 func NewPackageHomebrewConfig(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error) {
 	config := newHomebrewConfig()
@@ -178,7 +248,7 @@ func NewPackageHomebrewConfig(ctx context.Context, logger *logrus.Logger) ([]*mo
 		if err != nil {
 			return nil, err
 		}
-		pkgs = addUpgradablePackage(pkgs, upgradableMap)
+		pkgs = addUpgradablePackage(pkgs, upgradableMap, models.EcosystemHomebrew)
 
 		return pkgs, nil
 	}
@@ -201,7 +271,7 @@ func NewPackagePacConfig(ctx context.Context, logger *logrus.Logger) ([]*models.
 	if err != nil {
 		return nil, err
 	}
-	pkgs = addUpgradablePackage(pkgs, upgradableMap)
+	pkgs = addUpgradablePackage(pkgs, upgradableMap, models.EcosystemAlpm)
 	return pkgs, nil
 }
 
@@ -228,71 +298,116 @@ func newPacConfig() *PackageParserConfig {
 	return config
 }
 
-// NewPackageDebConfig provide a configuration for deb based package system
+// NewPackageDebConfig provide packages for deb based package system, read from the dpkg database.
 func NewPackageDebConfig(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error) {
-	config := newDebConfig()
-	parser := &PackageExtractRegexpBased{
-		config: config,
-	}
-
-	pkgs, err := parser.Extract(ctx, logger)
+	pkgs, err := ReadDpkgStatus("/")
 	if err != nil {
 		return nil, err
 	}
+	logger.Debugf("%d packages read from dpkg database", len(pkgs))
 
 	// Ajout des versions upgradables
-	upgradableMap, err := GetAptUpgradableMap(ctx)
+	upgradableMap, classes, err := GetAptUpgrades(ctx)
 	if err != nil {
-		return nil, err
+		logger.WithError(err).Warn("unable to list upgradable deb packages")
+		return pkgs, nil
 	}
-	pkgs = addUpgradablePackage(pkgs, upgradableMap)
+	pkgs = addUpgradablePackage(pkgs, upgradableMap, models.EcosystemDeb)
+	pkgs = classifyUpgrades(pkgs, classes)
 
 	return pkgs, nil
 }
 
-// NewDebConfig return dpkg package parser config
-func newDebConfig() *PackageParserConfig {
-	config := &PackageParserConfig{
-		//		ParseRegExp:            regexp.MustCompile(`ii\s*(?P<name>[\w-.]*)(?::amd64)?\s*(?P<version>[\w.\d-:+~]*)\s*(?P<architecture>[\w]*)\s*(?P<description>.*)$`),
-		ParseRegExp:                  regexp.MustCompile(`ii\s*(?P<name>[\w-.]*)(?::amd64)?\s*(?P<version>[\w.\d-:+~]*)\s*(?P<architecture>[\w]*)\s*(?P<description>.*)$`),
-		PackageIgnoreCondition:       nil,
-		PackageManagerBin:            "dpkg",
-		PackageManagerArgs:           "-l",
-		PackageManagerAdditionalArgs: []string{"--no-pager"},
+// NewPackageRpmConfig provide packages for rpm based package system, read from the rpm database.
+func NewPackageRpmConfig(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error) {
+	infos, err := rpmdb.ListPackages("/")
+	if err != nil {
+		return nil, err
 	}
-	return config
+	pkgs := make([]*models.Package, 0, len(infos))
+	for _, info := range infos {
+		pkgs = append(pkgs, rpmPackageToModel(info))
+	}
+	logger.Debugf("%d packages read from rpm database", len(pkgs))
+
+	// dnf or yum may be missing (e.g. minimal containers), packages are still reported.
+	upgradableMap, err := GetRpmUpgradableMap(ctx)
+	if err != nil {
+		logger.WithError(err).Warn("unable to list upgradable rpm packages")
+		return pkgs, nil
+	}
+	pkgs = addUpgradablePackage(pkgs, upgradableMap, models.EcosystemRpm)
+
+	classes, err := GetRpmUpgradeClasses(ctx, "/", pkgs, upgradableMap)
+	if err != nil {
+		logger.WithError(err).Warn("unable to classify upgradable rpm packages")
+		return pkgs, nil
+	}
+	pkgs = classifyUpgrades(pkgs, classes)
+
+	return pkgs, nil
 }
 
-// NewPackageRpmConfig provide a configuration for rpm based package system
-func NewPackageRpmConfig(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error) {
-	config := newRpmConfig()
-	parser := &PackageExtractRegexpBased{
-		config: config,
+// rpmPackageToModel converts rpm database metadata to a package.
+func rpmPackageToModel(info *rpmdb.PackageInfo) *models.Package {
+	pkg := &models.Package{
+		Name:           info.Name,
+		Version:        info.EVR(),
+		Architecture:   info.Arch,
+		Description:    info.Summary,
+		SourcePackage:  info.SourceName(),
+		InstalledSize:  info.Size,
+		Release:        info.Release,
+		Vendor:         info.Vendor,
+		License:        info.License,
+		InstallTime:    info.InstallTime,
+		SignatureKeyID: info.SigKeyID,
 	}
+	if info.Epoch >= 0 {
+		pkg.Epoch = strconv.FormatInt(info.Epoch, 10)
+	}
+	return pkg
+}
 
-	pkgs, err := parser.Extract(ctx, logger)
+// NewPackageApkConfig provide packages for apk based package system, read from the apk database.
+func NewPackageApkConfig(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error) {
+	pkgs, err := ReadApkInstalled("/")
 	if err != nil {
 		return nil, err
 	}
+	logger.Debugf("%d packages read from apk database", len(pkgs))
 
-	upgradableMap, err := GetRpmUpgradableMap(ctx)
+	// The APKINDEX cache is absent until `apk update` has been run, packages are still reported.
+	upgradableMap, err := GetApkUpgradableMap("/", pkgs)
 	if err != nil {
-		return nil, err
+		logger.WithError(err).Warn("unable to list upgradable apk packages")
+		return pkgs, nil
 	}
-	pkgs = addUpgradablePackage(pkgs, upgradableMap)
+	pkgs = addUpgradablePackage(pkgs, upgradableMap, models.EcosystemApk)
 
 	return pkgs, nil
 }
 
-// newRpmConfig return rpm package parser config
-func newRpmConfig() *PackageParserConfig {
-	config := &PackageParserConfig{
-		ParseRegExp:            regexp.MustCompile(`^(?P<name>.*)-(?P<version>\d{1,3}(?:\.|-)\d{1,3}(?:-\S{1,3})?\.\S{2,})\.(?P<architecture>\S+)$`),
-		PackageIgnoreCondition: nil,
-		PackageManagerBin:      "rpm",
-		PackageManagerArgs:     "-qa",
+// NewPackageSnapConfig provide snaps, read from the snapd state.
+func NewPackageSnapConfig(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error) {
+	pkgs, err := ReadSnaps("/")
+	if err != nil {
+		return nil, err
 	}
-	return config
+	logger.Debugf("%d snaps read from snapd state", len(pkgs))
+	return pkgs, nil
+}
+
+// NewPackageFlatpakConfig provide flatpak applications and runtimes of the system and users installations.
+func NewPackageFlatpakConfig(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error) {
+	homes, _ := filepath.Glob("/home/*")
+	homes = append(homes, "/root")
+	pkgs, err := ReadFlatpaks("/", homes)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("%d flatpaks read from flatpak installations", len(pkgs))
+	return pkgs, nil
 }
 
 // NewPackageAptConfig provide a configuration for apt based package system
@@ -308,11 +423,12 @@ func NewPackageAptConfig(ctx context.Context, logger *logrus.Logger) ([]*models.
 	}
 
 	// Ajout des versions upgradables
-	upgradableMap, err := GetAptUpgradableMap(ctx)
+	upgradableMap, classes, err := GetAptUpgrades(ctx)
 	if err != nil {
 		return nil, err
 	}
-	pkgs = addUpgradablePackage(pkgs, upgradableMap)
+	pkgs = addUpgradablePackage(pkgs, upgradableMap, models.EcosystemDeb)
+	pkgs = classifyUpgrades(pkgs, classes)
 
 	return pkgs, nil
 }
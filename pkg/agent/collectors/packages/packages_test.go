@@ -2,9 +2,12 @@ package packages
 
 import (
 	"context"
+	"fmt"
 	"runtime"
 	"testing"
 
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/packages/rpmdb"
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -25,30 +28,53 @@ func TestNewPackagesGrabber(t *testing.T) {
 	assert.NotEmpty(t, grabber)
 }
 
-func TestNewPackageDebConfig(t *testing.T) {
-	fixture := `ii  tzdata:amd64                  2018g-0+deb9u1        all          time zone and daylight-saving time data -libs`
-	config := newDebConfig()
-	parser := &PackageExtractRegexpBased{
-		config: config,
+func TestGrabPackages_MergesEveryManager(t *testing.T) {
+	logger := logrus.New()
+	static := func(pkgs ...*models.Package) func(context.Context, *logrus.Logger) ([]*models.Package, error) {
+		return func(context.Context, *logrus.Logger) ([]*models.Package, error) { return pkgs, nil }
 	}
-	pkg := *parser.parseSingleLine(fixture)
-	assert.Equal(t, "tzdata", pkg.Name)
-	assert.Equal(t, "2018g-0+deb9u1", pkg.Version)
-	assert.Equal(t, "all", pkg.Architecture)
-	assert.Equal(t, "time zone and daylight-saving time data -libs", pkg.Description)
+	failing := func(context.Context, *logrus.Logger) ([]*models.Package, error) {
+		return nil, fmt.Errorf("not present")
+	}
+	sources := []packageSource{
+		{ecosystem: models.EcosystemDeb, manager: "dpkg", extract: static(
+			&models.Package{Name: "curl", Version: "7.88.1-10", Architecture: "amd64"},
+			&models.Package{Name: "libc6", Version: "2.36-9", Architecture: "amd64"},
+			&models.Package{Name: "libc6", Version: "2.36-9", Architecture: "i386"},
+		)},
+		// Must be skipped, deb packages are already collected
+		{ecosystem: models.EcosystemDeb, manager: "apt", extract: static(&models.Package{Name: "curl", Version: "7.88.1-10"})},
+		{ecosystem: models.EcosystemRpm, manager: "rpm", extract: failing},
+		{ecosystem: models.EcosystemHomebrew, manager: "homebrew", extract: static(&models.Package{Name: "curl", Version: "7.88.1-10"})},
+	}
+
+	pkgs, err := grabPackages(context.Background(), logger, sources)
+	assert.NoError(t, err)
+	assert.Len(t, pkgs, 4)
+
+	keys := []string{}
+	for _, pkg := range pkgs {
+		keys = append(keys, pkg.Key())
+	}
+	assert.Equal(t, []string{
+		"deb/curl@7.88.1-10:amd64",
+		"deb/libc6@2.36-9:amd64",
+		"deb/libc6@2.36-9:i386",
+		"brew/curl@7.88.1-10",
+	}, keys)
+	assert.Equal(t, "dpkg", pkgs[0].Manager)
+	assert.Equal(t, "homebrew", pkgs[3].Manager)
 }
 
-func TestNewAltPackageDebConfig(t *testing.T) {
-	fixture := `rpm-plugin-selinux-4.14.2.1-2.fc29.x86_64`
-	config := newRpmConfig()
-	parser := &PackageExtractRegexpBased{
-		config: config,
+func TestGrabPackages_NoUsableManager(t *testing.T) {
+	sources := []packageSource{
+		{ecosystem: models.EcosystemRpm, manager: "rpm", extract: func(context.Context, *logrus.Logger) ([]*models.Package, error) {
+			return nil, fmt.Errorf("not present")
+		}},
 	}
-	pkg := *parser.parseSingleLine(fixture)
-	assert.Equal(t, "rpm-plugin-selinux", pkg.Name)
-	assert.Equal(t, "4.14.2.1-2.fc29", pkg.Version)
-	assert.Equal(t, "x86_64", pkg.Architecture)
-	assert.Equal(t, "", pkg.Description)
+	pkgs, err := grabPackages(context.Background(), logrus.New(), sources)
+	assert.Error(t, err)
+	assert.Nil(t, pkgs)
 }
 
 func TestNewHomebrewPackageConfig(t *testing.T) {
@@ -76,3 +102,48 @@ func TestNewArchPackageConfig(t *testing.T) {
 	assert.Equal(t, "", pkg.Architecture)
 	assert.Equal(t, "", pkg.Description)
 }
+
+func TestRpmPackageToModel(t *testing.T) {
+	infos, err := rpmdb.ListPackages("rpmdb/testdata/sqlite")
+	assert.NoError(t, err)
+
+	pkgs := map[string]*models.Package{}
+	for _, info := range infos {
+		pkg := rpmPackageToModel(info)
+		pkgs[pkg.Name] = pkg
+	}
+	grub := pkgs["grub2-common"]
+	assert.Equal(t, "1:2.02-0.86.el7", grub.Version)
+	assert.Equal(t, "1", grub.Epoch)
+	assert.Equal(t, "0.86.el7", grub.Release)
+	assert.Equal(t, "noarch", grub.Architecture)
+	assert.Equal(t, "grub2", grub.SourcePackage)
+	assert.Equal(t, "24c6a8a7f4a80eb5", grub.SignatureKeyID)
+
+	bash := pkgs["bash"]
+	assert.Equal(t, "5.1.8-6.el9", bash.Version)
+	assert.Empty(t, bash.Epoch)
+	assert.Equal(t, "Rocky Enterprise Software Foundation", bash.Vendor)
+	assert.Equal(t, int64(1700000000), bash.InstallTime)
+}
+
+func TestAddUpgradablePackage(t *testing.T) {
+	pkgs := []*models.Package{
+		{Name: "curl", Version: "7.88.1-10+deb12u4"},
+		{Name: "bash", Version: "5.2.15-2+b7"},
+		{Name: "vim", Version: "2:9.0.1378-2"},
+	}
+	upgradable := map[string]string{
+		"curl": "7.88.1-10+deb12u5",
+		// Older than installed, the candidate comes from a stale cache
+		"bash": "5.2.15-2",
+		"vim":  "2:9.1.0016-1",
+	}
+	pkgs = addUpgradablePackage(pkgs, upgradable, models.EcosystemDeb)
+	assert.False(t, pkgs[0].IsUpToDate)
+	assert.Equal(t, "7.88.1-10+deb12u5", pkgs[0].UpgradableVersion)
+	assert.True(t, pkgs[1].IsUpToDate)
+	assert.Empty(t, pkgs[1].UpgradableVersion)
+	assert.False(t, pkgs[2].IsUpToDate)
+	assert.Equal(t, "2:9.1.0016-1", pkgs[2].UpgradableVersion)
+}
@@ -0,0 +1,83 @@
+package packages
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/packages/rpmdb"
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+)
+
+// rootBinDirs are searched for go binaries in a filesystem tree, where PATH is unknown.
+var rootBinDirs = []string{"/usr/local/bin", "/usr/local/sbin", "/usr/bin", "/usr/sbin", "/bin", "/sbin"}
+
+// ReadRootPackages returns the packages installed in a filesystem tree such as an unpacked container image,
+// read from the package manager databases and, when enabled, from the language package metadata.
+// Nothing is run from the tree, so the upgradable versions are unknown. Locations are relative to the tree.
+// A tree without any package database returns an empty list.
+func ReadRootPackages(ctx context.Context, logger *logrus.Logger, root string, languages *options.LanguagePackagesOptions) ([]*models.Package, error) {
+	sources := []packageSource{
+		{ecosystem: models.EcosystemDeb, manager: "dpkg", extract: func(context.Context, *logrus.Logger) ([]*models.Package, error) {
+			return ReadDpkgStatus(root)
+		}},
+		{ecosystem: models.EcosystemRpm, manager: "rpm", extract: func(context.Context, *logrus.Logger) ([]*models.Package, error) {
+			infos, err := rpmdb.ListPackages(root)
+			if err != nil {
+				return nil, err
+			}
+			pkgs := make([]*models.Package, 0, len(infos))
+			for _, info := range infos {
+				pkgs = append(pkgs, rpmPackageToModel(info))
+			}
+			return pkgs, nil
+		}},
+		{ecosystem: models.EcosystemApk, manager: "apk", extract: func(context.Context, *logrus.Logger) ([]*models.Package, error) {
+			return ReadApkInstalled(root)
+		}},
+	}
+	if languages != nil && languages.Enabled {
+		sources = append(sources, packageSource{ecosystem: "language", extract: func(ctx context.Context, logger *logrus.Logger) ([]*models.Package, error) {
+			return readRootLanguagePackages(ctx, logger, root, languages)
+		}})
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pkgs, err := grabPackages(ctx, logger, sources)
+	if err != nil {
+		// Images built from scratch have no package at all
+		logger.WithError(err).WithField("root", root).Debug("no package found")
+		return []*models.Package{}, nil
+	}
+	for _, pkg := range pkgs {
+		if pkg.Location != "" {
+			pkg.Location = "/" + strings.TrimPrefix(strings.TrimPrefix(pkg.Location, filepath.Clean(root)), "/")
+		}
+	}
+	return pkgs, nil
+}
+
+// readRootLanguagePackages walks the language roots and the binary directories of a filesystem tree.
+func readRootLanguagePackages(ctx context.Context, logger *logrus.Logger, root string, cfg *options.LanguagePackagesOptions) ([]*models.Package, error) {
+	s := newLanguageScanner(logger, cfg)
+	roots := cfg.Roots
+	if len(roots) == 0 {
+		roots = defaultLanguageRoots
+	}
+	for _, dir := range roots {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		s.scanRoot(ctx, filepath.Join(root, dir))
+	}
+	if s.enabled(models.EcosystemGolang) {
+		for _, dir := range rootBinDirs {
+			s.scanGoBinariesDir(filepath.Join(root, dir))
+		}
+	}
+	return s.pkgs, nil
+}
@@ -0,0 +1,45 @@
+package packages
+
+import (
+	"context"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRootPackages(t *testing.T) {
+	ctx := context.Background()
+
+	pkgs, err := ReadRootPackages(ctx, logrus.New(), "testdata/dpkg", nil)
+	assert.NoError(t, err)
+	byKey := languagePackagesByKey(pkgs)
+	tzdata := byKey["deb/tzdata@2024a-0+deb12u1:all"]
+	if assert.NotNil(t, tzdata) {
+		assert.Equal(t, models.EcosystemDeb, tzdata.Ecosystem)
+		assert.Equal(t, "dpkg", tzdata.Manager)
+	}
+
+	pkgs, err = ReadRootPackages(ctx, logrus.New(), "testdata/apk", nil)
+	assert.NoError(t, err)
+	assert.Contains(t, languagePackagesByKey(pkgs), "apk/musl@1.2.4_git20230717-r4:x86_64")
+
+	// Language packages are found below the default roots of the tree, their locations are inside the tree
+	cfg := &options.LanguagePackagesOptions{Enabled: true, MaxDepth: 8}
+	pkgs, err = ReadRootPackages(ctx, logrus.New(), "testdata/lang/", cfg)
+	assert.NoError(t, err)
+	byKey = languagePackagesByKey(pkgs)
+	requests := byKey["pypi/requests@2.31.0"]
+	if assert.NotNil(t, requests) {
+		assert.Equal(t, "pip", requests.Manager)
+		assert.Equal(t, "/usr/lib/python3/dist-packages/requests-2.31.0.dist-info", requests.Location)
+	}
+	assert.Contains(t, byKey, "npm/lodash@4.17.21")
+
+	// A tree without package is not an error
+	pkgs, err = ReadRootPackages(ctx, logrus.New(), t.TempDir(), cfg)
+	assert.NoError(t, err)
+	assert.Empty(t, pkgs)
+}
@@ -0,0 +1,238 @@
+package packages
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/utils"
+	"github.com/klamhq/facter-oss/pkg/versions"
+)
+
+// updateinfoCacheGlobs are the locations of the cached updateinfo repository metadata, relative to the root path.
+// They are refreshed by dnf and yum, facter never downloads them. Zstandard and xz compressed files are not supported.
+var updateinfoCacheGlobs = []string{
+	"var/cache/dnf/*/repodata/*updateinfo.xml*",
+	"var/cache/libdnf5/*/repodata/*updateinfo.xml*",
+	"var/cache/yum/*/*/*/*updateinfo.xml*",
+	"var/cache/yum/*/*/*/gen/updateinfo.xml",
+}
+
+// updateinfoAdvisoryTypes are the advisory types used by dnf and yum.
+var updateinfoAdvisoryTypes = map[string]bool{
+	"security":    true,
+	"bugfix":      true,
+	"enhancement": true,
+	"newpackage":  true,
+}
+
+// updateinfo is the updateinfo.xml repository metadata, listing the advisories and the packages fixing them.
+type updateinfo struct {
+	Updates []updateinfoUpdate `xml:"update"`
+}
+
+type updateinfoUpdate struct {
+	Type     string              `xml:"type,attr"`
+	ID       string              `xml:"id"`
+	Severity string              `xml:"severity"`
+	Packages []updateinfoPackage `xml:"pkglist>collection>package"`
+}
+
+type updateinfoPackage struct {
+	Name    string `xml:"name,attr"`
+	Epoch   string `xml:"epoch,attr"`
+	Version string `xml:"version,attr"`
+	Release string `xml:"release,attr"`
+}
+
+// evr returns the full version of the package, as reported for installed packages.
+func (p updateinfoPackage) evr() string {
+	evr := p.Version + "-" + p.Release
+	if p.Epoch != "" && p.Epoch != "0" {
+		evr = p.Epoch + ":" + evr
+	}
+	return evr
+}
+
+// GetRpmUpgradeClasses classifies the upgrades of installed packages with the advisories they fix.
+// The cached updateinfo metadata is used when present, `dnf updateinfo` or `yum updateinfo` otherwise.
+func GetRpmUpgradeClasses(ctx context.Context, root string, installed []*models.Package, upgradable map[string]string) (map[string]*UpgradeClass, error) {
+	updates, err := readUpdateinfoCache(root)
+	if err == nil {
+		return matchUpdateinfo(updates, installed, upgradable), nil
+	}
+
+	out, err := utils.RunCmd(ctx, "dnf", "updateinfo", "list", "--updates")
+	if err != nil {
+		out, err = utils.RunCmd(ctx, "yum", "updateinfo", "list", "updates")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parseUpdateinfoList(out), nil
+}
+
+// readUpdateinfoCache reads the advisories of every cached repository updateinfo.
+func readUpdateinfoCache(root string) ([]updateinfoUpdate, error) {
+	files := []string{}
+	for _, pattern := range updateinfoCacheGlobs {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	updates := []updateinfoUpdate{}
+	read := 0
+	for _, file := range files {
+		info, err := readUpdateinfoFile(file)
+		if err != nil {
+			continue
+		}
+		read++
+		updates = append(updates, info.Updates...)
+	}
+	if read == 0 {
+		return nil, fmt.Errorf("no readable updateinfo found in dnf or yum cache")
+	}
+	return updates, nil
+}
+
+func readUpdateinfoFile(file string) (*updateinfo, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch filepath.Ext(file) {
+	case ".xml":
+	case ".gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", file, err)
+		}
+		defer gz.Close()
+		r = gz
+	case ".bz2":
+		r = bzip2.NewReader(f)
+	default:
+		return nil, fmt.Errorf("unsupported compression for %s", file)
+	}
+
+	var info updateinfo
+	if err := xml.NewDecoder(r).Decode(&info); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", file, err)
+	}
+	return &info, nil
+}
+
+// matchUpdateinfo returns the advisories fixed by the upgrade of each package: the advisory lists
+// a version of the package newer than the installed one, and not newer than the upgradable one.
+func matchUpdateinfo(updates []updateinfoUpdate, installed []*models.Package, upgradable map[string]string) map[string]*UpgradeClass {
+	installedVersions := map[string]string{}
+	for _, pkg := range installed {
+		installedVersions[pkg.Name] = pkg.Version
+	}
+
+	classes := map[string]*UpgradeClass{}
+	for _, update := range updates {
+		for _, pkg := range update.Packages {
+			candidate, ok := upgradable[pkg.Name]
+			current, isInstalled := installedVersions[pkg.Name]
+			if !ok || !isInstalled {
+				continue
+			}
+			evr := pkg.evr()
+			if versions.CompareRpm(evr, current) <= 0 || versions.CompareRpm(evr, candidate) > 0 {
+				continue
+			}
+			addAdvisory(classes, pkg.Name, models.Advisory{ID: update.ID, Type: update.Type, Severity: update.Severity})
+		}
+	}
+	return classes
+}
+
+// parseUpdateinfoList parses the advisories pending on the system, as listed by
+// `dnf updateinfo list --updates` or `yum updateinfo list updates`:
+//
+//	RHSA-2024:1234 Important/Sec. openssl-1:3.0.7-25.el9_3.x86_64
+//
+// and by dnf5 `dnf advisory list --updates`:
+//
+//	FEDORA-2024-3b2c security Moderate curl-8.2.1-4.fc39.x86_64 2024-01-16 01:23:45
+func parseUpdateinfoList(out []byte) map[string]*UpgradeClass {
+	classes := map[string]*UpgradeClass{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		var advisory models.Advisory
+		var nevra string
+		switch {
+		case len(fields) >= 4 && updateinfoAdvisoryTypes[fields[1]] && strings.Contains(fields[3], "-"):
+			advisory = models.Advisory{ID: fields[0], Type: fields[1], Severity: fields[2]}
+			nevra = fields[3]
+		case len(fields) == 3:
+			advisory = models.Advisory{ID: fields[0], Type: fields[1]}
+			if severity, ok := strings.CutSuffix(fields[1], "/Sec."); ok {
+				advisory.Type = "security"
+				advisory.Severity = severity
+			}
+			if !updateinfoAdvisoryTypes[advisory.Type] {
+				continue
+			}
+			nevra = fields[2]
+		default:
+			continue
+		}
+		if advisory.Severity == "None" || advisory.Severity == "unknown" {
+			advisory.Severity = ""
+		}
+		name := nevraName(nevra)
+		if name == "" {
+			continue
+		}
+		addAdvisory(classes, name, advisory)
+	}
+	return classes
+}
+
+// nevraName returns the name of a name-[epoch:]version-release.arch package.
+func nevraName(nevra string) string {
+	for range 2 {
+		i := strings.LastIndex(nevra, "-")
+		if i <= 0 {
+			return ""
+		}
+		nevra = nevra[:i]
+	}
+	return nevra
+}
+
+// addAdvisory records an advisory once, a package is a security update as soon as one advisory is.
+func addAdvisory(classes map[string]*UpgradeClass, name string, advisory models.Advisory) {
+	class, ok := classes[name]
+	if !ok {
+		class = &UpgradeClass{}
+		classes[name] = class
+	}
+	for _, known := range class.Advisories {
+		if known.ID == advisory.ID {
+			return
+		}
+	}
+	class.Advisories = append(class.Advisories, advisory)
+	if advisory.Type == "security" {
+		class.Security = true
+	}
+}
@@ -0,0 +1,111 @@
+package packages
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUpdateinfoList_Dnf(t *testing.T) {
+	out, err := os.ReadFile("testdata/updates/dnf-updateinfo-list.txt")
+	assert.NoError(t, err)
+
+	classes := parseUpdateinfoList(out)
+	assert.Len(t, classes, 6)
+	assert.True(t, classes["openssl"].Security)
+	assert.Equal(t, []models.Advisory{{ID: "RHSA-2024:0310", Type: "security", Severity: "Important"}}, classes["openssl"].Advisories)
+	assert.True(t, classes["openssl-libs"].Security)
+	assert.Equal(t, "Moderate", classes["sqlite-libs"].Advisories[0].Severity)
+	assert.False(t, classes["tzdata"].Security)
+	assert.Equal(t, "bugfix", classes["tzdata"].Advisories[0].Type)
+	assert.Equal(t, "enhancement", classes["dnf"].Advisories[0].Type)
+	assert.True(t, classes["python3.11"].Security)
+	assert.NotContains(t, classes, "python3")
+}
+
+func TestParseUpdateinfoList_Dnf5(t *testing.T) {
+	out, err := os.ReadFile("testdata/updates/dnf5-advisory-list.txt")
+	assert.NoError(t, err)
+
+	classes := parseUpdateinfoList(out)
+	assert.Len(t, classes, 3)
+	assert.Equal(t, []models.Advisory{{ID: "FEDORA-2024-3b2c0a1", Type: "security", Severity: "Moderate"}}, classes["curl"].Advisories)
+	assert.True(t, classes["libcurl"].Security)
+	assert.False(t, classes["kernel"].Security)
+	assert.Empty(t, classes["kernel"].Advisories[0].Severity)
+}
+
+func TestGetRpmUpgradeClasses_DnfCache(t *testing.T) {
+	installed := []*models.Package{
+		{Name: "openssl", Version: "1:3.0.7-18.el9_2"},
+		{Name: "tzdata", Version: "2023c-1.el9"},
+		{Name: "bash", Version: "5.1.8-6.el9"},
+	}
+	upgradable := map[string]string{
+		"openssl": "1:3.0.7-25.el9_3",
+		"tzdata":  "2024a-1.el9",
+		// The bash advisory is only fixed by a newer version than the available one
+		"bash": "5.1.8-7.el9",
+	}
+	classes, err := GetRpmUpgradeClasses(context.Background(), "testdata/updates/dnf", installed, upgradable)
+	assert.NoError(t, err)
+
+	// RHSA-2023:5997 is already installed
+	assert.Equal(t, []models.Advisory{{ID: "RHSA-2024:0310", Type: "security", Severity: "Important"}}, classes["openssl"].Advisories)
+	assert.True(t, classes["openssl"].Security)
+	assert.False(t, classes["tzdata"].Security)
+	assert.NotContains(t, classes, "bash")
+}
+
+func TestGetRpmUpgradeClasses_YumCache(t *testing.T) {
+	installed := []*models.Package{{Name: "curl", Version: "7.29.0-59.el7_9.1"}}
+	upgradable := map[string]string{"curl": "7.29.0-59.el7_9.2"}
+	classes, err := GetRpmUpgradeClasses(context.Background(), "testdata/updates/yum", installed, upgradable)
+	assert.NoError(t, err)
+	assert.True(t, classes["curl"].Security)
+	assert.Equal(t, "CESA-2024:0346", classes["curl"].Advisories[0].ID)
+}
+
+func TestGetRpmUpgradeClasses_Command(t *testing.T) {
+	orig := utils.RunCmd
+	defer func() { utils.RunCmd = orig }()
+
+	out, err := os.ReadFile("testdata/updates/dnf-updateinfo-list.txt")
+	assert.NoError(t, err)
+	utils.RunCmd = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "dnf" {
+			return nil, errors.New("dnf not found")
+		}
+		return out, nil
+	}
+	// No cached metadata under this root, yum is used
+	classes, err := GetRpmUpgradeClasses(context.Background(), "testdata/dpkg", nil, nil)
+	assert.NoError(t, err)
+	assert.True(t, classes["openssl"].Security)
+
+	utils.RunCmd = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, errors.New("command failed")
+	}
+	_, err = GetRpmUpgradeClasses(context.Background(), "testdata/dpkg", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestClassifyUpgrades(t *testing.T) {
+	pkgs := []*models.Package{
+		{Name: "openssl", Version: "1:3.0.7-18.el9_2", UpgradableVersion: "1:3.0.7-25.el9_3"},
+		{Name: "bash", Version: "5.1.8-6.el9", IsUpToDate: true},
+	}
+	classes := map[string]*UpgradeClass{
+		"openssl": {Security: true, Advisories: []models.Advisory{{ID: "RHSA-2024:0310", Type: "security"}}},
+		"bash":    {Security: true},
+	}
+	pkgs = classifyUpgrades(pkgs, classes)
+	assert.True(t, pkgs[0].SecurityUpdate)
+	assert.Equal(t, "RHSA-2024:0310", pkgs[0].Advisories[0].ID)
+	assert.False(t, pkgs[1].SecurityUpdate)
+}
@@ -36,9 +36,17 @@ func GetRpmUpgradableMap(ctx context.Context) (map[string]string, error) {
 			continue
 		}
 
+		// The name ends with the architecture, e.g. python3.11.x86_64, and is keyed as in the updateinfo list
 		rawName := match[1]
-		name := strings.Split(rawName, ".")[0] // remove .x86_64, .noarch, etc.
+		arch := strings.LastIndex(rawName, ".")
+		if arch <= 0 {
+			continue
+		}
 		version := match[2]
+		name := nevraName(rawName[:arch] + "-" + version)
+		if name == "" {
+			continue
+		}
 		result[name] = version
 	}
 
@@ -38,6 +38,20 @@ func TestGetRpmUpgradableMap(t *testing.T) {
 				"vim":  "9.0-1.fc35",
 			},
 		},
+		{
+			name: "dotted names",
+			mockFn: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				return []byte("Last metadata expiration check: 0:12:03 ago on Mon 15 Jan 2024.\n\n" +
+					"python3.11.x86_64 3.11.5-1.el9_3 appstream\n" +
+					"python3.x86_64 3.9.18-1.el9_3 baseos\n" +
+					"glibc-langpack-en.x86_64 2.34-83.el9_3.7 baseos\n"), nil
+			},
+			expect: map[string]string{
+				"python3.11":        "3.11.5-1.el9_3",
+				"python3":           "3.9.18-1.el9_3",
+				"glibc-langpack-en": "2.34-83.el9_3.7",
+			},
+		},
 		{
 			name: "dnf fails, yum succeeds",
 			mockFn: func(ctx context.Context, name string, args ...string) ([]byte, error) {
@@ -0,0 +1,141 @@
+package rpmdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Berkeley DB hash database constants, see dbinc/db_page.h in libdb sources.
+const (
+	bdbHashMagic        = 0x061561
+	bdbPageHeaderSize   = 26
+	bdbPageTypeHashUns  = 2  // P_HASH_UNSORTED
+	bdbPageTypeOverflow = 7  // P_OVERFLOW
+	bdbPageTypeHash     = 13 // P_HASH
+	bdbItemTypeOffPage  = 3  // H_OFFPAGE
+)
+
+// bdbDatabase reads the legacy Berkeley DB "Packages" hash database (RHEL/CentOS 7 and 8, SLES 12, ...).
+type bdbDatabase struct {
+	file       *os.File
+	order      binary.ByteOrder
+	pageSize   uint32
+	lastPageNo uint32
+}
+
+func openBerkeleyDB(path string) (*bdbDatabase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	meta := make([]byte, 72)
+	if _, err := io.ReadFull(f, meta); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to read berkeley db metadata: %w", err)
+	}
+
+	// Berkeley DB files are written in host byte order, the magic number tells which one.
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(meta[12:16]) != bdbHashMagic {
+		order = binary.BigEndian
+		if order.Uint32(meta[12:16]) != bdbHashMagic {
+			f.Close()
+			return nil, fmt.Errorf("%s is not a berkeley db hash database", path)
+		}
+	}
+	db := &bdbDatabase{
+		file:       f,
+		order:      order,
+		pageSize:   order.Uint32(meta[20:24]),
+		lastPageNo: order.Uint32(meta[32:36]),
+	}
+	if db.pageSize < 512 || db.pageSize > 64*1024 {
+		f.Close()
+		return nil, fmt.Errorf("invalid berkeley db page size %d", db.pageSize)
+	}
+	return db, nil
+}
+
+func (db *bdbDatabase) readPage(pageNo uint32) ([]byte, error) {
+	page := make([]byte, db.pageSize)
+	if _, err := db.file.ReadAt(page, int64(pageNo)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("unable to read berkeley db page %d: %w", pageNo, err)
+	}
+	return page, nil
+}
+
+// Blobs walks every hash page and returns the stored header blobs.
+// Keys and values alternate in the page index, rpm stores headers as values.
+func (db *bdbDatabase) Blobs() ([][]byte, error) {
+	blobs := [][]byte{}
+	for pageNo := uint32(1); pageNo <= db.lastPageNo; pageNo++ {
+		page, err := db.readPage(pageNo)
+		if err != nil {
+			return nil, err
+		}
+		pageType := page[25]
+		if pageType != bdbPageTypeHash && pageType != bdbPageTypeHashUns {
+			continue
+		}
+		numEntries := int(db.order.Uint16(page[20:22]))
+		for i := 1; i < numEntries; i += 2 {
+			indexOffset := bdbPageHeaderSize + i*2
+			if indexOffset+2 > len(page) {
+				break
+			}
+			itemOffset := int(db.order.Uint16(page[indexOffset : indexOffset+2]))
+			if itemOffset >= len(page) {
+				continue
+			}
+			// Headers are always bigger than the in-page threshold, other items
+			// are rpm internal records such as the header instance counter.
+			if page[itemOffset] != bdbItemTypeOffPage || itemOffset+12 > len(page) {
+				continue
+			}
+			firstPage := db.order.Uint32(page[itemOffset+4 : itemOffset+8])
+			length := db.order.Uint32(page[itemOffset+8 : itemOffset+12])
+			blob, err := db.readOverflow(firstPage, length)
+			if err != nil {
+				return nil, err
+			}
+			blobs = append(blobs, blob)
+		}
+	}
+	return blobs, nil
+}
+
+// readOverflow follows the overflow page chain holding a big value.
+func (db *bdbDatabase) readOverflow(pageNo, length uint32) ([]byte, error) {
+	blob := make([]byte, 0, length)
+	seen := map[uint32]bool{}
+	for pageNo != 0 && uint32(len(blob)) < length {
+		if seen[pageNo] || pageNo > db.lastPageNo {
+			return nil, fmt.Errorf("corrupted berkeley db overflow chain at page %d", pageNo)
+		}
+		seen[pageNo] = true
+		page, err := db.readPage(pageNo)
+		if err != nil {
+			return nil, err
+		}
+		if page[25] != bdbPageTypeOverflow {
+			return nil, fmt.Errorf("unexpected page type %d in berkeley db overflow chain", page[25])
+		}
+		// For overflow pages, the "free area offset" field holds the number of bytes used.
+		used := uint32(db.order.Uint16(page[22:24]))
+		if bdbPageHeaderSize+used > db.pageSize {
+			used = db.pageSize - bdbPageHeaderSize
+		}
+		blob = append(blob, page[bdbPageHeaderSize:bdbPageHeaderSize+used]...)
+		pageNo = db.order.Uint32(page[16:20])
+	}
+	if uint32(len(blob)) > length {
+		blob = blob[:length]
+	}
+	return blob, nil
+}
+
+func (db *bdbDatabase) Close() error {
+	return db.file.Close()
+}
@@ -0,0 +1,226 @@
+package rpmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// RPM header tags used by facter, see rpmtag.h in rpm sources.
+const (
+	TagRSAHeader     = 268
+	TagDSAHeader     = 267
+	TagSigPGP        = 259
+	TagSigGPG        = 262
+	TagName          = 1000
+	TagVersion       = 1001
+	TagRelease       = 1002
+	TagEpoch         = 1003
+	TagSummary       = 1004
+	TagDescription   = 1005
+	TagInstallTime   = 1008
+	TagSize          = 1009
+	TagVendor        = 1011
+	TagLicense       = 1014
+	TagArch          = 1022
+	TagFileSizes     = 1028
+	TagFileModes     = 1030
+	TagFileDigests   = 1035
+	TagFileFlags     = 1037
+	TagFileUsername  = 1039
+	TagFileGroupname = 1040
+	TagSourceRPM     = 1044
+	TagDirIndexes    = 1116
+	TagBasenames     = 1117
+	TagDirnames      = 1118
+	TagFileDigestAlg = 5011
+)
+
+// RPM header data types.
+const (
+	typeNull        = 0
+	typeChar        = 1
+	typeInt8        = 2
+	typeInt16       = 3
+	typeInt32       = 4
+	typeInt64       = 5
+	typeString      = 6
+	typeBin         = 7
+	typeStringArray = 8
+	typeI18NString  = 9
+)
+
+// headerEntryInfoSize is the size of an index entry: tag, type, offset and count, all int32.
+const headerEntryInfoSize = 16
+
+// maxHeaderSize mirrors rpm's own limit on the size of a header blob.
+const maxHeaderSize = 256 * 1024 * 1024
+
+type headerEntry struct {
+	tag    int32
+	typ    uint32
+	offset int32
+	count  uint32
+	data   []byte
+}
+
+// Header is a parsed RPM header blob, as stored in the rpm database.
+type Header struct {
+	entries map[int32]headerEntry
+}
+
+// ParseHeader parses an RPM header blob (index count, data length, index entries and data store).
+// Blobs in the rpm database do not carry the header magic, unlike headers in .rpm files.
+func ParseHeader(blob []byte) (*Header, error) {
+	if len(blob) < 8 {
+		return nil, fmt.Errorf("rpm header too short (%d bytes)", len(blob))
+	}
+	il := binary.BigEndian.Uint32(blob[0:4])
+	dl := binary.BigEndian.Uint32(blob[4:8])
+	indexEnd := 8 + uint64(il)*headerEntryInfoSize
+	if indexEnd+uint64(dl) > uint64(len(blob)) || indexEnd+uint64(dl) > maxHeaderSize {
+		return nil, fmt.Errorf("invalid rpm header: %d entries and %d data bytes for a %d bytes blob", il, dl, len(blob))
+	}
+	store := blob[indexEnd : indexEnd+uint64(dl)]
+
+	h := &Header{entries: make(map[int32]headerEntry, il)}
+	for i := uint64(0); i < uint64(il); i++ {
+		raw := blob[8+i*headerEntryInfoSize : 8+(i+1)*headerEntryInfoSize]
+		entry := headerEntry{
+			tag:    int32(binary.BigEndian.Uint32(raw[0:4])),
+			typ:    binary.BigEndian.Uint32(raw[4:8]),
+			offset: int32(binary.BigEndian.Uint32(raw[8:12])),
+			count:  binary.BigEndian.Uint32(raw[12:16]),
+		}
+		if entry.offset < 0 || int(entry.offset) > len(store) {
+			return nil, fmt.Errorf("invalid offset %d for rpm header tag %d", entry.offset, entry.tag)
+		}
+		entry.data = store[entry.offset:]
+		// Every string of an array takes at least its terminating NUL byte
+		if (entry.typ == typeStringArray || entry.typ == typeI18NString) && uint64(entry.count) > uint64(len(entry.data)) {
+			return nil, fmt.Errorf("invalid count %d for rpm header tag %d", entry.count, entry.tag)
+		}
+		h.entries[entry.tag] = entry
+	}
+	return h, nil
+}
+
+// Has reports whether tag is present in the header.
+func (h *Header) Has(tag int32) bool {
+	_, ok := h.entries[tag]
+	return ok
+}
+
+// String returns the value of a string tag, or the first element of a string array tag.
+func (h *Header) String(tag int32) string {
+	values := h.StringArray(tag)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// StringArray returns the values of a string, string array or i18n string tag.
+func (h *Header) StringArray(tag int32) []string {
+	entry, ok := h.entries[tag]
+	if !ok {
+		return nil
+	}
+	switch entry.typ {
+	case typeString, typeStringArray, typeI18NString:
+	default:
+		return nil
+	}
+	count := entry.count
+	if entry.typ == typeString {
+		count = 1
+	}
+	values := make([]string, 0, count)
+	data := entry.data
+	for i := uint32(0); i < count; i++ {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			break
+		}
+		values = append(values, string(data[:end]))
+		data = data[end+1:]
+	}
+	return values
+}
+
+// Int returns the first value of an integer tag and whether the tag was found.
+func (h *Header) Int(tag int32) (int64, bool) {
+	values := h.IntArray(tag)
+	if len(values) == 0 {
+		return 0, false
+	}
+	return values[0], true
+}
+
+// IntArray returns the values of an integer tag.
+func (h *Header) IntArray(tag int32) []int64 {
+	entry, ok := h.entries[tag]
+	if !ok {
+		return nil
+	}
+	var size int
+	switch entry.typ {
+	case typeChar, typeInt8:
+		size = 1
+	case typeInt16:
+		size = 2
+	case typeInt32:
+		size = 4
+	case typeInt64:
+		size = 8
+	default:
+		return nil
+	}
+	if uint64(entry.count)*uint64(size) > uint64(len(entry.data)) {
+		return nil
+	}
+	values := make([]int64, 0, entry.count)
+	for i := 0; i < int(entry.count); i++ {
+		raw := entry.data[i*size : (i+1)*size]
+		switch size {
+		case 1:
+			values = append(values, int64(raw[0]))
+		case 2:
+			values = append(values, int64(binary.BigEndian.Uint16(raw)))
+		case 4:
+			values = append(values, int64(binary.BigEndian.Uint32(raw)))
+		case 8:
+			values = append(values, int64(binary.BigEndian.Uint64(raw)))
+		}
+	}
+	return values
+}
+
+// FileNames returns the paths of the files of the package.
+// Paths are stored compressed, as a directory index and a base name per file.
+func (h *Header) FileNames() []string {
+	basenames := h.StringArray(TagBasenames)
+	dirnames := h.StringArray(TagDirnames)
+	dirIndexes := h.IntArray(TagDirIndexes)
+	if len(basenames) != len(dirIndexes) {
+		return nil
+	}
+	files := make([]string, 0, len(basenames))
+	for i, base := range basenames {
+		idx := dirIndexes[i]
+		if idx < 0 || int(idx) >= len(dirnames) {
+			continue
+		}
+		files = append(files, dirnames[idx]+base)
+	}
+	return files
+}
+
+// Bin returns the value of a binary tag.
+func (h *Header) Bin(tag int32) []byte {
+	entry, ok := h.entries[tag]
+	if !ok || entry.typ != typeBin || int(entry.count) > len(entry.data) {
+		return nil
+	}
+	return entry.data[:entry.count]
+}
@@ -0,0 +1,116 @@
+package rpmdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// NDB database constants, see lib/backend/ndb/rpmpkg.c in rpm sources.
+const (
+	ndbHeaderMagic        = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	ndbSlotMagic          = 'S' | 'l'<<8 | 'o'<<16 | 't'<<24
+	ndbBlobMagic          = 'B' | 'l'<<8 | 'b'<<16 | 'S'<<24
+	ndbVersion            = 0
+	ndbPageSize           = 4096
+	ndbSlotSize           = 16
+	ndbSlotEntriesPerPage = ndbPageSize / ndbSlotSize
+	ndbBlockSize          = 16
+	// The database header uses the room of the first two slots.
+	ndbHeaderSlots = 2
+	// Upper bound on the number of slot pages, to avoid huge allocations on corrupted files.
+	ndbMaxSlotPages = 2048
+)
+
+type ndbSlot struct {
+	pkgIndex  uint32
+	blkOffset uint32
+}
+
+// ndbDatabase reads the rpm "ndb" Packages.db database (openSUSE, SLES 15).
+type ndbDatabase struct {
+	file  *os.File
+	slots []ndbSlot
+}
+
+func openNDB(path string) (*ndbDatabase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, ndbHeaderSlots*ndbSlotSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to read ndb header: %w", err)
+	}
+	if binary.LittleEndian.Uint32(header[0:4]) != ndbHeaderMagic {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a ndb database", path)
+	}
+	if v := binary.LittleEndian.Uint32(header[4:8]); v != ndbVersion {
+		f.Close()
+		return nil, fmt.Errorf("unsupported ndb version %d", v)
+	}
+	slotPages := binary.LittleEndian.Uint32(header[12:16])
+	if slotPages == 0 || slotPages > ndbMaxSlotPages {
+		f.Close()
+		return nil, fmt.Errorf("invalid ndb slot page count %d", slotPages)
+	}
+
+	raw := make([]byte, (int(slotPages)*ndbSlotEntriesPerPage-ndbHeaderSlots)*ndbSlotSize)
+	if _, err := io.ReadFull(f, raw); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to read ndb slots: %w", err)
+	}
+	db := &ndbDatabase{file: f}
+	for off := 0; off < len(raw); off += ndbSlotSize {
+		entry := raw[off : off+ndbSlotSize]
+		if binary.LittleEndian.Uint32(entry[0:4]) != ndbSlotMagic {
+			f.Close()
+			return nil, fmt.Errorf("corrupted ndb slot at offset %d", off)
+		}
+		pkgIndex := binary.LittleEndian.Uint32(entry[4:8])
+		if pkgIndex == 0 {
+			// Free slot
+			continue
+		}
+		db.slots = append(db.slots, ndbSlot{
+			pkgIndex:  pkgIndex,
+			blkOffset: binary.LittleEndian.Uint32(entry[8:12]),
+		})
+	}
+	return db, nil
+}
+
+// Blobs returns the header blob referenced by every used slot.
+func (db *ndbDatabase) Blobs() ([][]byte, error) {
+	blobs := make([][]byte, 0, len(db.slots))
+	for _, slot := range db.slots {
+		offset := int64(slot.blkOffset) * ndbBlockSize
+		header := make([]byte, 16)
+		if _, err := db.file.ReadAt(header, offset); err != nil {
+			return nil, fmt.Errorf("unable to read ndb blob header for package %d: %w", slot.pkgIndex, err)
+		}
+		if binary.LittleEndian.Uint32(header[0:4]) != ndbBlobMagic {
+			return nil, fmt.Errorf("corrupted ndb blob for package %d", slot.pkgIndex)
+		}
+		if binary.LittleEndian.Uint32(header[4:8]) != slot.pkgIndex {
+			return nil, fmt.Errorf("ndb blob index mismatch for package %d", slot.pkgIndex)
+		}
+		length := binary.LittleEndian.Uint32(header[12:16])
+		if length > maxHeaderSize {
+			return nil, fmt.Errorf("ndb blob too big for package %d", slot.pkgIndex)
+		}
+		blob := make([]byte, length)
+		if _, err := db.file.ReadAt(blob, offset+16); err != nil {
+			return nil, fmt.Errorf("unable to read ndb blob for package %d: %w", slot.pkgIndex, err)
+		}
+		blobs = append(blobs, blob)
+	}
+	return blobs, nil
+}
+
+func (db *ndbDatabase) Close() error {
+	return db.file.Close()
+}
@@ -0,0 +1,248 @@
+package rpmdb
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// PackageInfo holds the metadata of an installed RPM package.
+type PackageInfo struct {
+	Name        string
+	Epoch       int64 // -1 when the package has no epoch
+	Version     string
+	Release     string
+	Arch        string
+	Summary     string
+	Vendor      string
+	License     string
+	SourceRPM   string
+	Size        int64
+	InstallTime int64
+	SigKeyID    string // Signing key ID, as reported by `rpm -qi` (e.g. "199e2f91fd431d51")
+	Files       []string
+	FileEntries []FileEntry
+	DigestAlgo  int64 // PGP hash algorithm of the file digests, see DigestAlgo* constants
+}
+
+// FileEntry is a file of a package, as recorded in the rpm database.
+type FileEntry struct {
+	Path   string
+	Digest string // Hex encoded, empty for directories, symbolic links and ghost files
+	Mode   int64
+	Flags  int64 // See FileFlag* constants
+}
+
+// RPM file flags, see rpmfiles.h in rpm sources.
+const (
+	FileFlagConfig    = 1 << 0
+	FileFlagDoc       = 1 << 1
+	FileFlagMissingOK = 1 << 3
+	FileFlagGhost     = 1 << 6
+)
+
+// PGP hash algorithms used for file digests (RFC 4880 section 9.4).
+const (
+	DigestAlgoMD5    = 1
+	DigestAlgoSHA1   = 2
+	DigestAlgoSHA256 = 8
+	DigestAlgoSHA384 = 9
+	DigestAlgoSHA512 = 10
+)
+
+// EVR returns the full version of the package, [epoch:]version-release.
+func (p *PackageInfo) EVR() string {
+	evr := p.Version
+	if p.Release != "" {
+		evr += "-" + p.Release
+	}
+	if p.Epoch > 0 {
+		evr = strconv.FormatInt(p.Epoch, 10) + ":" + evr
+	}
+	return evr
+}
+
+// SourceName returns the name of the source package, extracted from SourceRPM (name-version-release.src.rpm).
+func (p *PackageInfo) SourceName() string {
+	src := strings.TrimSuffix(strings.TrimSuffix(p.SourceRPM, ".rpm"), ".src")
+	src = strings.TrimSuffix(src, ".nosrc")
+	for range 2 {
+		i := strings.LastIndex(src, "-")
+		if i <= 0 {
+			return ""
+		}
+		src = src[:i]
+	}
+	return src
+}
+
+// NewPackageInfo extracts package metadata from a parsed header.
+func NewPackageInfo(h *Header) *PackageInfo {
+	p := &PackageInfo{
+		Name:      h.String(TagName),
+		Epoch:     -1,
+		Version:   h.String(TagVersion),
+		Release:   h.String(TagRelease),
+		Arch:      h.String(TagArch),
+		Summary:   h.String(TagSummary),
+		Vendor:    h.String(TagVendor),
+		License:   h.String(TagLicense),
+		SourceRPM: h.String(TagSourceRPM),
+	}
+	if epoch, ok := h.Int(TagEpoch); ok {
+		p.Epoch = epoch
+	}
+	if size, ok := h.Int(TagSize); ok {
+		p.Size = size
+	}
+	if installTime, ok := h.Int(TagInstallTime); ok {
+		p.InstallTime = installTime
+	}
+	p.Files = h.FileNames()
+	p.FileEntries = fileEntries(h, p.Files)
+	// Packages built before rpm 4.6 have no digest algorithm, their digests are MD5
+	p.DigestAlgo = DigestAlgoMD5
+	if algo, ok := h.Int(TagFileDigestAlg); ok {
+		p.DigestAlgo = algo
+	}
+	for _, tag := range []int32{TagRSAHeader, TagDSAHeader, TagSigGPG, TagSigPGP} {
+		if keyID := signatureKeyID(h.Bin(tag)); keyID != "" {
+			p.SigKeyID = keyID
+			break
+		}
+	}
+	return p
+}
+
+// fileEntries associates the digest, mode and flags of each file with its path.
+func fileEntries(h *Header, files []string) []FileEntry {
+	digests := h.StringArray(TagFileDigests)
+	modes := h.IntArray(TagFileModes)
+	flags := h.IntArray(TagFileFlags)
+	entries := make([]FileEntry, 0, len(files))
+	for i, path := range files {
+		entry := FileEntry{Path: path}
+		if i < len(digests) {
+			entry.Digest = digests[i]
+		}
+		if i < len(modes) {
+			entry.Mode = modes[i]
+		}
+		if i < len(flags) {
+			entry.Flags = flags[i]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// signatureKeyID extracts the issuer key ID from an OpenPGP signature packet (RFC 4880 section 5.2).
+func signatureKeyID(packet []byte) string {
+	if len(packet) < 2 || packet[0]&0x80 == 0 {
+		return ""
+	}
+	// Skip the packet header, both old and new formats.
+	var body []byte
+	if packet[0]&0x40 == 0 {
+		switch packet[0] & 0x03 {
+		case 0:
+			body = packet[2:]
+		case 1:
+			body = skip(packet, 3)
+		case 2:
+			body = skip(packet, 5)
+		default:
+			body = packet[1:]
+		}
+	} else {
+		switch l := packet[1]; {
+		case l < 192:
+			body = packet[2:]
+		case l < 224:
+			body = skip(packet, 3)
+		default:
+			body = skip(packet, 6)
+		}
+	}
+	if len(body) == 0 {
+		return ""
+	}
+
+	switch body[0] {
+	case 3:
+		// version, hashed length (5), type, creation time (4), key ID (8)
+		if len(body) < 15 {
+			return ""
+		}
+		return hex.EncodeToString(body[7:15])
+	case 4:
+		// version, type, public key algorithm, hash algorithm, hashed and unhashed subpackets
+		if len(body) < 6 {
+			return ""
+		}
+		hashedLen := int(binary.BigEndian.Uint16(body[4:6]))
+		hashed := skip(body, 6)
+		if len(hashed) < hashedLen {
+			return ""
+		}
+		if keyID := issuerFromSubpackets(hashed[:hashedLen]); keyID != "" {
+			return keyID
+		}
+		rest := hashed[hashedLen:]
+		if len(rest) < 2 {
+			return ""
+		}
+		unhashedLen := int(binary.BigEndian.Uint16(rest[0:2]))
+		if len(rest) < 2+unhashedLen {
+			return ""
+		}
+		return issuerFromSubpackets(rest[2 : 2+unhashedLen])
+	}
+	return ""
+}
+
+// issuerFromSubpackets returns the key ID found in an issuer (16) or issuer fingerprint (33) subpacket.
+func issuerFromSubpackets(data []byte) string {
+	for len(data) > 0 {
+		var length, headerLen int
+		switch l := int(data[0]); {
+		case l < 192:
+			length, headerLen = l, 1
+		case l < 255:
+			if len(data) < 2 {
+				return ""
+			}
+			length, headerLen = ((l-192)<<8)+int(data[1])+192, 2
+		default:
+			if len(data) < 5 {
+				return ""
+			}
+			length, headerLen = int(binary.BigEndian.Uint32(data[1:5])), 5
+		}
+		if length == 0 || len(data) < headerLen+length {
+			return ""
+		}
+		sub := data[headerLen : headerLen+length]
+		switch sub[0] & 0x7f {
+		case 16:
+			if len(sub) == 9 {
+				return hex.EncodeToString(sub[1:9])
+			}
+		case 33:
+			// version followed by the fingerprint, the key ID is its last 8 bytes
+			if len(sub) >= 10 {
+				return hex.EncodeToString(sub[len(sub)-8:])
+			}
+		}
+		data = data[headerLen+length:]
+	}
+	return ""
+}
+
+func skip(b []byte, n int) []byte {
+	if len(b) < n {
+		return nil
+	}
+	return b[n:]
+}
@@ -0,0 +1,106 @@
+// Package rpmdb reads the RPM package database without the rpm binary.
+// The sqlite (rpmdb.sqlite), Berkeley DB (Packages) and NDB (Packages.db) backends are supported.
+package rpmdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// database is implemented by each rpm database backend.
+type database interface {
+	// Blobs returns the raw header blobs of all installed packages.
+	Blobs() ([][]byte, error)
+	Close() error
+}
+
+// databaseCandidate associates a database file name with the backend able to read it.
+type databaseCandidate struct {
+	file string
+	open func(path string) (database, error)
+}
+
+// databaseDirs are the locations of the rpm database, relative to the root path.
+// /usr/lib/sysimage/rpm is used by recent Fedora and openSUSE releases.
+var databaseDirs = []string{"usr/lib/sysimage/rpm", "var/lib/rpm"}
+
+// databaseCandidates are ordered from the most recent backend to the oldest one,
+// as an upgraded system can still hold the files of the previous backend.
+var databaseCandidates = []databaseCandidate{
+	{file: "rpmdb.sqlite", open: func(path string) (database, error) { return openSQLite(path) }},
+	{file: "Packages.db", open: func(path string) (database, error) { return openNDB(path) }},
+	{file: "Packages", open: func(path string) (database, error) { return openBerkeleyDB(path) }},
+}
+
+// ErrNotFound is returned when no rpm database exists under the root path.
+var ErrNotFound = errors.New("rpm database not found")
+
+// FindDatabase returns the path of the rpm database found under root.
+func FindDatabase(root string) (string, error) {
+	for _, dir := range databaseDirs {
+		for _, candidate := range databaseCandidates {
+			path := filepath.Join(root, dir, candidate.file)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return path, nil
+			}
+		}
+	}
+	return "", ErrNotFound
+}
+
+// ReadHeaders opens the rpm database at path and returns the parsed header of every package.
+// The backend is selected from the file name.
+func ReadHeaders(path string) ([]*Header, error) {
+	var db database
+	var err error
+	for _, candidate := range databaseCandidates {
+		if filepath.Base(path) == candidate.file {
+			db, err = candidate.open(path)
+			break
+		}
+	}
+	if db == nil && err == nil {
+		return nil, fmt.Errorf("unknown rpm database format for %s", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	blobs, err := db.Blobs()
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]*Header, 0, len(blobs))
+	for _, blob := range blobs {
+		h, err := ParseHeader(blob)
+		if err != nil {
+			return nil, err
+		}
+		// The gpg-pubkey pseudo packages only carry imported keys, they have no arch.
+		if h.String(TagName) == "gpg-pubkey" {
+			continue
+		}
+		headers = append(headers, h)
+	}
+	return headers, nil
+}
+
+// ListPackages returns the packages installed under root.
+func ListPackages(root string) ([]*PackageInfo, error) {
+	path, err := FindDatabase(root)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := ReadHeaders(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read rpm database %s: %w", path, err)
+	}
+	pkgs := make([]*PackageInfo, 0, len(headers))
+	for _, h := range headers {
+		pkgs = append(pkgs, NewPackageInfo(h))
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,184 @@
+package rpmdb
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func checkFixturePackages(t *testing.T, pkgs []*PackageInfo) {
+	t.Helper()
+	// The gpg-pubkey pseudo package is skipped
+	assert.Len(t, pkgs, 3)
+
+	byName := map[string]*PackageInfo{}
+	for _, p := range pkgs {
+		byName[p.Name] = p
+	}
+
+	bash := byName["bash"]
+	if assert.NotNil(t, bash) {
+		assert.Equal(t, int64(-1), bash.Epoch)
+		assert.Equal(t, "5.1.8", bash.Version)
+		assert.Equal(t, "6.el9", bash.Release)
+		assert.Equal(t, "5.1.8-6.el9", bash.EVR())
+		assert.Equal(t, "x86_64", bash.Arch)
+		assert.Equal(t, "Rocky Enterprise Software Foundation", bash.Vendor)
+		assert.Equal(t, "bash-5.1.8-6.el9.src.rpm", bash.SourceRPM)
+		assert.Equal(t, "bash", bash.SourceName())
+		assert.Equal(t, "GPLv3+", bash.License)
+		assert.Equal(t, "The GNU Bourne Again shell", bash.Summary)
+		assert.Equal(t, int64(1700000000), bash.InstallTime)
+		assert.Equal(t, int64(7738934), bash.Size)
+		assert.Equal(t, "702d426d350d275d", bash.SigKeyID)
+		assert.Equal(t, []string{"/usr/bin/bash", "/etc/skel/.bashrc", "/usr/share/doc/bash/README"}, bash.Files)
+		assert.Equal(t, int64(DigestAlgoSHA256), bash.DigestAlgo)
+		if assert.Len(t, bash.FileEntries, 3) {
+			assert.Equal(t, FileEntry{
+				Path:   "/usr/bin/bash",
+				Digest: "c226670a3feaacf15785a9b79a42987e3410ffa953dfb569db468bfcfb2ff3a7",
+				Mode:   0o100755,
+			}, bash.FileEntries[0])
+			assert.Equal(t, int64(FileFlagConfig), bash.FileEntries[1].Flags)
+			assert.Equal(t, int64(FileFlagDoc), bash.FileEntries[2].Flags)
+		}
+	}
+
+	grub := byName["grub2-common"]
+	if assert.NotNil(t, grub) {
+		assert.Equal(t, int64(1), grub.Epoch)
+		assert.Equal(t, "1:2.02-0.86.el7", grub.EVR())
+		assert.Equal(t, "noarch", grub.Arch)
+		assert.Equal(t, "grub2", grub.SourceName())
+		assert.Equal(t, "24c6a8a7f4a80eb5", grub.SigKeyID)
+	}
+
+	python := byName["python3-libs"]
+	if assert.NotNil(t, python) {
+		assert.Equal(t, "3.9.18-1.el9_3", python.EVR())
+		assert.Equal(t, "python3.9", python.SourceName())
+		assert.Empty(t, python.SigKeyID)
+	}
+}
+
+func TestListPackages_SQLite(t *testing.T) {
+	pkgs, err := ListPackages("testdata/sqlite")
+	assert.NoError(t, err)
+	checkFixturePackages(t, pkgs)
+}
+
+func TestListPackages_BerkeleyDB(t *testing.T) {
+	pkgs, err := ListPackages("testdata/bdb")
+	assert.NoError(t, err)
+	checkFixturePackages(t, pkgs)
+}
+
+func TestListPackages_NDB(t *testing.T) {
+	pkgs, err := ListPackages("testdata/ndb")
+	assert.NoError(t, err)
+	checkFixturePackages(t, pkgs)
+}
+
+func TestListPackages_NotFound(t *testing.T) {
+	pkgs, err := ListPackages(t.TempDir())
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, pkgs)
+}
+
+func TestFindDatabase(t *testing.T) {
+	path, err := FindDatabase("testdata/ndb")
+	assert.NoError(t, err)
+	assert.Equal(t, "testdata/ndb/usr/lib/sysimage/rpm/Packages.db", path)
+}
+
+func TestReadHeaders_WrongFormat(t *testing.T) {
+	_, err := ReadHeaders("testdata/bdb/var/lib/rpm/rpmdb.sqlite")
+	assert.Error(t, err)
+	_, err = ReadHeaders("testdata/unknown.db")
+	assert.Error(t, err)
+}
+
+func TestParseHeader_Invalid(t *testing.T) {
+	_, err := ParseHeader([]byte{0, 0})
+	assert.Error(t, err)
+	// 1 entry announced but no room for its index
+	_, err = ParseHeader([]byte{0, 0, 0, 1, 0, 0, 0, 0})
+	assert.Error(t, err)
+	// A string array of 2^32-1 values in a 4 bytes data store
+	_, err = ParseHeader([]byte{0, 0, 0, 1, 0, 0, 0, 4, 0, 0, 0x04, 0x75, 0, 0, 0, 8, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 'a', 0, 'b', 0})
+	assert.Error(t, err)
+}
+
+func TestWalkTable_InvalidCellCount(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "rpmdb.sqlite")
+	assert.NoError(t, err)
+	defer f.Close()
+	page := make([]byte, 512)
+	// The first page starts with the database header
+	page[sqliteHeaderSize] = sqlitePageLeafTable
+	// 65535 cells whose pointers do not fit in a page
+	page[sqliteHeaderSize+3], page[sqliteHeaderSize+4] = 0xff, 0xff
+	_, err = f.Write(page)
+	assert.NoError(t, err)
+
+	db := &sqliteDatabase{file: f, pageSize: 512, usableSize: 512, pageCount: 1}
+	err = db.walkTable(1, 0, func([]any) error { return nil })
+	assert.ErrorContains(t, err, "invalid sqlite cell count")
+}
+
+func TestLeafPayload_Invalid(t *testing.T) {
+	db := &sqliteDatabase{pageSize: 512, usableSize: 512, pageCount: 4}
+	cases := map[string][]byte{
+		"truncated size":  {0x81},
+		"truncated rowid": {0x05, 0x81},
+		// 2^64-1, negative once converted to int
+		"negative size": {0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x00},
+		// Larger than the 4 pages of the database, read from overflow pages
+		"oversized": {0x81, 0x80, 0x80, 0x00, 0x01, 0x00},
+	}
+	for name, cell := range cases {
+		t.Run(name, func(t *testing.T) {
+			// The cell ends the page, its varints are truncated by the end of the page
+			page := make([]byte, 512)
+			copy(page[len(page)-len(cell):], cell)
+			_, err := db.leafPayload(page, len(page)-len(cell))
+			assert.ErrorContains(t, err, "invalid sqlite cell payload")
+		})
+	}
+}
+
+func TestDecodeSQLiteRecord_Invalid(t *testing.T) {
+	cases := map[string][]byte{
+		"empty":            {},
+		"truncated header": {0x81},
+		// The header size does not count its own varint
+		"header too small": {0x00, 0x01},
+		"header too large": {0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		// A blob of about 2^63 bytes in a 10 bytes record
+		"oversized value": {0x0a, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"truncated value": {0x02, 0x11, 'a'},
+	}
+	for name, payload := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeSQLiteRecord(payload)
+			assert.Error(t, err)
+		})
+	}
+
+	record, err := decodeSQLiteRecord([]byte{0x03, 0x01, 0x0f, 0x2a, 'a'})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int64(42), "a"}, record)
+}
+
+func TestPackageInfo_SourceName(t *testing.T) {
+	cases := map[string]string{
+		"openssl-3.0.7-24.el9.src.rpm":             "openssl",
+		"java-17-openjdk-17.0.9.0.9-2.el9.src.rpm": "java-17-openjdk",
+		"": "",
+	}
+	for srpm, expected := range cases {
+		p := &PackageInfo{SourceRPM: srpm}
+		assert.Equal(t, expected, p.SourceName(), srpm)
+	}
+}
@@ -0,0 +1,319 @@
+package rpmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// SQLite file format constants, see https://www.sqlite.org/fileformat.html
+const (
+	sqliteMagic             = "SQLite format 3\x00"
+	sqliteHeaderSize        = 100
+	sqlitePageInteriorTable = 0x05
+	sqlitePageLeafTable     = 0x0d
+	// Upper bound on the b-tree depth, to stop on corrupted files with page loops.
+	sqliteMaxDepth = 64
+)
+
+// sqliteDatabase is a minimal read-only reader of the SQLite file format.
+// It only supports walking table b-trees, which is all rpm's sqlite backend needs:
+// headers are stored in the "Packages" table as (hnum INTEGER PRIMARY KEY, blob BLOB).
+// Changes still in a write-ahead log (-wal file) are not read.
+type sqliteDatabase struct {
+	file       *os.File
+	pageSize   int
+	usableSize int
+	pageCount  uint32
+}
+
+func openSQLite(path string) (*sqliteDatabase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, sqliteHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to read sqlite header: %w", err)
+	}
+	if string(header[0:16]) != sqliteMagic {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a sqlite database", path)
+	}
+	pageSize := int(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		f.Close()
+		return nil, fmt.Errorf("invalid sqlite page size %d", pageSize)
+	}
+	if enc := binary.BigEndian.Uint32(header[56:60]); enc > 1 {
+		f.Close()
+		return nil, fmt.Errorf("unsupported sqlite text encoding %d", enc)
+	}
+	db := &sqliteDatabase{
+		file:       f,
+		pageSize:   pageSize,
+		usableSize: pageSize - int(header[20]),
+		pageCount:  binary.BigEndian.Uint32(header[28:32]),
+	}
+	// The page count of the header is not trusted beyond the size of the file
+	if info, err := f.Stat(); err == nil {
+		if pages := uint32(info.Size() / int64(pageSize)); db.pageCount == 0 || db.pageCount > pages {
+			db.pageCount = pages
+		}
+	}
+	return db, nil
+}
+
+func (db *sqliteDatabase) readPage(pageNo uint32) ([]byte, error) {
+	if pageNo == 0 || pageNo > db.pageCount {
+		return nil, fmt.Errorf("sqlite page %d out of range", pageNo)
+	}
+	page := make([]byte, db.pageSize)
+	if _, err := db.file.ReadAt(page, int64(pageNo-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("unable to read sqlite page %d: %w", pageNo, err)
+	}
+	return page, nil
+}
+
+// tableRootPage looks for a table in the sqlite_schema table, stored on page 1.
+func (db *sqliteDatabase) tableRootPage(name string) (uint32, error) {
+	var root uint32
+	err := db.walkTable(1, 0, func(record []any) error {
+		if len(record) < 4 {
+			return nil
+		}
+		typ, _ := record[0].(string)
+		tblName, _ := record[1].(string)
+		if typ == "table" && strings.EqualFold(tblName, name) {
+			if page, ok := record[3].(int64); ok {
+				root = uint32(page)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if root == 0 {
+		return 0, fmt.Errorf("table %s not found in sqlite database", name)
+	}
+	return root, nil
+}
+
+// walkTable calls fn for every record of the table b-tree rooted at pageNo.
+func (db *sqliteDatabase) walkTable(pageNo uint32, depth int, fn func(record []any) error) error {
+	if depth > sqliteMaxDepth {
+		return fmt.Errorf("sqlite b-tree too deep, database is probably corrupted")
+	}
+	page, err := db.readPage(pageNo)
+	if err != nil {
+		return err
+	}
+	headerOffset := 0
+	if pageNo == 1 {
+		headerOffset = sqliteHeaderSize
+	}
+	pageType := page[headerOffset]
+	cellCount := int(binary.BigEndian.Uint16(page[headerOffset+3 : headerOffset+5]))
+	pointers := headerOffset + 8
+	if pageType == sqlitePageInteriorTable {
+		pointers = headerOffset + 12
+	}
+	if pointers+cellCount*2 > len(page) {
+		return fmt.Errorf("invalid sqlite cell count %d on page %d", cellCount, pageNo)
+	}
+
+	switch pageType {
+	case sqlitePageInteriorTable:
+		for i := 0; i < cellCount; i++ {
+			cell := int(binary.BigEndian.Uint16(page[pointers+i*2:]))
+			if cell+4 > len(page) {
+				return fmt.Errorf("invalid sqlite cell offset on page %d", pageNo)
+			}
+			if err := db.walkTable(binary.BigEndian.Uint32(page[cell:cell+4]), depth+1, fn); err != nil {
+				return err
+			}
+		}
+		rightMost := binary.BigEndian.Uint32(page[headerOffset+8 : headerOffset+12])
+		return db.walkTable(rightMost, depth+1, fn)
+	case sqlitePageLeafTable:
+		for i := 0; i < cellCount; i++ {
+			cell := int(binary.BigEndian.Uint16(page[pointers+i*2:]))
+			if cell >= len(page) {
+				return fmt.Errorf("invalid sqlite cell offset on page %d", pageNo)
+			}
+			payload, err := db.leafPayload(page, cell)
+			if err != nil {
+				return err
+			}
+			record, err := decodeSQLiteRecord(payload)
+			if err != nil {
+				return err
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unexpected sqlite page type %#x on page %d", pageType, pageNo)
+	}
+}
+
+// leafPayload returns the full payload of a table leaf cell, following overflow pages when needed.
+func (db *sqliteDatabase) leafPayload(page []byte, cell int) ([]byte, error) {
+	payloadSize, n := readVarint(page[cell:])
+	cell += n
+	_, rowidSize := readVarint(page[cell:])
+	cell += rowidSize
+	// A payload cannot be larger than the database, which also keeps its size a positive int
+	if n == 0 || rowidSize == 0 || payloadSize > uint64(db.pageCount)*uint64(db.usableSize) {
+		return nil, fmt.Errorf("invalid sqlite cell payload")
+	}
+
+	size := int(payloadSize)
+	maxLocal := db.usableSize - 35
+	if size <= maxLocal {
+		if cell+size > len(page) {
+			return nil, fmt.Errorf("invalid sqlite cell payload")
+		}
+		return page[cell : cell+size], nil
+	}
+
+	minLocal := ((db.usableSize-12)*32)/255 - 23
+	local := minLocal + (size-minLocal)%(db.usableSize-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if cell+local+4 > len(page) {
+		return nil, fmt.Errorf("invalid sqlite cell payload")
+	}
+	payload := make([]byte, 0, size)
+	payload = append(payload, page[cell:cell+local]...)
+	next := binary.BigEndian.Uint32(page[cell+local : cell+local+4])
+	for next != 0 && len(payload) < size {
+		overflow, err := db.readPage(next)
+		if err != nil {
+			return nil, err
+		}
+		chunk := min(size-len(payload), db.usableSize-4)
+		payload = append(payload, overflow[4:4+chunk]...)
+		next = binary.BigEndian.Uint32(overflow[0:4])
+	}
+	if len(payload) != size {
+		return nil, fmt.Errorf("truncated sqlite overflow chain")
+	}
+	return payload, nil
+}
+
+// decodeSQLiteRecord decodes a record into int64, float64, string, []byte or nil values.
+func decodeSQLiteRecord(payload []byte) ([]any, error) {
+	headerSize, n := readVarint(payload)
+	if n == 0 || headerSize < uint64(n) || headerSize > uint64(len(payload)) {
+		return nil, fmt.Errorf("invalid sqlite record header")
+	}
+	types := []uint64{}
+	for pos := n; pos < int(headerSize); {
+		t, n := readVarint(payload[pos:])
+		if n == 0 {
+			return nil, fmt.Errorf("invalid sqlite record header")
+		}
+		types = append(types, t)
+		pos += n
+	}
+
+	values := make([]any, 0, len(types))
+	data := payload[headerSize:]
+	for _, t := range types {
+		var size uint64
+		switch {
+		case t == 0, t == 8, t == 9:
+			size = 0
+		case t >= 1 && t <= 4:
+			size = t
+		case t == 5:
+			size = 6
+		case t == 6, t == 7:
+			size = 8
+		case t >= 12:
+			size = (t - 12) / 2
+		default:
+			return nil, fmt.Errorf("unsupported sqlite serial type %d", t)
+		}
+		if size > uint64(len(data)) {
+			return nil, fmt.Errorf("truncated sqlite record")
+		}
+		raw := data[:size]
+		data = data[size:]
+
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t == 8:
+			values = append(values, int64(0))
+		case t == 9:
+			values = append(values, int64(1))
+		case t <= 6:
+			var v int64
+			for _, b := range raw {
+				v = v<<8 | int64(b)
+			}
+			// sign extension
+			shift := 64 - 8*uint(size)
+			values = append(values, (v<<shift)>>shift)
+		case t == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(raw)))
+		case t%2 == 0:
+			values = append(values, bytes.Clone(raw))
+		default:
+			values = append(values, string(raw))
+		}
+	}
+	return values, nil
+}
+
+// readVarint decodes a sqlite big-endian variable length integer, returning the value and its size.
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// Blobs returns the header blobs stored in the Packages table.
+func (db *sqliteDatabase) Blobs() ([][]byte, error) {
+	root, err := db.tableRootPage("Packages")
+	if err != nil {
+		return nil, err
+	}
+	blobs := [][]byte{}
+	err = db.walkTable(root, 0, func(record []any) error {
+		if len(record) < 2 {
+			return nil
+		}
+		if blob, ok := record[1].([]byte); ok {
+			blobs = append(blobs, blob)
+		}
+		return nil
+	})
+	return blobs, err
+}
+
+func (db *sqliteDatabase) Close() error {
+	return db.file.Close()
+}
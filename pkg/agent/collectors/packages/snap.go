@@ -0,0 +1,115 @@
+package packages
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// snapdStateFile is the snapd state, relative to the root path.
+const snapdStateFile = "var/lib/snapd/state.json"
+
+// snapMountDirs are the locations snaps are mounted at, relative to the root path.
+// Fedora and Arch use /var/lib/snapd/snap as /snap is not allowed by their filesystem layout.
+var snapMountDirs = []string{"snap", "var/lib/snapd/snap"}
+
+// snapdState holds the part of the snapd state describing installed snaps.
+type snapdState struct {
+	Data struct {
+		Snaps map[string]snapState `json:"snaps"`
+	} `json:"data"`
+}
+
+type snapState struct {
+	Active  bool   `json:"active"`
+	Current string `json:"current"`
+	Channel string `json:"channel"`
+}
+
+// snapYaml holds the fields of meta/snap.yaml used for the inventory.
+type snapYaml struct {
+	Name          string   `yaml:"name"`
+	Version       string   `yaml:"version"`
+	Summary       string   `yaml:"summary"`
+	Confinement   string   `yaml:"confinement"`
+	Architectures []string `yaml:"architectures"`
+	License       string   `yaml:"license"`
+}
+
+// ReadSnaps returns the snaps installed under root, read from the snapd state and the snap metadata.
+func ReadSnaps(root string) ([]*models.Package, error) {
+	data, err := os.ReadFile(filepath.Join(root, snapdStateFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read snapd state: %w", err)
+	}
+	var state snapdState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("unable to parse snapd state: %w", err)
+	}
+
+	names := make([]string, 0, len(state.Data.Snaps))
+	for name := range state.Data.Snaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pkgs := make([]*models.Package, 0, len(names))
+	for _, name := range names {
+		snap := state.Data.Snaps[name]
+		pkg := &models.Package{
+			Name:      name,
+			Revision:  snap.Current,
+			Channel:   snap.Channel,
+			Ecosystem: models.EcosystemSnap,
+			Manager:   "snapd",
+			Status:    "active",
+			Origin:    "snapstore",
+		}
+		if !snap.Active {
+			pkg.Status = "disabled"
+		}
+		// Revisions of snaps installed from a local file are prefixed by "x"
+		if strings.HasPrefix(snap.Current, "x") {
+			pkg.Origin = "local"
+		}
+		if meta, location := readSnapYaml(root, name, snap.Current); meta != nil {
+			pkg.Version = meta.Version
+			pkg.Description = meta.Summary
+			pkg.Confinement = meta.Confinement
+			pkg.License = meta.License
+			pkg.Location = location
+			if len(meta.Architectures) == 1 {
+				pkg.Architecture = meta.Architectures[0]
+			}
+			if pkg.Confinement == "" {
+				// Confinement defaults to strict when snap.yaml does not say otherwise
+				pkg.Confinement = "strict"
+			}
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// readSnapYaml reads meta/snap.yaml of a mounted snap revision.
+func readSnapYaml(root, name, revision string) (*snapYaml, string) {
+	for _, dir := range snapMountDirs {
+		location := filepath.Join(root, dir, name, revision)
+		data, err := os.ReadFile(filepath.Join(location, "meta", "snap.yaml"))
+		if err != nil {
+			continue
+		}
+		var meta snapYaml
+		if err := yaml.Unmarshal(data, &meta); err != nil {
+			continue
+		}
+		return &meta, "/" + filepath.Join(dir, name, revision)
+	}
+	return nil, ""
+}
@@ -0,0 +1,82 @@
+package packages
+
+import (
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSnaps(t *testing.T) {
+	pkgs, err := ReadSnaps("testdata/snap")
+	assert.NoError(t, err)
+	assert.Len(t, pkgs, 4)
+
+	byName := map[string]*models.Package{}
+	for _, pkg := range pkgs {
+		assert.Equal(t, models.EcosystemSnap, pkg.Ecosystem)
+		byName[pkg.Name] = pkg
+	}
+
+	firefox := byName["firefox"]
+	assert.Equal(t, "122.0-2", firefox.Version)
+	assert.Equal(t, "3626", firefox.Revision)
+	assert.Equal(t, "latest/stable/ubuntu-22.04", firefox.Channel)
+	assert.Equal(t, "strict", firefox.Confinement)
+	assert.Equal(t, "snapstore", firefox.Origin)
+	assert.Equal(t, "amd64", firefox.Architecture)
+	assert.Equal(t, "MPL-2.0", firefox.License)
+	assert.Equal(t, "/snap/firefox/3626", firefox.Location)
+
+	assert.Equal(t, "20240111", byName["core22"].Version)
+	assert.Equal(t, "strict", byName["core22"].Confinement)
+	assert.Equal(t, "classic", byName["code"].Confinement)
+	assert.Equal(t, "05047486", byName["code"].Version)
+
+	// Not mounted, only the state is known
+	local := byName["hello-local"]
+	assert.Equal(t, "local", local.Origin)
+	assert.Equal(t, "disabled", local.Status)
+	assert.Empty(t, local.Version)
+	assert.Empty(t, local.Confinement)
+}
+
+func TestReadSnaps_NoSnapd(t *testing.T) {
+	_, err := ReadSnaps("testdata/dpkg")
+	assert.Error(t, err)
+}
+
+func TestReadFlatpaks(t *testing.T) {
+	pkgs, err := ReadFlatpaks("testdata/flatpak", []string{"/home/alice", "/home/bob"})
+	assert.NoError(t, err)
+	assert.Len(t, pkgs, 3)
+
+	byName := map[string]*models.Package{}
+	for _, pkg := range pkgs {
+		assert.Equal(t, models.EcosystemFlatpak, pkg.Ecosystem)
+		assert.Equal(t, "x86_64", pkg.Architecture)
+		byName[pkg.Name] = pkg
+	}
+
+	firefox := byName["org.mozilla.firefox"]
+	assert.Equal(t, "122.0", firefox.Version)
+	assert.Equal(t, "stable", firefox.Channel)
+	assert.Equal(t, "flathub", firefox.Origin)
+	assert.Equal(t, "Fast, Private & Safe Web Browser", firefox.Description)
+	assert.Equal(t, "MPL-2.0", firefox.License)
+	assert.Equal(t, "0f3a6c2b9d8e7f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f7081920", firefox.Revision)
+
+	platform := byName["org.freedesktop.Platform"]
+	assert.Equal(t, "23.08", platform.Version)
+	assert.Equal(t, "flathub", platform.Origin)
+
+	spotify := byName["com.spotify.Client"]
+	assert.Equal(t, "1.2.26.1187.g36b715a1", spotify.Version)
+	assert.Equal(t, "flathub-beta", spotify.Origin)
+	assert.Equal(t, "testdata/flatpak/home/alice/.local/share/flatpak/app/com.spotify.Client/x86_64/stable", spotify.Location)
+}
+
+func TestReadFlatpaks_NoInstallation(t *testing.T) {
+	_, err := ReadFlatpaks("testdata/dpkg", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,307 @@
+package vulnerability
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+)
+
+// Feeds holds the enrichment data of the vulnerabilities, read from local files only.
+type Feeds struct {
+	nvd  map[string]nvdEntry
+	epss map[string]epssEntry
+	kev  map[string]kevEntry
+}
+
+type nvdEntry struct {
+	published    string
+	cvssV3Score  float64
+	cvssV3Vector string
+	cvssV4Score  float64
+	cvssV4Vector string
+}
+
+type epssEntry struct {
+	probability float64
+	percentile  float64
+}
+
+type kevEntry struct {
+	dateAdded  string
+	ransomware bool
+}
+
+// nvdCVE is a CVE of the NVD CVE API 2.0, only the fields used are kept.
+type nvdCVE struct {
+	ID        string `json:"id"`
+	Published string `json:"published"`
+	Metrics   struct {
+		CvssMetricV40 []nvdMetric `json:"cvssMetricV40"`
+		CvssMetricV31 []nvdMetric `json:"cvssMetricV31"`
+		CvssMetricV30 []nvdMetric `json:"cvssMetricV30"`
+	} `json:"metrics"`
+}
+
+type nvdMetric struct {
+	Type     string `json:"type"` // Primary for the scores of the NVD, Secondary for the ones of the CNA
+	CvssData struct {
+		VectorString string  `json:"vectorString"`
+		BaseScore    float64 `json:"baseScore"`
+	} `json:"cvssData"`
+}
+
+// kevCatalog is the CISA Known Exploited Vulnerabilities catalog.
+type kevCatalog struct {
+	Vulnerabilities []struct {
+		CveID                      string `json:"cveID"`
+		DateAdded                  string `json:"dateAdded"`
+		KnownRansomwareCampaignUse string `json:"knownRansomwareCampaignUse"`
+	} `json:"vulnerabilities"`
+}
+
+// LoadFeeds reads the configured feeds, only the entries of the given vulnerabilities are kept
+// as the NVD and EPSS feeds cover every published CVE.
+func LoadFeeds(cfg options.VulnEnrichmentOptions, ids map[string]bool) (*Feeds, error) {
+	feeds := &Feeds{nvd: map[string]nvdEntry{}, epss: map[string]epssEntry{}, kev: map[string]kevEntry{}}
+	if cfg.Nvd != "" {
+		if err := feeds.loadNVD(cfg.Nvd, ids); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Epss != "" {
+		if err := feeds.loadEPSS(cfg.Epss, ids); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Kev != "" {
+		if err := feeds.loadKEV(cfg.Kev); err != nil {
+			return nil, err
+		}
+	}
+	return feeds, nil
+}
+
+// openFeed opens a feed file, transparently decompressing gzipped ones.
+func openFeed(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open feed: %w", err)
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, f}, nil
+}
+
+// loadNVD reads a NVD feed file, or the .json and .json.gz files of a directory such as the yearly nvdcve-2.0-<year> feeds.
+func (f *Feeds) loadNVD(path string, ids map[string]bool) error {
+	files := []string{path}
+	if fi, err := os.Stat(path); err != nil {
+		return fmt.Errorf("unable to open feed: %w", err)
+	} else if fi.IsDir() {
+		files = nil
+		for _, pattern := range []string{"*.json", "*.json.gz"} {
+			matches, _ := filepath.Glob(filepath.Join(path, pattern))
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+	}
+	for _, file := range files {
+		if err := f.loadNVDFile(file, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadNVDFile streams the vulnerabilities array of a feed, yearly feeds hold tens of thousands of CVEs.
+func (f *Feeds) loadNVDFile(path string, ids map[string]bool) error {
+	r, err := openFeed(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	dec := json.NewDecoder(r)
+	if err := seekJSONArray(dec, "vulnerabilities"); err != nil {
+		return fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	for dec.More() {
+		var item struct {
+			CVE nvdCVE `json:"cve"`
+		}
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+		if !ids[item.CVE.ID] {
+			continue
+		}
+		entry := nvdEntry{published: item.CVE.Published}
+		if m := primaryMetric(item.CVE.Metrics.CvssMetricV31, item.CVE.Metrics.CvssMetricV30); m != nil {
+			entry.cvssV3Score, entry.cvssV3Vector = m.CvssData.BaseScore, m.CvssData.VectorString
+		}
+		if m := primaryMetric(item.CVE.Metrics.CvssMetricV40); m != nil {
+			entry.cvssV4Score, entry.cvssV4Vector = m.CvssData.BaseScore, m.CvssData.VectorString
+		}
+		f.nvd[item.CVE.ID] = entry
+	}
+	return nil
+}
+
+// seekJSONArray moves the decoder into the array of a top level key.
+func seekJSONArray(dec *json.Decoder, key string) error {
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return errors.New("not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != key {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return fmt.Errorf("%s is not an array", key)
+		}
+		return nil
+	}
+	return fmt.Errorf("no %s found", key)
+}
+
+// primaryMetric returns the first score of the NVD in the lists, in order, or the first one of a CNA.
+func primaryMetric(lists ...[]nvdMetric) *nvdMetric {
+	var secondary *nvdMetric
+	for _, metrics := range lists {
+		for i := range metrics {
+			if metrics[i].Type == "Primary" {
+				return &metrics[i]
+			}
+			if secondary == nil {
+				secondary = &metrics[i]
+			}
+		}
+	}
+	return secondary
+}
+
+// loadEPSS reads the EPSS CSV, made of a "#model_version:...,score_date:..." comment and "cve,epss,percentile" rows.
+func (f *Feeds) loadEPSS(path string, ids map[string]bool) error {
+	r, err := openFeed(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+		if len(record) < 3 || !ids[record[0]] {
+			continue
+		}
+		probability, err1 := strconv.ParseFloat(record[1], 64)
+		percentile, err2 := strconv.ParseFloat(record[2], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		f.epss[record[0]] = epssEntry{probability: probability, percentile: percentile}
+	}
+}
+
+func (f *Feeds) loadKEV(path string) error {
+	r, err := openFeed(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var catalog kevCatalog
+	if err := json.NewDecoder(r).Decode(&catalog); err != nil {
+		return fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	for _, vuln := range catalog.Vulnerabilities {
+		f.kev[vuln.CveID] = kevEntry{dateAdded: vuln.DateAdded, ransomware: vuln.KnownRansomwareCampaignUse == "Known"}
+	}
+	return nil
+}
+
+// VulnerabilityIDs returns the identifiers of the vulnerabilities of the installed packages.
+func VulnerabilityIDs(matches []models.PackageVulnMatch) map[string]bool {
+	ids := map[string]bool{}
+	for _, match := range matches {
+		if !match.Matched {
+			continue
+		}
+		for _, vuln := range match.Vulnerabilities {
+			ids[vuln.VulnerabilityId] = true
+		}
+	}
+	return ids
+}
+
+// Enrich returns a finding per vulnerability of the installed packages, completed by the feeds.
+// Vulnerabilities reported by a scanner for packages which are not installed are left out.
+func Enrich(matches []models.PackageVulnMatch, feeds *Feeds) []*models.VulnerabilityFinding {
+	findings := []*models.VulnerabilityFinding{}
+	for _, match := range matches {
+		if !match.Matched {
+			continue
+		}
+		for _, vuln := range match.Vulnerabilities {
+			finding := &models.VulnerabilityFinding{
+				ID:               vuln.VulnerabilityId,
+				Package:          match.PackageName,
+				Ecosystem:        match.Ecosystem,
+				InstalledVersion: match.InstalledVersion,
+				Severity:         vuln.Severity,
+				FixedVersion:     vuln.FixedVersion,
+			}
+			if nvd, ok := feeds.nvd[vuln.VulnerabilityId]; ok {
+				finding.Published = nvd.published
+				finding.CVSSv3Score, finding.CVSSv3Vector = nvd.cvssV3Score, nvd.cvssV3Vector
+				finding.CVSSv4Score, finding.CVSSv4Vector = nvd.cvssV4Score, nvd.cvssV4Vector
+			}
+			if epss, ok := feeds.epss[vuln.VulnerabilityId]; ok {
+				finding.EPSS, finding.EPSSPercentile = epss.probability, epss.percentile
+			}
+			if kev, ok := feeds.kev[vuln.VulnerabilityId]; ok {
+				finding.KnownExploited = true
+				finding.KEVDateAdded = kev.dateAdded
+				finding.KnownRansomware = kev.ransomware
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,108 @@
+package vulnerability
+
+import (
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/stretchr/testify/assert"
+)
+
+var enrichedMatches = []models.PackageVulnMatch{
+	{
+		PackageName: "openssl", InstalledVersion: "3.0.11-1~deb12u2", Matched: true,
+		Vulnerabilities: []models.MatchedVuln{{VulnerabilityId: "CVE-2024-0727", Severity: "MEDIUM", FixedVersion: "3.0.13-1~deb12u1"}},
+	},
+	{
+		PackageName: "liblzma5", InstalledVersion: "5.6.0-0.2", Matched: true,
+		Vulnerabilities: []models.MatchedVuln{{VulnerabilityId: "CVE-2024-3094", Severity: "CRITICAL", FixedVersion: "5.6.1+really5.4.5-1"}},
+	},
+	{
+		PackageName: "requests", InstalledVersion: "2.28.1", Matched: true,
+		Vulnerabilities: []models.MatchedVuln{{VulnerabilityId: "CVE-2023-32681", Severity: "MEDIUM"}},
+	},
+	{
+		PackageName: "curl", InstalledVersion: "7.68.0", Matched: false,
+		Vulnerabilities: []models.MatchedVuln{{VulnerabilityId: "CVE-2023-38545", Severity: "CRITICAL"}},
+	},
+}
+
+var testFeeds = options.VulnEnrichmentOptions{
+	Nvd:  "testdata/feeds/nvd",
+	Epss: "testdata/feeds/epss_scores.csv.gz",
+	Kev:  "testdata/feeds/known_exploited_vulnerabilities.json",
+}
+
+func TestEnrich(t *testing.T) {
+	ids := VulnerabilityIDs(enrichedMatches)
+	assert.Equal(t, map[string]bool{"CVE-2024-0727": true, "CVE-2024-3094": true, "CVE-2023-32681": true}, ids)
+
+	feeds, err := LoadFeeds(testFeeds, ids)
+	assert.NoError(t, err)
+	assert.Len(t, feeds.nvd, 2)
+
+	findings := Enrich(enrichedMatches, feeds)
+	assert.Len(t, findings, 3)
+	assert.Equal(t, &models.VulnerabilityFinding{
+		ID:               "CVE-2024-0727",
+		Package:          "openssl",
+		InstalledVersion: "3.0.11-1~deb12u2",
+		Severity:         "MEDIUM",
+		FixedVersion:     "3.0.13-1~deb12u1",
+		CVSSv3Score:      5.5,
+		CVSSv3Vector:     "CVSS:3.1/AV:L/AC:L/PR:N/UI:R/S:U/C:N/I:N/A:H",
+		EPSS:             0.00052,
+		EPSSPercentile:   0.21563,
+		Published:        "2024-01-26T09:15:07.837",
+	}, findings[0])
+
+	xz := findings[1]
+	assert.Equal(t, 10.0, xz.CVSSv4Score)
+	assert.Equal(t, "CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:H/SI:H/SA:H", xz.CVSSv4Vector)
+	assert.Equal(t, 10.0, xz.CVSSv3Score)
+	assert.True(t, xz.KnownExploited)
+	assert.Equal(t, "2024-04-01", xz.KEVDateAdded)
+	assert.False(t, xz.KnownRansomware)
+	assert.Equal(t, 0.84353, xz.EPSS)
+
+	assert.Equal(t, 0.0, findings[2].CVSSv3Score)
+	assert.Equal(t, 0.00192, findings[2].EPSS)
+}
+
+func TestLoadFeeds_Errors(t *testing.T) {
+	_, err := LoadFeeds(options.VulnEnrichmentOptions{Kev: "testdata/feeds/missing.json"}, nil)
+	assert.Error(t, err)
+	_, err = LoadFeeds(options.VulnEnrichmentOptions{Nvd: "testdata/trivy.json"}, nil)
+	assert.ErrorContains(t, err, "no vulnerabilities found")
+
+	feeds, err := LoadFeeds(options.VulnEnrichmentOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, Enrich(enrichedMatches, feeds), 3)
+}
+
+func TestRatePolicy(t *testing.T) {
+	feeds, err := LoadFeeds(testFeeds, VulnerabilityIDs(enrichedMatches))
+	assert.NoError(t, err)
+	findings := Enrich(enrichedMatches, feeds)
+
+	risk := RatePolicy(findings, options.RiskPolicyOptions{})
+	assert.Equal(t, models.RiskMedium, findings[0].Risk)
+	assert.Equal(t, models.RiskCritical, findings[1].Risk)
+	// No score, rated from the scanner severity
+	assert.Equal(t, models.RiskMedium, findings[2].Risk)
+	assert.Equal(t, &models.HostRisk{
+		Rating:         models.RiskCritical,
+		Counts:         map[string]int{models.RiskMedium: 2, models.RiskCritical: 1},
+		KnownExploited: 1,
+	}, risk)
+
+	// Only exploitation matters
+	risk = RatePolicy(findings, options.RiskPolicyOptions{High: options.RiskLevelOptions{Kev: true}, Low: options.RiskLevelOptions{Epss: 0.001}})
+	assert.Equal(t, models.RiskNone, findings[0].Risk)
+	assert.Equal(t, models.RiskHigh, findings[1].Risk)
+	assert.Equal(t, models.RiskLow, findings[2].Risk)
+	assert.Equal(t, models.RiskHigh, risk.Rating)
+
+	risk = RatePolicy(nil, options.RiskPolicyOptions{})
+	assert.Equal(t, models.RiskNone, risk.Rating)
+}
@@ -0,0 +1,124 @@
+package vulnerability
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+)
+
+// grypeEcosystems maps the type of a grype artifact to the ecosystem of the collected packages.
+var grypeEcosystems = map[string]string{
+	"deb":          models.EcosystemDeb,
+	"rpm":          models.EcosystemRpm,
+	"apk":          models.EcosystemApk,
+	"alpm":         models.EcosystemAlpm,
+	"python":       models.EcosystemPypi,
+	"npm":          models.EcosystemNpm,
+	"gem":          models.EcosystemGem,
+	"go-module":    models.EcosystemGolang,
+	"java-archive": models.EcosystemMaven,
+}
+
+// grypeOutput is the JSON output of `grype -o json`, only the fields used are kept.
+type grypeOutput struct {
+	Matches []grypeMatch `json:"matches"`
+}
+
+type grypeMatch struct {
+	Vulnerability          grypeVulnerability   `json:"vulnerability"`
+	RelatedVulnerabilities []grypeVulnerability `json:"relatedVulnerabilities"`
+	Artifact               grypeArtifact        `json:"artifact"`
+}
+
+type grypeVulnerability struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	Fix         struct {
+		Versions []string `json:"versions"`
+		State    string   `json:"state"`
+	} `json:"fix"`
+}
+
+type grypeArtifact struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+}
+
+// GrypeScanner scans the root filesystem with Grype and matches the vulnerabilities it reports with the installed packages.
+type GrypeScanner struct {
+	Logger  *logrus.Logger
+	Options options.VulnScannerOptions
+	Root    string // Root filesystem scanned, / when empty
+}
+
+func (s *GrypeScanner) Name() string {
+	return ScannerGrype
+}
+
+// Scan runs `grype dir:<root>`, the database location and updates are configured through the environment.
+func (s *GrypeScanner) Scan(ctx context.Context, packages []*models.Package) ([]models.PackageVulnMatch, error) {
+	env := []string{}
+	if s.Options.DbPath != "" {
+		env = append(env, "GRYPE_DB_CACHE_DIR="+s.Options.DbPath)
+	}
+	if s.Options.Offline {
+		env = append(env, "GRYPE_DB_AUTO_UPDATE=false", "GRYPE_DB_VALIDATE_AGE=false", "GRYPE_CHECK_FOR_APP_UPDATE=false")
+	}
+	out, err := runExternalScanner(ctx, s.Options, "grype", env, grypeArgs(s.Options, s.Root))
+	if err != nil {
+		return nil, fmt.Errorf("unable to run grype: %w", err)
+	}
+
+	var output grypeOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		return nil, fmt.Errorf("unable to parse grype output: %w", err)
+	}
+	return matchFindings(s.Logger, packages, grypeFindings(&output)), nil
+}
+
+// grypeArgs excludes the skipped directories with globs relative to the scanned directory.
+func grypeArgs(opts options.VulnScannerOptions, root string) []string {
+	args := []string{"dir:" + scanRoot(root), "--output", "json", "--quiet"}
+	for _, dir := range skipDirs(opts) {
+		args = append(args, "--exclude", "./"+strings.Trim(dir, "/")+"/**")
+	}
+	return append(args, opts.Args...)
+}
+
+// grypeFindings converts the grype matches, vulnerabilities of other databases are reported by their related CVE.
+func grypeFindings(output *grypeOutput) []finding {
+	findings := make([]finding, 0, len(output.Matches))
+	for _, match := range output.Matches {
+		related := make([]string, 0, len(match.RelatedVulnerabilities))
+		description := match.Vulnerability.Description
+		for _, vuln := range match.RelatedVulnerabilities {
+			related = append(related, vuln.ID)
+			if description == "" {
+				description = vuln.Description
+			}
+		}
+		fixed := ""
+		if match.Vulnerability.Fix.State == "fixed" {
+			fixed = strings.Join(match.Vulnerability.Fix.Versions, ", ")
+		}
+		findings = append(findings, finding{
+			ecosystem: grypeEcosystems[match.Artifact.Type],
+			vuln: models.Vulnerability{
+				VulnerabilityID:  preferredID(match.Vulnerability.ID, related),
+				PkgName:          match.Artifact.Name,
+				InstalledVersion: match.Artifact.Version,
+				FixedVersion:     fixed,
+				Severity:         normalizeSeverity(match.Vulnerability.Severity),
+				Description:      description,
+			},
+		})
+	}
+	return findings
+}
@@ -0,0 +1,424 @@
+package vulnerability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/osrelease"
+	"github.com/klamhq/facter-oss/pkg/versions"
+	"github.com/sirupsen/logrus"
+)
+
+// OSVRecord is a vulnerability in the OSV format, see https://ossf.github.io/osv-schema/.
+// Only the fields used for matching and reporting are kept.
+type OSVRecord struct {
+	ID               string         `json:"id"`
+	Modified         string         `json:"modified,omitempty"`
+	Withdrawn        string         `json:"withdrawn,omitempty"`
+	Aliases          []string       `json:"aliases,omitempty"`
+	Summary          string         `json:"summary,omitempty"`
+	Details          string         `json:"details,omitempty"`
+	Severity         []OSVSeverity  `json:"severity,omitempty"`
+	Affected         []OSVAffected  `json:"affected"`
+	DatabaseSpecific map[string]any `json:"database_specific,omitempty"`
+}
+
+type OSVSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type OSVAffected struct {
+	Package           OSVPackage     `json:"package"`
+	Ranges            []OSVRange     `json:"ranges,omitempty"`
+	Versions          []string       `json:"versions,omitempty"`
+	EcosystemSpecific map[string]any `json:"ecosystem_specific,omitempty"`
+	DatabaseSpecific  map[string]any `json:"database_specific,omitempty"`
+}
+
+type OSVPackage struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Purl      string `json:"purl,omitempty"`
+}
+
+// OSVRange is a list of events, a version is affected from an introduced event until the next fixed
+// or last_affected one. GIT ranges are not supported, commits cannot be related to installed packages.
+type OSVRange struct {
+	Type   string     `json:"type"`
+	Events []OSVEvent `json:"events"`
+}
+
+type OSVEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// osvEcosystem relates an OSV ecosystem to the ecosystem of the packages it covers, and for
+// distributions to the ID of /etc/os-release, as their advisories only apply to their own packages.
+type osvEcosystem struct {
+	ecosystem string
+	distro    string
+}
+
+// osvEcosystems maps the OSV ecosystems, without their release qualifier, to the collected packages.
+var osvEcosystems = map[string]osvEcosystem{
+	"Debian":      {ecosystem: models.EcosystemDeb, distro: "debian"},
+	"Ubuntu":      {ecosystem: models.EcosystemDeb, distro: "ubuntu"},
+	"Alpine":      {ecosystem: models.EcosystemApk, distro: "alpine"},
+	"Wolfi":       {ecosystem: models.EcosystemApk, distro: "wolfi"},
+	"Chainguard":  {ecosystem: models.EcosystemApk, distro: "chainguard"},
+	"Red Hat":     {ecosystem: models.EcosystemRpm, distro: "rhel"},
+	"Rocky Linux": {ecosystem: models.EcosystemRpm, distro: "rocky"},
+	"AlmaLinux":   {ecosystem: models.EcosystemRpm, distro: "almalinux"},
+	"Mageia":      {ecosystem: models.EcosystemRpm, distro: "mageia"},
+	"Photon OS":   {ecosystem: models.EcosystemRpm, distro: "photon"},
+	"PyPI":        {ecosystem: models.EcosystemPypi},
+	"npm":         {ecosystem: models.EcosystemNpm},
+	"Go":          {ecosystem: models.EcosystemGolang},
+	"Maven":       {ecosystem: models.EcosystemMaven},
+	"RubyGems":    {ecosystem: models.EcosystemGem},
+}
+
+// osvSeverities maps the vendor severities found in OSV records and Grype matches to the severities reported by Trivy.
+var osvSeverities = map[string]string{
+	"critical":   "CRITICAL",
+	"important":  "HIGH",
+	"high":       "HIGH",
+	"moderate":   "MEDIUM",
+	"medium":     "MEDIUM",
+	"low":        "LOW",
+	"negligible": "LOW",
+}
+
+// pypiNameSeparatorRE matches the runs of separators ignored when comparing python distribution names (PEP 503).
+var pypiNameSeparatorRE = regexp.MustCompile(`[-_.]+`)
+
+// splitOSVEcosystem splits an OSV ecosystem such as "Debian:12" or "Alpine:v3.19" into its name and release.
+func splitOSVEcosystem(ecosystem string) (string, string) {
+	name, release, _ := strings.Cut(ecosystem, ":")
+	return name, release
+}
+
+// osvPackageName returns the name a package is stored and looked up with, python names are normalized.
+func osvPackageName(ecosystem, name string) string {
+	if ecosystem == models.EcosystemPypi {
+		return pypiNameSeparatorRE.ReplaceAllString(strings.ToLower(name), "-")
+	}
+	return name
+}
+
+// Distro identifies the distribution of the host, as described by os-release.
+type Distro struct {
+	ID        string
+	VersionID string
+}
+
+// ReadDistro reads the distribution of the system installed under root.
+// An empty Distro is returned when os-release is missing, advisories of every distribution are then used.
+func ReadDistro(root string) Distro {
+	values := osrelease.Read(root)
+	return Distro{ID: values["ID"], VersionID: values["VERSION_ID"]}
+}
+
+// covers reports whether advisories for a distribution release apply to the host.
+// Releases are written differently by each distribution ("12", "v3.19", "22.04:LTS", "enterprise_linux:9::appstream"),
+// one of their components must be the version of the host or a prefix of it, e.g. "9" for "9.3".
+func (d Distro) covers(distro, release string) bool {
+	if distro == "" || d.ID == "" {
+		return true
+	}
+	if d.ID != distro {
+		return false
+	}
+	if release == "" || d.VersionID == "" {
+		return true
+	}
+	for _, component := range strings.Split(release, ":") {
+		component = strings.TrimPrefix(component, "v")
+		if component != "" && (component == d.VersionID || strings.HasPrefix(d.VersionID, component+".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// OSVDatabase is a local vulnerability database in the OSV format, as written by ImportOSV.
+// Records are stored by OSV ecosystem and package name, <dir>/<ecosystem>/<package>.json, so that
+// matching only reads the records of the installed packages.
+type OSVDatabase struct {
+	dir string
+}
+
+// OpenOSVDatabase opens the database stored in dir.
+func OpenOSVDatabase(dir string) (*OSVDatabase, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open vulnerability database: %w", err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("vulnerability database %s is not a directory", dir)
+	}
+	return &OSVDatabase{dir: dir}, nil
+}
+
+func (db *OSVDatabase) path(osvEcosystem, name string) string {
+	return filepath.Join(db.dir, url.PathEscape(osvEcosystem), url.PathEscape(name)+".json")
+}
+
+// Records returns the records affecting a package of an OSV ecosystem, without release qualifier.
+func (db *OSVDatabase) Records(osvEcosystem, name string) ([]OSVRecord, error) {
+	data, err := os.ReadFile(db.path(osvEcosystem, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []OSVRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", db.path(osvEcosystem, name), err)
+	}
+	return records, nil
+}
+
+// BuiltinScanner matches the packages against a local OSV database, without any external scanner.
+type BuiltinScanner struct {
+	Logger   *logrus.Logger
+	Database string
+	Root     string // Root of the system whose os-release selects the distribution advisories
+}
+
+func (s *BuiltinScanner) Name() string {
+	return ScannerBuiltin
+}
+
+func (s *BuiltinScanner) Scan(ctx context.Context, packages []*models.Package) ([]models.PackageVulnMatch, error) {
+	db, err := OpenOSVDatabase(s.Database)
+	if err != nil {
+		return nil, err
+	}
+	return MatchOSV(ctx, s.Logger, db, ReadDistro(s.Root), packages)
+}
+
+// MatchOSV matches the installed packages against the records of the database applying to the distribution.
+// Distribution packages are also looked up by source package, distributions publish advisories for those.
+func MatchOSV(ctx context.Context, logger *logrus.Logger, db *OSVDatabase, distro Distro, packages []*models.Package) ([]models.PackageVulnMatch, error) {
+	matches := []models.PackageVulnMatch{}
+	for _, pkg := range packages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vulns, err := matchPackage(db, distro, pkg)
+		if err != nil {
+			logger.WithError(err).Warnf("unable to match vulnerabilities of %s", pkg.Name)
+			continue
+		}
+		if len(vulns) == 0 {
+			continue
+		}
+		logger.Debugf("%d vulnerabilities matched for %s %s", len(vulns), pkg.Name, pkg.Version)
+		matches = append(matches, models.PackageVulnMatch{
+			PackageName:      pkg.Name,
+			InstalledVersion: pkg.Version,
+			Ecosystem:        pkg.Ecosystem,
+			Vulnerabilities:  vulns,
+			Matched:          true,
+			Package:          pkg,
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].PackageName < matches[j].PackageName })
+	return matches, nil
+}
+
+// matchPackage returns the vulnerabilities affecting the installed version of a package, once per identifier.
+func matchPackage(db *OSVDatabase, distro Distro, pkg *models.Package) ([]models.MatchedVuln, error) {
+	names := []string{osvPackageName(pkg.Ecosystem, pkg.Name)}
+	if pkg.SourcePackage != "" && pkg.SourcePackage != pkg.Name {
+		names = append(names, pkg.SourcePackage)
+	}
+
+	vulns := []models.MatchedVuln{}
+	seen := map[string]bool{}
+	for _, osvName := range sortedKeys(osvEcosystems) {
+		eco := osvEcosystems[osvName]
+		if eco.ecosystem != pkg.Ecosystem || !distro.covers(eco.distro, "") {
+			continue
+		}
+		for _, name := range names {
+			records, err := db.Records(osvName, name)
+			if err != nil {
+				return nil, err
+			}
+			for _, record := range records {
+				if record.Withdrawn != "" || seen[record.ID] {
+					continue
+				}
+				fixed, affected := recordAffects(record, osvName, name, distro, pkg)
+				if !affected {
+					continue
+				}
+				id := preferredID(record.ID, record.Aliases)
+				if seen[id] {
+					continue
+				}
+				seen[record.ID], seen[id] = true, true
+				for _, alias := range record.Aliases {
+					seen[alias] = true
+				}
+				vulns = append(vulns, models.MatchedVuln{
+					VulnerabilityId: id,
+					Severity:        recordSeverity(record),
+					Title:           record.Summary,
+					Description:     record.Details,
+					FixedVersion:    fixed,
+				})
+			}
+		}
+	}
+	return vulns, nil
+}
+
+// recordAffects reports whether a record affects the installed version of the package, and the versions fixing it.
+func recordAffects(record OSVRecord, osvName, name string, distro Distro, pkg *models.Package) (string, bool) {
+	for _, affected := range record.Affected {
+		ecosystem, release := splitOSVEcosystem(affected.Package.Ecosystem)
+		if ecosystem != osvName || osvPackageName(pkg.Ecosystem, affected.Package.Name) != name {
+			continue
+		}
+		if !distro.covers(osvEcosystems[ecosystem].distro, release) {
+			continue
+		}
+		if fixed, ok := versionAffected(pkg.Ecosystem, pkg.Version, affected); ok {
+			return fixed, true
+		}
+	}
+	return "", false
+}
+
+// versionAffected evaluates the explicit versions and the ranges of an affected package.
+// The fixed versions of the matching ranges are returned, comma separated.
+func versionAffected(ecosystem, version string, affected OSVAffected) (string, bool) {
+	for _, v := range affected.Versions {
+		if v == version {
+			return "", true
+		}
+	}
+
+	fixed := []string{}
+	found := false
+	for _, r := range affected.Ranges {
+		var compare func(a, b string) int
+		switch r.Type {
+		case "ECOSYSTEM":
+			compare = func(a, b string) int { return versions.Compare(ecosystem, a, b) }
+		case "SEMVER":
+			compare = func(a, b string) int {
+				return versions.CompareSemver(strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v"))
+			}
+		default:
+			continue
+		}
+		if rangeAffects(compare, version, r.Events) {
+			found = true
+			for _, event := range r.Events {
+				if event.Fixed != "" {
+					fixed = append(fixed, event.Fixed)
+				}
+			}
+		}
+	}
+	return strings.Join(fixed, ", "), found
+}
+
+// rangeAffects walks the events of a range in version order, as described by the OSV schema.
+// The "0" introduced version stands for any version.
+func rangeAffects(compare func(a, b string) int, version string, events []OSVEvent) bool {
+	eventVersion := func(e OSVEvent) string {
+		switch {
+		case e.Introduced != "":
+			return e.Introduced
+		case e.Fixed != "":
+			return e.Fixed
+		case e.LastAffected != "":
+			return e.LastAffected
+		}
+		return e.Limit
+	}
+	sorted := append([]OSVEvent{}, events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := eventVersion(sorted[i]), eventVersion(sorted[j])
+		if a == "0" || b == "0" {
+			return a == "0" && b != "0"
+		}
+		return compare(a, b) < 0
+	})
+
+	affected := false
+	for _, event := range sorted {
+		switch {
+		case event.Introduced != "":
+			if event.Introduced == "0" || compare(version, event.Introduced) >= 0 {
+				affected = true
+			}
+		case event.Fixed != "":
+			if compare(version, event.Fixed) >= 0 {
+				affected = false
+			}
+		case event.LastAffected != "":
+			if compare(version, event.LastAffected) > 0 {
+				affected = false
+			}
+		case event.Limit != "":
+			if event.Limit != "*" && compare(version, event.Limit) >= 0 {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
+
+// recordSeverity returns the vendor severity of a record, found in the database or ecosystem specific
+// fields depending on the publisher, or in an Ubuntu severity entry. CVSS vectors are not scored.
+func recordSeverity(record OSVRecord) string {
+	candidates := []any{record.DatabaseSpecific["severity"]}
+	for _, affected := range record.Affected {
+		candidates = append(candidates, affected.EcosystemSpecific["severity"], affected.DatabaseSpecific["severity"])
+	}
+	for _, severity := range record.Severity {
+		if severity.Type == "Ubuntu" {
+			candidates = append(candidates, severity.Score)
+		}
+	}
+	for _, candidate := range candidates {
+		value, ok := candidate.(string)
+		if !ok {
+			continue
+		}
+		if severity, ok := osvSeverities[strings.ToLower(value)]; ok {
+			return severity
+		}
+	}
+	return "UNKNOWN"
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,215 @@
+package vulnerability
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportStats counts the records read by ImportOSV.
+type ImportStats struct {
+	Imported int // Records stored in the database
+	Skipped  int // Records without any affected package of a supported ecosystem
+}
+
+// ImportOSV loads OSV records into the database stored in dir, creating it when needed.
+// Sources are JSON files holding one record or an array of records, zip archives of such files
+// as published by osv.dev, or directories containing either. Records already in the database
+// are replaced by the imported ones with the same ID, all records are dropped first when replace is set.
+// Withdrawn records are stored too, so that a refresh withdraws them, and ignored by MatchOSV.
+func ImportOSV(dir string, sources []string, replace bool) (*ImportStats, error) {
+	stats := &ImportStats{}
+	records := map[string]OSVRecord{}
+	add := func(name string, data []byte) error {
+		parsed, err := parseOSVRecords(data)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s: %w", name, err)
+		}
+		for _, record := range parsed {
+			records[record.ID] = record
+		}
+		return nil
+	}
+	for _, source := range sources {
+		if err := readOSVSource(source, add); err != nil {
+			return nil, err
+		}
+	}
+
+	// Group the records by the packages they affect
+	groups := map[string]map[string]OSVRecord{}
+	for _, id := range sortedKeys(records) {
+		record := records[id]
+		stored := false
+		for _, affected := range record.Affected {
+			ecosystem, _ := splitOSVEcosystem(affected.Package.Ecosystem)
+			eco, supported := osvEcosystems[ecosystem]
+			if !supported || affected.Package.Name == "" {
+				continue
+			}
+			key := filepath.Join(url.PathEscape(ecosystem), url.PathEscape(osvPackageName(eco.ecosystem, affected.Package.Name))+".json")
+			if groups[key] == nil {
+				groups[key] = map[string]OSVRecord{}
+			}
+			groups[key][record.ID] = record
+			stored = true
+		}
+		if stored {
+			stats.Imported++
+		} else {
+			stats.Skipped++
+		}
+	}
+
+	if replace {
+		for ecosystem := range osvEcosystems {
+			if err := os.RemoveAll(filepath.Join(dir, url.PathEscape(ecosystem))); err != nil {
+				return nil, fmt.Errorf("unable to clear vulnerability database: %w", err)
+			}
+		}
+	}
+	for _, key := range sortedKeys(groups) {
+		if err := mergeOSVFile(filepath.Join(dir, key), groups[key]); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// readOSVSource calls add with the content of each JSON file of a source.
+func readOSVSource(source string, add func(name string, data []byte) error) error {
+	fi, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", source, err)
+	}
+	if !fi.IsDir() {
+		return readOSVFile(source, add)
+	}
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isOSVFile(path) {
+			return nil
+		}
+		return readOSVFile(path, add)
+	})
+}
+
+func isOSVFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".json" || ext == ".zip"
+}
+
+func readOSVFile(path string, add func(name string, data []byte) error) error {
+	if strings.ToLower(filepath.Ext(path)) != ".zip" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", path, err)
+		}
+		return add(path, data)
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", path, err)
+	}
+	defer archive.Close()
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || strings.ToLower(filepath.Ext(file.Name)) != ".json" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("unable to read %s in %s: %w", file.Name, path, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("unable to read %s in %s: %w", file.Name, path, err)
+		}
+		if err := add(path+"/"+file.Name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseOSVRecords parses a single record or an array of records, every record must have an ID.
+func parseOSVRecords(data []byte) ([]OSVRecord, error) {
+	data = bytes.TrimSpace(data)
+	var records []OSVRecord
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, err
+		}
+	} else {
+		var record OSVRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	for _, record := range records {
+		if record.ID == "" {
+			return nil, errors.New("record without id")
+		}
+	}
+	return records, nil
+}
+
+// mergeOSVFile adds records to a package file of the database, the file is replaced atomically.
+func mergeOSVFile(path string, records map[string]OSVRecord) error {
+	merged := map[string]OSVRecord{}
+	if data, err := os.ReadFile(path); err == nil {
+		var existing []OSVRecord
+		if err := json.Unmarshal(data, &existing); err != nil {
+			return fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+		for _, record := range existing {
+			merged[record.ID] = record
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	for id, record := range records {
+		merged[id] = record
+	}
+
+	list := make([]OSVRecord, 0, len(merged))
+	for _, id := range sortedKeys(merged) {
+		list = append(list, merged[id])
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("unable to create vulnerability database: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".import-*")
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
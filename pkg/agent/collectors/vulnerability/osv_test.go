@@ -0,0 +1,194 @@
+package vulnerability
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/versions"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func importTestdata(t *testing.T) *OSVDatabase {
+	dir := filepath.Join(t.TempDir(), "vulndb")
+	stats, err := ImportOSV(dir, []string{"testdata/osv"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, stats.Imported)
+	assert.Equal(t, 1, stats.Skipped)
+
+	db, err := OpenOSVDatabase(dir)
+	assert.NoError(t, err)
+	return db
+}
+
+func TestMatchOSV(t *testing.T) {
+	db := importTestdata(t)
+	pkgs := []*models.Package{
+		{Name: "openssl", Version: "3.0.11-1~deb12u2", Ecosystem: models.EcosystemDeb},
+		{Name: "libssl3", Version: "3.0.11-1~deb12u2", SourcePackage: "openssl", Ecosystem: models.EcosystemDeb},
+		{Name: "Requests", Version: "2.28.1", Ecosystem: models.EcosystemPypi},
+		{Name: "lodash", Version: "4.17.20", Ecosystem: models.EcosystemNpm},
+		{Name: "bash", Version: "5.2.15-2+b2", Ecosystem: models.EcosystemDeb},
+	}
+
+	matches, err := MatchOSV(context.Background(), logrus.New(), db, Distro{ID: "debian", VersionID: "12"}, pkgs)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 4)
+
+	assert.Equal(t, "Requests", matches[0].PackageName)
+	assert.Equal(t, []models.MatchedVuln{{
+		VulnerabilityId: "CVE-2023-32681",
+		Severity:        "MEDIUM",
+		Title:           "Unintended leak of Proxy-Authorization header in requests",
+		Description:     "Requests is leaking Proxy-Authorization headers to destination servers when redirected to an HTTPS endpoint.",
+		FixedVersion:    "2.31.0",
+	}}, matches[0].Vulnerabilities)
+
+	assert.Equal(t, "libssl3", matches[1].PackageName)
+	assert.Equal(t, "lodash", matches[2].PackageName)
+	assert.Equal(t, "CVE-2020-28500", matches[2].Vulnerabilities[0].VulnerabilityId)
+
+	assert.Equal(t, "openssl", matches[3].PackageName)
+	assert.True(t, matches[3].Matched)
+	assert.Equal(t, "3.0.11-1~deb12u2", matches[3].InstalledVersion)
+	assert.Len(t, matches[3].Vulnerabilities, 1)
+	assert.Equal(t, "CVE-2024-0727", matches[3].Vulnerabilities[0].VulnerabilityId)
+	assert.Equal(t, "3.0.13-1~deb12u1", matches[3].Vulnerabilities[0].FixedVersion)
+	assert.Equal(t, "UNKNOWN", matches[3].Vulnerabilities[0].Severity)
+}
+
+func TestMatchOSV_Fixed(t *testing.T) {
+	db := importTestdata(t)
+	pkgs := []*models.Package{
+		{Name: "openssl", Version: "3.0.13-1~deb12u1", Ecosystem: models.EcosystemDeb},
+		{Name: "requests", Version: "2.31.0", Ecosystem: models.EcosystemPypi},
+		{Name: "lodash", Version: "4.17.21", Ecosystem: models.EcosystemNpm},
+	}
+
+	matches, err := MatchOSV(context.Background(), logrus.New(), db, Distro{ID: "debian", VersionID: "12"}, pkgs)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestMatchOSV_OtherDistribution(t *testing.T) {
+	db := importTestdata(t)
+	pkgs := []*models.Package{{Name: "openssl", Version: "3.0.2-0ubuntu1.10", Ecosystem: models.EcosystemDeb}}
+
+	matches, err := MatchOSV(context.Background(), logrus.New(), db, Distro{ID: "ubuntu", VersionID: "22.04"}, pkgs)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "CVE-2024-0727", matches[0].Vulnerabilities[0].VulnerabilityId)
+	assert.Equal(t, "3.0.2-0ubuntu1.14", matches[0].Vulnerabilities[0].FixedVersion)
+	assert.Equal(t, "LOW", matches[0].Vulnerabilities[0].Severity)
+
+	matches, err = MatchOSV(context.Background(), logrus.New(), db, Distro{ID: "ubuntu", VersionID: "24.04"}, pkgs)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestImportOSV_ZipAndRefresh(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "all.zip")
+	f, err := os.Create(archive)
+	assert.NoError(t, err)
+	w := zip.NewWriter(f)
+	entry, err := w.Create("GHSA-29mw-wpgm-hmr9.json")
+	assert.NoError(t, err)
+	_, err = entry.Write([]byte(`{"id": "GHSA-29mw-wpgm-hmr9", "withdrawn": "2024-03-01T00:00:00Z",
+		"affected": [{"package": {"ecosystem": "npm", "name": "lodash"}, "versions": ["4.17.20"]}]}`))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.NoError(t, f.Close())
+
+	db := importTestdata(t)
+	pkgs := []*models.Package{{Name: "lodash", Version: "4.17.20", Ecosystem: models.EcosystemNpm}}
+	matches, err := MatchOSV(context.Background(), logrus.New(), db, Distro{}, pkgs)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	// The refreshed record is withdrawn
+	stats, err := ImportOSV(db.dir, []string{archive}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Imported)
+	matches, err = MatchOSV(context.Background(), logrus.New(), db, Distro{}, pkgs)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+	records, err := db.Records("PyPI", "requests")
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	_, err = ImportOSV(db.dir, []string{archive}, true)
+	assert.NoError(t, err)
+	records, err = db.Records("PyPI", "requests")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestImportOSV_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	assert.NoError(t, os.WriteFile(invalid, []byte(`{"summary": "no id"}`), 0o644))
+
+	_, err := ImportOSV(filepath.Join(dir, "vulndb"), []string{invalid}, false)
+	assert.Error(t, err)
+	_, err = ImportOSV(filepath.Join(dir, "vulndb"), []string{filepath.Join(dir, "missing.json")}, false)
+	assert.Error(t, err)
+}
+
+func TestRangeAffects(t *testing.T) {
+	compare := func(a, b string) int { return versions.CompareDpkg(a, b) }
+	tests := []struct {
+		name    string
+		version string
+		events  []OSVEvent
+		want    bool
+	}{
+		{"any version", "1.0", []OSVEvent{{Introduced: "0"}}, true},
+		{"below fixed", "1:1.2-1", []OSVEvent{{Introduced: "0"}, {Fixed: "1:1.3-1"}}, true},
+		{"fixed", "1:1.3-1", []OSVEvent{{Introduced: "0"}, {Fixed: "1:1.3-1"}}, false},
+		{"epoch wins", "1.9", []OSVEvent{{Introduced: "0"}, {Fixed: "1:1.3-1"}}, true},
+		{"before introduced", "1.0", []OSVEvent{{Introduced: "1.1"}, {Fixed: "1.3"}}, false},
+		{"last affected", "1.3", []OSVEvent{{Introduced: "1.1"}, {LastAffected: "1.3"}}, true},
+		{"after last affected", "1.4", []OSVEvent{{Introduced: "1.1"}, {LastAffected: "1.3"}}, false},
+		{"second branch", "2.1", []OSVEvent{{Fixed: "1.3"}, {Introduced: "2.0"}, {Introduced: "1.0"}, {Fixed: "2.2"}}, true},
+		{"between branches", "1.5", []OSVEvent{{Introduced: "1.0"}, {Fixed: "1.3"}, {Introduced: "2.0"}, {Fixed: "2.2"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rangeAffects(compare, tt.version, tt.events))
+		})
+	}
+}
+
+func TestDistroCovers(t *testing.T) {
+	tests := []struct {
+		distro  Distro
+		name    string
+		release string
+		want    bool
+	}{
+		{Distro{ID: "debian", VersionID: "12"}, "debian", "12", true},
+		{Distro{ID: "debian", VersionID: "12"}, "debian", "11", false},
+		{Distro{ID: "debian", VersionID: "12"}, "ubuntu", "12", false},
+		{Distro{ID: "alpine", VersionID: "3.19.1"}, "alpine", "v3.19", true},
+		{Distro{ID: "alpine", VersionID: "3.19.1"}, "alpine", "v3.1", false},
+		{Distro{ID: "ubuntu", VersionID: "22.04"}, "ubuntu", "Pro:22.04:LTS", true},
+		{Distro{ID: "rocky", VersionID: "9.3"}, "rocky", "9", true},
+		{Distro{ID: "rhel", VersionID: "9.4"}, "rhel", "enterprise_linux:9::appstream", true},
+		{Distro{ID: "debian"}, "debian", "12", true},
+		{Distro{}, "debian", "12", true},
+		{Distro{ID: "debian", VersionID: "12"}, "", "", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.distro.covers(tt.name, tt.release), "%v %s:%s", tt.distro, tt.name, tt.release)
+	}
+}
+
+func TestReadDistro(t *testing.T) {
+	assert.Equal(t, Distro{ID: "debian", VersionID: "12"}, ReadDistro("testdata/root"))
+	assert.Equal(t, Distro{}, ReadDistro("testdata/missing"))
+}
@@ -0,0 +1,79 @@
+package vulnerability
+
+import (
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+)
+
+// DefaultRiskPolicy is used when the configuration sets no risk level.
+var DefaultRiskPolicy = options.RiskPolicyOptions{
+	Critical: options.RiskLevelOptions{Kev: true, Cvss: 9.0},
+	High:     options.RiskLevelOptions{Cvss: 7.0, Epss: 0.5},
+	Medium:   options.RiskLevelOptions{Cvss: 4.0, Epss: 0.1},
+	Low:      options.RiskLevelOptions{Cvss: 0.1},
+}
+
+// severityRisks rates the vulnerabilities without any CVSS score from the severity of the scanner.
+var severityRisks = map[string]string{
+	"CRITICAL": models.RiskCritical,
+	"HIGH":     models.RiskHigh,
+	"MEDIUM":   models.RiskMedium,
+	"LOW":      models.RiskLow,
+}
+
+// RatePolicy rates each finding with the policy and returns the risk of the host, the highest of its findings.
+func RatePolicy(findings []*models.VulnerabilityFinding, policy options.RiskPolicyOptions) *models.HostRisk {
+	if policy.Critical == (options.RiskLevelOptions{}) && policy.High == (options.RiskLevelOptions{}) &&
+		policy.Medium == (options.RiskLevelOptions{}) && policy.Low == (options.RiskLevelOptions{}) {
+		policy.Critical, policy.High, policy.Medium, policy.Low = DefaultRiskPolicy.Critical, DefaultRiskPolicy.High, DefaultRiskPolicy.Medium, DefaultRiskPolicy.Low
+	}
+	levels := []struct {
+		rating string
+		level  options.RiskLevelOptions
+	}{
+		{models.RiskCritical, policy.Critical},
+		{models.RiskHigh, policy.High},
+		{models.RiskMedium, policy.Medium},
+		{models.RiskLow, policy.Low},
+	}
+
+	risk := &models.HostRisk{Rating: models.RiskNone, Counts: map[string]int{}}
+	for _, finding := range findings {
+		finding.Risk = models.RiskNone
+		for _, l := range levels {
+			if meets(finding, l.level) {
+				finding.Risk = l.rating
+				break
+			}
+		}
+		// Without score, the severity of the scanner is the only information
+		if finding.Risk == models.RiskNone && finding.CVSSv3Score == 0 && finding.CVSSv4Score == 0 {
+			if rating, ok := severityRisks[finding.Severity]; ok {
+				finding.Risk = rating
+			}
+		}
+
+		risk.Counts[finding.Risk]++
+		if finding.KnownExploited {
+			risk.KnownExploited++
+		}
+		if finding.Reachability == models.ReachabilityExposed {
+			risk.Exposed++
+		}
+		if models.RiskAtLeast(finding.Risk, risk.Rating) {
+			risk.Rating = finding.Risk
+		}
+	}
+	return risk
+}
+
+// meets reports whether a finding meets any condition set for a level.
+func meets(finding *models.VulnerabilityFinding, level options.RiskLevelOptions) bool {
+	score := finding.CVSSv4Score
+	if score == 0 {
+		score = finding.CVSSv3Score
+	}
+	return (level.Kev && finding.KnownExploited) ||
+		(level.Cvss > 0 && score >= level.Cvss) ||
+		(level.Epss > 0 && finding.EPSS >= level.Epss)
+}
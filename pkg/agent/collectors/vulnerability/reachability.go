@@ -0,0 +1,137 @@
+package vulnerability
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+)
+
+// reachabilityOrder ranks the reachability of a package, the highest one of its processes is kept.
+var reachabilityOrder = map[string]int{
+	models.ReachabilityNotRunning: 0,
+	models.ReachabilityRunning:    1,
+	models.ReachabilityListening:  2,
+	models.ReachabilityExposed:    3,
+}
+
+// ownedEcosystems are the ecosystems of the packages the executables of the processes are looked up in.
+var ownedEcosystems = map[string]bool{
+	models.EcosystemDeb:  true,
+	models.EcosystemRpm:  true,
+	models.EcosystemApk:  true,
+	models.EcosystemAlpm: true,
+}
+
+// AssessReachability sets the reachability of the findings from the packages of the running processes and
+// of the processes listening on a socket, a socket bound to a wildcard address being exposed when the host
+// has a non-loopback address. Without any process nor connection collected, the reachability is left unknown, as
+// it is for the findings of the other ecosystems, e.g. a python library run by the interpreter of another package.
+func AssessReachability(findings []*models.VulnerabilityFinding, processes []*schema.Process, network *schema.Network) {
+	connections := network.GetConnections()
+	if len(processes) == 0 && len(connections) == 0 {
+		return
+	}
+
+	reachability := map[string]string{}
+	listening := map[string]map[string]bool{}
+	raise := func(pkg, level string) {
+		if current, ok := reachability[pkg]; !ok || reachabilityOrder[level] > reachabilityOrder[current] {
+			reachability[pkg] = level
+		}
+	}
+
+	pidPackages := map[int64]string{}
+	for _, process := range processes {
+		if pkg := processPackage(process); pkg != "" {
+			pidPackages[process.GetPid()] = pkg
+			raise(pkg, models.ReachabilityRunning)
+		}
+	}
+
+	routable := hasRoutableAddress(network.GetInterfaces())
+	for _, conn := range connections {
+		if conn.GetState() != schema.State_STATE_LISTENING {
+			continue
+		}
+		pkg := processPackage(conn.GetProcess())
+		if pkg == "" {
+			pkg = pidPackages[conn.GetProcess().GetPid()]
+		}
+		if pkg == "" {
+			continue
+		}
+		addr := conn.GetLocal().GetIp().GetAddr()
+		if exposedAddress(addr, routable) {
+			raise(pkg, models.ReachabilityExposed)
+		} else {
+			raise(pkg, models.ReachabilityListening)
+		}
+		if listening[pkg] == nil {
+			listening[pkg] = map[string]bool{}
+		}
+		listening[pkg][socketName(conn.GetProtocol(), addr, conn.GetLocal().GetPort())] = true
+	}
+
+	for _, finding := range findings {
+		if finding.Ecosystem != "" && !ownedEcosystems[finding.Ecosystem] {
+			continue
+		}
+		finding.Reachability = models.ReachabilityNotRunning
+		if level, ok := reachability[finding.Package]; ok {
+			finding.Reachability = level
+		}
+		if sockets := listening[finding.Package]; len(sockets) > 0 {
+			finding.Listening = sortedKeys(sockets)
+		}
+	}
+}
+
+// processPackage returns the package of the executable of a process, empty when it is unknown.
+func processPackage(process *schema.Process) string {
+	name := process.GetPackage().GetName()
+	if name == "unknown" {
+		return ""
+	}
+	return name
+}
+
+// exposedAddress reports whether a socket bound to the address can be reached from another host.
+func exposedAddress(addr string, routable bool) bool {
+	if addr == "" || addr == "*" {
+		return routable
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return true
+	}
+	if ip.IsUnspecified() {
+		return routable
+	}
+	return !ip.IsLoopback()
+}
+
+// hasRoutableAddress reports whether an interface has a non-loopback address, or whether the interfaces are unknown.
+func hasRoutableAddress(interfaces []*schema.Interface) bool {
+	if len(interfaces) == 0 {
+		return true
+	}
+	for _, iface := range interfaces {
+		for _, ip := range iface.GetIps() {
+			if parsed := net.ParseIP(ip.GetAddr()); parsed != nil && !parsed.IsLoopback() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func socketName(protocol schema.Protocol, addr string, port uint32) string {
+	proto := strings.ToLower(strings.TrimPrefix(protocol.String(), "PROTOCOL_"))
+	if addr == "" {
+		addr = "*"
+	}
+	return proto + "/" + net.JoinHostPort(addr, strconv.FormatUint(uint64(port), 10))
+}
@@ -0,0 +1,87 @@
+package vulnerability
+
+import (
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func listeningConn(pid int64, pkg, addr string, port uint32) *schema.ConnectionState {
+	return &schema.ConnectionState{
+		Protocol: schema.Protocol_PROTOCOL_TCP,
+		State:    schema.State_STATE_LISTENING,
+		Local:    &schema.IpPort{Ip: &schema.Ip{Addr: addr}, Port: port},
+		Process:  &schema.Process{Pid: pid, Package: &schema.Package{Name: pkg}},
+	}
+}
+
+func TestAssessReachability(t *testing.T) {
+	findings := []*models.VulnerabilityFinding{
+		{ID: "CVE-2024-6387", Package: "openssh-server"},
+		{ID: "CVE-2024-0727", Package: "openssl"},
+		{ID: "CVE-2023-44487", Package: "nginx"},
+		{ID: "CVE-2024-24790", Package: "postgresql-15"},
+		{ID: "CVE-2024-3094", Package: "liblzma5"},
+	}
+	processes := []*schema.Process{
+		{Pid: 812, Name: "sshd", Package: &schema.Package{Name: "openssh-server"}},
+		{Pid: 990, Name: "nginx", Package: &schema.Package{Name: "nginx"}},
+		{Pid: 1204, Name: "postgres", Package: &schema.Package{Name: "postgresql-15"}},
+		{Pid: 1500, Name: "openssl", Package: &schema.Package{Name: "openssl"}},
+		{Pid: 1, Name: "init", Package: &schema.Package{Name: "unknown"}},
+	}
+	network := &schema.Network{
+		Interfaces: []*schema.Interface{
+			{Name: "lo", Ips: []*schema.Ip{{Addr: "127.0.0.1"}, {Addr: "::1"}}},
+			{Name: "eth0", Ips: []*schema.Ip{{Addr: "192.168.1.20"}}},
+		},
+		Connections: []*schema.ConnectionState{
+			listeningConn(812, "openssh-server", "0.0.0.0", 22),
+			listeningConn(812, "openssh-server", "::", 22),
+			// Package unknown by the connection collector, found from the process
+			listeningConn(990, "", "127.0.0.1", 8080),
+			listeningConn(1204, "postgresql-15", "::1", 5432),
+			listeningConn(1, "unknown", "0.0.0.0", 111),
+			{
+				Protocol: schema.Protocol_PROTOCOL_TCP,
+				State:    schema.State_STATE_ESTABLISHED,
+				Local:    &schema.IpPort{Ip: &schema.Ip{Addr: "192.168.1.20"}, Port: 443},
+				Process:  &schema.Process{Pid: 1500, Package: &schema.Package{Name: "openssl"}},
+			},
+		},
+	}
+
+	AssessReachability(findings, processes, network)
+	assert.Equal(t, models.ReachabilityExposed, findings[0].Reachability)
+	assert.Equal(t, []string{"tcp/0.0.0.0:22", "tcp/[::]:22"}, findings[0].Listening)
+	assert.Equal(t, models.ReachabilityRunning, findings[1].Reachability)
+	assert.Nil(t, findings[1].Listening)
+	assert.Equal(t, models.ReachabilityListening, findings[2].Reachability)
+	assert.Equal(t, []string{"tcp/127.0.0.1:8080"}, findings[2].Listening)
+	assert.Equal(t, models.ReachabilityListening, findings[3].Reachability)
+	assert.Equal(t, models.ReachabilityNotRunning, findings[4].Reachability)
+
+	risk := RatePolicy(findings, options.RiskPolicyOptions{})
+	assert.Equal(t, 1, risk.Exposed)
+
+	// A wildcard address is local only on a host without any other address
+	network.Interfaces = network.Interfaces[:1]
+	AssessReachability(findings, processes, network)
+	assert.Equal(t, models.ReachabilityListening, findings[0].Reachability)
+
+	unknown := []*models.VulnerabilityFinding{{ID: "CVE-2024-6387", Package: "openssh-server"}}
+	AssessReachability(unknown, nil, nil)
+	assert.Equal(t, "", unknown[0].Reachability)
+
+	// The python library is not the deb package of the same name run by a process
+	requests := []*models.VulnerabilityFinding{
+		{ID: "CVE-2023-32681", Package: "requests", Ecosystem: models.EcosystemPypi},
+		{ID: "CVE-2023-32681", Package: "requests", Ecosystem: models.EcosystemDeb},
+	}
+	AssessReachability(requests, []*schema.Process{{Pid: 42, Package: &schema.Package{Name: "requests"}}}, nil)
+	assert.Equal(t, "", requests[0].Reachability)
+	assert.Equal(t, models.ReachabilityRunning, requests[1].Reachability)
+}
@@ -0,0 +1,249 @@
+package vulnerability
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+)
+
+// Vulnerability scanner backends.
+const (
+	ScannerTrivy   = "trivy"
+	ScannerGrype   = "grype"
+	ScannerBuiltin = "builtin"
+)
+
+// Strategies combining the results of several scanners.
+const (
+	StrategyPrefer = "prefer" // Results of the first scanner succeeding
+	StrategyMerge  = "merge"  // Union of the results of every scanner succeeding
+)
+
+// defaultSkipDirs are the directories not scanned when the configuration lists none.
+var defaultSkipDirs = []string{"/run"}
+
+// Scanner finds the vulnerabilities of the installed packages.
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, packages []*models.Package) ([]models.PackageVulnMatch, error)
+}
+
+// NewScanners returns the configured scanners in order of preference. Without any configured,
+// the builtin scanner is used when a database is set, and Trivy otherwise.
+func NewScanners(logger *logrus.Logger, cfg *options.VulnerabilitiesOptions) ([]Scanner, error) {
+	return NewScannersForRoot(logger, cfg, "/")
+}
+
+// NewScannersForRoot returns the configured scanners of the filesystem tree under root, such as an unpacked image.
+func NewScannersForRoot(logger *logrus.Logger, cfg *options.VulnerabilitiesOptions, root string) ([]Scanner, error) {
+	names := cfg.Scanners
+	if len(names) == 0 {
+		names = []string{ScannerTrivy}
+		if cfg.Database != "" {
+			names = []string{ScannerBuiltin}
+		}
+	}
+
+	scanners := make([]Scanner, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case ScannerTrivy:
+			scanners = append(scanners, &TrivyScanner{Logger: logger, Options: cfg.Trivy, Root: root})
+		case ScannerGrype:
+			scanners = append(scanners, &GrypeScanner{Logger: logger, Options: cfg.Grype, Root: root})
+		case ScannerBuiltin:
+			if cfg.Database == "" {
+				return nil, fmt.Errorf("builtin vulnerability scanner requires a database")
+			}
+			scanners = append(scanners, &BuiltinScanner{Logger: logger, Database: cfg.Database, Root: root})
+		default:
+			return nil, fmt.Errorf("unknown vulnerability scanner %q", name)
+		}
+	}
+	return scanners, nil
+}
+
+// RunScanners runs the scanners following the strategy, prefer by default.
+// An error is returned when no scanner succeeded.
+func RunScanners(ctx context.Context, logger *logrus.Logger, scanners []Scanner, strategy string, packages []*models.Package) ([]models.PackageVulnMatch, error) {
+	if strategy == "" {
+		strategy = StrategyPrefer
+	}
+	if strategy != StrategyPrefer && strategy != StrategyMerge {
+		return nil, fmt.Errorf("unknown vulnerability scanner strategy %q", strategy)
+	}
+
+	var errs []error
+	var results [][]models.PackageVulnMatch
+	for _, scanner := range scanners {
+		start := time.Now()
+		matches, err := scanner.Scan(ctx, packages)
+		if err != nil {
+			logger.WithError(err).Warnf("%s vulnerability scan failed", scanner.Name())
+			errs = append(errs, fmt.Errorf("%s: %w", scanner.Name(), err))
+			continue
+		}
+		logger.WithField("duration", time.Since(start)).Debugf("%d vulnerable packages found by %s", len(matches), scanner.Name())
+		if strategy == StrategyPrefer {
+			return matches, nil
+		}
+		results = append(results, matches)
+	}
+	if len(results) == 0 {
+		if len(errs) == 0 {
+			return nil, errors.New("no vulnerability scanner configured")
+		}
+		return nil, errors.Join(errs...)
+	}
+	return MergeMatches(results...), nil
+}
+
+// MergeMatches merges the results of several scanners, a vulnerability found for a package by more
+// than one scanner is reported once, with the details of the first result completed by the next ones.
+// The packages are identified by the collected package they were reported for, as scanners do not
+// report versions the same way, e.g. without epoch.
+func MergeMatches(results ...[]models.PackageVulnMatch) []models.PackageVulnMatch {
+	merged := []models.PackageVulnMatch{}
+	packages := map[string]int{}
+	for _, matches := range results {
+		for _, match := range matches {
+			key := match.Ecosystem + "/" + match.PackageName + "@" + match.InstalledVersion
+			if match.Package != nil {
+				key = match.Package.Key()
+			}
+			i, ok := packages[key]
+			if !ok {
+				packages[key] = len(merged)
+				merged = append(merged, models.PackageVulnMatch{
+					PackageName:      match.PackageName,
+					InstalledVersion: match.InstalledVersion,
+					Ecosystem:        match.Ecosystem,
+					Vulnerabilities:  []models.MatchedVuln{},
+					Package:          match.Package,
+				})
+				i = len(merged) - 1
+			}
+			merged[i].Matched = merged[i].Matched || match.Matched
+			merged[i].Vulnerabilities = mergeVulns(merged[i].Vulnerabilities, match.Vulnerabilities)
+		}
+	}
+	return merged
+}
+
+func mergeVulns(vulns, others []models.MatchedVuln) []models.MatchedVuln {
+	for _, other := range others {
+		known := false
+		for i := range vulns {
+			if vulns[i].VulnerabilityId != other.VulnerabilityId {
+				continue
+			}
+			known = true
+			if vulns[i].Severity == "" || vulns[i].Severity == "UNKNOWN" {
+				vulns[i].Severity = other.Severity
+			}
+			if vulns[i].Title == "" {
+				vulns[i].Title = other.Title
+			}
+			if vulns[i].Description == "" {
+				vulns[i].Description = other.Description
+			}
+			if vulns[i].FixedVersion == "" {
+				vulns[i].FixedVersion = other.FixedVersion
+			}
+		}
+		if !known {
+			vulns = append(vulns, other)
+		}
+	}
+	return vulns
+}
+
+// runScanner runs an external scanner and returns its standard output. It is a variable to be replaced in tests.
+var runScanner = func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			lines := strings.Split(msg, "\n")
+			return nil, fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// runExternalScanner checks the scanner is installed and runs it within the configured timeout.
+func runExternalScanner(ctx context.Context, opts options.VulnScannerOptions, defaultBinary string, env []string, args []string) ([]byte, error) {
+	binary := opts.Binary
+	if binary == "" {
+		binary = defaultBinary
+	}
+	if _, err := exec.LookPath(binary); err != nil {
+		return nil, fmt.Errorf("%s is not installed: %w", binary, err)
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(opts.Timeout)*time.Second)
+		defer cancel()
+	}
+	return runScanner(ctx, env, binary, args...)
+}
+
+// scanRoot returns the root filesystem scanned by the external scanners.
+func scanRoot(root string) string {
+	if root == "" {
+		return "/"
+	}
+	return root
+}
+
+func skipDirs(opts options.VulnScannerOptions) []string {
+	if len(opts.SkipDirs) == 0 {
+		return defaultSkipDirs
+	}
+	return opts.SkipDirs
+}
+
+// preferredID returns the CVE related to an identifier of another database when there is exactly one,
+// so that a vulnerability is reported the same way by every scanner.
+func preferredID(id string, related []string) string {
+	if strings.HasPrefix(id, "CVE-") {
+		return id
+	}
+	cve := ""
+	for _, alias := range related {
+		if strings.HasPrefix(alias, "CVE-") && alias != cve {
+			if cve != "" {
+				return id
+			}
+			cve = alias
+		}
+	}
+	if cve != "" {
+		return cve
+	}
+	return id
+}
+
+// normalizeSeverity returns a vendor severity the way Trivy reports it, e.g. Important as HIGH.
+func normalizeSeverity(severity string) string {
+	if normalized, ok := osvSeverities[strings.ToLower(severity)]; ok {
+		return normalized
+	}
+	if severity == "" {
+		return "UNKNOWN"
+	}
+	return strings.ToUpper(severity)
+}
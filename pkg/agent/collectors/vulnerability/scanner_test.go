@@ -0,0 +1,193 @@
+package vulnerability
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockScanner replaces the external scanner with a fixture and records the command line.
+func mockScanner(t *testing.T, fixture string, err error) *[]string {
+	called := []string{}
+	previous := runScanner
+	runScanner = func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+		called = append(append(append(called, env...), name), args...)
+		if err != nil {
+			return nil, err
+		}
+		return os.ReadFile(fixture)
+	}
+	t.Cleanup(func() { runScanner = previous })
+	return &called
+}
+
+type fakeScanner struct {
+	name    string
+	matches []models.PackageVulnMatch
+	err     error
+}
+
+func (s *fakeScanner) Name() string { return s.name }
+
+func (s *fakeScanner) Scan(ctx context.Context, packages []*models.Package) ([]models.PackageVulnMatch, error) {
+	return s.matches, s.err
+}
+
+var scannedPackages = []*models.Package{
+	{Name: "openssl", Version: "3.0.11-1~deb12u2", Ecosystem: models.EcosystemDeb},
+	{Name: "requests", Version: "2.28.1", Ecosystem: models.EcosystemPypi},
+}
+
+func TestTrivyScanner(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("trivy scan unsupported on darwin")
+	}
+	called := mockScanner(t, "testdata/trivy.json", nil)
+	s := &TrivyScanner{Logger: logrus.New(), Options: options.VulnScannerOptions{Binary: "sh", DbPath: "/var/cache/trivy", Offline: true, Timeout: 60, Args: []string{"--ignore-unfixed"}}}
+
+	matches, err := s.Scan(context.Background(), scannedPackages)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sh", "rootfs", "--scanners", "vuln", "--pkg-types", "os", "--format", "json", "--quiet",
+		"--skip-dirs", "/run", "--timeout", "60s", "--cache-dir", "/var/cache/trivy",
+		"--skip-db-update", "--skip-java-db-update", "--offline-scan", "--ignore-unfixed", "/"}, *called)
+	assert.Len(t, matches, 1)
+	assert.True(t, matches[0].Matched)
+	assert.Equal(t, "CVE-2024-0727", matches[0].Vulnerabilities[0].VulnerabilityId)
+}
+
+func TestTrivyScanner_Errors(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("trivy scan unsupported on darwin")
+	}
+	s := &TrivyScanner{Logger: logrus.New(), Options: options.VulnScannerOptions{Binary: "sh"}}
+
+	mockScanner(t, "", errors.New("exit status 1: FATAL unable to initialize the database"))
+	_, err := s.Scan(context.Background(), scannedPackages)
+	assert.ErrorContains(t, err, "unable to initialize the database")
+
+	mockScanner(t, "testdata/root/etc/os-release", nil)
+	_, err = s.Scan(context.Background(), scannedPackages)
+	assert.ErrorContains(t, err, "unable to parse trivy output")
+
+	s.Options.Binary = "nonexistentbinary123"
+	_, err = s.Scan(context.Background(), scannedPackages)
+	assert.ErrorContains(t, err, "nonexistentbinary123 is not installed")
+}
+
+func TestGrypeScanner(t *testing.T) {
+	called := mockScanner(t, "testdata/grype.json", nil)
+	s := &GrypeScanner{Logger: logrus.New(), Options: options.VulnScannerOptions{Binary: "sh", DbPath: "/var/cache/grype", Offline: true, SkipDirs: []string{"/run", "/var/lib/docker/"}}}
+
+	matches, err := s.Scan(context.Background(), scannedPackages)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GRYPE_DB_CACHE_DIR=/var/cache/grype", "GRYPE_DB_AUTO_UPDATE=false", "GRYPE_DB_VALIDATE_AGE=false", "GRYPE_CHECK_FOR_APP_UPDATE=false",
+		"sh", "dir:/", "--output", "json", "--quiet", "--exclude", "./run/**", "--exclude", "./var/lib/docker/**"}, *called)
+	assert.Len(t, matches, 3)
+
+	byName := map[string]models.PackageVulnMatch{}
+	for _, match := range matches {
+		byName[match.PackageName] = match
+	}
+	assert.True(t, byName["openssl"].Matched)
+	assert.Equal(t, models.MatchedVuln{
+		VulnerabilityId: "CVE-2024-0727",
+		Severity:        "LOW",
+		Description:     "Processing a maliciously formatted PKCS12 file may lead OpenSSL to crash.",
+		FixedVersion:    "3.0.13-1~deb12u1",
+	}, byName["openssl"].Vulnerabilities[0])
+	assert.True(t, byName["requests"].Matched)
+	assert.Equal(t, "CVE-2023-32681", byName["requests"].Vulnerabilities[0].VulnerabilityId)
+	assert.Equal(t, "MEDIUM", byName["requests"].Vulnerabilities[0].Severity)
+	assert.False(t, byName["tar"].Matched)
+	assert.Equal(t, "", byName["tar"].Vulnerabilities[0].FixedVersion)
+	assert.Equal(t, "LOW", byName["tar"].Vulnerabilities[0].Severity)
+}
+
+func TestNewScanners(t *testing.T) {
+	scanners, err := NewScanners(logrus.New(), &options.VulnerabilitiesOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, scanners, 1)
+	assert.Equal(t, ScannerTrivy, scanners[0].Name())
+
+	scanners, err = NewScanners(logrus.New(), &options.VulnerabilitiesOptions{Database: "/var/lib/facter/vulndb"})
+	assert.NoError(t, err)
+	assert.Equal(t, ScannerBuiltin, scanners[0].Name())
+
+	scanners, err = NewScanners(logrus.New(), &options.VulnerabilitiesOptions{Scanners: []string{"grype", "trivy"}})
+	assert.NoError(t, err)
+	assert.Equal(t, ScannerGrype, scanners[0].Name())
+	assert.Equal(t, ScannerTrivy, scanners[1].Name())
+
+	_, err = NewScanners(logrus.New(), &options.VulnerabilitiesOptions{Scanners: []string{"builtin"}})
+	assert.Error(t, err)
+	_, err = NewScanners(logrus.New(), &options.VulnerabilitiesOptions{Scanners: []string{"clair"}})
+	assert.Error(t, err)
+}
+
+func TestRunScanners(t *testing.T) {
+	logger := logrus.New()
+	failing := &fakeScanner{name: "trivy", err: errors.New("trivy is not installed")}
+	first := &fakeScanner{name: "grype", matches: []models.PackageVulnMatch{{
+		PackageName: "openssl", InstalledVersion: "3.0.11-1~deb12u2", Matched: true,
+		Vulnerabilities: []models.MatchedVuln{{VulnerabilityId: "CVE-2024-0727", Severity: "UNKNOWN"}},
+	}}}
+	second := &fakeScanner{name: "builtin", matches: []models.PackageVulnMatch{
+		{
+			PackageName: "openssl", InstalledVersion: "3.0.11-1~deb12u2", Matched: true,
+			Vulnerabilities: []models.MatchedVuln{
+				{VulnerabilityId: "CVE-2024-0727", Severity: "MEDIUM", FixedVersion: "3.0.13-1~deb12u1"},
+				{VulnerabilityId: "CVE-2024-2511", Severity: "LOW"},
+			},
+		},
+		{PackageName: "requests", InstalledVersion: "2.28.1", Matched: true, Vulnerabilities: []models.MatchedVuln{{VulnerabilityId: "CVE-2023-32681"}}},
+	}}
+
+	matches, err := RunScanners(context.Background(), logger, []Scanner{failing, first, second}, "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, first.matches, matches)
+
+	matches, err = RunScanners(context.Background(), logger, []Scanner{failing, first, second}, StrategyMerge, nil)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 2)
+	assert.Equal(t, []models.MatchedVuln{
+		{VulnerabilityId: "CVE-2024-0727", Severity: "MEDIUM", FixedVersion: "3.0.13-1~deb12u1"},
+		{VulnerabilityId: "CVE-2024-2511", Severity: "LOW"},
+	}, matches[0].Vulnerabilities)
+	assert.Equal(t, "requests", matches[1].PackageName)
+
+	_, err = RunScanners(context.Background(), logger, []Scanner{failing}, StrategyMerge, nil)
+	assert.ErrorContains(t, err, "trivy: trivy is not installed")
+	_, err = RunScanners(context.Background(), logger, []Scanner{first}, "fastest", nil)
+	assert.Error(t, err)
+}
+
+func TestMergeMatches_Epoch(t *testing.T) {
+	vim := &models.Package{Name: "vim", Version: "2:9.0.1378-2", Ecosystem: models.EcosystemDeb}
+	// Trivy reports the version without its epoch
+	trivy := matchFindings(logrus.New(), []*models.Package{vim}, []finding{{
+		ecosystem: models.EcosystemDeb,
+		vuln:      models.Vulnerability{VulnerabilityID: "CVE-2023-4733", PkgName: "vim", InstalledVersion: "9.0.1378-2", Severity: "HIGH"},
+	}})
+	builtin := []models.PackageVulnMatch{{
+		PackageName: "vim", InstalledVersion: vim.Version, Ecosystem: models.EcosystemDeb, Matched: true, Package: vim,
+		Vulnerabilities: []models.MatchedVuln{{VulnerabilityId: "CVE-2023-4733"}, {VulnerabilityId: "CVE-2023-4734"}},
+	}}
+
+	matches := MergeMatches(trivy, builtin)
+	if assert.Len(t, matches, 1) {
+		assert.Same(t, vim, matches[0].Package)
+		assert.Equal(t, []models.MatchedVuln{{VulnerabilityId: "CVE-2023-4733", Severity: "HIGH"}, {VulnerabilityId: "CVE-2023-4734"}},
+			matches[0].Vulnerabilities)
+	}
+
+	// The same name in another ecosystem is another package
+	builtin[0].Ecosystem, builtin[0].Package = models.EcosystemPypi, nil
+	assert.Len(t, MergeMatches(trivy, builtin), 2)
+}
@@ -4,74 +4,195 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"runtime"
+	"strings"
 
 	"github.com/klamhq/facter-oss/pkg/models"
-	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/klamhq/facter-oss/pkg/options"
+	"github.com/klamhq/facter-oss/pkg/versions"
 	"github.com/sirupsen/logrus"
 )
 
-// RunTrivyScan executes a Trivy scan on the root filesystem and returns a slice of matched vulnerabilities.
-// It uses the Trivy CLI tool to scan for vulnerabilities in installed packages and matches them against the provided package list.
-func RunTrivyScan(ctx context.Context, logger *logrus.Logger, packages []*schema.Package) []models.PackageVulnMatch {
-	cmd := exec.CommandContext(ctx, "trivy", "rootfs", "--scanners", "vuln", "--skip-dirs", "/run", "--pkg-types", "os", "--format", "json", "--quiet", "/")
-	out, err := cmd.Output()
+// TrivyScanner scans the root filesystem with Trivy and matches the vulnerabilities it reports with the installed packages.
+type TrivyScanner struct {
+	Logger  *logrus.Logger
+	Options options.VulnScannerOptions
+	Root    string // Root filesystem scanned, / when empty
+}
+
+func (s *TrivyScanner) Name() string {
+	return ScannerTrivy
+}
+
+// Scan runs `trivy rootfs` on the operating system packages. Root filesystem scans are not supported on macOS.
+func (s *TrivyScanner) Scan(ctx context.Context, packages []*models.Package) ([]models.PackageVulnMatch, error) {
+	if runtime.GOOS == "darwin" {
+		return nil, fmt.Errorf("trivy scan unsupported on macOS (darwin)")
+	}
+	out, err := runExternalScanner(ctx, s.Options, "trivy", nil, trivyArgs(s.Options, s.Root))
 	if err != nil {
-		logger.Errorf("Unable to run trivy %s:", err)
+		return nil, fmt.Errorf("unable to run trivy, see https://trivy.dev/latest/getting-started/installation/: %w", err)
 	}
 
 	var output models.TrivyOutput
-	err = json.Unmarshal(out, &output)
-	if err != nil {
-		logger.Errorf("Unable to unmarshall trivy json %s:", err)
+	if err := json.Unmarshal(out, &output); err != nil {
+		return nil, fmt.Errorf("unable to parse trivy output: %w", err)
+	}
+	return MatchVulns(s.Logger, packages, &output), nil
+}
+
+func trivyArgs(opts options.VulnScannerOptions, root string) []string {
+	args := []string{"rootfs", "--scanners", "vuln", "--pkg-types", "os", "--format", "json", "--quiet"}
+	for _, dir := range skipDirs(opts) {
+		args = append(args, "--skip-dirs", dir)
+	}
+	if opts.Timeout > 0 {
+		args = append(args, "--timeout", fmt.Sprintf("%ds", opts.Timeout))
+	}
+	if opts.DbPath != "" {
+		args = append(args, "--cache-dir", opts.DbPath)
+	}
+	if opts.Offline {
+		args = append(args, "--skip-db-update", "--skip-java-db-update", "--offline-scan")
+	}
+	args = append(args, opts.Args...)
+	return append(args, scanRoot(root))
+}
+
+// trivyEcosystems maps the type of a trivy os-pkgs result, the OS family, to the ecosystem of its packages.
+var trivyEcosystems = map[string]string{
+	"debian":                       models.EcosystemDeb,
+	"ubuntu":                       models.EcosystemDeb,
+	"alpine":                       models.EcosystemApk,
+	"wolfi":                        models.EcosystemApk,
+	"chainguard":                   models.EcosystemApk,
+	"redhat":                       models.EcosystemRpm,
+	"centos":                       models.EcosystemRpm,
+	"rocky":                        models.EcosystemRpm,
+	"alma":                         models.EcosystemRpm,
+	"fedora":                       models.EcosystemRpm,
+	"amazon":                       models.EcosystemRpm,
+	"oracle":                       models.EcosystemRpm,
+	"photon":                       models.EcosystemRpm,
+	"cbl-mariner":                  models.EcosystemRpm,
+	"azurelinux":                   models.EcosystemRpm,
+	"opensuse.leap":                models.EcosystemRpm,
+	"opensuse.tumbleweed":          models.EcosystemRpm,
+	"suse linux enterprise server": models.EcosystemRpm,
+}
+
+// isAffected returns true when a package of the ecosystem is installed with the version reported by
+// the scanner, or with a version below the fixed version of the vulnerability.
+// An empty ecosystem, on either side, matches any ecosystem.
+func isAffected(installed []*models.Package, ecosystem string, vuln models.Vulnerability) bool {
+	for _, pkg := range installed {
+		if ecosystem != "" && pkg.Ecosystem != "" && pkg.Ecosystem != ecosystem {
+			continue
+		}
+		if pkg.Version == vuln.InstalledVersion {
+			return true
+		}
+		scheme := ecosystem
+		if scheme == "" {
+			scheme = pkg.Ecosystem
+		}
+		if versions.Below(scheme, pkg.Version, vuln.FixedVersion) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectedPackage returns the installed package reported by a scanner, scanners may report the version without
+// its epoch. Without any version matching, the only installed package of the name in the ecosystem is returned.
+func collectedPackage(installed []*models.Package, ecosystem, version string) *models.Package {
+	var candidates []*models.Package
+	for _, pkg := range installed {
+		if ecosystem != "" && pkg.Ecosystem != "" && pkg.Ecosystem != ecosystem {
+			continue
+		}
+		if pkg.Version == version || withoutEpoch(pkg.Version) == version {
+			return pkg
+		}
+		candidates = append(candidates, pkg)
+	}
+	if len(candidates) == 1 {
+		return candidates[0]
 	}
-	packageVulnMatch := MatchVulns(logger, packages, &output)
-	return packageVulnMatch
+	return nil
+}
+
+// withoutEpoch strips the "epoch:" prefix of a deb or rpm version.
+func withoutEpoch(version string) string {
+	if epoch, rest, ok := strings.Cut(version, ":"); ok && epoch != "" && strings.Trim(epoch, "0123456789") == "" {
+		return rest
+	}
+	return version
+}
+
+// finding is a vulnerability reported by a scanner for a package of an ecosystem.
+type finding struct {
+	ecosystem string
+	vuln      models.Vulnerability
 }
 
 // MatchVulns matches vulnerabilities from Trivy output with installed packages.
-// It creates a map of package names to versions and checks if the installed version matches the version reported by Trivy.
-func MatchVulns(logger *logrus.Logger, packages []*schema.Package, trivyOutput *models.TrivyOutput) []models.PackageVulnMatch {
-	pkgMap := make(map[string]string)
+// Packages are looked up by name in the ecosystem of the trivy result, the installed version must match the version reported by Trivy
+// or be below the fixed version, as scanners do not always report versions the way the package database does (e.g. without epoch).
+func MatchVulns(logger *logrus.Logger, packages []*models.Package, trivyOutput *models.TrivyOutput) []models.PackageVulnMatch {
+	findings := []finding{}
+	for _, res := range trivyOutput.Results {
+		ecosystem := trivyEcosystems[res.Type]
+		for _, vuln := range res.Vulnerabilities {
+			findings = append(findings, finding{ecosystem: ecosystem, vuln: vuln})
+		}
+	}
+	return matchFindings(logger, packages, findings)
+}
+
+// matchFindings groups the findings of a scanner by package, and flags the packages found installed.
+func matchFindings(logger *logrus.Logger, packages []*models.Package, findings []finding) []models.PackageVulnMatch {
+	pkgMap := make(map[string][]*models.Package)
 	for _, pkg := range packages {
-		pkgMap[pkg.Name] = pkg.Version
+		pkgMap[pkg.Name] = append(pkgMap[pkg.Name], pkg)
 	}
 
 	matches := make(map[string]*models.PackageVulnMatch)
 
-	for _, res := range trivyOutput.Results {
-		for _, vuln := range res.Vulnerabilities {
-			installedVersion, found := pkgMap[vuln.PkgName]
-			matchKey := fmt.Sprintf("%s@%s", vuln.PkgName, vuln.InstalledVersion)
-
-			if _, exists := matches[matchKey]; !exists {
-				matches[matchKey] = &models.PackageVulnMatch{
-					PackageName:      vuln.PkgName,
-					InstalledVersion: vuln.InstalledVersion,
-					Vulnerabilities:  []models.MatchedVuln{},
-					Matched:          found && installedVersion == vuln.InstalledVersion,
-				}
-			}
+	for _, f := range findings {
+		vuln := f.vuln
+		found := isAffected(pkgMap[vuln.PkgName], f.ecosystem, vuln)
+		matchKey := fmt.Sprintf("%s/%s@%s", f.ecosystem, vuln.PkgName, vuln.InstalledVersion)
 
-			matches[matchKey].Vulnerabilities = append(matches[matchKey].Vulnerabilities, models.MatchedVuln{
-				VulnerabilityId: vuln.VulnerabilityID,
-				Severity:        vuln.Severity,
-				Title:           vuln.Title,
-				Description:     vuln.Description,
-				FixedVersion:    vuln.FixedVersion,
-			})
-
-			if found && installedVersion == vuln.InstalledVersion {
-				logger.Debugf("Matched vulnerability: %s, Package: %s, Version: %s", vuln.VulnerabilityID, vuln.PkgName, vuln.InstalledVersion)
-			} else {
-				logger.Debugf("Unmatched vulnerability: %s, Package: %s, Version: %s", vuln.VulnerabilityID, vuln.PkgName, vuln.InstalledVersion)
+		if _, exists := matches[matchKey]; !exists {
+			matches[matchKey] = &models.PackageVulnMatch{
+				PackageName:      vuln.PkgName,
+				InstalledVersion: vuln.InstalledVersion,
+				Ecosystem:        f.ecosystem,
+				Vulnerabilities:  []models.MatchedVuln{},
+				Matched:          found,
+				Package:          collectedPackage(pkgMap[vuln.PkgName], f.ecosystem, vuln.InstalledVersion),
 			}
 		}
+
+		matches[matchKey].Vulnerabilities = append(matches[matchKey].Vulnerabilities, models.MatchedVuln{
+			VulnerabilityId: vuln.VulnerabilityID,
+			Severity:        vuln.Severity,
+			Title:           vuln.Title,
+			Description:     vuln.Description,
+			FixedVersion:    vuln.FixedVersion,
+		})
+
+		if found {
+			logger.Debugf("Matched vulnerability: %s, Package: %s, Version: %s", vuln.VulnerabilityID, vuln.PkgName, vuln.InstalledVersion)
+		} else {
+			logger.Debugf("Unmatched vulnerability: %s, Package: %s, Version: %s", vuln.VulnerabilityID, vuln.PkgName, vuln.InstalledVersion)
+		}
 	}
 
 	var vulnsMatch []models.PackageVulnMatch
-	for _, match := range matches {
-		vulnsMatch = append(vulnsMatch, *match)
+	for _, key := range sortedKeys(matches) {
+		vulnsMatch = append(vulnsMatch, *matches[key])
 	}
 
 	return vulnsMatch
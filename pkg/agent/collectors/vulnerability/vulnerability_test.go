@@ -4,7 +4,6 @@ import (
 	"testing"
 
 	"github.com/klamhq/facter-oss/pkg/models"
-	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/sirupsen/logrus"
 )
 
@@ -30,13 +29,7 @@ func TestMatchVulns_MatchedVulnerability(t *testing.T) {
 			},
 		},
 	}
-	p := []*schema.Package{
-		{
-			Name:    pkgs[0].Name,
-			Version: pkgs[0].Version,
-		},
-	}
-	result := MatchVulns(logger, p, trivyOutput)
+	result := MatchVulns(logger, pkgs, trivyOutput)
 	if len(result) != 1 {
 		t.Fatalf("expected 1 match, got %d", len(result))
 	}
@@ -81,13 +74,7 @@ func TestMatchVulns_UnmatchedVulnerability(t *testing.T) {
 		},
 	}
 
-	p := []*schema.Package{
-		{
-			Name:    pkgs[0].Name,
-			Version: pkgs[0].Version,
-		},
-	}
-	result := MatchVulns(logger, p, trivyOutput)
+	result := MatchVulns(logger, pkgs, trivyOutput)
 	if len(result) != 1 {
 		t.Fatalf("expected 1 match, got %d", len(result))
 	}
@@ -145,17 +132,7 @@ func TestMatchVulns_MultipleVulnerabilitiesAndPackages(t *testing.T) {
 		},
 	}
 
-	p := []*schema.Package{
-		{
-			Name:    pkgs[0].Name,
-			Version: pkgs[0].Version,
-		},
-		{
-			Name:    pkgs[1].Name,
-			Version: pkgs[1].Version,
-		},
-	}
-	result := MatchVulns(logger, p, trivyOutput)
+	result := MatchVulns(logger, pkgs, trivyOutput)
 	if len(result) != 2 {
 		t.Fatalf("expected 2 matches, got %d", len(result))
 	}
@@ -190,9 +167,83 @@ func TestMatchVulns_MultipleVulnerabilitiesAndPackages(t *testing.T) {
 func TestMatchVulns_EmptyInputs(t *testing.T) {
 	logger := logrus.New()
 	trivyOutput := &models.TrivyOutput{}
-	p := []*schema.Package{}
-	result := MatchVulns(logger, p, trivyOutput)
+	pkgs := []*models.Package{}
+	result := MatchVulns(logger, pkgs, trivyOutput)
 	if len(result) != 0 {
 		t.Errorf("expected 0 matches, got %d", len(result))
 	}
 }
+
+func TestMatchVulns_EcosystemAware(t *testing.T) {
+	logger := logrus.New()
+	pkgs := []*models.Package{
+		{Name: "curl", Version: "8.5.0", Ecosystem: models.EcosystemHomebrew},
+		{Name: "curl", Version: "7.88.1-10+deb12u5", Ecosystem: models.EcosystemDeb},
+	}
+	trivyOutput := &models.TrivyOutput{
+		Results: []models.Result{
+			{
+				Class: "os-pkgs",
+				Type:  "debian",
+				Vulnerabilities: []models.Vulnerability{
+					{PkgName: "curl", InstalledVersion: "8.5.0", VulnerabilityID: "CVE-2024-0001"},
+					{PkgName: "curl", InstalledVersion: "7.88.1-10+deb12u5", VulnerabilityID: "CVE-2024-0002"},
+				},
+			},
+		},
+	}
+	result := MatchVulns(logger, pkgs, trivyOutput)
+	if len(result) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(result))
+	}
+	for _, match := range result {
+		switch match.InstalledVersion {
+		case "8.5.0":
+			if match.Matched {
+				t.Errorf("homebrew curl must not match a debian vulnerability")
+			}
+		case "7.88.1-10+deb12u5":
+			if !match.Matched {
+				t.Errorf("expected debian curl match to be true")
+			}
+		}
+	}
+}
+
+func TestMatchVulns_BelowFixedVersion(t *testing.T) {
+	logger := logrus.New()
+	pkgs := []*models.Package{
+		{Name: "vim", Version: "2:9.0.1378-2", Ecosystem: models.EcosystemDeb},
+		{Name: "openssl", Version: "3.0.13-1~deb12u1", Ecosystem: models.EcosystemDeb},
+	}
+	trivyOutput := &models.TrivyOutput{
+		Results: []models.Result{
+			{
+				Class: "os-pkgs",
+				Type:  "debian",
+				Vulnerabilities: []models.Vulnerability{
+					// Reported without epoch, still below the fix
+					{PkgName: "vim", InstalledVersion: "9.0.1378-2", VulnerabilityID: "CVE-2024-0003", FixedVersion: "2:9.0.1378-2+deb12u1"},
+					// Already fixed by the installed version
+					{PkgName: "openssl", InstalledVersion: "3.0.11-1~deb12u2", VulnerabilityID: "CVE-2024-0004", FixedVersion: "3.0.13-1~deb12u1"},
+				},
+			},
+		},
+	}
+	result := MatchVulns(logger, pkgs, trivyOutput)
+	if len(result) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(result))
+	}
+	for _, match := range result {
+		switch match.PackageName {
+		case "vim":
+			if !match.Matched {
+				t.Errorf("expected vim below the fixed version to match")
+			}
+		case "openssl":
+			if match.Matched {
+				t.Errorf("openssl is already fixed and must not match")
+			}
+		}
+	}
+}
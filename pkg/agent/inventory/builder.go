@@ -10,6 +10,7 @@ import (
 
 	"github.com/klamhq/facter-oss/pkg/agent/collect/applications"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/compliance"
+	"github.com/klamhq/facter-oss/pkg/agent/collect/images"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/networks"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/packages"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/platform"
@@ -18,6 +19,8 @@ import (
 	"github.com/klamhq/facter-oss/pkg/agent/collect/systemservices"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/users"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/vulnerability"
+	compliancecollector "github.com/klamhq/facter-oss/pkg/agent/collectors/compliance"
+	"github.com/klamhq/facter-oss/pkg/agent/collectors/exposure"
 	"github.com/klamhq/facter-oss/pkg/agent/store"
 	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
@@ -46,6 +49,12 @@ type Builder struct {
 	SSHInfos            ssh.SSHInfosCollector
 	ComplianceReport    compliance.ComplianceCollector
 	VulnerabilityReport vulnerability.VulnerabilityCollector
+	Images              images.ImagesCollector
+
+	// Extensions holds the data collected by the last Build which has no field in the schema.
+	Extensions *models.HostExtensions
+	// ExtensionsRequest is the extensions counterpart of the request returned by ManageDelta.
+	ExtensionsRequest *models.ExtensionsRequest
 }
 
 func newInventoryStore(cfg options.RunOptions) (store.InventoryStore, error) {
@@ -69,9 +78,11 @@ func NewBuilder(cfg options.RunOptions, systemGather *models.System, logger *log
 		Log:          logger,
 		Cfg:          cfg,
 		SystemGather: systemGather,
-		maxParallel:  runtime.NumCPU(),
-		Now:          time.Now,
-		Store:        s,
+		// The platform collector starts the services one from its own goroutine,
+		// a limit of one would deadlock on single CPU hosts.
+		maxParallel: max(runtime.NumCPU(), 2),
+		Now:         time.Now,
+		Store:       s,
 		// Default collectors set to nil, will be initialized later if enabled in the config
 		Platform: nil,
 		WhoAmI: func() (string, error) {
@@ -96,7 +107,8 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 	if u, err := user.Current(); err == nil {
 		inv.Metadata.RunningUser = u.Name
 	}
-	g, ctx := errgroup.WithContext(ctx)
+	// The group context is canceled once the group is done, the later steps use the context of the build
+	g, gctx := errgroup.WithContext(ctx)
 	g.SetLimit(b.maxParallel)
 
 	// Collectors are called in a specific order to handle dependencies
@@ -106,23 +118,31 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 	var (
 		platform            *schema.Platform
 		users               []*schema.User
-		pkgs                []*schema.Package
+		pkgs                []*models.Package
+		modifiedFiles       []*models.ModifiedFile
 		services            []*schema.SystemdService
 		processes           []*schema.Process
 		sshKeyAccess        []*schema.SshKeyAccess
 		sshKeyInfos         []*schema.SshKeyInfo
 		knownHosts          []*schema.KnownHost
 		networks            *schema.Network
+		firewallInventory   *models.FirewallInventory
+		sockets             []*models.Socket
+		routing             *models.RoutingInventory
 		apps                []*schema.Application
 		complianceReport    *schema.ComplianceReport
 		vulnerabilityReport *schema.VulnerabilityReport
+		vulnFindings        []*models.VulnerabilityFinding
+		hostRisk            *models.HostRisk
+		imageInventories    []*models.ImageInventory
+		exposures           []*models.Exposure
 		mu                  sync.Mutex
 	)
 
 	g.Go(func() error {
 		if b.Cfg.Facter.Inventory.Platform.Enabled && b.Platform != nil {
 			start := time.Now()
-			p, e := b.Platform.CollectPlatform(ctx)
+			p, e := b.Platform.CollectPlatform(gctx)
 			if e != nil {
 				b.Log.WithError(e).Error("platform")
 			}
@@ -135,7 +155,7 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 			if b.Cfg.Facter.Inventory.SystemdService.Enabled {
 				pLocal := platform
 				start := time.Now()
-				s, se := b.SystemServices.CollectSystemServices(ctx, pLocal.InitSystem)
+				s, se := b.SystemServices.CollectSystemServices(gctx, pLocal.InitSystem)
 				if se != nil {
 					b.Log.WithError(se).Error("initsystem services")
 				}
@@ -154,7 +174,7 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 	g.Go(func() error {
 		if b.Cfg.Facter.Inventory.Packages.Enabled {
 			start := time.Now()
-			pk, pkerr := b.Packages.CollectPackages(ctx)
+			pk, pkerr := b.Packages.CollectPackages(gctx)
 			if pkerr != nil {
 				b.Log.WithError(pkerr).Error("packages")
 			}
@@ -163,6 +183,18 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 			mu.Unlock()
 			defer func(n string) { b.Log.WithField("collector", n).WithField("duration", time.Since(start)).Info("done") }("packages")
 
+			if b.Cfg.Facter.Inventory.Packages.Integrity.Enabled {
+				start := time.Now()
+				mf, mferr := b.Packages.CollectIntegrity(gctx)
+				if mferr != nil {
+					b.Log.WithError(mferr).Error("package integrity")
+				}
+				mu.Lock()
+				modifiedFiles = mf
+				mu.Unlock()
+				defer func(n string) { b.Log.WithField("collector", n).WithField("duration", time.Since(start)).Info("done") }("package integrity")
+			}
+
 		}
 		return nil
 	})
@@ -171,7 +203,7 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 	g.Go(func() error {
 		if b.Cfg.Facter.Inventory.Applications.Enabled {
 			start := time.Now()
-			a, apperr := b.Applications.CollectApplications(ctx)
+			a, apperr := b.Applications.CollectApplications(gctx)
 			if apperr != nil {
 				b.Log.WithError(apperr).Error("applications")
 			}
@@ -187,7 +219,7 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 	g.Go(func() error {
 		if b.Cfg.Facter.Inventory.Networks.Enabled {
 			start := time.Now()
-			net, neterr := b.Networks.CollectNetworks(ctx)
+			net, neterr := b.Networks.CollectNetworks(gctx)
 			if neterr != nil {
 				b.Log.WithError(neterr).Error("networks")
 			}
@@ -196,6 +228,34 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 			mu.Unlock()
 			defer func(n string) { b.Log.WithField("collector", n).WithField("duration", time.Since(start)).Info("done") }("networks")
 
+			if b.Cfg.Facter.Inventory.Networks.Firewall.Enabled {
+				fw, fwerr := b.Networks.CollectFirewall(gctx)
+				if fwerr != nil {
+					b.Log.WithError(fwerr).Warn("firewall")
+				}
+				mu.Lock()
+				firewallInventory = fw
+				mu.Unlock()
+			}
+			if b.Cfg.Facter.Inventory.Networks.Connections.Enabled {
+				socks, sockerr := b.Networks.CollectSockets(gctx)
+				if sockerr != nil {
+					b.Log.WithError(sockerr).Warn("sockets")
+				}
+				mu.Lock()
+				sockets = socks
+				mu.Unlock()
+			}
+			if b.Cfg.Facter.Inventory.Networks.Routing.Enabled {
+				rt, rterr := b.Networks.CollectRouting(gctx)
+				if rterr != nil {
+					b.Log.WithError(rterr).Warn("routing")
+				}
+				mu.Lock()
+				routing = rt
+				mu.Unlock()
+			}
+
 		}
 		return nil
 	})
@@ -204,7 +264,7 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 	g.Go(func() error {
 		if b.Cfg.Facter.Inventory.User.Enabled {
 			start := time.Now()
-			u, uerr := b.Users.CollectUsers(ctx)
+			u, uerr := b.Users.CollectUsers(gctx)
 			if uerr != nil {
 				b.Log.WithError(uerr).Error("users")
 			}
@@ -214,7 +274,7 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 			// SSH
 			if b.Cfg.Facter.Inventory.SSH.Enabled {
 				b.SSHInfos = ssh.New(b.Log, &b.Cfg.Facter.Inventory.SSH)
-				ska, kh, ski, ssherr := b.SSHInfos.CollectSSHInfos(ctx, users)
+				ska, kh, ski, ssherr := b.SSHInfos.CollectSSHInfos(gctx, users)
 				if ssherr != nil {
 					b.Log.WithError(ssherr).Error("ssh")
 				}
@@ -234,7 +294,7 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 	g.Go(func() error {
 		if b.Cfg.Facter.Inventory.Process.Enabled {
 			start := time.Now()
-			proc, procerr := b.Processes.CollectProcess(ctx)
+			proc, procerr := b.Processes.CollectProcess(gctx)
 			if procerr != nil {
 				b.Log.WithError(procerr).Error("processes")
 			}
@@ -247,11 +307,11 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 		return nil
 	})
 
-	// 7) Compliance
+	// 7) Compliance, the native rules are evaluated once the inventory they may check is collected
 	g.Go(func() error {
-		if b.Cfg.Facter.Compliance.Enabled {
+		if b.Cfg.Facter.Compliance.Enabled && b.ComplianceReport.Engine() != compliancecollector.EngineNative {
 			start := time.Now()
-			cReport, cReportErr := b.ComplianceReport.CollectCompliance(ctx)
+			cReport, cReportErr := b.ComplianceReport.CollectCompliance(gctx)
 			if cReportErr != nil {
 				b.Log.WithError(cReportErr).Error("compliance report")
 			}
@@ -272,23 +332,38 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 	if b.Cfg.Facter.Vulnerabilities.Enabled {
 		start := time.Now()
 		var vReportErr error
-		if runtime.GOOS == "darwin" {
-			b.Log.
-				WithField("collector", "vulnerability report").
-				Warn("Skipping vulnerability scan: unsupported on macOS (darwin)")
+		vulnerabilityReport, vReportErr = b.VulnerabilityReport.CollectVulnerability(ctx, pkgs)
+		if vReportErr != nil {
+			b.Log.WithError(vReportErr).Error("vulnerability report")
 		} else {
-			vulnerabilityReport, vReportErr = b.VulnerabilityReport.CollectVulnerability(ctx, pkgs)
+			vulnFindings, hostRisk, vReportErr = b.VulnerabilityReport.AssessVulnerabilities(vulnerabilityReport, processes, networks)
 			if vReportErr != nil {
-				b.Log.WithError(vReportErr).Error("vulnerability report")
+				b.Log.WithError(vReportErr).Error("vulnerability assessment")
 			}
 		}
 
 		defer func(n string) { b.Log.WithField("collector", n).WithField("duration", time.Since(start)).Info("done") }("vulnerability report")
 	}
 
+	// 9) Docker images, unpacked one at a time once the host is collected as they use disk and CPU
+	if docker := b.Cfg.Facter.Inventory.Applications.Docker; docker.Enabled && docker.Images.Scan && b.Images != nil {
+		start := time.Now()
+		var imgErr error
+		imageInventories, imgErr = b.Images.CollectImages(ctx)
+		if imgErr != nil {
+			b.Log.WithError(imgErr).Error("docker images")
+		}
+		defer func(n string) { b.Log.WithField("collector", n).WithField("duration", time.Since(start)).Info("done") }("docker images")
+	}
+
+	// 10) Exposure of the listening sockets, from their bind address, the host addresses and the firewall rules
+	if b.Cfg.Facter.Inventory.Networks.Enabled && b.Cfg.Facter.Inventory.Networks.Connections.Enabled {
+		exposures = exposure.Analyse(networks, processes, firewallInventory)
+	}
+
 	inv.Platform = platform
 	inv.Application = apps
-	inv.Packages = pkgs
+	inv.Packages = packages.ToSchema(pkgs)
 	inv.Users = users
 	inv.Network = networks
 	inv.Processes = processes
@@ -296,9 +371,33 @@ func (b *Builder) Build(ctx context.Context) (*schema.HostInventory, error) {
 	inv.KnownHost = knownHosts
 	inv.SshKeyInfo = sshKeyInfos
 	inv.SystemdService = services
-	inv.ComplianceReport = complianceReport
 	inv.VulnerabilityReport = vulnerabilityReport
 
+	if b.Cfg.Facter.Compliance.Enabled && b.ComplianceReport.Engine() == compliancecollector.EngineNative {
+		start := time.Now()
+		cReport, cReportErr := b.ComplianceReport.EvaluateRules(ctx, inv, pkgs)
+		if cReportErr != nil {
+			b.Log.WithError(cReportErr).Error("compliance rules")
+		}
+		complianceReport = cReport
+		defer func(n string) { b.Log.WithField("collector", n).WithField("duration", time.Since(start)).Info("done") }("compliance rules")
+	}
+	inv.ComplianceReport = complianceReport
+
+	b.Extensions = &models.HostExtensions{
+		Hostname:        inv.Hostname,
+		Packages:        pkgs,
+		ModifiedFiles:   modifiedFiles,
+		Vulnerabilities: vulnFindings,
+		Risk:            hostRisk,
+		Images:          imageInventories,
+		Compliance:      ComplianceScore(complianceReport, time.Now()),
+		Firewall:        firewallInventory,
+		Exposures:       exposures,
+		Sockets:         sockets,
+		Routing:         routing,
+	}
+
 	return inv, nil
 }
 
@@ -313,11 +412,38 @@ func (b *Builder) ManageDelta(fullInventory *schema.HostInventory) (*schema.Inve
 		result = &schema.InventoryRequest{
 			Content: &schema.InventoryRequest_Full{Full: fullInventory},
 		}
+		b.ExtensionsRequest = &models.ExtensionsRequest{Full: b.Extensions}
 		return result, fullInventory
 	} else {
 		b.Log.Info("Previous inventory found, computing delta")
 		delta := ComputeDelta(previous, fullInventory, b.Log)
-		if IsDeltaEmpty(delta) {
+
+		// Extensions are stored since they exist, an older store only holds the inventory.
+		var extDelta *models.HostExtensionsDelta
+		if previousExt, extErr := b.Store.GetExtensions(fullInventory.Hostname); extErr == nil && b.Extensions != nil {
+			extDelta = ComputeExtensionsDelta(previousExt, b.Extensions)
+			// Packages are identified by their ecosystem, which the schema cannot carry.
+			delta.PackagesAdded = packages.ToSchema(extDelta.PackagesAdded)
+			delta.PackagesRemoved = packages.ToSchema(extDelta.PackagesRemoved)
+		}
+
+		failing, passing := ComputeComplianceTransitions(previous.ComplianceReport, fullInventory.ComplianceReport)
+		if b.Extensions != nil && b.Extensions.Compliance != nil {
+			b.Extensions.Compliance.NewlyFailing = ruleIDs(failing)
+			b.Extensions.Compliance.NewlyPassing = ruleIDs(passing)
+		}
+		if extDelta != nil {
+			extDelta.ComplianceNewlyFailing = failing
+			extDelta.ComplianceNewlyPassing = passing
+		}
+		if len(failing) > 0 {
+			b.Log.WithField("rules", ruleIDs(failing)).Warnf("%d compliance rules failing since the previous run", len(failing))
+		}
+		if extDelta != nil && len(extDelta.ExposuresAdded) > 0 {
+			b.Log.WithField("sockets", exposureKeys(extDelta.ExposuresAdded)).Warnf("%d sockets exposed since the previous run", len(extDelta.ExposuresAdded))
+		}
+
+		if IsDeltaEmpty(delta) && (extDelta == nil || extDelta.IsEmpty()) {
 			b.Log.Info("No changes detected, nothing to send")
 			return nil, nil
 		}
@@ -325,6 +451,11 @@ func (b *Builder) ManageDelta(fullInventory *schema.HostInventory) (*schema.Inve
 		result = &schema.InventoryRequest{
 			Content: &schema.InventoryRequest_Delta{Delta: delta},
 		}
+		if extDelta != nil {
+			b.ExtensionsRequest = &models.ExtensionsRequest{Delta: extDelta}
+		} else {
+			b.ExtensionsRequest = &models.ExtensionsRequest{Full: b.Extensions}
+		}
 		b.Log.Debugf("Send this delta %s", result)
 		return result, fullInventory
 	}
@@ -3,6 +3,7 @@ package inventory
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/klamhq/facter-oss/pkg/agent/collect/applications"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/networks"
@@ -153,6 +154,72 @@ func TestBuilder_ManageDelta_NoDeltaSentWhenNoChange(t *testing.T) {
 	b.Store.Close()
 }
 
+func TestBuilder_ManageDelta_PackagesDiffedByEcosystem(t *testing.T) {
+	cfg := options.RunOptions{}
+	cfg.Facter.Store.Path = "/tmp/store"
+	system := &models.System{}
+	system.Host.Hostname = "host5"
+	logger := logrus.New()
+	b, err := NewBuilder(cfg, system, logger)
+	assert.NoError(t, err)
+
+	// Same name and version, the schema alone cannot tell them apart
+	previousPkgs := []*models.Package{{Name: "curl", Version: "8.5.0", Ecosystem: models.EcosystemDeb}}
+	err = b.Store.Save("host5", &schema.HostInventory{Hostname: "host5", Packages: packages.ToSchema(previousPkgs)})
+	assert.NoError(t, err)
+	err = b.Store.SaveExtensions("host5", &models.HostExtensions{Hostname: "host5", Packages: previousPkgs})
+	assert.NoError(t, err)
+
+	pkgs := []*models.Package{{Name: "curl", Version: "8.5.0", Ecosystem: models.EcosystemHomebrew}}
+	b.Extensions = &models.HostExtensions{Hostname: "host5", Packages: pkgs}
+	req, _ := b.ManageDelta(&schema.HostInventory{Hostname: "host5", Packages: packages.ToSchema(pkgs)})
+	assert.NotNil(t, req)
+	assert.Len(t, req.GetDelta().PackagesAdded, 1)
+	assert.Len(t, req.GetDelta().PackagesRemoved, 1)
+	assert.NotNil(t, b.ExtensionsRequest.Delta)
+	assert.Equal(t, models.EcosystemHomebrew, b.ExtensionsRequest.Delta.PackagesAdded[0].Ecosystem)
+
+	err = b.Store.Delete("host5")
+	assert.NoError(t, err)
+	b.Store.Close()
+}
+
+func TestBuilder_ManageDelta_ComplianceTransitions(t *testing.T) {
+	cfg := options.RunOptions{}
+	cfg.Facter.Store.Path = "/tmp/store"
+	system := &models.System{}
+	system.Host.Hostname = "host6"
+	logger := logrus.New()
+	b, err := NewBuilder(cfg, system, logger)
+	assert.NoError(t, err)
+
+	previous := &schema.HostInventory{Hostname: "host6", ComplianceReport: &schema.ComplianceReport{
+		Profile:     "facter:cis-linux-l1",
+		RuleResults: []*schema.RuleCheckResult{{Id: "sshd_disable_root_login", Result: "pass"}},
+	}}
+	err = b.Store.Save("host6", previous)
+	assert.NoError(t, err)
+	err = b.Store.SaveExtensions("host6", &models.HostExtensions{Hostname: "host6", Compliance: ComplianceScore(previous.ComplianceReport, time.Now())})
+	assert.NoError(t, err)
+
+	// Only the compliance report changed, which the schema delta cannot carry
+	current := &schema.HostInventory{Hostname: "host6", ComplianceReport: &schema.ComplianceReport{
+		Profile:     "facter:cis-linux-l1",
+		RuleResults: []*schema.RuleCheckResult{{Id: "sshd_disable_root_login", Result: "fail"}},
+	}}
+	b.Extensions = &models.HostExtensions{Hostname: "host6", Compliance: ComplianceScore(current.ComplianceReport, time.Now())}
+	req, _ := b.ManageDelta(current)
+	assert.NotNil(t, req)
+	assert.NotNil(t, b.ExtensionsRequest.Delta)
+	assert.Len(t, b.ExtensionsRequest.Delta.ComplianceNewlyFailing, 1)
+	assert.Equal(t, b.Extensions.Compliance, b.ExtensionsRequest.Delta.ComplianceScore)
+	assert.Equal(t, []string{"sshd_disable_root_login"}, b.Extensions.Compliance.NewlyFailing)
+
+	err = b.Store.Delete("host6")
+	assert.NoError(t, err)
+	b.Store.Close()
+}
+
 func TestBuilder_CollectAll(t *testing.T) {
 	cfg := options.RunOptions{}
 	cfg.Facter.Store.Path = "/tmp/store"
@@ -0,0 +1,76 @@
+package inventory
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+)
+
+// ComplianceScore returns the score of a compliance report at the time of the run, nil without report.
+func ComplianceScore(report *schema.ComplianceReport, at time.Time) *models.ComplianceScore {
+	if report == nil {
+		return nil
+	}
+	score := &models.ComplianceScore{Time: at.UTC().Format(time.RFC3339), Profile: report.Profile}
+	if report.Score != nil {
+		value, err1 := strconv.ParseFloat(report.Score.Value, 64)
+		maximum, err2 := strconv.ParseFloat(report.Score.Maximum, 64)
+		if err1 == nil && err2 == nil && maximum > 0 {
+			score.Score = 100 * value / maximum
+		}
+	}
+	for _, r := range report.RuleResults {
+		switch r.Result {
+		case "pass":
+			score.Passed++
+		case "fail":
+			score.Failed++
+		}
+	}
+	return score
+}
+
+// ComputeComplianceTransitions returns the rules failing and the rules passing since the previous report.
+// Only the rules evaluated by both reports are compared, a rule added to the profile is not a transition.
+func ComputeComplianceTransitions(oldReport, newReport *schema.ComplianceReport) (failing, passing []*models.ComplianceTransition) {
+	if oldReport == nil || newReport == nil {
+		return nil, nil
+	}
+	previous := make(map[string]string, len(oldReport.RuleResults))
+	for _, r := range oldReport.RuleResults {
+		previous[r.Id] = r.Result
+	}
+	for _, r := range newReport.RuleResults {
+		old, ok := previous[r.Id]
+		if !ok || old == r.Result {
+			continue
+		}
+		transition := &models.ComplianceTransition{ID: r.Id, Title: r.Title, Severity: r.Severity, Previous: old, Result: r.Result}
+		switch {
+		case r.Result == "fail":
+			failing = append(failing, transition)
+		case r.Result == "pass" && old == "fail":
+			passing = append(passing, transition)
+		}
+	}
+	return failing, passing
+}
+
+// sameComplianceScore reports whether two runs have the same score, whatever their time.
+func sameComplianceScore(a, b *models.ComplianceScore) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Profile == b.Profile && a.Score == b.Score && a.Passed == b.Passed && a.Failed == b.Failed
+}
+
+// ruleIDs returns the ids of the rules of the transitions.
+func ruleIDs(transitions []*models.ComplianceTransition) []string {
+	var ids []string
+	for _, t := range transitions {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
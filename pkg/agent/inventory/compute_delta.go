@@ -3,9 +3,12 @@ package inventory
 import (
 	"fmt"
 	"hash/fnv"
+	"slices"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/klamhq/facter-oss/pkg/models"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -102,6 +105,148 @@ func ComputeDelta(oldInv, newInv *schema.HostInventory, logger *logrus.Logger) *
 	return delta
 }
 
+// ComputeExtensionsDelta computes the difference between the extensions of two runs.
+func ComputeExtensionsDelta(oldExt, newExt *models.HostExtensions) *models.HostExtensionsDelta {
+	delta := &models.HostExtensionsDelta{
+		Hostname: newExt.Hostname,
+	}
+
+	delta.PackagesAdded, delta.PackagesRemoved = DiffByKey(
+		oldExt.Packages,
+		newExt.Packages,
+		(*models.Package).Key,
+	)
+	delta.ModifiedFilesAdded, delta.ModifiedFilesRemoved = DiffByKey(
+		oldExt.ModifiedFiles,
+		newExt.ModifiedFiles,
+		(*models.ModifiedFile).Key,
+	)
+	delta.VulnerabilitiesAdded, delta.VulnerabilitiesRemoved = DiffByKey(
+		oldExt.Vulnerabilities,
+		newExt.Vulnerabilities,
+		(*models.VulnerabilityFinding).Key,
+	)
+	if newExt.Risk != nil && !cmp.Equal(oldExt.Risk, newExt.Risk) {
+		delta.Risk = newExt.Risk
+	}
+	delta.ImagesAdded, delta.ImagesRemoved = DiffByKey(
+		oldExt.Images,
+		newExt.Images,
+		(*models.ImageInventory).Key,
+	)
+	delta.ImagesChanged = changedImages(oldExt.Images, newExt.Images)
+	if newExt.Compliance != nil && !sameComplianceScore(oldExt.Compliance, newExt.Compliance) {
+		delta.ComplianceScore = newExt.Compliance
+	}
+	if newExt.Firewall != nil && !cmp.Equal(oldExt.Firewall, newExt.Firewall, ignoreFirewallCounters) {
+		delta.Firewall = newExt.Firewall
+	}
+	delta.ExposuresAdded, delta.ExposuresRemoved = diffExposures(reachable(oldExt.Exposures), reachable(newExt.Exposures))
+	if newExt.Routing != nil && !cmp.Equal(oldExt.Routing, newExt.Routing, ignoreRoutingChurn) {
+		delta.Routing = newExt.Routing
+	}
+
+	return delta
+}
+
+// reachable returns the exposures of the sockets reachable from another host.
+func reachable(exposures []*models.Exposure) []*models.Exposure {
+	var result []*models.Exposure
+	for _, e := range exposures {
+		if e.Level != models.ExposureLoopback {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// diffExposures returns the sockets which are new or reachable from further than in the previous run, and the
+// sockets which are no longer reachable or reachable from less far.
+func diffExposures(oldList, newList []*models.Exposure) (added, removed []*models.Exposure) {
+	oldMap := make(map[string]*models.Exposure, len(oldList))
+	for _, e := range oldList {
+		oldMap[e.Key()] = e
+	}
+	newMap := make(map[string]*models.Exposure, len(newList))
+	for _, e := range newList {
+		newMap[e.Key()] = e
+		if old, ok := oldMap[e.Key()]; !ok || levelRank(e.Level) > levelRank(old.Level) {
+			added = append(added, e)
+		}
+	}
+	for _, e := range oldList {
+		if current, ok := newMap[e.Key()]; !ok || levelRank(current.Level) < levelRank(e.Level) {
+			removed = append(removed, e)
+		}
+	}
+	return added, removed
+}
+
+// levelRank ranks the exposure levels, from the loopback to the internet.
+func levelRank(level string) int {
+	return slices.Index([]string{models.ExposureLoopback, models.ExposureLan, models.ExposurePublic}, level)
+}
+
+// exposureKeys returns the keys of the exposures.
+func exposureKeys(exposures []*models.Exposure) []string {
+	var keys []string
+	for _, e := range exposures {
+		keys = append(keys, e.Key())
+	}
+	return keys
+}
+
+// ignoreFirewallCounters ignores the counters of the firewall chains and rules, which change on every run.
+var ignoreFirewallCounters = cmp.Options{
+	cmpopts.IgnoreFields(models.FirewallChain{}, "Packets", "Bytes"),
+	cmpopts.IgnoreFields(models.FirewallRule{}, "Packets", "Bytes"),
+}
+
+// ignoreRoutingChurn ignores the neighbours, whose entries age and are resolved again, and the processes of the
+// network namespaces, which change on every run.
+var ignoreRoutingChurn = cmp.Options{
+	cmpopts.IgnoreFields(models.RoutingInventory{}, "Neighbours"),
+	cmpopts.IgnoreFields(models.NetworkNamespace{}, "Pid", "Processes"),
+}
+
+// changedImages returns the images of both runs whose packages, vulnerabilities or tags changed.
+func changedImages(oldImages, newImages []*models.ImageInventory) []*models.ImageInventory {
+	previous := make(map[string]*models.ImageInventory, len(oldImages))
+	for _, img := range oldImages {
+		previous[img.Key()] = img
+	}
+	var changed []*models.ImageInventory
+	for _, img := range newImages {
+		if old, ok := previous[img.Key()]; ok && !cmp.Equal(old, img) {
+			changed = append(changed, img)
+		}
+	}
+	return changed
+}
+
+// DiffByKey computes the added and removed items between two lists, items are compared by key only.
+// Removed items are returned in the order of the old list.
+func DiffByKey[T any](oldList, newList []T, getKey func(T) string) (added, removed []T) {
+	oldKeys := make(map[string]bool, len(oldList))
+	for _, o := range oldList {
+		oldKeys[getKey(o)] = true
+	}
+	newKeys := make(map[string]bool, len(newList))
+	for _, n := range newList {
+		k := getKey(n)
+		newKeys[k] = true
+		if !oldKeys[k] {
+			added = append(added, n)
+		}
+	}
+	for _, o := range oldList {
+		if !newKeys[getKey(o)] {
+			removed = append(removed, o)
+		}
+	}
+	return
+}
+
 // DiffGenericByHash computes the difference between two lists of proto messages based on their hashes.
 func DiffGenericByHash[T proto.Message](
 	oldList, newList []T,
@@ -149,8 +294,6 @@ func IsDeltaEmpty(d *schema.HostDeltaInventory) bool {
 		len(d.SshkeyaccessRemoved) == 0 &&
 		len(d.SshkeyinfoAdded) == 0 &&
 		len(d.SshkeyinfoRemoved) == 0 &&
-		len(d.SystemdservicesAdded) == 0 &&
-		len(d.SystemdservicesRemoved) == 0 &&
 		len(d.ProcessesAdded) == 0 &&
 		len(d.ProcessesRemoved) == 0 &&
 		d.Platform == nil &&
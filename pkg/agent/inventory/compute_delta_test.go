@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -91,3 +92,251 @@ func TestDiffByHash(t *testing.T) {
 	assert.Len(t, removed, 1)
 	assert.Equal(t, "pkg2", removed[0].Name)
 }
+
+func TestComputeExtensionsDelta(t *testing.T) {
+	oldExt := &models.HostExtensions{
+		Hostname: "test-host",
+		Packages: []*models.Package{
+			{Name: "curl", Version: "7.88.1-10", Architecture: "amd64", Ecosystem: models.EcosystemDeb},
+			{Name: "curl", Version: "8.5.0", Ecosystem: models.EcosystemHomebrew},
+		},
+	}
+	newExt := &models.HostExtensions{
+		Hostname: "test-host",
+		Packages: []*models.Package{
+			{Name: "curl", Version: "7.88.1-10", Architecture: "amd64", Ecosystem: models.EcosystemDeb},
+			{Name: "curl", Version: "8.6.0", Ecosystem: models.EcosystemHomebrew},
+		},
+	}
+
+	delta := ComputeExtensionsDelta(oldExt, newExt)
+	assert.Equal(t, "test-host", delta.Hostname)
+	assert.Len(t, delta.PackagesAdded, 1)
+	assert.Equal(t, "brew/curl@8.6.0", delta.PackagesAdded[0].Key())
+	assert.Len(t, delta.PackagesRemoved, 1)
+	assert.Equal(t, "brew/curl@8.5.0", delta.PackagesRemoved[0].Key())
+	assert.False(t, delta.IsEmpty())
+
+	assert.True(t, ComputeExtensionsDelta(newExt, newExt).IsEmpty())
+}
+
+func TestComputeExtensionsDelta_ModifiedFiles(t *testing.T) {
+	cat := &models.ModifiedFile{Path: "/usr/bin/cat", Package: "coreutils", Ecosystem: models.EcosystemDeb, Reason: models.FileChanged, Actual: "11e1f795"}
+	oldExt := &models.HostExtensions{
+		Hostname:      "test-host",
+		ModifiedFiles: []*models.ModifiedFile{cat},
+	}
+	newExt := &models.HostExtensions{
+		Hostname: "test-host",
+		ModifiedFiles: []*models.ModifiedFile{
+			cat,
+			{Path: "/usr/bin/ls", Package: "coreutils", Ecosystem: models.EcosystemDeb, Reason: models.FileChanged, Actual: "61e240cc"},
+		},
+	}
+
+	delta := ComputeExtensionsDelta(oldExt, newExt)
+	assert.Len(t, delta.ModifiedFilesAdded, 1)
+	assert.Equal(t, "/usr/bin/ls", delta.ModifiedFilesAdded[0].Path)
+	assert.Empty(t, delta.ModifiedFilesRemoved)
+	assert.False(t, delta.IsEmpty())
+
+	// Modified again, the previous modification is replaced
+	newExt.ModifiedFiles = []*models.ModifiedFile{
+		{Path: "/usr/bin/cat", Package: "coreutils", Ecosystem: models.EcosystemDeb, Reason: models.FileChanged, Actual: "0badc0de"},
+	}
+	delta = ComputeExtensionsDelta(oldExt, newExt)
+	assert.Len(t, delta.ModifiedFilesAdded, 1)
+	assert.Len(t, delta.ModifiedFilesRemoved, 1)
+}
+
+func TestComputeExtensionsDelta_Vulnerabilities(t *testing.T) {
+	openssl := &models.VulnerabilityFinding{ID: "CVE-2024-0727", Package: "openssl", InstalledVersion: "3.0.11-1~deb12u2", Risk: models.RiskMedium}
+	oldExt := &models.HostExtensions{
+		Hostname:        "test-host",
+		Vulnerabilities: []*models.VulnerabilityFinding{openssl},
+		Risk:            &models.HostRisk{Rating: models.RiskMedium, Counts: map[string]int{models.RiskMedium: 1}},
+	}
+	newExt := &models.HostExtensions{
+		Hostname: "test-host",
+		Vulnerabilities: []*models.VulnerabilityFinding{
+			openssl,
+			{ID: "CVE-2024-3094", Package: "liblzma5", InstalledVersion: "5.6.0-0.2", KnownExploited: true, Risk: models.RiskCritical},
+		},
+		Risk: &models.HostRisk{Rating: models.RiskCritical, Counts: map[string]int{models.RiskMedium: 1, models.RiskCritical: 1}, KnownExploited: 1},
+	}
+
+	delta := ComputeExtensionsDelta(oldExt, newExt)
+	assert.Len(t, delta.VulnerabilitiesAdded, 1)
+	assert.Equal(t, "CVE-2024-3094", delta.VulnerabilitiesAdded[0].ID)
+	assert.Empty(t, delta.VulnerabilitiesRemoved)
+	assert.Equal(t, newExt.Risk, delta.Risk)
+	assert.False(t, delta.IsEmpty())
+
+	// Upgraded, the vulnerability is fixed
+	delta = ComputeExtensionsDelta(newExt, oldExt)
+	assert.Len(t, delta.VulnerabilitiesRemoved, 1)
+	assert.Equal(t, models.RiskMedium, delta.Risk.Rating)
+
+	assert.True(t, ComputeExtensionsDelta(newExt, newExt).IsEmpty())
+}
+
+func TestComputeExtensionsDelta_Images(t *testing.T) {
+	nginx := &models.ImageInventory{
+		ID:       "sha256:aaaa",
+		RepoTags: []string{"nginx:1.25"},
+		Packages: []*models.Package{{Name: "openssl", Version: "3.0.11-1~deb12u2", Ecosystem: models.EcosystemDeb}},
+	}
+	redis := &models.ImageInventory{ID: "sha256:bbbb", RepoTags: []string{"redis:7"}}
+	oldExt := &models.HostExtensions{Hostname: "test-host", Images: []*models.ImageInventory{nginx, redis}}
+
+	nginxRescanned := &models.ImageInventory{
+		ID:              nginx.ID,
+		RepoTags:        nginx.RepoTags,
+		Packages:        nginx.Packages,
+		Vulnerabilities: []*models.VulnerabilityFinding{{ID: "CVE-2024-0727", Package: "openssl", InstalledVersion: "3.0.11-1~deb12u2"}},
+	}
+	postgres := &models.ImageInventory{ID: "sha256:cccc", RepoTags: []string{"postgres:16"}}
+	newExt := &models.HostExtensions{Hostname: "test-host", Images: []*models.ImageInventory{nginxRescanned, postgres}}
+
+	delta := ComputeExtensionsDelta(oldExt, newExt)
+	assert.Equal(t, []*models.ImageInventory{postgres}, delta.ImagesAdded)
+	assert.Equal(t, []*models.ImageInventory{redis}, delta.ImagesRemoved)
+	assert.Equal(t, []*models.ImageInventory{nginxRescanned}, delta.ImagesChanged)
+	assert.False(t, delta.IsEmpty())
+
+	assert.True(t, ComputeExtensionsDelta(newExt, newExt).IsEmpty())
+}
+
+func TestComputeExtensionsDelta_Firewall(t *testing.T) {
+	ufw := func(rules ...*models.UfwRule) *models.FirewallInventory {
+		return &models.FirewallInventory{Backend: models.FirewallBackendUfw, Ufw: &models.UfwStatus{Active: true, Rules: rules}}
+	}
+	ssh := &models.UfwRule{To: "22/tcp", Action: "ALLOW", Direction: "IN", From: "Anywhere"}
+	oldExt := &models.HostExtensions{Hostname: "test-host", Firewall: ufw(ssh)}
+	newExt := &models.HostExtensions{Hostname: "test-host", Firewall: ufw(ssh, &models.UfwRule{To: "80/tcp", Action: "ALLOW", Direction: "IN", From: "Anywhere"})}
+
+	delta := ComputeExtensionsDelta(oldExt, newExt)
+	assert.Equal(t, newExt.Firewall, delta.Firewall)
+	assert.False(t, delta.IsEmpty())
+
+	assert.True(t, ComputeExtensionsDelta(oldExt, &models.HostExtensions{Hostname: "test-host", Firewall: ufw(ssh)}).IsEmpty())
+
+	// Counters change on every run
+	rule := models.FirewallRule{Origin: "iptables", Table: "filter", Chain: "INPUT", Position: 1, Action: models.FirewallActionAccept}
+	counted := rule
+	counted.Packets, counted.Bytes = 12, 720
+	oldExt.Firewall.Rules = []*models.FirewallRule{&rule}
+	newExt = &models.HostExtensions{Hostname: "test-host", Firewall: ufw(ssh)}
+	newExt.Firewall.Rules = []*models.FirewallRule{&counted}
+	assert.True(t, ComputeExtensionsDelta(oldExt, newExt).IsEmpty())
+}
+
+func TestComputeExtensionsDelta_Exposures(t *testing.T) {
+	ssh := &models.Exposure{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Level: models.ExposureLan}
+	postgres := &models.Exposure{Protocol: "tcp", Address: "127.0.0.1", Port: 5432, Level: models.ExposureLoopback}
+	oldExt := &models.HostExtensions{Hostname: "test-host", Exposures: []*models.Exposure{ssh}}
+
+	sshPublic := &models.Exposure{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Level: models.ExposurePublic}
+	redis := &models.Exposure{Protocol: "tcp", Address: "0.0.0.0", Port: 6379, Level: models.ExposureLan}
+	newExt := &models.HostExtensions{Hostname: "test-host", Exposures: []*models.Exposure{sshPublic, postgres, redis}}
+
+	delta := ComputeExtensionsDelta(oldExt, newExt)
+	assert.Equal(t, []*models.Exposure{sshPublic, redis}, delta.ExposuresAdded, "loopback sockets are not exposed")
+	assert.Empty(t, delta.ExposuresRemoved, "ssh is reachable from further")
+	assert.False(t, delta.IsEmpty())
+
+	assert.True(t, ComputeExtensionsDelta(oldExt, &models.HostExtensions{Hostname: "test-host", Exposures: []*models.Exposure{ssh, postgres}}).IsEmpty())
+}
+
+func TestComputeExtensionsDelta_ExposureLowered(t *testing.T) {
+	sshPublic := &models.Exposure{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Level: models.ExposurePublic}
+	ssh := &models.Exposure{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Level: models.ExposureLan, Filtered: true}
+	oldExt := &models.HostExtensions{Hostname: "test-host", Exposures: []*models.Exposure{sshPublic}}
+	newExt := &models.HostExtensions{Hostname: "test-host", Exposures: []*models.Exposure{ssh}}
+
+	delta := ComputeExtensionsDelta(oldExt, newExt)
+	assert.Empty(t, delta.ExposuresAdded, "the socket is less exposed")
+	assert.Equal(t, []*models.Exposure{sshPublic}, delta.ExposuresRemoved)
+
+	delta = ComputeExtensionsDelta(newExt, oldExt)
+	assert.Equal(t, []*models.Exposure{sshPublic}, delta.ExposuresAdded)
+	assert.Empty(t, delta.ExposuresRemoved)
+}
+
+func TestComputeExtensionsDelta_Routing(t *testing.T) {
+	routing := func(gateway, neighbourState string, processes int) *models.RoutingInventory {
+		return &models.RoutingInventory{
+			Routes:     []*models.Route{{Family: "inet", Table: "main", Type: "unicast", Destination: "0.0.0.0/0", Gateway: gateway, Interface: "eth0", Default: true}},
+			Neighbours: []*models.Neighbour{{Family: "inet", Address: gateway, LinkAddress: "52:54:00:12:34:56", Interface: "eth0", State: neighbourState}},
+			Namespaces: []*models.NetworkNamespace{{Inode: 4026531840, Host: true, Pid: 1, Processes: processes, Interfaces: []string{"eth0", "lo"}}},
+		}
+	}
+	oldExt := &models.HostExtensions{Hostname: "test-host", Routing: routing("192.168.1.1", "REACHABLE", 120)}
+
+	newExt := &models.HostExtensions{Hostname: "test-host", Routing: routing("192.168.1.1", "STALE", 131)}
+	assert.True(t, ComputeExtensionsDelta(oldExt, newExt).IsEmpty(), "neighbours and processes change on every run")
+
+	newExt = &models.HostExtensions{Hostname: "test-host", Routing: routing("192.168.1.254", "REACHABLE", 120)}
+	delta := ComputeExtensionsDelta(oldExt, newExt)
+	assert.Equal(t, newExt.Routing, delta.Routing)
+	assert.False(t, delta.IsEmpty())
+}
+
+func TestComputeComplianceTransitions(t *testing.T) {
+	oldReport := &schema.ComplianceReport{Profile: "facter:cis-linux-l1", RuleResults: []*schema.RuleCheckResult{
+		{Id: "sshd_disable_root_login", Result: "pass"},
+		{Id: "sysctl_net_ipv4_ip_forward", Result: "fail"},
+		{Id: "package_telnet_removed", Result: "notchecked"},
+		{Id: "service_cups_disabled", Result: "fail"},
+	}}
+	newReport := &schema.ComplianceReport{Profile: "facter:cis-linux-l1", RuleResults: []*schema.RuleCheckResult{
+		{Id: "sshd_disable_root_login", Title: "SSH root login is disabled", Severity: "high", Result: "fail"},
+		{Id: "sysctl_net_ipv4_ip_forward", Severity: "medium", Result: "pass"},
+		{Id: "package_telnet_removed", Result: "fail"},
+		{Id: "service_cups_disabled", Result: "fail"},
+		{Id: "file_permissions_etc_shadow", Result: "fail"},
+	}}
+
+	failing, passing := ComputeComplianceTransitions(oldReport, newReport)
+	assert.Equal(t, []*models.ComplianceTransition{
+		{ID: "sshd_disable_root_login", Title: "SSH root login is disabled", Severity: "high", Previous: "pass", Result: "fail"},
+		{ID: "package_telnet_removed", Previous: "notchecked", Result: "fail"},
+	}, failing, "rules new to the report are not transitions")
+	assert.Equal(t, []*models.ComplianceTransition{
+		{ID: "sysctl_net_ipv4_ip_forward", Severity: "medium", Previous: "fail", Result: "pass"},
+	}, passing)
+
+	failing, passing = ComputeComplianceTransitions(nil, newReport)
+	assert.Empty(t, failing)
+	assert.Empty(t, passing)
+}
+
+func TestComplianceScore(t *testing.T) {
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, ComplianceScore(nil, at))
+
+	report := &schema.ComplianceReport{
+		Profile: "xccdf_org.ssgproject.content_profile_cis_level1_server",
+		Score:   &schema.Score{Maximum: "100.000000", Value: "75.500000"},
+		RuleResults: []*schema.RuleCheckResult{
+			{Id: "a", Result: "pass"}, {Id: "b", Result: "pass"}, {Id: "c", Result: "fail"}, {Id: "d", Result: "notapplicable"},
+		},
+	}
+	score := ComplianceScore(report, at)
+	assert.Equal(t, &models.ComplianceScore{
+		Time:    "2026-10-01T12:00:00Z",
+		Profile: "xccdf_org.ssgproject.content_profile_cis_level1_server",
+		Score:   75.5,
+		Passed:  2,
+		Failed:  1,
+	}, score)
+
+	// The score is only part of the delta when it changed
+	oldExt := &models.HostExtensions{Hostname: "test-host", Compliance: score}
+	rerun := *score
+	rerun.Time = "2026-10-02T12:00:00Z"
+	assert.True(t, ComputeExtensionsDelta(oldExt, &models.HostExtensions{Hostname: "test-host", Compliance: &rerun}).IsEmpty())
+	rerun.Score, rerun.Passed, rerun.Failed = 50, 1, 2
+	delta := ComputeExtensionsDelta(oldExt, &models.HostExtensions{Hostname: "test-host", Compliance: &rerun})
+	assert.Equal(t, &rerun, delta.ComplianceScore)
+}
@@ -0,0 +1,109 @@
+package remediation
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isAnsible reports whether a fix is a list of Ansible tasks rather than a shell script.
+func isAnsible(script string) bool {
+	_, ok := ansibleTasks(script)
+	return ok
+}
+
+// ansibleTasks parses the tasks of an Ansible fix.
+func ansibleTasks(script string) ([]*yaml.Node, bool) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(script), &doc); err != nil || len(doc.Content) == 0 {
+		return nil, false
+	}
+	seq := doc.Content[0]
+	if seq.Kind != yaml.SequenceNode || len(seq.Content) == 0 {
+		return nil, false
+	}
+	for _, task := range seq.Content {
+		if task.Kind != yaml.MappingNode {
+			return nil, false
+		}
+	}
+	return seq.Content, true
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
+
+func mapping(pairs ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: pairs}
+}
+
+// addTags adds tags to a task, after its own tags.
+func addTags(task *yaml.Node, tags []*yaml.Node) {
+	for i := 0; i+1 < len(task.Content); i += 2 {
+		if task.Content[i].Value != "tags" {
+			continue
+		}
+		existing := task.Content[i+1]
+		if existing.Kind == yaml.ScalarNode {
+			existing = &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{existing}}
+		}
+		existing.Content = append(existing.Content, tags...)
+		task.Content[i+1] = existing
+		return
+	}
+	task.Content = append(task.Content, scalar("tags"), &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, Content: tags})
+}
+
+// generateAnsible returns a playbook with the tasks of the fixes, tagged by rule id and severity.
+// Shell fixes are run by a shell task.
+func generateAnsible(plan *Plan) ([]byte, error) {
+	tasks := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, fix := range plan.Fixes {
+		tags := []*yaml.Node{scalar(fix.RuleID)}
+		if fix.Severity != "" {
+			tags = append(tags, scalar(fix.Severity))
+		}
+		if ruleTasks, ok := ansibleTasks(fix.Script); ok {
+			for _, task := range ruleTasks {
+				addTags(task, tags)
+				tasks.Content = append(tasks.Content, task)
+			}
+			continue
+		}
+		name := fix.RuleID
+		if fix.Title != "" {
+			name = fmt.Sprintf("%s: %s", fix.RuleID, fix.Title)
+		}
+		tasks.Content = append(tasks.Content, mapping(
+			scalar("name"), scalar(name),
+			scalar("ansible.builtin.shell"), &yaml.Node{Kind: yaml.ScalarNode, Style: yaml.LiteralStyle, Value: fix.Script + "\n"},
+			scalar("args"), mapping(scalar("executable"), scalar("/bin/bash")),
+			scalar("tags"), &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, Content: tags},
+		))
+	}
+
+	playbook := &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{mapping(
+		scalar("name"), scalar(fmt.Sprintf("Remediate the failing rules of %s", plan.Profile)),
+		scalar("hosts"), scalar("all"),
+		scalar("become"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"},
+		scalar("tasks"), tasks,
+	)}}
+
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	for _, line := range plan.header() {
+		b.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+	}
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(playbook); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,53 @@
+package remediation
+
+import (
+	"fmt"
+	"strings"
+)
+
+const bashSeparator = "###############################################################################"
+
+// generateBash returns a script running the fix of every rule in its own subshell, a failing fix
+// is reported and does not stop the next ones. Ansible fixes are left as comments.
+func generateBash(plan *Plan) []byte {
+	var b strings.Builder
+	b.WriteString("#!/usr/bin/env bash\n")
+	for _, line := range plan.header() {
+		b.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+	}
+	b.WriteString(`
+if [ "$(id -u)" -ne 0 ]; then
+    echo "This remediation must be run as root" >&2
+    exit 1
+fi
+
+failed=0
+`)
+	for _, fix := range plan.Fixes {
+		fmt.Fprintf(&b, "\n%s\n# %s (%s)\n", bashSeparator, fix.RuleID, orUnknown(fix.Severity))
+		if fix.Title != "" {
+			fmt.Fprintf(&b, "# %s\n", fix.Title)
+		}
+		b.WriteString(bashSeparator + "\n")
+		if isAnsible(fix.Script) {
+			b.WriteString("# Only an Ansible fix is available, see facter compliance remediate --format ansible\n")
+			continue
+		}
+		fmt.Fprintf(&b, "echo %s\n(\n%s\n) || { echo %s >&2; failed=1; }\n",
+			shellQuote("Remediating "+fix.RuleID), fix.Script, shellQuote("Remediation of "+fix.RuleID+" failed"))
+	}
+	b.WriteString("\nexit $failed\n")
+	return []byte(b.String())
+}
+
+// shellQuote quotes a string as a single shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func orUnknown(severity string) string {
+	if severity == "" {
+		return "unknown"
+	}
+	return severity
+}
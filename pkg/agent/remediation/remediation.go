@@ -0,0 +1,152 @@
+package remediation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Remediation formats.
+const (
+	FormatBash    = "bash"    // Shell script
+	FormatAnsible = "ansible" // Ansible playbook
+)
+
+// ValidFormat reports whether format is one of the Format* constants.
+func ValidFormat(format string) bool {
+	return format == FormatBash || format == FormatAnsible
+}
+
+// Fix is the remediation of a failing rule.
+type Fix struct {
+	RuleID   string
+	Title    string
+	Severity string
+	Script   string // Shell script or Ansible tasks
+}
+
+// Plan is the remediation of the failing rules of a compliance report.
+type Plan struct {
+	Hostname string
+	Profile  string
+	Fixes    []Fix
+	// Manual are the ids of the failing rules without fix.
+	Manual []string
+}
+
+// FromSchema converts the compliance report of an inventory.
+func FromSchema(report *schema.ComplianceReport) *models.ComplianceReport {
+	if report == nil {
+		return nil
+	}
+	r := &models.ComplianceReport{Profile: report.Profile}
+	if report.Score != nil {
+		r.Score = models.Score{Maximum: report.Score.Maximum, Value: report.Score.Value}
+	}
+	for _, result := range report.RuleResults {
+		r.RuleResults = append(r.RuleResults, models.RuleCheckResult{
+			ID:          result.Id,
+			Title:       result.Title,
+			Description: result.Description,
+			Result:      result.Result,
+			Severity:    result.Severity,
+			Fix:         result.Fix,
+		})
+	}
+	return r
+}
+
+// matchRule reports whether a rule id is one of the ids, given in full or without the
+// xccdf_<vendor>_rule_ prefix of the OpenSCAP rules.
+func matchRule(id string, ids []string) bool {
+	for _, want := range ids {
+		if id == want || strings.HasSuffix(id, "_rule_"+want) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPlan returns the fixes of the failing rules of the report, of the rules given or every rule,
+// except the skipped ones.
+func NewPlan(hostname string, report *models.ComplianceReport, rules, skip []string) *Plan {
+	plan := &Plan{Hostname: hostname, Profile: report.Profile}
+	for _, result := range report.RuleResults {
+		if result.Result != "fail" || (len(rules) > 0 && !matchRule(result.ID, rules)) || matchRule(result.ID, skip) {
+			continue
+		}
+		script := strings.TrimSpace(result.Fix)
+		if script == "" {
+			plan.Manual = append(plan.Manual, result.ID)
+			continue
+		}
+		plan.Fixes = append(plan.Fixes, Fix{
+			RuleID:   result.ID,
+			Title:    strings.Join(strings.Fields(result.Title), " "),
+			Severity: result.Severity,
+			Script:   script,
+		})
+	}
+	// Most severe first
+	slices.SortStableFunc(plan.Fixes, func(a, b Fix) int {
+		return severityRank(b.Severity) - severityRank(a.Severity)
+	})
+	return plan
+}
+
+func severityRank(severity string) int {
+	return slices.Index([]string{"info", "low", "medium", "high", "critical"}, strings.ToLower(severity))
+}
+
+// Generate returns the script or the playbook of the plan in the given format.
+func Generate(format string, plan *Plan) ([]byte, error) {
+	switch format {
+	case FormatBash:
+		return generateBash(plan), nil
+	case FormatAnsible:
+		return generateAnsible(plan)
+	}
+	return nil, fmt.Errorf("unsupported remediation format %q", format)
+}
+
+// header returns the comment lines describing the plan, without comment markers.
+func (p *Plan) header() []string {
+	lines := []string{
+		fmt.Sprintf("Remediation of the failing rules of the %s profile on %s.", p.Profile, p.Hostname),
+		"Generated by facter, which never applies it: review every fix before applying it.",
+	}
+	if len(p.Manual) > 0 {
+		lines = append(lines, "", "Failing rules without automated fix, to remediate manually:")
+		for _, id := range p.Manual {
+			lines = append(lines, "  - "+id)
+		}
+	}
+	return lines
+}
+
+// Diff returns the unified diff between the current content of a file, empty when it does not exist, and the generated one.
+func Diff(name string, current, generated []byte) (string, error) {
+	from := name
+	if current == nil {
+		from = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(current),
+		B:        splitLines(generated),
+		FromFile: from,
+		ToFile:   name,
+		Context:  3,
+	})
+}
+
+// splitLines splits a content in lines, keeping their line feed.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	return difflib.SplitLines(strings.TrimSuffix(string(content), "\n"))
+}
@@ -0,0 +1,103 @@
+package remediation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func testReport() *models.ComplianceReport {
+	return &models.ComplianceReport{
+		Profile: "xccdf_org.ssgproject.content_profile_cis_level1_server",
+		RuleResults: []models.RuleCheckResult{
+			{ID: "xccdf_org.ssgproject.content_rule_sshd_disable_root_login", Title: "Disable SSH Root Login", Result: "pass", Severity: "medium", Fix: "sed -i ..."},
+			{ID: "xccdf_org.ssgproject.content_rule_sysctl_kernel_randomize_va_space", Title: "Enable\n  Randomized Layout", Result: "fail", Severity: "medium",
+				Fix: "sysctl -q -n -w kernel.randomize_va_space=2\n"},
+			{ID: "xccdf_org.ssgproject.content_rule_file_permissions_etc_shadow", Title: "Verify Permissions on /etc/shadow", Result: "fail", Severity: "high",
+				Fix: "chmod u-x,g-wx,o-rwx /etc/shadow"},
+			{ID: "xccdf_org.ssgproject.content_rule_package_telnet_removed", Title: "Remove telnet", Result: "fail", Severity: "low",
+				Fix: "- name: Ensure telnet is removed\n  ansible.builtin.package:\n    name: telnet\n    state: absent\n  tags:\n    - CCE-80000-0\n"},
+			{ID: "xccdf_org.ssgproject.content_rule_partition_for_tmp", Title: "Ensure /tmp Located On Separate Partition", Result: "fail", Severity: "low"},
+		},
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	plan := NewPlan("web-01", testReport(), nil, nil)
+	ids := []string{}
+	for _, fix := range plan.Fixes {
+		ids = append(ids, fix.RuleID)
+	}
+	assert.Equal(t, []string{
+		"xccdf_org.ssgproject.content_rule_file_permissions_etc_shadow",
+		"xccdf_org.ssgproject.content_rule_sysctl_kernel_randomize_va_space",
+		"xccdf_org.ssgproject.content_rule_package_telnet_removed",
+	}, ids, "failing rules with fix, most severe first")
+	assert.Equal(t, "Enable Randomized Layout", plan.Fixes[1].Title)
+	assert.Equal(t, []string{"xccdf_org.ssgproject.content_rule_partition_for_tmp"}, plan.Manual)
+
+	plan = NewPlan("web-01", testReport(), []string{"sysctl_kernel_randomize_va_space", "package_telnet_removed", "sshd_disable_root_login"}, []string{"xccdf_org.ssgproject.content_rule_package_telnet_removed"})
+	assert.Len(t, plan.Fixes, 1)
+	assert.Equal(t, "xccdf_org.ssgproject.content_rule_sysctl_kernel_randomize_va_space", plan.Fixes[0].RuleID)
+	assert.Empty(t, plan.Manual)
+}
+
+func TestGenerateBash(t *testing.T) {
+	bin, err := Generate(FormatBash, NewPlan("web-01", testReport(), nil, nil))
+	assert.NoError(t, err)
+	script := string(bin)
+
+	assert.True(t, strings.HasPrefix(script, "#!/usr/bin/env bash\n# Remediation of the failing rules of the xccdf_org.ssgproject.content_profile_cis_level1_server profile on web-01.\n"))
+	assert.Contains(t, script, "#   - xccdf_org.ssgproject.content_rule_partition_for_tmp\n")
+	assert.Contains(t, script, "(\nchmod u-x,g-wx,o-rwx /etc/shadow\n) || { echo 'Remediation of xccdf_org.ssgproject.content_rule_file_permissions_etc_shadow failed' >&2; failed=1; }\n")
+	assert.Contains(t, script, "(\nsysctl -q -n -w kernel.randomize_va_space=2\n)")
+	assert.NotContains(t, script, "ansible.builtin.package")
+	assert.True(t, strings.HasSuffix(script, "exit $failed\n"))
+
+	_, err = Generate("puppet", &Plan{})
+	assert.Error(t, err)
+}
+
+func TestGenerateAnsible(t *testing.T) {
+	bin, err := Generate(FormatAnsible, NewPlan("web-01", testReport(), nil, nil))
+	assert.NoError(t, err)
+	assert.Contains(t, string(bin), "#   - xccdf_org.ssgproject.content_rule_partition_for_tmp\n")
+
+	var playbook []struct {
+		Hosts  string           `yaml:"hosts"`
+		Become bool             `yaml:"become"`
+		Tasks  []map[string]any `yaml:"tasks"`
+	}
+	assert.NoError(t, yaml.Unmarshal(bin, &playbook))
+	assert.Len(t, playbook, 1)
+	assert.Equal(t, "all", playbook[0].Hosts)
+	assert.True(t, playbook[0].Become)
+	tasks := playbook[0].Tasks
+	assert.Len(t, tasks, 3)
+
+	assert.Equal(t, "xccdf_org.ssgproject.content_rule_file_permissions_etc_shadow: Verify Permissions on /etc/shadow", tasks[0]["name"])
+	assert.Equal(t, "chmod u-x,g-wx,o-rwx /etc/shadow\n", tasks[0]["ansible.builtin.shell"])
+	assert.Equal(t, []any{"xccdf_org.ssgproject.content_rule_file_permissions_etc_shadow", "high"}, tasks[0]["tags"])
+
+	// Ansible fixes are kept, with their own tags
+	assert.Equal(t, "Ensure telnet is removed", tasks[2]["name"])
+	assert.Equal(t, map[string]any{"name": "telnet", "state": "absent"}, tasks[2]["ansible.builtin.package"])
+	assert.Equal(t, []any{"CCE-80000-0", "xccdf_org.ssgproject.content_rule_package_telnet_removed", "low"}, tasks[2]["tags"])
+}
+
+func TestDiff(t *testing.T) {
+	diff, err := Diff("fix.sh", nil, []byte("#!/usr/bin/env bash\nexit 0\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "--- /dev/null\n+++ fix.sh\n@@ -0,0 +1,2 @@\n+#!/usr/bin/env bash\n+exit 0\n", diff)
+
+	diff, err = Diff("fix.sh", []byte("#!/usr/bin/env bash\nexit 1\n"), []byte("#!/usr/bin/env bash\nexit 0\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "--- fix.sh\n+++ fix.sh\n@@ -1,2 +1,2 @@\n #!/usr/bin/env bash\n-exit 1\n+exit 0\n", diff)
+
+	diff, err = Diff("fix.sh", []byte("exit 0\n"), []byte("exit 0\n"))
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+}
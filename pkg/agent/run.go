@@ -2,10 +2,13 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/klamhq/facter-oss/pkg/agent/collect/applications"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/compliance"
+	"github.com/klamhq/facter-oss/pkg/agent/collect/images"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/networks"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/packages"
 	"github.com/klamhq/facter-oss/pkg/agent/collect/platform"
@@ -20,9 +23,13 @@ import (
 	"github.com/klamhq/facter-oss/pkg/options"
 	"github.com/klamhq/facter-oss/pkg/performance"
 	"github.com/klamhq/facter-oss/pkg/utils"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/sirupsen/logrus"
 )
 
+// ErrRiskThreshold is returned by Run when the risk of the host reaches the failOn rating of the risk policy.
+var ErrRiskThreshold = errors.New("vulnerability risk policy threshold exceeded")
+
 // RunAgent is the main function to run the agent.
 // It collects system facts, crafts a protobuf message, and sends it to the configured output.
 // It also handles performance profiling if enabled in the configuration.
@@ -38,17 +45,72 @@ func Run(cfg *options.RunOptions) error {
 
 	logger.Debugf("Log verbosity set to %s", defaultLogLevel)
 
+	if failOn := cfg.Facter.Vulnerabilities.Policy.FailOn; failOn != "" && !models.ValidRisk(failOn) {
+		return fmt.Errorf("invalid risk policy failOn %q, expected low, medium, high or critical", failOn)
+	}
+
 	if cfg.Facter.PerformanceProfiling.Enabled {
 		performance.Profiling(logger)
 	}
 	systemGather := system.GetSystem()
 
-	b, err := inventory.NewBuilder(*cfg, systemGather, logger)
+	b, err := newBuilder(cfg, systemGather, logger)
 	if err != nil {
 		logger.WithError(err).Error("Run")
 		return err
 	}
 
+	inventory, err := b.Build(context.Background())
+	if err != nil {
+		logger.WithError(err).Error("Unable to build inventory")
+		return err
+	}
+	inventoryMsg, fullInventory := b.ManageDelta(inventory)
+	if inventoryMsg == nil {
+		logger.Info("No inventory changes detected, nothing to do !")
+		// The score history records every run, whether the inventory is sent or not
+		if b.Extensions != nil && b.Extensions.Compliance != nil {
+			if err := b.Store.AppendComplianceScore(inventory.Hostname, b.Extensions.Compliance); err != nil {
+				logger.WithError(err).Error("Failed to save compliance score history")
+			}
+		}
+		return checkRiskPolicy(cfg, b.Extensions)
+	}
+
+	err = sink.SinkInventory(cfg, logger, b.Store, inventoryMsg, fullInventory, b.ExtensionsRequest, b.Extensions)
+	if err != nil {
+		logger.WithError(err).Error("Failed to sink inventory")
+		return err
+	}
+
+	elapsed := time.Since(start).Round(time.Millisecond)
+	logger.Infof("Runned in %s", elapsed)
+	return checkRiskPolicy(cfg, b.Extensions)
+}
+
+// Collect collects the system facts and returns the full inventory and its extensions, without
+// computing the changes since the previous run nor sending them.
+func Collect(cfg *options.RunOptions, logger *logrus.Logger) (*schema.HostInventory, *models.HostExtensions, error) {
+	b, err := newBuilder(cfg, system.GetSystem(), logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer b.Store.Close()
+
+	inventory, err := b.Build(context.Background())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to build inventory: %w", err)
+	}
+	return inventory, b.Extensions, nil
+}
+
+// newBuilder returns an inventory builder with the collectors of the configuration.
+func newBuilder(cfg *options.RunOptions, systemGather *models.System, logger *logrus.Logger) (*inventory.Builder, error) {
+	b, err := inventory.NewBuilder(*cfg, systemGather, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	b.Platform = platform.New(b.Log, &b.Cfg.Facter.Inventory.Platform, models.SystemPaths{
 		InitCheckPath: b.Cfg.Facter.Inventory.Platform.System.InitCheckPath,
 		MachineID:     b.Cfg.Facter.Inventory.Platform.System.MachineID,
@@ -71,24 +133,23 @@ func Run(cfg *options.RunOptions) error {
 
 	b.VulnerabilityReport = vulnerability.New(b.Log, &b.Cfg.Facter.Vulnerabilities)
 
-	inventory, err := b.Build(context.Background())
-	if err != nil {
-		logger.WithError(err).Error("Unable to build inventory")
-		return err
-	}
-	inventoryMsg, fullInventory := b.ManageDelta(inventory)
-	if inventoryMsg == nil {
-		logger.Info("No inventory changes detected, nothing to do !")
+	b.Images = images.New(b.Log, &b.Cfg.Facter.Inventory.Applications.Docker.Images, &b.Cfg.Facter.Inventory.Packages, &b.Cfg.Facter.Vulnerabilities)
+
+	return b, nil
+}
+
+// checkRiskPolicy fails when the risk of the host reaches the failOn rating, once the inventory has been sent,
+// or when the risk could not be computed.
+func checkRiskPolicy(cfg *options.RunOptions, extensions *models.HostExtensions) error {
+	failOn := cfg.Facter.Vulnerabilities.Policy.FailOn
+	if failOn == "" {
 		return nil
 	}
-
-	err = sink.SinkInventory(cfg, logger, b.Store, inventoryMsg, fullInventory)
-	if err != nil {
-		logger.WithError(err).Error("Failed to sink inventory")
-		return err
+	if extensions == nil || extensions.Risk == nil {
+		return fmt.Errorf("unable to apply the risk policy, the vulnerability risk of the host was not computed")
+	}
+	if models.RiskAtLeast(extensions.Risk.Rating, failOn) {
+		return fmt.Errorf("%w: host risk is %s, policy fails on %s", ErrRiskThreshold, extensions.Risk.Rating, failOn)
 	}
-
-	elapsed := time.Since(start).Round(time.Millisecond)
-	logger.Infof("Runned in %s", elapsed)
 	return nil
 }
@@ -3,6 +3,7 @@ package agent
 import (
 	"testing"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
 	"github.com/stretchr/testify/assert"
 )
@@ -56,3 +57,29 @@ func TestRunFullOpts(t *testing.T) {
 	err := Run(&cfg)
 	assert.NoError(t, err)
 }
+
+func TestRunInvalidFailOn(t *testing.T) {
+	cfg := options.RunOptions{}
+	cfg.Facter.Vulnerabilities.Policy.FailOn = "severe"
+	err := Run(&cfg)
+	assert.ErrorContains(t, err, "invalid risk policy")
+
+	// Every host reaches none
+	cfg.Facter.Vulnerabilities.Policy.FailOn = models.RiskNone
+	err = Run(&cfg)
+	assert.ErrorContains(t, err, "invalid risk policy")
+}
+
+func TestCheckRiskPolicy(t *testing.T) {
+	cfg := options.RunOptions{}
+	ext := &models.HostExtensions{Risk: &models.HostRisk{Rating: models.RiskHigh}}
+	assert.NoError(t, checkRiskPolicy(&cfg, ext))
+
+	cfg.Facter.Vulnerabilities.Policy.FailOn = models.RiskCritical
+	assert.NoError(t, checkRiskPolicy(&cfg, ext))
+	assert.ErrorContains(t, checkRiskPolicy(&cfg, &models.HostExtensions{}), "risk of the host was not computed")
+	assert.Error(t, checkRiskPolicy(&cfg, nil))
+
+	cfg.Facter.Vulnerabilities.Policy.FailOn = models.RiskHigh
+	assert.ErrorIs(t, checkRiskPolicy(&cfg, ext), ErrRiskThreshold)
+}
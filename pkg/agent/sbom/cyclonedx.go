@@ -0,0 +1,297 @@
+package sbom
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// CycloneDX 1.5 document, only the fields written are declared, see https://cyclonedx.org/docs/1.5/json/.
+type cdxBOM struct {
+	BOMFormat       string             `json:"bomFormat"`
+	SpecVersion     string             `json:"specVersion"`
+	SerialNumber    string             `json:"serialNumber"`
+	Version         int                `json:"version"`
+	Metadata        cdxMetadata        `json:"metadata"`
+	Components      []cdxComponent     `json:"components"`
+	Dependencies    []cdxDependency    `json:"dependencies,omitempty"`
+	Vulnerabilities []cdxVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	BOMRef      string        `json:"bom-ref,omitempty"`
+	Type        string        `json:"type"`
+	Publisher   string        `json:"publisher,omitempty"`
+	Name        string        `json:"name"`
+	Version     string        `json:"version,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Licenses    []cdxLicense  `json:"licenses,omitempty"`
+	PURL        string        `json:"purl,omitempty"`
+	Properties  []cdxProperty `json:"properties,omitempty"`
+}
+
+// cdxLicense is either a licence or an expression.
+type cdxLicense struct {
+	License    *cdxLicenseID `json:"license,omitempty"`
+	Expression string        `json:"expression,omitempty"`
+}
+
+type cdxLicenseID struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+type cdxVulnerability struct {
+	BOMRef         string        `json:"bom-ref"`
+	ID             string        `json:"id"`
+	Source         *cdxSource    `json:"source,omitempty"`
+	Ratings        []cdxRating   `json:"ratings,omitempty"`
+	Published      string        `json:"published,omitempty"`
+	Recommendation string        `json:"recommendation,omitempty"`
+	Affects        []cdxAffect   `json:"affects"`
+	Properties     []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type cdxRating struct {
+	Source   *cdxSource `json:"source,omitempty"`
+	Score    float64    `json:"score,omitempty"`
+	Severity string     `json:"severity"`
+	Method   string     `json:"method"`
+	Vector   string     `json:"vector,omitempty"`
+}
+
+type cdxAffect struct {
+	Ref      string              `json:"ref"`
+	Versions []cdxAffectVersions `json:"versions,omitempty"`
+}
+
+type cdxAffectVersions struct {
+	Version string `json:"version"`
+	Status  string `json:"status"`
+}
+
+// cdxSubjectTypes are the CycloneDX component types of the subjects.
+var cdxSubjectTypes = map[string]string{
+	SubjectHost:  "device",
+	SubjectImage: "container",
+}
+
+func generateCycloneDX(subject *Subject) ([]byte, error) {
+	comps := components(subject)
+	subjectRef := subject.Kind + ":" + subject.Name
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: now().UTC().Format(time.RFC3339),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: "facter", Version: subject.ToolVersion}}},
+			Component: cdxComponent{BOMRef: subjectRef, Type: cdxSubjectTypes[subject.Kind], Name: subject.Name},
+		},
+		Components: make([]cdxComponent, 0, len(comps)+1),
+	}
+
+	dependsOn := make([]string, 0, len(comps)+1)
+	if subject.OSName != "" {
+		osRef := "os:" + subject.OSName + "@" + subject.OSVersion
+		bom.Components = append(bom.Components, cdxComponent{BOMRef: osRef, Type: "operating-system", Name: subject.OSName, Version: subject.OSVersion})
+		dependsOn = append(dependsOn, osRef)
+	}
+	for _, c := range comps {
+		bom.Components = append(bom.Components, cdxComponent{
+			BOMRef:      c.ref,
+			Type:        "library",
+			Publisher:   c.pkg.Maintainer,
+			Name:        c.pkg.Name,
+			Version:     c.pkg.Version,
+			Description: c.pkg.Description,
+			Licenses:    cdxLicenses(c.license),
+			PURL:        c.purl,
+			Properties:  cdxPackageProperties(c.pkg),
+		})
+		dependsOn = append(dependsOn, c.ref)
+	}
+	bom.Dependencies = []cdxDependency{{Ref: subjectRef, DependsOn: dependsOn}}
+	bom.Vulnerabilities = cdxVulnerabilities(subject.Vulnerabilities, comps)
+
+	return marshal(bom)
+}
+
+func cdxLicenses(l license) []cdxLicense {
+	switch {
+	case l.id != "":
+		return []cdxLicense{{License: &cdxLicenseID{ID: l.id}}}
+	case l.expression != "":
+		return []cdxLicense{{Expression: l.expression}}
+	case l.raw != "":
+		return []cdxLicense{{License: &cdxLicenseID{Name: l.raw}}}
+	}
+	return nil
+}
+
+func cdxPackageProperties(pkg *models.Package) []cdxProperty {
+	var props []cdxProperty
+	for _, p := range []cdxProperty{
+		{"facter:package:ecosystem", pkg.Ecosystem},
+		{"facter:package:manager", pkg.Manager},
+		{"facter:package:architecture", pkg.Architecture},
+		{"facter:package:source", pkg.SourcePackage},
+		{"facter:package:location", pkg.Location},
+	} {
+		if p.Value != "" {
+			props = append(props, p)
+		}
+	}
+	return props
+}
+
+// cdxVulnerabilities returns a vulnerability per identifier, affecting every package it was found for.
+func cdxVulnerabilities(findings []*models.VulnerabilityFinding, comps []*component) []cdxVulnerability {
+	var vulns []cdxVulnerability
+	index := map[string]int{}
+	for _, finding := range findings {
+		i, ok := index[finding.ID]
+		if !ok {
+			i = len(vulns)
+			index[finding.ID] = i
+			vulns = append(vulns, cdxVulnerability{
+				BOMRef:    "vuln:" + finding.ID,
+				ID:        finding.ID,
+				Source:    &cdxSource{Name: vulnSource(finding.ID), URL: advisoryURL(finding.ID)},
+				Ratings:   cdxRatings(finding),
+				Published: published(finding.Published),
+				Affects:   []cdxAffect{},
+			})
+			vulns[i].Properties = cdxVulnerabilityProperties(finding)
+		}
+		for _, c := range affected(finding, comps) {
+			if slices.ContainsFunc(vulns[i].Affects, func(a cdxAffect) bool { return a.Ref == c.ref }) {
+				continue
+			}
+			vulns[i].Affects = append(vulns[i].Affects, cdxAffect{Ref: c.ref, Versions: []cdxAffectVersions{{Version: c.pkg.Version, Status: "affected"}}})
+		}
+		if finding.FixedVersion != "" {
+			fix := "Upgrade " + finding.Package + " to " + finding.FixedVersion
+			if !strings.Contains(vulns[i].Recommendation, fix) {
+				vulns[i].Recommendation = strings.TrimPrefix(vulns[i].Recommendation+"; "+fix, "; ")
+			}
+		}
+	}
+	return vulns
+}
+
+// published returns the publication date of the NVD, written without time zone, as a RFC 3339 UTC time.
+func published(date string) string {
+	if date == "" {
+		return ""
+	}
+	t, err := time.Parse("2006-01-02T15:04:05.999999999", date)
+	if err != nil {
+		return date
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func vulnSource(id string) string {
+	if strings.HasPrefix(id, "CVE-") {
+		return "NVD"
+	}
+	return "OSV"
+}
+
+// cdxRatings returns the CVSS scores of a finding, and the severity of the scanner when there is none.
+func cdxRatings(finding *models.VulnerabilityFinding) []cdxRating {
+	var ratings []cdxRating
+	if finding.CVSSv4Score > 0 {
+		ratings = append(ratings, cdxRating{Source: &cdxSource{Name: "NVD"}, Score: finding.CVSSv4Score, Severity: cvssSeverity(finding.CVSSv4Score), Method: "CVSSv4", Vector: finding.CVSSv4Vector})
+	}
+	if finding.CVSSv3Score > 0 {
+		method := "CVSSv3"
+		if strings.HasPrefix(finding.CVSSv3Vector, "CVSS:3.1/") {
+			method = "CVSSv31"
+		}
+		ratings = append(ratings, cdxRating{Source: &cdxSource{Name: "NVD"}, Score: finding.CVSSv3Score, Severity: cvssSeverity(finding.CVSSv3Score), Method: method, Vector: finding.CVSSv3Vector})
+	}
+	if len(ratings) == 0 && finding.Severity != "" {
+		ratings = append(ratings, cdxRating{Severity: cdxSeverity(finding.Severity), Method: "other"})
+	}
+	return ratings
+}
+
+// cvssSeverity returns the qualitative rating of a CVSS score.
+func cvssSeverity(score float64) string {
+	switch {
+	case score >= 9.0:
+		return "critical"
+	case score >= 7.0:
+		return "high"
+	case score >= 4.0:
+		return "medium"
+	case score > 0:
+		return "low"
+	}
+	return "none"
+}
+
+func cdxSeverity(severity string) string {
+	switch s := strings.ToLower(severity); s {
+	case "critical", "high", "medium", "low", "info", "none":
+		return s
+	case "moderate":
+		return "medium"
+	case "negligible":
+		return "info"
+	}
+	return "unknown"
+}
+
+func cdxVulnerabilityProperties(finding *models.VulnerabilityFinding) []cdxProperty {
+	var props []cdxProperty
+	if finding.EPSS > 0 {
+		props = append(props,
+			cdxProperty{"facter:epss:score", strconv.FormatFloat(finding.EPSS, 'f', -1, 64)},
+			cdxProperty{"facter:epss:percentile", strconv.FormatFloat(finding.EPSSPercentile, 'f', -1, 64)})
+	}
+	if finding.KnownExploited {
+		props = append(props, cdxProperty{"facter:kev:date_added", finding.KEVDateAdded})
+		if finding.KnownRansomware {
+			props = append(props, cdxProperty{"facter:kev:known_ransomware", "true"})
+		}
+	}
+	if finding.Risk != "" {
+		props = append(props, cdxProperty{"facter:risk", finding.Risk})
+	}
+	if finding.Reachability != "" {
+		props = append(props, cdxProperty{"facter:reachability", finding.Reachability})
+	}
+	return props
+}
@@ -0,0 +1,130 @@
+package sbom
+
+import (
+	"regexp"
+	"strings"
+)
+
+// spdxLicenses are the SPDX license identifiers recognised in the package metadata, by lower case identifier.
+// Licences out of this list are exported by name in CycloneDX and as LicenseRef in SPDX.
+var spdxLicenses = canonical(
+	"0BSD", "AFL-2.1", "AFL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later", "Apache-1.1", "Apache-2.0",
+	"Artistic-1.0", "Artistic-1.0-Perl", "Artistic-2.0", "BlueOak-1.0.0", "BSD-1-Clause", "BSD-2-Clause",
+	"BSD-3-Clause", "BSD-4-Clause", "BSL-1.0", "bzip2-1.0.6", "CC-BY-3.0", "CC-BY-4.0", "CC-BY-SA-3.0",
+	"CC-BY-SA-4.0", "CC0-1.0", "CDDL-1.0", "CDDL-1.1", "curl", "EPL-1.0", "EPL-2.0", "EUPL-1.2", "FSFAP",
+	"FTL", "GFDL-1.2-or-later", "GFDL-1.3-only", "GFDL-1.3-or-later", "GPL-1.0-or-later", "GPL-2.0",
+	"GPL-2.0-only", "GPL-2.0-or-later", "GPL-3.0", "GPL-3.0-only", "GPL-3.0-or-later", "HPND", "IJG", "ISC",
+	"LGPL-2.0-only", "LGPL-2.0-or-later", "LGPL-2.1", "LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0",
+	"LGPL-3.0-only", "LGPL-3.0-or-later", "Libpng", "MIT", "MIT-0", "MPL-1.1", "MPL-2.0", "NCSA",
+	"OFL-1.1", "OpenSSL", "PHP-3.01", "PostgreSQL", "PSF-2.0", "Python-2.0", "Ruby", "Sleepycat",
+	"Unicode-DFS-2016", "Unlicense", "UPL-1.0", "Vim", "W3C", "WTFPL", "X11", "Zlib", "ZPL-2.1",
+)
+
+// spdxExceptions are the SPDX license exceptions recognised after a WITH operator.
+var spdxExceptions = canonical(
+	"Autoconf-exception-3.0", "Bison-exception-2.2", "Classpath-exception-2.0", "GCC-exception-3.1",
+	"LLVM-exception", "OpenSSL-exception", "Linux-syscall-note",
+)
+
+func canonical(ids ...string) map[string]string {
+	m := make(map[string]string, len(ids))
+	for _, id := range ids {
+		m[strings.ToLower(id)] = id
+	}
+	return m
+}
+
+var (
+	licenseTokenRE = regexp.MustCompile(`\(|\)|[^\s()]+`)
+	licenseRefRE   = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+)
+
+// license is the licence of a package, as declared in its metadata.
+type license struct {
+	raw        string
+	id         string // Set when the licence is a single SPDX list identifier
+	expression string // Set when the licence is a valid SPDX expression
+}
+
+// parseLicense recognises the SPDX identifiers and expressions, e.g. "MIT OR apache-2.0" is
+// "MIT OR Apache-2.0", whatever the case of the identifiers and operators.
+func parseLicense(raw string) license {
+	l := license{raw: strings.TrimSpace(raw)}
+	if l.raw == "" {
+		return l
+	}
+	tokens := licenseTokenRE.FindAllString(l.raw, -1)
+	normalized := make([]string, 0, len(tokens))
+	depth, operand := 0, false
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch upper := strings.ToUpper(token); {
+		case token == "(":
+			if operand {
+				return l
+			}
+			depth++
+		case token == ")":
+			if !operand || depth == 0 {
+				return l
+			}
+			depth--
+		case upper == "AND" || upper == "OR":
+			if !operand {
+				return l
+			}
+			token, operand = upper, false
+		case upper == "WITH":
+			if !operand || i+1 == len(tokens) {
+				return l
+			}
+			exception, ok := spdxExceptions[strings.ToLower(tokens[i+1])]
+			if !ok {
+				return l
+			}
+			normalized[len(normalized)-1] += " WITH " + exception
+			i++
+			continue
+		default:
+			if operand {
+				return l
+			}
+			id, ok := spdxID(token)
+			if !ok {
+				return l
+			}
+			token, operand = id, true
+		}
+		normalized = append(normalized, token)
+	}
+	if depth != 0 || !operand {
+		return l
+	}
+	l.expression = strings.ReplaceAll(strings.ReplaceAll(strings.Join(normalized, " "), "( ", "("), " )", ")")
+	// Identifiers with a + and references are valid in expressions only
+	if len(normalized) == 1 && !strings.ContainsAny(l.expression, " +") && !strings.HasPrefix(l.expression, "LicenseRef-") {
+		l.id = l.expression
+	}
+	return l
+}
+
+// spdxID returns the canonical form of a SPDX identifier, "or later" ones written with a + included.
+func spdxID(token string) (string, bool) {
+	if strings.HasPrefix(token, "LicenseRef-") {
+		return token, true
+	}
+	base, plus := strings.CutSuffix(token, "+")
+	id, ok := spdxLicenses[strings.ToLower(base)]
+	if !ok {
+		return "", false
+	}
+	if plus {
+		id += "+"
+	}
+	return id, true
+}
+
+// licenseRef returns the SPDX reference of a licence out of the SPDX list.
+func licenseRef(raw string) string {
+	return "LicenseRef-" + strings.Trim(licenseRefRE.ReplaceAllString(raw, "-"), "-")
+}
@@ -0,0 +1,94 @@
+package sbom
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// defaultNamespaces are the purl namespaces of the OS packages when the OS of the subject is unknown.
+var defaultNamespaces = map[string]string{
+	models.EcosystemDeb:  "debian",
+	models.EcosystemApk:  "alpine",
+	models.EcosystemAlpm: "arch",
+}
+
+// PackageURL returns the package-url of a package, see https://github.com/package-url/purl-spec.
+// OS packages are namespaced by the os-release ID of the subject and qualified with its distribution.
+// Packages of an ecosystem without registered purl type, e.g. snaps or Homebrew formulae, are generic ones.
+func PackageURL(pkg *models.Package, osName, osVersion string) string {
+	purlType, namespace, name, version := pkg.Ecosystem, "", pkg.Name, pkg.Version
+	qualifiers := map[string]string{}
+
+	switch pkg.Ecosystem {
+	case models.EcosystemDeb, models.EcosystemRpm, models.EcosystemApk, models.EcosystemAlpm:
+		namespace = strings.ToLower(osName)
+		if namespace == "" {
+			namespace = defaultNamespaces[pkg.Ecosystem]
+		}
+		qualifiers["arch"] = pkg.Architecture
+		if osName != "" && osVersion != "" {
+			qualifiers["distro"] = strings.ToLower(osName) + "-" + osVersion
+		}
+		if pkg.SourcePackage != "" && pkg.SourcePackage != pkg.Name {
+			qualifiers["upstream"] = pkg.SourcePackage
+		}
+		if pkg.Ecosystem == models.EcosystemRpm && pkg.Epoch != "" {
+			// The epoch of rpm packages is a qualifier, not part of the version
+			qualifiers["epoch"] = pkg.Epoch
+			version = strings.TrimPrefix(version, pkg.Epoch+":")
+		}
+	case models.EcosystemPypi:
+		name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	case models.EcosystemNpm:
+		if strings.HasPrefix(name, "@") {
+			namespace, name, _ = strings.Cut(name, "/")
+		}
+	case models.EcosystemGolang:
+		namespace, name = path.Split(name)
+		namespace = strings.TrimSuffix(namespace, "/")
+	case models.EcosystemMaven:
+		if group, artifact, ok := strings.Cut(name, ":"); ok {
+			namespace, name = group, artifact
+		}
+	case models.EcosystemGem:
+	default:
+		purlType = "generic"
+	}
+
+	var b strings.Builder
+	b.WriteString("pkg:" + purlType + "/")
+	if namespace != "" {
+		for _, segment := range strings.Split(namespace, "/") {
+			b.WriteString(escapePURL(segment) + "/")
+		}
+	}
+	b.WriteString(escapePURL(name))
+	if version != "" {
+		b.WriteString("@" + escapePURL(version))
+	}
+	keys := make([]string, 0, len(qualifiers))
+	for key, value := range qualifiers {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i == 0 {
+			b.WriteString("?")
+		} else {
+			b.WriteString("&")
+		}
+		b.WriteString(key + "=" + escapePURL(qualifiers[key]))
+	}
+	return b.String()
+}
+
+// escapePURL percent-encodes a component of a purl, '@' included as it separates the version.
+func escapePURL(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "@", "%40")
+}
@@ -0,0 +1,193 @@
+package sbom
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+)
+
+// SBOM formats.
+const (
+	FormatCycloneDX = "cyclonedx-json" // CycloneDX 1.5 JSON
+	FormatSPDX      = "spdx-json"      // SPDX 2.3 JSON
+)
+
+// Kinds of subject described by a SBOM.
+const (
+	SubjectHost  = "host"
+	SubjectImage = "image"
+)
+
+// Subject is what a SBOM describes, a host or a container image, with its packages and their vulnerabilities.
+type Subject struct {
+	Name            string
+	Kind            string // See Subject* constants
+	OSName          string // os-release ID, e.g. debian, used as namespace of the OS package purls
+	OSVersion       string
+	ToolVersion     string // Version of facter, written in the creation information
+	Packages        []*models.Package
+	Vulnerabilities []*models.VulnerabilityFinding
+}
+
+// now and newUUID are variables to be replaced in tests, the documents are otherwise unique.
+var (
+	now     = time.Now
+	newUUID = func() string {
+		var b [16]byte
+		_, _ = rand.Read(b[:])
+		b[6] = b[6]&0x0f | 0x40 // Version 4
+		b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	}
+)
+
+// ValidFormat reports whether format is one of the Format* constants.
+func ValidFormat(format string) bool {
+	return format == FormatCycloneDX || format == FormatSPDX
+}
+
+// Generate returns the SBOM of the subject in the given format.
+func Generate(format string, subject *Subject) ([]byte, error) {
+	switch format {
+	case FormatCycloneDX:
+		return generateCycloneDX(subject)
+	case FormatSPDX:
+		return generateSPDX(subject)
+	default:
+		return nil, fmt.Errorf("unknown SBOM format %q, expected %s or %s", format, FormatCycloneDX, FormatSPDX)
+	}
+}
+
+// FromInventory returns the host described by a full inventory and its extensions. The packages of the
+// inventory are used when there are no extensions, they miss the ecosystem and licence of the packages.
+func FromInventory(inv *schema.HostInventory, ext *models.HostExtensions) *Subject {
+	subject := &Subject{
+		Name:        inv.GetHostname(),
+		Kind:        SubjectHost,
+		OSName:      inv.GetPlatform().GetOs().GetName(),
+		OSVersion:   inv.GetPlatform().GetOs().GetVersion(),
+		ToolVersion: inv.GetMetadata().GetFacterVersion(),
+	}
+	if ext != nil && (len(ext.Packages) > 0 || len(ext.Vulnerabilities) > 0) {
+		if subject.Name == "" {
+			subject.Name = ext.Hostname
+		}
+		subject.Packages = ext.Packages
+		subject.Vulnerabilities = ext.Vulnerabilities
+		return subject
+	}
+	for _, pkg := range inv.GetPackages() {
+		subject.Packages = append(subject.Packages, &models.Package{
+			Name:         pkg.GetName(),
+			Version:      pkg.GetVersion(),
+			Architecture: pkg.GetArchitecture(),
+			Description:  pkg.GetDescription(),
+		})
+	}
+	return subject
+}
+
+// FromImage returns the subject of a scanned docker image, named after its first tag.
+func FromImage(img *models.ImageInventory, toolVersion string) *Subject {
+	return &Subject{
+		Name:            img.Name(),
+		Kind:            SubjectImage,
+		OSName:          img.OSName,
+		OSVersion:       img.OSVersion,
+		ToolVersion:     toolVersion,
+		Packages:        img.Packages,
+		Vulnerabilities: img.Vulnerabilities,
+	}
+}
+
+// FindImage returns the image referenced by a tag, an identifier or a prefix of it, nil when none matches.
+func FindImage(images []*models.ImageInventory, ref string) *models.ImageInventory {
+	for _, img := range images {
+		if slices.Contains(img.RepoTags, ref) {
+			return img
+		}
+	}
+	id := strings.TrimPrefix(ref, "sha256:")
+	for _, img := range images {
+		if id != "" && strings.HasPrefix(strings.TrimPrefix(img.ID, "sha256:"), id) {
+			return img
+		}
+	}
+	return nil
+}
+
+// marshal writes a document as indented JSON, the & of the purl qualifiers being kept as is.
+func marshal(doc any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// component is a package of the subject, with its identifiers in the documents.
+type component struct {
+	ref     string
+	purl    string
+	pkg     *models.Package
+	license license
+}
+
+// components returns the packages of the subject sorted by key, the duplicates being dropped.
+func components(subject *Subject) []*component {
+	seen := map[string]bool{}
+	result := []*component{}
+	for _, pkg := range subject.Packages {
+		key := pkg.Key()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, &component{
+			ref:     key,
+			purl:    PackageURL(pkg, subject.OSName, subject.OSVersion),
+			pkg:     pkg,
+			license: parseLicense(pkg.License),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ref < result[j].ref })
+	return result
+}
+
+// affected returns the components a finding applies to, matched by ecosystem, name and installed version,
+// or by ecosystem and name only when the scanner reports the version differently, e.g. without the epoch.
+func affected(finding *models.VulnerabilityFinding, comps []*component) []*component {
+	var byName, byVersion []*component
+	for _, c := range comps {
+		if c.pkg.Name != finding.Package || (finding.Ecosystem != "" && c.pkg.Ecosystem != "" && c.pkg.Ecosystem != finding.Ecosystem) {
+			continue
+		}
+		byName = append(byName, c)
+		if c.pkg.Version == finding.InstalledVersion {
+			byVersion = append(byVersion, c)
+		}
+	}
+	if len(byVersion) > 0 {
+		return byVersion
+	}
+	return byName
+}
+
+// advisoryURL returns the page of a vulnerability in the public database of its identifier.
+func advisoryURL(id string) string {
+	if strings.HasPrefix(id, "CVE-") {
+		return "https://nvd.nist.gov/vuln/detail/" + id
+	}
+	return "https://osv.dev/vulnerability/" + id
+}
@@ -0,0 +1,223 @@
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSubject = &Subject{
+	Name:        "web-01",
+	Kind:        SubjectHost,
+	OSName:      "debian",
+	OSVersion:   "12",
+	ToolVersion: "0.1.0",
+	Packages: []*models.Package{
+		{Name: "openssl", Version: "3.0.11-1~deb12u2", Architecture: "amd64", Ecosystem: models.EcosystemDeb, Maintainer: "Debian OpenSSL Team <pkg-openssl-devel@alioth-lists.debian.net>"},
+		{Name: "libssl3", Version: "3.0.11-1~deb12u2", Architecture: "amd64", SourcePackage: "openssl", Ecosystem: models.EcosystemDeb},
+		{Name: "requests", Version: "2.28.1", License: "Apache 2.0", Ecosystem: models.EcosystemPypi, Location: "/usr/lib/python3/dist-packages"},
+		{Name: "@babel/core", Version: "7.24.0", License: "mit", Ecosystem: models.EcosystemNpm},
+		{Name: "firefox", Version: "128.0", Revision: "4539", Ecosystem: models.EcosystemSnap},
+		// Duplicate entry, exported once
+		{Name: "openssl", Version: "3.0.11-1~deb12u2", Architecture: "amd64", Ecosystem: models.EcosystemDeb},
+	},
+	Vulnerabilities: []*models.VulnerabilityFinding{
+		{ID: "CVE-2024-0727", Package: "openssl", InstalledVersion: "3.0.11-1~deb12u2", Severity: "MEDIUM", FixedVersion: "3.0.13-1~deb12u1",
+			CVSSv3Score: 5.5, CVSSv3Vector: "CVSS:3.1/AV:L/AC:L/PR:N/UI:R/S:U/C:N/I:N/A:H", EPSS: 0.00052, EPSSPercentile: 0.21563,
+			Published: "2024-01-26T09:15:07.837", Risk: models.RiskMedium},
+		{ID: "CVE-2024-0727", Package: "libssl3", InstalledVersion: "3.0.11-1~deb12u2", Severity: "MEDIUM", FixedVersion: "3.0.13-1~deb12u1", CVSSv3Score: 5.5},
+		{ID: "GHSA-j8r2-6x86-q33q", Package: "requests", InstalledVersion: "2.28.1", Severity: "MODERATE", Risk: models.RiskMedium},
+	},
+}
+
+func fixedClock(t *testing.T) {
+	previousNow, previousUUID := now, newUUID
+	now = func() time.Time { return time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC) }
+	newUUID = func() string { return "3e671687-395b-41f5-a30f-a58921a69b79" }
+	t.Cleanup(func() { now, newUUID = previousNow, previousUUID })
+}
+
+func TestPackageURL(t *testing.T) {
+	for _, tc := range []struct {
+		pkg       models.Package
+		osName    string
+		osVersion string
+		want      string
+	}{
+		{models.Package{Name: "libssl3", Version: "3.0.11-1~deb12u2", Architecture: "amd64", SourcePackage: "openssl", Ecosystem: models.EcosystemDeb}, "debian", "12",
+			"pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64&distro=debian-12&upstream=openssl"},
+		{models.Package{Name: "openssl", Version: "1:3.0.7-27.el9", Epoch: "1", Architecture: "x86_64", Ecosystem: models.EcosystemRpm}, "rhel", "9.4",
+			"pkg:rpm/rhel/openssl@3.0.7-27.el9?arch=x86_64&distro=rhel-9.4&epoch=1"},
+		{models.Package{Name: "musl", Version: "1.2.4-r2", Architecture: "x86_64", Ecosystem: models.EcosystemApk}, "", "",
+			"pkg:apk/alpine/musl@1.2.4-r2?arch=x86_64"},
+		{models.Package{Name: "Flask_Login", Version: "0.6.3", Ecosystem: models.EcosystemPypi}, "debian", "12", "pkg:pypi/flask-login@0.6.3"},
+		{models.Package{Name: "@babel/core", Version: "7.24.0", Ecosystem: models.EcosystemNpm}, "", "", "pkg:npm/%40babel/core@7.24.0"},
+		{models.Package{Name: "github.com/spf13/cobra", Version: "v1.8.0", Ecosystem: models.EcosystemGolang}, "", "", "pkg:golang/github.com/spf13/cobra@v1.8.0"},
+		{models.Package{Name: "org.apache.logging.log4j:log4j-core", Version: "2.14.1", Ecosystem: models.EcosystemMaven}, "", "",
+			"pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"},
+		{models.Package{Name: "firefox", Version: "128.0", Ecosystem: models.EcosystemSnap}, "ubuntu", "24.04", "pkg:generic/firefox@128.0"},
+		{models.Package{Name: "curl", Version: "8.5.0", Ecosystem: models.EcosystemHomebrew}, "macos", "14.5", "pkg:generic/curl@8.5.0"},
+	} {
+		pkg := tc.pkg
+		assert.Equal(t, tc.want, PackageURL(&pkg, tc.osName, tc.osVersion))
+	}
+}
+
+func TestParseLicense(t *testing.T) {
+	assert.Equal(t, license{raw: "mit", id: "MIT", expression: "MIT"}, parseLicense("mit"))
+	assert.Equal(t, license{raw: "GPL-2.0+", expression: "GPL-2.0+"}, parseLicense("GPL-2.0+"))
+	assert.Equal(t, "(MIT OR Apache-2.0) AND BSD-3-Clause", parseLicense("(MIT or apache-2.0) and BSD-3-Clause").expression)
+	assert.Equal(t, "GPL-2.0-only WITH Classpath-exception-2.0", parseLicense("GPL-2.0-only WITH classpath-exception-2.0").expression)
+	for _, raw := range []string{"Apache 2.0", "GPLv2+", "MIT OR", "(MIT", "MIT Apache-2.0", "MIT WITH Unknown-exception", ""} {
+		assert.Equal(t, "", parseLicense(raw).expression, raw)
+	}
+	assert.Equal(t, "LicenseRef-Apache-2.0", licenseRef("Apache 2.0"))
+}
+
+func TestGenerate_CycloneDX(t *testing.T) {
+	fixedClock(t)
+	bin, err := Generate(FormatCycloneDX, testSubject)
+	assert.NoError(t, err)
+
+	var bom cdxBOM
+	assert.NoError(t, json.Unmarshal(bin, &bom))
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Equal(t, "1.5", bom.SpecVersion)
+	assert.Equal(t, "urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79", bom.SerialNumber)
+	assert.Equal(t, "2026-10-19T08:00:00Z", bom.Metadata.Timestamp)
+	assert.Equal(t, cdxComponent{BOMRef: "host:web-01", Type: "device", Name: "web-01"}, bom.Metadata.Component)
+
+	// Operating system and 5 packages
+	assert.Len(t, bom.Components, 6)
+	assert.Equal(t, "operating-system", bom.Components[0].Type)
+	byName := map[string]cdxComponent{}
+	for _, c := range bom.Components {
+		byName[c.Name] = c
+	}
+	assert.Equal(t, "pkg:deb/debian/openssl@3.0.11-1~deb12u2?arch=amd64&distro=debian-12", byName["openssl"].PURL)
+	assert.Equal(t, "deb/openssl@3.0.11-1~deb12u2:amd64", byName["openssl"].BOMRef)
+	assert.Equal(t, []cdxLicense{{License: &cdxLicenseID{ID: "MIT"}}}, byName["@babel/core"].Licenses)
+	assert.Equal(t, []cdxLicense{{License: &cdxLicenseID{Name: "Apache 2.0"}}}, byName["requests"].Licenses)
+	assert.Contains(t, byName["requests"].Properties, cdxProperty{"facter:package:location", "/usr/lib/python3/dist-packages"})
+	assert.Len(t, bom.Dependencies[0].DependsOn, 6)
+
+	assert.Len(t, bom.Vulnerabilities, 2)
+	openssl := bom.Vulnerabilities[0]
+	assert.Equal(t, "CVE-2024-0727", openssl.ID)
+	assert.Equal(t, &cdxSource{Name: "NVD", URL: "https://nvd.nist.gov/vuln/detail/CVE-2024-0727"}, openssl.Source)
+	assert.Equal(t, []cdxRating{{Source: &cdxSource{Name: "NVD"}, Score: 5.5, Severity: "medium", Method: "CVSSv31", Vector: "CVSS:3.1/AV:L/AC:L/PR:N/UI:R/S:U/C:N/I:N/A:H"}}, openssl.Ratings)
+	assert.Equal(t, "2024-01-26T09:15:07Z", openssl.Published)
+	assert.Equal(t, []cdxAffect{
+		{Ref: "deb/openssl@3.0.11-1~deb12u2:amd64", Versions: []cdxAffectVersions{{Version: "3.0.11-1~deb12u2", Status: "affected"}}},
+		{Ref: "deb/libssl3@3.0.11-1~deb12u2:amd64", Versions: []cdxAffectVersions{{Version: "3.0.11-1~deb12u2", Status: "affected"}}},
+	}, openssl.Affects)
+	assert.Equal(t, "Upgrade openssl to 3.0.13-1~deb12u1; Upgrade libssl3 to 3.0.13-1~deb12u1", openssl.Recommendation)
+	assert.Contains(t, openssl.Properties, cdxProperty{"facter:epss:score", "0.00052"})
+
+	ghsa := bom.Vulnerabilities[1]
+	assert.Equal(t, &cdxSource{Name: "OSV", URL: "https://osv.dev/vulnerability/GHSA-j8r2-6x86-q33q"}, ghsa.Source)
+	assert.Equal(t, []cdxRating{{Severity: "medium", Method: "other"}}, ghsa.Ratings)
+}
+
+func TestGenerate_SPDX(t *testing.T) {
+	fixedClock(t)
+	bin, err := Generate(FormatSPDX, testSubject)
+	assert.NoError(t, err)
+
+	var doc spdxDocument
+	assert.NoError(t, json.Unmarshal(bin, &doc))
+	assert.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+	assert.Equal(t, "CC0-1.0", doc.DataLicense)
+	assert.Equal(t, "https://github.com/klamhq/facter-oss/spdxdocs/host-web-01-3e671687-395b-41f5-a30f-a58921a69b79", doc.DocumentNamespace)
+	assert.Equal(t, spdxCreationInfo{Created: "2026-10-19T08:00:00Z", Creators: []string{"Tool: facter-0.1.0"}}, doc.CreationInfo)
+
+	// Subject, operating system and 5 packages
+	assert.Len(t, doc.Packages, 7)
+	assert.Len(t, doc.Relationships, 7)
+	assert.Equal(t, spdxRelationship{"SPDXRef-DOCUMENT", "DESCRIBES", "SPDXRef-Subject"}, doc.Relationships[0])
+
+	byName := map[string]spdxPackage{}
+	for _, p := range doc.Packages {
+		byName[p.Name] = p
+	}
+	babel := byName["@babel/core"]
+	assert.Equal(t, "SPDXRef-Package-3-babel-core", babel.SPDXID)
+	assert.Equal(t, "MIT", babel.LicenseDeclared)
+	assert.Equal(t, []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: "pkg:npm/%40babel/core@7.24.0"}}, babel.ExternalRefs)
+
+	assert.Equal(t, "LicenseRef-Apache-2.0", byName["requests"].LicenseDeclared)
+	assert.Equal(t, []spdxExtractedLicense{{LicenseID: "LicenseRef-Apache-2.0", ExtractedText: "Apache 2.0", Name: "Apache 2.0"}}, doc.ExtractedLicenses)
+
+	openssl := byName["openssl"]
+	assert.Equal(t, "NOASSERTION", openssl.LicenseDeclared)
+	assert.Equal(t, "Organization: Debian OpenSSL Team (pkg-openssl-devel@alioth-lists.debian.net)", openssl.Supplier)
+	assert.Equal(t, spdxExternalRef{
+		ReferenceCategory: "SECURITY", ReferenceType: "advisory", ReferenceLocator: "https://nvd.nist.gov/vuln/detail/CVE-2024-0727",
+		Comment: "CVE-2024-0727, severity MEDIUM, CVSS 5.5, fixed in 3.0.13-1~deb12u1",
+	}, openssl.ExternalRefs[1])
+	assert.Equal(t, "built from the openssl source package", byName["libssl3"].SourceInfo)
+}
+
+func TestGenerate_UnknownFormat(t *testing.T) {
+	_, err := Generate("syft-json", testSubject)
+	assert.Error(t, err)
+	assert.False(t, ValidFormat("syft-json"))
+}
+
+func TestFromInventory(t *testing.T) {
+	inv := &schema.HostInventory{
+		Hostname: "web-01",
+		Platform: &schema.Platform{Os: &schema.Os{Name: "debian", Version: "12"}},
+		Metadata: &schema.Metadata{FacterVersion: "0.1.0"},
+		Packages: []*schema.Package{{Name: "openssl", Version: "3.0.11-1~deb12u2"}},
+	}
+	subject := FromInventory(inv, &models.HostExtensions{Hostname: "web-01", Packages: testSubject.Packages, Vulnerabilities: testSubject.Vulnerabilities})
+	assert.Equal(t, "debian", subject.OSName)
+	assert.Equal(t, "12", subject.OSVersion)
+	assert.Len(t, subject.Packages, 6)
+	assert.Len(t, subject.Vulnerabilities, 3)
+
+	// Inventory stored before the extensions
+	subject = FromInventory(inv, nil)
+	assert.Equal(t, []*models.Package{{Name: "openssl", Version: "3.0.11-1~deb12u2"}}, subject.Packages)
+	assert.Equal(t, "pkg:generic/openssl@3.0.11-1~deb12u2", PackageURL(subject.Packages[0], subject.OSName, subject.OSVersion))
+}
+
+func TestFromImage(t *testing.T) {
+	images := []*models.ImageInventory{
+		{ID: "sha256:4f1a2b", RepoTags: []string{"<none>:<none>"}},
+		{ID: "sha256:9c8d7e", RepoTags: []string{"nginx:1.25", "nginx:latest"}, OSName: "debian", OSVersion: "12", Packages: testSubject.Packages},
+	}
+	assert.Equal(t, images[1], FindImage(images, "nginx:latest"))
+	assert.Equal(t, images[1], FindImage(images, "9c8d"))
+	assert.Equal(t, images[0], FindImage(images, "sha256:4f1a2b"))
+	assert.Nil(t, FindImage(images, "redis:7"))
+	assert.Nil(t, FindImage(images, ""))
+
+	subject := FromImage(images[1], "0.1.0")
+	assert.Equal(t, "nginx:1.25", subject.Name)
+	assert.Equal(t, SubjectImage, subject.Kind)
+	assert.Equal(t, "debian", subject.OSName)
+	assert.Len(t, subject.Packages, 6)
+
+	// Untagged images are named after their identifier
+	assert.Equal(t, "sha256:4f1a2b", FromImage(images[0], "0.1.0").Name)
+}
+
+func TestAffected_Ecosystem(t *testing.T) {
+	comps := components(&Subject{Packages: []*models.Package{
+		{Name: "requests", Version: "2.28.1", Ecosystem: models.EcosystemPypi},
+		{Name: "requests", Version: "2.28.1", Ecosystem: models.EcosystemDeb},
+	}})
+	finding := &models.VulnerabilityFinding{ID: "CVE-2023-32681", Package: "requests", Ecosystem: models.EcosystemPypi, InstalledVersion: "2.28.1"}
+	assert.Equal(t, []*component{comps[1]}, affected(finding, comps))
+	assert.Equal(t, "pypi/requests@2.28.1", comps[1].ref)
+
+	// Without ecosystem, every package of that name
+	finding.Ecosystem = ""
+	assert.Len(t, affected(finding, comps), 2)
+}
@@ -0,0 +1,199 @@
+package sbom
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// spdxNamespace prefixes the namespace of the documents, made unique by the subject name and an UUID.
+const spdxNamespace = "https://github.com/klamhq/facter-oss/spdxdocs/"
+
+// SPDX 2.3 document, only the fields written are declared, see https://spdx.github.io/spdx-spec/v2.3/.
+type spdxDocument struct {
+	SPDXVersion       string                 `json:"spdxVersion"`
+	DataLicense       string                 `json:"dataLicense"`
+	SPDXID            string                 `json:"SPDXID"`
+	Name              string                 `json:"name"`
+	DocumentNamespace string                 `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo       `json:"creationInfo"`
+	Packages          []spdxPackage          `json:"packages"`
+	Relationships     []spdxRelationship     `json:"relationships"`
+	ExtractedLicenses []spdxExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	Supplier              string            `json:"supplier,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	CopyrightText         string            `json:"copyrightText"`
+	Description           string            `json:"description,omitempty"`
+	SourceInfo            string            `json:"sourceInfo,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+	Comment           string `json:"comment,omitempty"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type spdxExtractedLicense struct {
+	LicenseID     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+	Name          string `json:"name"`
+}
+
+const (
+	spdxNoAssertion = "NOASSERTION"
+	spdxSubjectID   = "SPDXRef-Subject"
+)
+
+// spdxSubjectPurposes are the primary purposes of the subject packages.
+var spdxSubjectPurposes = map[string]string{
+	SubjectHost:  "DEVICE",
+	SubjectImage: "CONTAINER",
+}
+
+var (
+	spdxIDRE     = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+	maintainerRE = regexp.MustCompile(`^\s*([^<]+?)\s*<([^>]+)>\s*$`)
+)
+
+func generateSPDX(subject *Subject) ([]byte, error) {
+	comps := components(subject)
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              subject.Name,
+		DocumentNamespace: spdxNamespace + url.PathEscape(subject.Kind+"-"+subject.Name) + "-" + newUUID(),
+		CreationInfo: spdxCreationInfo{
+			Created:  now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: facter-" + subject.ToolVersion},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:                spdxSubjectID,
+			Name:                  subject.Name,
+			DownloadLocation:      spdxNoAssertion,
+			LicenseConcluded:      spdxNoAssertion,
+			LicenseDeclared:       spdxNoAssertion,
+			CopyrightText:         spdxNoAssertion,
+			PrimaryPackagePurpose: spdxSubjectPurposes[subject.Kind],
+		}},
+		Relationships: []spdxRelationship{{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: spdxSubjectID}},
+	}
+	if subject.OSName != "" {
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SPDXID:                "SPDXRef-OperatingSystem",
+			Name:                  subject.OSName,
+			VersionInfo:           subject.OSVersion,
+			DownloadLocation:      spdxNoAssertion,
+			LicenseConcluded:      spdxNoAssertion,
+			LicenseDeclared:       spdxNoAssertion,
+			CopyrightText:         spdxNoAssertion,
+			PrimaryPackagePurpose: "OPERATING-SYSTEM",
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: spdxSubjectID, RelationshipType: "CONTAINS", RelatedSPDXElement: "SPDXRef-OperatingSystem"})
+	}
+
+	// Vulnerabilities are security references of the packages, SPDX 2.3 has no vulnerability element
+	advisories := map[*component][]spdxExternalRef{}
+	for _, finding := range subject.Vulnerabilities {
+		for _, c := range affected(finding, comps) {
+			ref := spdxExternalRef{ReferenceCategory: "SECURITY", ReferenceType: "advisory", ReferenceLocator: advisoryURL(finding.ID), Comment: spdxVulnerabilityComment(finding)}
+			advisories[c] = append(advisories[c], ref)
+		}
+	}
+
+	extracted := map[string]bool{}
+	for i, c := range comps {
+		id := "SPDXRef-Package-" + strconv.Itoa(i+1) + "-" + strings.Trim(spdxIDRE.ReplaceAllString(c.pkg.Name, "-"), "-")
+		declared := spdxNoAssertion
+		switch {
+		case c.license.expression != "":
+			declared = c.license.expression
+		case c.license.raw != "":
+			declared = licenseRef(c.license.raw)
+			if !extracted[declared] {
+				extracted[declared] = true
+				doc.ExtractedLicenses = append(doc.ExtractedLicenses, spdxExtractedLicense{LicenseID: declared, ExtractedText: c.license.raw, Name: c.license.raw})
+			}
+		}
+		pkg := spdxPackage{
+			SPDXID:                id,
+			Name:                  c.pkg.Name,
+			VersionInfo:           c.pkg.Version,
+			Supplier:              spdxSupplier(c.pkg.Vendor, c.pkg.Maintainer),
+			DownloadLocation:      spdxNoAssertion,
+			LicenseConcluded:      spdxNoAssertion,
+			LicenseDeclared:       declared,
+			CopyrightText:         spdxNoAssertion,
+			Description:           c.pkg.Description,
+			ExternalRefs:          []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: c.purl}},
+			PrimaryPackagePurpose: "LIBRARY",
+		}
+		if c.pkg.SourcePackage != "" && c.pkg.SourcePackage != c.pkg.Name {
+			pkg.SourceInfo = "built from the " + c.pkg.SourcePackage + " source package"
+		}
+		pkg.ExternalRefs = append(pkg.ExternalRefs, advisories[c]...)
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: spdxSubjectID, RelationshipType: "CONTAINS", RelatedSPDXElement: id})
+	}
+
+	return marshal(doc)
+}
+
+// spdxSupplier returns the vendor of a package, or its maintainer written "name (email)".
+func spdxSupplier(vendor, maintainer string) string {
+	if vendor != "" {
+		return "Organization: " + vendor
+	}
+	if maintainer == "" {
+		return ""
+	}
+	if m := maintainerRE.FindStringSubmatch(maintainer); m != nil {
+		return "Organization: " + m[1] + " (" + m[2] + ")"
+	}
+	return "Organization: " + maintainer
+}
+
+// spdxVulnerabilityComment summarises the assessment of a vulnerability in the comment of its reference.
+func spdxVulnerabilityComment(finding *models.VulnerabilityFinding) string {
+	parts := []string{finding.ID}
+	if finding.Severity != "" {
+		parts = append(parts, "severity "+finding.Severity)
+	}
+	if score := finding.CVSSv3Score; score > 0 {
+		parts = append(parts, "CVSS "+strconv.FormatFloat(score, 'f', -1, 64))
+	}
+	if finding.KnownExploited {
+		parts = append(parts, "known exploited")
+	}
+	if finding.FixedVersion != "" {
+		parts = append(parts, "fixed in "+finding.FixedVersion)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -1,10 +1,16 @@
 package sink
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
+	"github.com/klamhq/facter-oss/pkg/agent/sbom"
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/sirupsen/logrus"
@@ -36,3 +42,82 @@ func exportToFile(inventoryMsg *schema.InventoryRequest, logger *logrus.Logger,
 	logger.Infof("File saved to %s", dest)
 	return nil
 }
+
+// extensionsFilename derives the extensions file name from the inventory one, e.g. facter.json -> facter.extensions.json.
+func extensionsFilename(outputFilename string) string {
+	return strings.TrimSuffix(outputFilename, path.Ext(outputFilename)) + ".extensions.json"
+}
+
+// exportExtensionsToFile writes the extensions as JSON next to the inventory file.
+func exportExtensionsToFile(extensions *models.ExtensionsRequest, logger *logrus.Logger, cfg *options.RunOptions) error {
+	if extensions == nil {
+		return nil
+	}
+	bin, err := json.MarshalIndent(extensions, "", "  ")
+	if err != nil {
+		logger.WithError(err).Error("Unable to marshal inventory extensions")
+		return err
+	}
+	dest := path.Join(cfg.Facter.Sink.Output.OutputDirectory, extensionsFilename(cfg.Facter.Sink.Output.OutputFilename))
+	if err := os.WriteFile(dest, bin, 0644); err != nil {
+		logger.WithError(err).Error("Unable to write inventory extensions")
+		return err
+	}
+	logger.Infof("Extensions saved to %s", dest)
+	return nil
+}
+
+// exportSBOMToFile writes the SBOM of the host, in the configured format, in place of the inventory file.
+func exportSBOMToFile(fullInventory *schema.HostInventory, fullExtensions *models.HostExtensions, logger *logrus.Logger, cfg *options.RunOptions) error {
+	if fullInventory == nil {
+		err := fmt.Errorf("fullInventory is nil")
+		logger.WithError(err).Error("Cannot generate SBOM of nil inventory")
+		return err
+	}
+	bin, err := sbom.Generate(cfg.Facter.Sink.Output.Format, sbom.FromInventory(fullInventory, fullExtensions))
+	if err != nil {
+		logger.WithError(err).Error("Unable to generate SBOM")
+		return err
+	}
+	dest := path.Join(cfg.Facter.Sink.Output.OutputDirectory, cfg.Facter.Sink.Output.OutputFilename)
+	if err := os.WriteFile(dest, bin, 0644); err != nil {
+		logger.WithError(err).Error("Unable to write SBOM")
+		return err
+	}
+	logger.Infof("SBOM saved to %s", dest)
+	return nil
+}
+
+// ReadExport reads a full inventory exported by the file output, in protobuf or JSON, and the extensions
+// exported next to it when there are. Exports of the changes since the previous run are rejected.
+func ReadExport(file string) (*schema.HostInventory, *models.HostExtensions, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read inventory export: %w", err)
+	}
+	var request schema.InventoryRequest
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		err = protojson.Unmarshal(data, &request)
+	} else {
+		err = proto.Unmarshal(data, &request)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse inventory export %s: %w", file, err)
+	}
+	if request.GetFull() == nil {
+		return nil, nil, fmt.Errorf("%s holds the changes of the inventory, a full inventory is required", file)
+	}
+
+	data, err = os.ReadFile(path.Join(path.Dir(file), extensionsFilename(path.Base(file))))
+	if errors.Is(err, os.ErrNotExist) {
+		return request.GetFull(), nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read inventory extensions export: %w", err)
+	}
+	var extensions models.ExtensionsRequest
+	if err := json.Unmarshal(data, &extensions); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse inventory extensions export: %w", err)
+	}
+	return request.GetFull(), extensions.Full, nil
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/sirupsen/logrus"
@@ -101,3 +102,106 @@ func TestExportToFile_InvalidPath(t *testing.T) {
 	err := exportToFile(inventoryMsg, logger, cfg)
 	assert.Error(t, err)
 }
+
+func TestExportExtensionsToFile(t *testing.T) {
+	dir := tempDir(t)
+	cfg := &options.RunOptions{}
+	cfg.Facter.Sink.Output.OutputDirectory = dir
+	cfg.Facter.Sink.Output.OutputFilename = "test.json"
+	logger := logrus.New()
+
+	extensions := &models.ExtensionsRequest{Full: &models.HostExtensions{
+		Hostname: "json-host",
+		Packages: []*models.Package{{Name: "curl", Version: "8.5.0", Ecosystem: models.EcosystemHomebrew, Manager: "homebrew"}},
+	}}
+	err := exportExtensionsToFile(extensions, logger, cfg)
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "test.extensions.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"ecosystem": "brew"`)
+	assert.Contains(t, string(data), `"manager": "homebrew"`)
+}
+
+func TestExportExtensionsToFile_Nil(t *testing.T) {
+	dir := tempDir(t)
+	cfg := &options.RunOptions{}
+	cfg.Facter.Sink.Output.OutputDirectory = dir
+	cfg.Facter.Sink.Output.OutputFilename = "test.json"
+
+	assert.NoError(t, exportExtensionsToFile(nil, logrus.New(), cfg))
+	_, err := os.Stat(filepath.Join(dir, "test.extensions.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExtensionsFilename(t *testing.T) {
+	assert.Equal(t, "facter.extensions.json", extensionsFilename("facter.json"))
+	assert.Equal(t, "facter.extensions.json", extensionsFilename("facter.pb"))
+	assert.Equal(t, "facter.extensions.json", extensionsFilename("facter"))
+}
+
+func TestExportSBOMToFile(t *testing.T) {
+	dir := tempDir(t)
+	cfg := &options.RunOptions{}
+	cfg.Facter.Sink.Output.Format = "cyclonedx-json"
+	cfg.Facter.Sink.Output.OutputDirectory = dir
+	cfg.Facter.Sink.Output.OutputFilename = "sbom.cdx.json"
+
+	inventory := &schema.HostInventory{Hostname: "sbom-host", Platform: &schema.Platform{Os: &schema.Os{Name: "debian", Version: "12"}}}
+	extensions := &models.HostExtensions{
+		Hostname: "sbom-host",
+		Packages: []*models.Package{{Name: "curl", Version: "7.88.1-10+deb12u5", Architecture: "amd64", Ecosystem: models.EcosystemDeb}},
+	}
+	assert.NoError(t, exportSBOMToFile(inventory, extensions, logrus.New(), cfg))
+
+	data, err := os.ReadFile(filepath.Join(dir, "sbom.cdx.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"specVersion": "1.5"`)
+	assert.Contains(t, string(data), `"purl": "pkg:deb/debian/curl@7.88.1-10+deb12u5?arch=amd64&distro=debian-12"`)
+
+	assert.Error(t, exportSBOMToFile(nil, extensions, logrus.New(), cfg))
+}
+
+func TestReadExport(t *testing.T) {
+	dir := tempDir(t)
+	cfg := &options.RunOptions{}
+	cfg.Facter.Sink.Output.OutputDirectory = dir
+	logger := logrus.New()
+
+	inventory := &schema.HostInventory{Hostname: "export-host"}
+	full := &schema.InventoryRequest{Content: &schema.InventoryRequest_Full{Full: inventory}}
+	extensions := &models.ExtensionsRequest{Full: &models.HostExtensions{
+		Hostname: "export-host",
+		Packages: []*models.Package{{Name: "curl", Version: "8.5.0", Ecosystem: models.EcosystemHomebrew}},
+	}}
+
+	for _, format := range []string{"proto", "json"} {
+		cfg.Facter.Sink.Output.Format = format
+		cfg.Facter.Sink.Output.OutputFilename = "export." + format
+		assert.NoError(t, exportToFile(full, logger, cfg))
+		assert.NoError(t, exportExtensionsToFile(extensions, logger, cfg))
+
+		inv, ext, err := ReadExport(filepath.Join(dir, "export."+format))
+		assert.NoError(t, err)
+		assert.Equal(t, "export-host", inv.GetHostname())
+		assert.Equal(t, "curl", ext.Packages[0].Name)
+	}
+
+	// Without extensions
+	cfg.Facter.Sink.Output.Format = "proto"
+	cfg.Facter.Sink.Output.OutputFilename = "alone.pb"
+	assert.NoError(t, exportToFile(full, logger, cfg))
+	inv, ext, err := ReadExport(filepath.Join(dir, "alone.pb"))
+	assert.NoError(t, err)
+	assert.Equal(t, "export-host", inv.GetHostname())
+	assert.Nil(t, ext)
+
+	cfg.Facter.Sink.Output.OutputFilename = "delta.pb"
+	delta := &schema.InventoryRequest{Content: &schema.InventoryRequest_Delta{Delta: &schema.HostDeltaInventory{}}}
+	assert.NoError(t, exportToFile(delta, logger, cfg))
+	_, _, err = ReadExport(filepath.Join(dir, "delta.pb"))
+	assert.ErrorContains(t, err, "a full inventory is required")
+
+	_, _, err = ReadExport(filepath.Join(dir, "missing.pb"))
+	assert.Error(t, err)
+}
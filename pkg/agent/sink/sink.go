@@ -1,7 +1,9 @@
 package sink
 
 import (
+	"github.com/klamhq/facter-oss/pkg/agent/sbom"
 	"github.com/klamhq/facter-oss/pkg/agent/store"
+	"github.com/klamhq/facter-oss/pkg/models"
 	"github.com/klamhq/facter-oss/pkg/options"
 	"github.com/klamhq/facter-oss/pkg/utils"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
@@ -9,15 +11,29 @@ import (
 )
 
 // SinkInventory sinks the inventory to the configured output(s).
-func SinkInventory(cfg *options.RunOptions, logger *logrus.Logger, store store.InventoryStore, inventory *schema.InventoryRequest, fullInventory *schema.HostInventory) error {
+// The extensions are exported by the file output only, the remote protocol has no room for them.
+func SinkInventory(cfg *options.RunOptions, logger *logrus.Logger, store store.InventoryStore, inventory *schema.InventoryRequest, fullInventory *schema.HostInventory, extensions *models.ExtensionsRequest, fullExtensions *models.HostExtensions) error {
 	var err error
 	// Save the inventory to a file if configured or to remote if enabled
 	hostname := utils.GetHostnameFromInventory(inventory)
 	switch cfg.Facter.Sink.Output.Type {
 	case "file":
+		if sbom.ValidFormat(cfg.Facter.Sink.Output.Format) {
+			// A SBOM lists every package, it is made from the full inventory whatever the changes
+			err = exportSBOMToFile(fullInventory, fullExtensions, logger, cfg)
+			if err != nil {
+				logger.WithError(err).Error("Failed to export SBOM to file")
+			}
+			break
+		}
 		err = exportToFile(inventory, logger, cfg)
 		if err != nil {
 			logger.WithError(err).Error("Failed to export inventory to file")
+			break
+		}
+		err = exportExtensionsToFile(extensions, logger, cfg)
+		if err != nil {
+			logger.WithError(err).Error("Failed to export inventory extensions to file")
 		}
 	case "remote":
 		err = sendOverGrpc(&cfg.Facter.Sink.Output.FacterServer, inventory, logger)
@@ -38,6 +54,18 @@ func SinkInventory(cfg *options.RunOptions, logger *logrus.Logger, store store.I
 		logger.Error("Failed to save inventory:", err)
 		return err
 	}
+	if fullExtensions != nil {
+		if err = store.SaveExtensions(hostname, fullExtensions); err != nil {
+			logger.Error("Failed to save inventory extensions:", err)
+			return err
+		}
+		if fullExtensions.Compliance != nil {
+			if err = store.AppendComplianceScore(hostname, fullExtensions.Compliance); err != nil {
+				logger.Error("Failed to save compliance score history:", err)
+				return err
+			}
+		}
+	}
 	logger.Infof("Inventory for host %s saved to local store %s", hostname, cfg.Facter.Store.Path)
 	if err := store.Close(); err != nil {
 		logger.WithError(err).Error("Failed to close inventory store")
@@ -32,7 +32,7 @@ func TestSinkInventory_ExportToFileFails(t *testing.T) {
 	fullInventory := &schema.HostInventory{}
 	fullInventory.Hostname = mockHostname
 
-	err := SinkInventory(cfg, logger, s, inventory, fullInventory)
+	err := SinkInventory(cfg, logger, s, inventory, fullInventory, nil, nil)
 	assert.Error(t, err)
 	assert.Nil(t, s.Save(mockHostname, fullInventory))
 	assert.NoError(t, s.Delete(mockHostname))
@@ -56,7 +56,7 @@ func TestSinkInventory_SaveFails(t *testing.T) {
 	fullInventory := &schema.HostInventory{}
 	fullInventory.Hostname = mockHostname
 
-	err := SinkInventory(cfg, logger, s, inventory, fullInventory)
+	err := SinkInventory(cfg, logger, s, inventory, fullInventory, nil, nil)
 	assert.Error(t, err)
 	assert.Nil(t, s.Save(mockHostname, fullInventory))
 	assert.NoError(t, s.Delete(mockHostname))
@@ -80,6 +80,6 @@ func TestSinkInventory_Success(t *testing.T) {
 	fullInventory := &schema.HostInventory{}
 	fullInventory.Hostname = mockHostname
 
-	err := SinkInventory(cfg, logger, s, inventory, fullInventory)
+	err := SinkInventory(cfg, logger, s, inventory, fullInventory, nil, nil)
 	assert.NoError(t, err)
 }
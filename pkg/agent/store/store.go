@@ -1,8 +1,10 @@
 package store
 
 import (
+	"encoding/json"
 	"fmt"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	bolt "go.etcd.io/bbolt"
 	proto "google.golang.org/protobuf/proto"
@@ -12,6 +14,10 @@ type InventoryStore interface {
 	Get(hostname string) (*schema.HostInventory, error)
 	Save(hostname string, inv *schema.HostInventory) error
 	Delete(hostname string) error
+	GetExtensions(hostname string) (*models.HostExtensions, error)
+	SaveExtensions(hostname string, ext *models.HostExtensions) error
+	AppendComplianceScore(hostname string, score *models.ComplianceScore) error
+	ComplianceHistory(hostname string) ([]*models.ComplianceScore, error)
 	Close() error
 }
 
@@ -19,7 +25,14 @@ type boltInventoryStore struct {
 	db *bolt.DB
 }
 
-const inventoryBucket = "inventory"
+const (
+	inventoryBucket         = "inventory"
+	extensionsBucket        = "extensions"
+	complianceHistoryBucket = "compliance_history"
+)
+
+// complianceHistorySize is the number of compliance scores kept by host, the oldest ones are dropped.
+const complianceHistorySize = 1000
 
 func NewBoltInventoryStore(path string) (*boltInventoryStore, error) {
 	db, err := bolt.Open(path, 0600, nil)
@@ -28,8 +41,12 @@ func NewBoltInventoryStore(path string) (*boltInventoryStore, error) {
 	}
 	// init bucket
 	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(inventoryBucket))
-		return err
+		for _, name := range []string{inventoryBucket, extensionsBucket, complianceHistoryBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	return &boltInventoryStore{db}, err
 }
@@ -60,9 +77,74 @@ func (b *boltInventoryStore) Get(hostname string) (*schema.HostInventory, error)
 
 func (b *boltInventoryStore) Delete(hostname string) error {
 	return b.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(inventoryBucket))
-		return bucket.Delete([]byte(hostname))
+		for _, name := range []string{inventoryBucket, extensionsBucket} {
+			if err := tx.Bucket([]byte(name)).Delete([]byte(hostname)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveExtensions stores the extensions as JSON, they are not part of the protobuf schema.
+func (b *boltInventoryStore) SaveExtensions(hostname string, ext *models.HostExtensions) error {
+	data, err := json.Marshal(ext)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(extensionsBucket))
+		return bucket.Put([]byte(hostname), data)
+	})
+}
+
+func (b *boltInventoryStore) GetExtensions(hostname string) (*models.HostExtensions, error) {
+	var ext models.HostExtensions
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(extensionsBucket))
+		data := bucket.Get([]byte(hostname))
+		if data == nil {
+			return fmt.Errorf("not found")
+		}
+		return json.Unmarshal(data, &ext)
+	})
+	return &ext, err
+}
+
+// AppendComplianceScore adds the score of a compliance run to the history of the host. The history is
+// kept when the inventory is deleted after a failed send.
+func (b *boltInventoryStore) AppendComplianceScore(hostname string, score *models.ComplianceScore) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(complianceHistoryBucket))
+		var history []*models.ComplianceScore
+		if data := bucket.Get([]byte(hostname)); data != nil {
+			if err := json.Unmarshal(data, &history); err != nil {
+				return err
+			}
+		}
+		history = append(history, score)
+		if len(history) > complianceHistorySize {
+			history = history[len(history)-complianceHistorySize:]
+		}
+		data, err := json.Marshal(history)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(hostname), data)
+	})
+}
+
+// ComplianceHistory returns the compliance scores of the host, oldest first.
+func (b *boltInventoryStore) ComplianceHistory(hostname string) ([]*models.ComplianceScore, error) {
+	var history []*models.ComplianceScore
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(complianceHistoryBucket)).Get([]byte(hostname))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &history)
 	})
+	return history, err
 }
 
 func (b *boltInventoryStore) Close() error {
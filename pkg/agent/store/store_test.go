@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/klamhq/facter-oss/pkg/models"
 	schema "github.com/klamhq/facter-schema/proto/klamhq/rpc/facter/v1"
 	"github.com/stretchr/testify/assert"
 )
@@ -74,6 +75,36 @@ func TestDeleteInventory(t *testing.T) {
 
 }
 
+func TestSaveAndGetExtensions(t *testing.T) {
+	path := path.Join(t.TempDir(), "test.db")
+	store, err := NewBoltInventoryStore(path)
+	assert.NoError(t, err)
+
+	_, err = store.GetExtensions("test-host")
+	assert.EqualError(t, err, "not found")
+
+	ext := &models.HostExtensions{
+		Hostname: "test-host",
+		Packages: []*models.Package{{Name: "curl", Version: "8.5.0", Ecosystem: models.EcosystemHomebrew, Manager: "homebrew"}},
+	}
+	assert.NoError(t, store.SaveExtensions("test-host", ext))
+
+	retrieved, err := store.GetExtensions("test-host")
+	assert.NoError(t, err)
+	assert.Equal(t, ext, retrieved)
+
+	// File lists are only used during the collection
+	ext.Packages[0].Files = []string{"/usr/bin/curl"}
+	assert.NoError(t, store.SaveExtensions("test-host", ext))
+	retrieved, err = store.GetExtensions("test-host")
+	assert.NoError(t, err)
+	assert.Nil(t, retrieved.Packages[0].Files)
+
+	assert.NoError(t, store.Delete("test-host"))
+	_, err = store.GetExtensions("test-host")
+	assert.Error(t, err, "Extensions should be deleted with the inventory")
+}
+
 func TestCloseStore(t *testing.T) {
 	path := path.Join(t.TempDir(), "test.db")
 	store, err := NewBoltInventoryStore(path)
@@ -87,3 +118,32 @@ func TestCloseNilStore(t *testing.T) {
 	err := store.Close()
 	assert.NoError(t, err)
 }
+
+func TestComplianceHistory(t *testing.T) {
+	path := path.Join(t.TempDir(), "test.db")
+	store, err := NewBoltInventoryStore(path)
+	assert.NoError(t, err)
+
+	history, err := store.ComplianceHistory("test-host")
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	first := &models.ComplianceScore{Time: "2026-10-01T12:00:00Z", Profile: "facter:cis-linux-l1", Score: 90, Passed: 36, Failed: 4}
+	second := &models.ComplianceScore{Time: "2026-10-02T12:00:00Z", Profile: "facter:cis-linux-l1", Score: 87.5, Passed: 35, Failed: 5, NewlyFailing: []string{"sshd_disable_root_login"}}
+	assert.NoError(t, store.AppendComplianceScore("test-host", first))
+	assert.NoError(t, store.AppendComplianceScore("test-host", second))
+
+	// The history is kept when the inventory is deleted
+	assert.NoError(t, store.Delete("test-host"))
+	history, err = store.ComplianceHistory("test-host")
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.ComplianceScore{first, second}, history)
+
+	for i := 0; i < complianceHistorySize; i++ {
+		assert.NoError(t, store.AppendComplianceScore("test-host", second))
+	}
+	history, err = store.ComplianceHistory("test-host")
+	assert.NoError(t, err)
+	assert.Len(t, history, complianceHistorySize)
+	assert.Equal(t, second, history[0], "the oldest scores are dropped")
+}
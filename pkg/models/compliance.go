@@ -19,3 +19,24 @@ type RuleCheckResult struct {
 	Severity    string `json:"severity"`
 	Fix         string `json:"fix"`
 }
+
+// ComplianceScore is the score of a compliance run, the scores of the runs are kept in the local store.
+type ComplianceScore struct {
+	Time    string  `json:"time"` // RFC 3339
+	Profile string  `json:"profile"`
+	Score   float64 `json:"score"` // Percentage of the maximum score
+	Passed  int     `json:"passed"`
+	Failed  int     `json:"failed"`
+	// Rules failing or passing since the previous run.
+	NewlyFailing []string `json:"newly_failing,omitempty"`
+	NewlyPassing []string `json:"newly_passing,omitempty"`
+}
+
+// ComplianceTransition is a rule whose result changed between two runs.
+type ComplianceTransition struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Severity string `json:"severity"`
+	Previous string `json:"previous"`
+	Result   string `json:"result"`
+}
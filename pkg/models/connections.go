@@ -1,14 +1,15 @@
 package models
 
 // Connections represents a network connection with its details.
-// It includes local and remote ports, process information, protocol type,
+// It includes local and remote ports, process information, family and protocol,
 // remote and local IP addresses, process ID, package name, state, process name,
 // and process path.
 type Connections struct {
 	LocalPort   uint32
 	RemotePort  uint32
 	Process     string
-	Protocol    uint32
+	Family      string // See SocketFamily* constants
+	Protocol    string // tcp or udp
 	RemoteIp    string
 	LocalIp     string
 	Pid         int32
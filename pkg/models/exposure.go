@@ -0,0 +1,33 @@
+package models
+
+import (
+	"net"
+	"strconv"
+)
+
+// Exposure levels of a listening socket, in increasing order.
+const (
+	ExposureLoopback = "loopback" // Reachable from the host only, by its bind address or the firewall
+	ExposureLan      = "lan"      // Reachable from private networks only
+	ExposurePublic   = "public"   // Reachable from the internet
+)
+
+// Exposure tells from where a listening socket can be reached, from its bind address, the addresses of
+// the host and the input rules of the firewall.
+type Exposure struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     uint32 `json:"port"`
+	Level    string `json:"level"` // See Exposure* constants
+	// Filtered is set when the firewall lowers the level given by the bind address.
+	Filtered bool   `json:"filtered,omitempty"`
+	Process  string `json:"process,omitempty"`
+	Pid      int64  `json:"pid,omitempty"`
+	User     string `json:"user,omitempty"`
+	Package  string `json:"package,omitempty"`
+}
+
+// Key returns a stable identifier of the socket of the exposure, it does not change with its level.
+func (e *Exposure) Key() string {
+	return e.Protocol + "/" + net.JoinHostPort(e.Address, strconv.FormatUint(uint64(e.Port), 10))
+}
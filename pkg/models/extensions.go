@@ -0,0 +1,83 @@
+package models
+
+// HostExtensions holds the inventory data which has no field in the facter schema.
+// It is stored next to the HostInventory and exported alongside it by the file sink. The extensions are local
+// only: the remote sink sends the schema inventory, which cannot carry them.
+type HostExtensions struct {
+	Hostname      string          `json:"hostname"`
+	Packages      []*Package      `json:"packages,omitempty"`
+	ModifiedFiles []*ModifiedFile `json:"modified_files,omitempty"`
+	// Vulnerabilities of the installed packages, enriched from the local feeds, and the resulting risk.
+	Vulnerabilities []*VulnerabilityFinding `json:"vulnerabilities,omitempty"`
+	Risk            *HostRisk               `json:"risk,omitempty"`
+	// Packages and vulnerabilities of the local container images.
+	Images []*ImageInventory `json:"images,omitempty"`
+	// Score of the compliance report of the run.
+	Compliance *ComplianceScore `json:"compliance,omitempty"`
+	// Firewall backends and their configuration.
+	Firewall *FirewallInventory `json:"firewall,omitempty"`
+	// Exposure of the listening sockets.
+	Exposures []*Exposure `json:"exposures,omitempty"`
+	// Open sockets with their state and owner.
+	Sockets []*Socket `json:"sockets,omitempty"`
+	// Routes, policy routing rules, neighbours and network namespaces.
+	Routing *RoutingInventory `json:"routing,omitempty"`
+}
+
+// HostExtensionsDelta holds the changes of the extensions between two runs.
+type HostExtensionsDelta struct {
+	Hostname        string     `json:"hostname"`
+	PackagesAdded   []*Package `json:"packages_added,omitempty"`
+	PackagesRemoved []*Package `json:"packages_removed,omitempty"`
+	// Files modified since the previous run, and files restored or no longer installed.
+	ModifiedFilesAdded   []*ModifiedFile `json:"modified_files_added,omitempty"`
+	ModifiedFilesRemoved []*ModifiedFile `json:"modified_files_removed,omitempty"`
+	// Vulnerabilities found since the previous run, and vulnerabilities fixed or no longer installed.
+	VulnerabilitiesAdded   []*VulnerabilityFinding `json:"vulnerabilities_added,omitempty"`
+	VulnerabilitiesRemoved []*VulnerabilityFinding `json:"vulnerabilities_removed,omitempty"`
+	// Risk is set when the risk of the host changed.
+	Risk *HostRisk `json:"risk,omitempty"`
+	// Images scanned for the first time, images removed, and images whose packages or vulnerabilities changed.
+	ImagesAdded   []*ImageInventory `json:"images_added,omitempty"`
+	ImagesRemoved []*ImageInventory `json:"images_removed,omitempty"`
+	ImagesChanged []*ImageInventory `json:"images_changed,omitempty"`
+	// Compliance rules failing or passing since the previous run, and the compliance score when it changed.
+	ComplianceNewlyFailing []*ComplianceTransition `json:"compliance_newly_failing,omitempty"`
+	ComplianceNewlyPassing []*ComplianceTransition `json:"compliance_newly_passing,omitempty"`
+	ComplianceScore        *ComplianceScore        `json:"compliance_score,omitempty"`
+	// Firewall is set when the firewall configuration changed.
+	Firewall *FirewallInventory `json:"firewall,omitempty"`
+	// Sockets reachable from another host since the previous run, or reachable from further, and sockets
+	// no longer reachable at their previous level.
+	ExposuresAdded   []*Exposure `json:"exposures_added,omitempty"`
+	ExposuresRemoved []*Exposure `json:"exposures_removed,omitempty"`
+	// Routing is set when the routes, the policy routing rules or the network namespaces changed.
+	Routing *RoutingInventory `json:"routing,omitempty"`
+}
+
+// IsEmpty returns true when no change has been detected.
+func (d *HostExtensionsDelta) IsEmpty() bool {
+	return len(d.PackagesAdded) == 0 &&
+		len(d.PackagesRemoved) == 0 &&
+		len(d.ModifiedFilesAdded) == 0 &&
+		len(d.ModifiedFilesRemoved) == 0 &&
+		len(d.VulnerabilitiesAdded) == 0 &&
+		len(d.VulnerabilitiesRemoved) == 0 &&
+		d.Risk == nil &&
+		len(d.ImagesAdded) == 0 &&
+		len(d.ImagesRemoved) == 0 &&
+		len(d.ImagesChanged) == 0 &&
+		len(d.ComplianceNewlyFailing) == 0 &&
+		len(d.ComplianceNewlyPassing) == 0 &&
+		d.ComplianceScore == nil &&
+		d.Firewall == nil &&
+		len(d.ExposuresAdded) == 0 &&
+		len(d.ExposuresRemoved) == 0 &&
+		d.Routing == nil
+}
+
+// ExtensionsRequest mirrors the InventoryRequest of the facter schema, only one of Full or Delta is set.
+type ExtensionsRequest struct {
+	Full  *HostExtensions      `json:"full,omitempty"`
+	Delta *HostExtensionsDelta `json:"delta,omitempty"`
+}
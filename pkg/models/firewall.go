@@ -0,0 +1,172 @@
+package models
+
+// Firewall backends, the backend is the tool managing the rules of the host.
+const (
+	FirewallBackendFirewalld      = "firewalld"
+	FirewallBackendUfw            = "ufw"
+	FirewallBackendNftables       = "nftables"
+	FirewallBackendIptablesNft    = "iptables-nft"
+	FirewallBackendIptablesLegacy = "iptables-legacy"
+	FirewallBackendNone           = "none"
+)
+
+// Address families of the normalised firewall rules, inet chains see both IPv4 and IPv6 packets.
+const (
+	FirewallFamilyIPv4 = "ipv4"
+	FirewallFamilyIPv6 = "ipv6"
+	FirewallFamilyInet = "inet"
+)
+
+// Actions of the normalised firewall rules, extension targets of iptables keep their lower case name.
+const (
+	FirewallActionAccept     = "accept"
+	FirewallActionDrop       = "drop"
+	FirewallActionReject     = "reject"
+	FirewallActionReturn     = "return"
+	FirewallActionJump       = "jump"
+	FirewallActionGoto       = "goto"
+	FirewallActionLog        = "log"
+	FirewallActionQueue      = "queue"
+	FirewallActionMasquerade = "masquerade"
+	FirewallActionSnat       = "snat"
+	FirewallActionDnat       = "dnat"
+	FirewallActionRedirect   = "redirect"
+)
+
+// FirewallInventory holds the firewall configuration of the host as read from each backend found.
+type FirewallInventory struct {
+	// Backend managing the rules: firewalld or ufw when active, otherwise the kernel framework holding rules.
+	Backend string `json:"backend"`
+	// Backends found on the host, active or not.
+	Backends  []string        `json:"backends,omitempty"`
+	Nftables  *NftRuleset     `json:"nftables,omitempty"`
+	Firewalld *FirewalldState `json:"firewalld,omitempty"`
+	Ufw       *UfwStatus      `json:"ufw,omitempty"`
+	// Chains and rules of iptables, ip6tables and nftables in the normalised representation.
+	Chains []*FirewallChain `json:"chains,omitempty"`
+	Rules  []*FirewallRule  `json:"rules,omitempty"`
+}
+
+// FirewallChain is a chain of the normalised ruleset. Base chains are attached to a hook of the kernel and
+// their policy applies to the packets no rule decided on.
+type FirewallChain struct {
+	// Origin is the tool the chain was read from: iptables, ip6tables or nftables.
+	Origin  string `json:"origin"`
+	Family  string `json:"family"`
+	Table   string `json:"table"`
+	Name    string `json:"name"`
+	Hook    string `json:"hook,omitempty"`
+	Policy  string `json:"policy,omitempty"`
+	Packets uint64 `json:"packets,omitempty"`
+	Bytes   uint64 `json:"bytes,omitempty"`
+}
+
+// FirewallRule is a rule of the normalised ruleset, whatever the backend it was read from. An empty criterion
+// matches every packet, a value prefixed with ! is excluded. Ports ranges are written 1000-2000.
+type FirewallRule struct {
+	Origin string `json:"origin"`
+	Family string `json:"family"`
+	Table  string `json:"table"`
+	Chain  string `json:"chain"`
+	// Position of the rule in its chain, starting at 1.
+	Position             int      `json:"position"`
+	Protocol             string   `json:"protocol,omitempty"`
+	SourceAddresses      []string `json:"source_addresses,omitempty"`
+	DestinationAddresses []string `json:"destination_addresses,omitempty"`
+	SourcePorts          []string `json:"source_ports,omitempty"`
+	DestinationPorts     []string `json:"destination_ports,omitempty"`
+	InInterface          string   `json:"in_interface,omitempty"`
+	OutInterface         string   `json:"out_interface,omitempty"`
+	// States are the conntrack states matched, in lower case.
+	States []string `json:"states,omitempty"`
+	// Action is empty for the rules which only count the packets, Target is the chain of a jump or goto
+	// and the address of a nat action.
+	Action  string `json:"action,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	Packets uint64 `json:"packets,omitempty"`
+	Bytes   uint64 `json:"bytes,omitempty"`
+}
+
+// NftRuleset is the nftables ruleset of the host.
+type NftRuleset struct {
+	Version string      `json:"version,omitempty"`
+	Tables  []*NftTable `json:"tables,omitempty"`
+}
+
+type NftTable struct {
+	Family string      `json:"family"`
+	Name   string      `json:"name"`
+	Chains []*NftChain `json:"chains,omitempty"`
+	Sets   []*NftSet   `json:"sets,omitempty"`
+}
+
+// NftChain is a chain of a table, base chains are attached to a hook and have a policy.
+type NftChain struct {
+	Name     string     `json:"name"`
+	Type     string     `json:"type,omitempty"`
+	Hook     string     `json:"hook,omitempty"`
+	Priority *int       `json:"priority,omitempty"`
+	Policy   string     `json:"policy,omitempty"`
+	Rules    []*NftRule `json:"rules,omitempty"`
+}
+
+// NftRule is a rule of a chain, its statements are rendered in the nft syntax without the counter values.
+type NftRule struct {
+	Handle  int    `json:"handle"`
+	Expr    string `json:"expr"`
+	Verdict string `json:"verdict,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// NftSet is a named set or map of a table.
+type NftSet struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Map      string   `json:"map,omitempty"`
+	Flags    []string `json:"flags,omitempty"`
+	Elements []string `json:"elements,omitempty"`
+}
+
+// FirewalldState is the runtime configuration of firewalld.
+type FirewalldState struct {
+	Running     bool             `json:"running"`
+	DefaultZone string           `json:"default_zone,omitempty"`
+	Zones       []*FirewalldZone `json:"zones,omitempty"`
+}
+
+// FirewalldZone is a zone of firewalld, only the active zones and the default zone are collected.
+type FirewalldZone struct {
+	Name       string   `json:"name"`
+	Active     bool     `json:"active"`
+	Target     string   `json:"target,omitempty"`
+	Interfaces []string `json:"interfaces,omitempty"`
+	Sources    []string `json:"sources,omitempty"`
+	Services   []string `json:"services,omitempty"`
+	Ports      []string `json:"ports,omitempty"`
+	Protocols  []string `json:"protocols,omitempty"`
+	Masquerade bool     `json:"masquerade"`
+	// ForwardPorts are the port forwardings, as port=22:proto=tcp:toport=2222:toaddr=.
+	ForwardPorts []string `json:"forward_ports,omitempty"`
+	RichRules    []string `json:"rich_rules,omitempty"`
+}
+
+// UfwStatus is the status of ufw with its default policies and rules.
+type UfwStatus struct {
+	Active          bool       `json:"active"`
+	Logging         string     `json:"logging,omitempty"`
+	DefaultIncoming string     `json:"default_incoming,omitempty"`
+	DefaultOutgoing string     `json:"default_outgoing,omitempty"`
+	DefaultRouted   string     `json:"default_routed,omitempty"`
+	Rules           []*UfwRule `json:"rules,omitempty"`
+}
+
+// UfwRule is a rule of ufw, as listed by ufw status.
+type UfwRule struct {
+	To        string `json:"to"`
+	Action    string `json:"action"`
+	Direction string `json:"direction,omitempty"`
+	From      string `json:"from"`
+	IPv6      bool   `json:"ipv6,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+// ImageInventory holds the packages and the vulnerabilities of a local container image.
+type ImageInventory struct {
+	ID          string   `json:"id"`
+	RepoTags    []string `json:"repo_tags,omitempty"`
+	RepoDigests []string `json:"repo_digests,omitempty"`
+	Created     int64    `json:"created,omitempty"` // Creation time of the image, in seconds since the epoch
+	// Distribution of the image, as described by its os-release.
+	OSName    string     `json:"os_name,omitempty"`
+	OSVersion string     `json:"os_version,omitempty"`
+	Packages  []*Package `json:"packages,omitempty"`
+	// Vulnerabilities of the packages of the image, they are never reachable as nothing runs from the image itself.
+	Vulnerabilities []*VulnerabilityFinding `json:"vulnerabilities,omitempty"`
+	Risk            *HostRisk               `json:"risk,omitempty"`
+	// Error is set when the image could not be unpacked or scanned.
+	Error string `json:"error,omitempty"`
+}
+
+// Key returns the image identifier, the digest of its configuration.
+func (i *ImageInventory) Key() string {
+	return i.ID
+}
+
+// Name returns the first tag of the image, or its identifier when untagged.
+func (i *ImageInventory) Name() string {
+	for _, tag := range i.RepoTags {
+		if tag != "<none>:<none>" {
+			return tag
+		}
+	}
+	return i.ID
+}
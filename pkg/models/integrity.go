@@ -0,0 +1,24 @@
+package models
+
+// ModifiedFile is a file installed by a package which no longer matches the package database.
+type ModifiedFile struct {
+	Path      string `json:"path"`
+	Package   string `json:"package"`
+	Ecosystem string `json:"ecosystem"`
+	Reason    string `json:"reason"`             // See File* constants
+	Expected  string `json:"expected,omitempty"` // Digest or mode recorded in the package database
+	Actual    string `json:"actual,omitempty"`   // Digest or mode found on disk
+	Config    bool   `json:"config,omitempty"`   // Configuration file, expected to be modified by administrators
+}
+
+// Reasons of a file modification.
+const (
+	FileChanged     = "changed"
+	FileMissing     = "missing"
+	FilePermissions = "permissions"
+)
+
+// Key returns a stable identifier of the modification, a file modified again has a new key.
+func (f *ModifiedFile) Key() string {
+	return f.Ecosystem + "/" + f.Package + ":" + f.Path + "#" + f.Reason + "=" + f.Actual
+}
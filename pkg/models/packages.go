@@ -3,19 +3,75 @@ package models
 // Package is used to store relevant information about installed by package manager.
 // This structure is returned by method `Grab`
 type Package struct {
-	Name              string `json:"name,omitempty"`
-	Version           string `json:"version,omitempty"`
-	Architecture      string `json:"architecture,omitempty"`
-	Description       string `json:"description,omitempty"`
-	UpgradableVersion string `json:"upgradable_version,omitempty"`
-	IsUpToDate        bool   `json:"is_up_to_date,omitempty"`
+	Name              string     `json:"name,omitempty"`
+	Version           string     `json:"version,omitempty"`
+	Architecture      string     `json:"architecture,omitempty"`
+	Description       string     `json:"description,omitempty"`
+	UpgradableVersion string     `json:"upgradable_version,omitempty"`
+	IsUpToDate        bool       `json:"is_up_to_date,omitempty"`
+	SourcePackage     string     `json:"source_package,omitempty"`
+	Maintainer        string     `json:"maintainer,omitempty"`
+	InstalledSize     int64      `json:"installed_size,omitempty"` // Installed size in bytes
+	Status            string     `json:"status,omitempty"`         // Raw package manager status, e.g. "install ok installed" for dpkg
+	Epoch             string     `json:"epoch,omitempty"`
+	Release           string     `json:"release,omitempty"`
+	Vendor            string     `json:"vendor,omitempty"`
+	License           string     `json:"license,omitempty"`
+	InstallTime       int64      `json:"install_time,omitempty"`     // Unix timestamp of the installation
+	SignatureKeyID    string     `json:"signature_key_id,omitempty"` // ID of the key used to sign the package
+	Files             []string   `json:"-"`                          // Files owned by the package, used to build the file owners index and never exported
+	Ecosystem         string     `json:"ecosystem,omitempty"`        // Packaging ecosystem, see Ecosystem* constants
+	Manager           string     `json:"manager,omitempty"`          // Package manager the package was read from, e.g. dpkg or homebrew
+	Location          string     `json:"location,omitempty"`         // Path the package was found at, for packages not installed by a system package manager
+	Revision          string     `json:"revision,omitempty"`         // Snap revision or flatpak commit
+	Channel           string     `json:"channel,omitempty"`          // Snap tracking channel or flatpak branch
+	Origin            string     `json:"origin,omitempty"`           // Store or remote the package was installed from
+	Confinement       string     `json:"confinement,omitempty"`      // Snap confinement: strict, classic or devmode
+	SecurityUpdate    bool       `json:"security_update,omitempty"`  // The upgradable version fixes security issues
+	Advisories        []Advisory `json:"advisories,omitempty"`       // Advisories fixed by the upgradable version
+}
+
+// Advisory is a vendor advisory (e.g. RHSA, FEDORA) fixed by an available upgrade.
+type Advisory struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`               // security, bugfix, enhancement or newpackage
+	Severity string `json:"severity,omitempty"` // Vendor severity, e.g. Important or Moderate
+}
+
+// Packaging ecosystems, named after the package-url types when one exists.
+const (
+	EcosystemDeb      = "deb"
+	EcosystemRpm      = "rpm"
+	EcosystemApk      = "apk"
+	EcosystemAlpm     = "alpm"
+	EcosystemHomebrew = "brew"
+	EcosystemPypi     = "pypi"
+	EcosystemNpm      = "npm"
+	EcosystemGem      = "gem"
+	EcosystemGolang   = "golang"
+	EcosystemMaven    = "maven"
+	EcosystemSnap     = "snap"
+	EcosystemFlatpak  = "flatpak"
+)
+
+// Key returns a stable identifier of the package, same-named packages from different
+// ecosystems or architectures have different keys.
+func (p *Package) Key() string {
+	key := p.Ecosystem + "/" + p.Name + "@" + p.Version
+	if p.Architecture != "" {
+		key += ":" + p.Architecture
+	}
+	return key
 }
 
 type PackageVulnMatch struct {
 	PackageName      string        `json:"name"`
 	InstalledVersion string        `json:"version"`
+	Ecosystem        string        `json:"ecosystem,omitempty"`
 	Vulnerabilities  []MatchedVuln `json:"vulnerabilities"`
 	Matched          bool
+	// Package is the collected package the scanner reported, nil when it is not part of the collected packages.
+	Package *Package `json:"-"`
 }
 
 type MatchedVuln struct {
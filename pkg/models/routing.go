@@ -0,0 +1,84 @@
+package models
+
+// RoutingInventory holds the routing tables, the policy routing rules, the neighbours and the network namespaces
+// of the host. The rules and the IPv6 neighbours are only known from netlink.
+type RoutingInventory struct {
+	Routes     []*Route            `json:"routes,omitempty"`
+	Rules      []*RoutingRule      `json:"rules,omitempty"`
+	Neighbours []*Neighbour        `json:"neighbours,omitempty"`
+	Namespaces []*NetworkNamespace `json:"namespaces,omitempty"`
+}
+
+// Route is a route of a routing table, as shown by ip route show table all.
+type Route struct {
+	Family string `json:"family"` // See SocketFamilyInet and SocketFamilyInet6
+	// Table is main, local, default or the number of the table, empty when it is unknown.
+	Table string `json:"table,omitempty"`
+	// Type is unicast, local, broadcast, anycast, multicast, blackhole, unreachable, prohibit or throw.
+	Type string `json:"type"`
+	// Destination is a network, 0.0.0.0/0 or ::/0 for a default route.
+	Destination string `json:"destination"`
+	Source      string `json:"source,omitempty"`
+	Gateway     string `json:"gateway,omitempty"`
+	Interface   string `json:"interface,omitempty"`
+	// PreferredSource is the address given to the packets sent by the host along the route.
+	PreferredSource string `json:"preferred_source,omitempty"`
+	Metric          uint32 `json:"metric"`
+	// Protocol is the origin of the route: kernel, boot, static, dhcp, ra or the number of the protocol.
+	Protocol string `json:"protocol,omitempty"`
+	Scope    string `json:"scope,omitempty"` // universe, site, link, host or nowhere
+	// Default is set for a default route, whose gateway is a default gateway of the host.
+	Default bool `json:"default,omitempty"`
+	// Nexthops of a multipath route, whose gateway and interface are then empty.
+	Nexthops []*RouteNexthop `json:"nexthops,omitempty"`
+}
+
+// RouteNexthop is a nexthop of a multipath route.
+type RouteNexthop struct {
+	Gateway   string `json:"gateway,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	Weight    uint32 `json:"weight"`
+}
+
+// RoutingRule is a policy routing rule, as shown by ip rule.
+type RoutingRule struct {
+	Family   string `json:"family"` // See SocketFamilyInet and SocketFamilyInet6
+	Priority uint32 `json:"priority"`
+	// Not is set when the selector of the rule is inverted.
+	Not          bool   `json:"not,omitempty"`
+	From         string `json:"from,omitempty"`
+	To           string `json:"to,omitempty"`
+	InInterface  string `json:"in_interface,omitempty"`
+	OutInterface string `json:"out_interface,omitempty"`
+	// Fwmark is the firewall mark of the selector, with its mask as in 0x1/0xff.
+	Fwmark string `json:"fwmark,omitempty"`
+	// Action is lookup, goto, nop, blackhole, unreachable or prohibit.
+	Action string `json:"action"`
+	Table  string `json:"table,omitempty"`
+	Goto   uint32 `json:"goto,omitempty"`
+}
+
+// Neighbour is an entry of the ARP or NDP table.
+type Neighbour struct {
+	Family      string `json:"family"` // See SocketFamilyInet and SocketFamilyInet6
+	Address     string `json:"address"`
+	LinkAddress string `json:"link_address,omitempty"`
+	Interface   string `json:"interface"`
+	// State is REACHABLE, STALE, DELAY, PROBE, FAILED, INCOMPLETE or PERMANENT.
+	State string `json:"state"`
+	// Router is set for an IPv6 neighbour advertising itself as a router.
+	Router bool `json:"router,omitempty"`
+}
+
+// NetworkNamespace is a network namespace of the host and its interfaces.
+type NetworkNamespace struct {
+	// Name of a namespace created by ip netns, empty for the namespaces of containers.
+	Name  string `json:"name,omitempty"`
+	Inode uint64 `json:"inode"`
+	// Host is set for the namespace of the init process.
+	Host bool `json:"host,omitempty"`
+	// Pid is the lowest pid of the processes of the namespace, zero without any process.
+	Pid        int32    `json:"pid,omitempty"`
+	Processes  int      `json:"processes"`
+	Interfaces []string `json:"interfaces,omitempty"`
+}
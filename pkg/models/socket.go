@@ -0,0 +1,51 @@
+package models
+
+// Socket families.
+const (
+	SocketFamilyInet  = "inet"
+	SocketFamilyInet6 = "inet6"
+	SocketFamilyUnix  = "unix"
+)
+
+// Socket protocols, tcp and udp for the inet families, the socket type for the unix family.
+const (
+	SocketProtocolTcp       = "tcp"
+	SocketProtocolUdp       = "udp"
+	SocketProtocolStream    = "stream"
+	SocketProtocolDgram     = "dgram"
+	SocketProtocolSeqpacket = "seqpacket"
+)
+
+// Socket states, named after the TCP states of the kernel. A udp or unix socket which is not connected is UNCONN.
+const (
+	SocketStateEstablished = "ESTABLISHED"
+	SocketStateSynSent     = "SYN_SENT"
+	SocketStateSynRecv     = "SYN_RECV"
+	SocketStateFinWait1    = "FIN_WAIT1"
+	SocketStateFinWait2    = "FIN_WAIT2"
+	SocketStateTimeWait    = "TIME_WAIT"
+	SocketStateClose       = "CLOSE"
+	SocketStateCloseWait   = "CLOSE_WAIT"
+	SocketStateLastAck     = "LAST_ACK"
+	SocketStateListen      = "LISTEN"
+	SocketStateClosing     = "CLOSING"
+	SocketStateUnconn      = "UNCONN"
+)
+
+// Socket is an open socket of the host and the process owning it.
+type Socket struct {
+	Family        string `json:"family"`   // See SocketFamily* constants
+	Protocol      string `json:"protocol"` // See SocketProtocol* constants
+	State         string `json:"state"`    // See SocketState* constants
+	LocalAddress  string `json:"local_address,omitempty"`
+	LocalPort     uint32 `json:"local_port,omitempty"`
+	RemoteAddress string `json:"remote_address,omitempty"`
+	RemotePort    uint32 `json:"remote_port,omitempty"`
+	// Path of a unix socket, an abstract socket starts with @.
+	Path string `json:"path,omitempty"`
+	// UID is nil when the kernel does not report the owner of the socket.
+	UID     *uint32 `json:"uid,omitempty"`
+	Inode   uint64  `json:"inode"`
+	Pid     int32   `json:"pid,omitempty"`
+	Process string  `json:"process,omitempty"`
+}
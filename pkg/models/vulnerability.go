@@ -22,6 +22,8 @@ type SeverityCountByHost struct {
 
 type Result struct {
 	Target          string          `json:"Target"`
+	Class           string          `json:"Class"`
+	Type            string          `json:"Type"` // OS family for os-pkgs results, e.g. debian or alpine
 	Vulnerabilities []Vulnerability `json:"Vulnerabilities"`
 }
 
@@ -33,3 +35,81 @@ type VulnCount struct {
 	Id           string `json:"id"`
 	PackageCount int    `json:"packageCount"`
 }
+
+// VulnerabilityFinding is a vulnerability of an installed package, enriched from the local CVSS, EPSS and KEV feeds.
+type VulnerabilityFinding struct {
+	ID               string  `json:"id"`
+	Package          string  `json:"package"`
+	Ecosystem        string  `json:"ecosystem,omitempty"` // See Ecosystem* constants, empty when the scanner does not tell
+	InstalledVersion string  `json:"installed_version"`
+	Severity         string  `json:"severity,omitempty"`
+	FixedVersion     string  `json:"fixed_version,omitempty"`
+	CVSSv3Score      float64 `json:"cvss_v3_score,omitempty"`
+	CVSSv3Vector     string  `json:"cvss_v3_vector,omitempty"`
+	CVSSv4Score      float64 `json:"cvss_v4_score,omitempty"`
+	CVSSv4Vector     string  `json:"cvss_v4_vector,omitempty"`
+	EPSS             float64 `json:"epss,omitempty"`            // Probability of exploitation in the next 30 days
+	EPSSPercentile   float64 `json:"epss_percentile,omitempty"` // Share of the vulnerabilities with a lower EPSS
+	KnownExploited   bool    `json:"known_exploited,omitempty"` // Listed in the CISA Known Exploited Vulnerabilities catalog
+	KEVDateAdded     string  `json:"kev_date_added,omitempty"`
+	KnownRansomware  bool    `json:"known_ransomware,omitempty"` // Known to be used in ransomware campaigns
+	Published        string  `json:"published,omitempty"`
+	Risk             string  `json:"risk"` // See Risk* constants
+	// Reachability of the package from its processes, see Reachability* constants, empty when unknown
+	Reachability string   `json:"reachability,omitempty"`
+	Listening    []string `json:"listening,omitempty"` // Sockets listened by the package, e.g. tcp/0.0.0.0:22
+}
+
+// Key returns a stable identifier of the finding, it changes when the package is upgraded. Same-named
+// packages from different ecosystems have different keys.
+func (f *VulnerabilityFinding) Key() string {
+	key := f.Package + "@" + f.InstalledVersion + "#" + f.ID
+	if f.Ecosystem != "" {
+		key = f.Ecosystem + "/" + key
+	}
+	return key
+}
+
+// HostRisk is the risk rating of a host, computed by the risk policy from its vulnerabilities.
+type HostRisk struct {
+	Rating         string         `json:"rating"`           // Highest risk of the vulnerabilities, see Risk* constants
+	Counts         map[string]int `json:"counts,omitempty"` // Number of vulnerabilities by risk
+	KnownExploited int            `json:"known_exploited,omitempty"`
+	Exposed        int            `json:"exposed,omitempty"` // Number of vulnerabilities of packages listening on a non-loopback address
+}
+
+// Risk ratings, in increasing order.
+const (
+	RiskNone     = "none"
+	RiskLow      = "low"
+	RiskMedium   = "medium"
+	RiskHigh     = "high"
+	RiskCritical = "critical"
+)
+
+var riskOrder = map[string]int{RiskNone: 0, RiskLow: 1, RiskMedium: 2, RiskHigh: 3, RiskCritical: 4}
+
+// RiskAtLeast reports whether the rating is equal to or higher than threshold.
+// Unknown ratings are lower than any rating.
+func RiskAtLeast(rating, threshold string) bool {
+	r, ok := riskOrder[rating]
+	if !ok {
+		return false
+	}
+	return r >= riskOrder[threshold]
+}
+
+// Reachability of a vulnerable package, in increasing order of exposure.
+const (
+	ReachabilityNotRunning = "not_running"
+	ReachabilityRunning    = "running"   // Run by a process which listens on no socket
+	ReachabilityListening  = "listening" // Listens on loopback addresses only
+	ReachabilityExposed    = "exposed"   // Listens on a non-loopback address
+)
+
+// ValidRisk reports whether rating is a rating a risk policy can fail on: low, medium, high or critical.
+// Every host reaches none, failing on it would always fail.
+func ValidRisk(rating string) bool {
+	r, ok := riskOrder[rating]
+	return ok && r > riskOrder[RiskNone]
+}
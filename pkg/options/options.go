@@ -34,6 +34,7 @@ type NetworksOptions struct {
 	Ports       PortsOptions       `yaml:"ports"`
 	Connections ConnectionsOptions `yaml:"connections"`
 	Firewall    FirewallOptions    `yaml:"firewall"`
+	Routing     RoutingOptions     `yaml:"routing"`
 	PublicIp    PublicIpOptions    `yaml:"publicIp"`
 	GeoIp       GeoIpOptions       `yaml:"geoIp"`
 }
@@ -83,6 +84,8 @@ type OsOptions struct {
 // ConnectionsOptions contains the options for fetch logs configurations
 type ConnectionsOptions struct {
 	Enabled bool `yaml:"enabled"`
+	// Unix adds the unix domain sockets to the socket inventory
+	Unix bool `yaml:"unix"`
 }
 
 // ApplicationsOptions contains the options for fetch applications configurations
@@ -94,7 +97,18 @@ type ApplicationsOptions struct {
 // docker define options for enable gather facts of docker (networks, images, containers, configuration)
 // Use docker environment variable for configure docker host connection (env DOCKER_HOST, etc ...)
 type docker struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled bool                `yaml:"enabled"`
+	Images  DockerImagesOptions `yaml:"images"`
+}
+
+// DockerImagesOptions contains the options for scan the packages and the vulnerabilities of the local images
+type DockerImagesOptions struct {
+	Scan      bool   `yaml:"scan"`
+	Source    string `yaml:"source"`    // export: images saved through the docker API, storage: layers read from the overlay2 storage of the daemon
+	DataRoot  string `yaml:"dataRoot"`  // Docker data root as mounted for facter, the layer paths reported by the daemon when empty, only used by the storage source
+	TempDir   string `yaml:"tempDir"`   // Directory where the images are unpacked, the system temporary directory when empty
+	MaxImages int    `yaml:"maxImages"` // Maximum number of images scanned, the most recent first, 0 for all
+	Dangling  bool   `yaml:"dangling"`  // Scan the untagged images too
 }
 
 // VirtualizationOptions contains the options for fetch logs configurations
@@ -138,7 +152,24 @@ type FacterServerOptions struct {
 
 // PackagesOptions contains the options for fetch installed package
 type PackagesOptions struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled   bool                    `yaml:"enabled"`
+	Languages LanguagePackagesOptions `yaml:"languages"`
+	Integrity PackageIntegrityOptions `yaml:"integrity"`
+}
+
+// PackageIntegrityOptions contains the options for verify installed files against the package database checksums
+type PackageIntegrityOptions struct {
+	Enabled     bool `yaml:"enabled"`
+	ConfigFiles bool `yaml:"configFiles"` // Also verify configuration files, usually modified on purpose
+}
+
+// LanguagePackagesOptions contains the options for fetch packages of language ecosystems (pip, npm, gems, go binaries, jars)
+type LanguagePackagesOptions struct {
+	Enabled    bool     `yaml:"enabled"`
+	Roots      []string `yaml:"roots"`      // Directories searched recursively, defaults are used when empty
+	MaxDepth   int      `yaml:"maxDepth"`   // Maximum directory depth below each root
+	Ecosystems []string `yaml:"ecosystems"` // Subset of pypi, npm, gem, golang, maven, all when empty
+	Processes  bool     `yaml:"processes"`  // Read go build info of running process executables
 }
 
 // GeoIpOptions contains the options for fetch  Geo IP location
@@ -165,6 +196,13 @@ type FirewallOptions struct {
 	Enabled bool `yaml:"enabled"`
 }
 
+// RoutingOptions contains the options for fetch routes, rules, neighbours and network namespaces
+type RoutingOptions struct {
+	Enabled bool `yaml:"enabled"`
+	// Namespaces adds the network namespaces and their interfaces
+	Namespaces bool `yaml:"namespaces"`
+}
+
 // PublicIpOptions contains the options for fetch public ip
 type PublicIpOptions struct {
 	Enabled        bool   `yaml:"enabled"`
@@ -179,12 +217,65 @@ type PortsOptions struct {
 
 // ComplianceOptions contains the options for fetch compliance information
 type ComplianceOptions struct {
-	Enabled    bool   `yaml:"enabled"`
-	Profile    string `yaml:"profile"`
-	ResultFile string `yaml:"resultFile"`
+	Enabled    bool                    `yaml:"enabled"`
+	Engine     string                  `yaml:"engine"` // openscap, native or auto: openscap when installed with a datastream for the OS, native otherwise
+	Profile    string                  `yaml:"profile"`
+	ResultFile string                  `yaml:"resultFile"`
+	DataStream string                  `yaml:"dataStream"` // OpenSCAP datastream, the SCAP Security Guide one of the OS when empty
+	Tailoring  string                  `yaml:"tailoring"`  // XCCDF tailoring file, its profiles can be used as profile
+	Rules      []string                `yaml:"rules"`      // Rules evaluated, every rule of the profile or packs when empty
+	SkipRules  []string                `yaml:"skipRules"`  // Rules not evaluated
+	Native     NativeComplianceOptions `yaml:"native"`
+}
+
+// NativeComplianceOptions contains the rule packs evaluated by the native compliance engine
+type NativeComplianceOptions struct {
+	Packs    []string `yaml:"packs"`    // Bundled rule packs, cis-linux-l1 when empty
+	RuleDirs []string `yaml:"ruleDirs"` // Directories of custom YAML rule packs, their rules replace the bundled rules of the same id
 }
 
 // VulnerabilitiesOptions contains the options for fetch installed vulnerabilities
 type VulnerabilitiesOptions struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled    bool                  `yaml:"enabled"`
+	Database   string                `yaml:"database"` // OSV database directory loaded by `facter vulndb import`, used by the builtin scanner
+	Scanners   []string              `yaml:"scanners"` // trivy, grype or builtin, in order of preference. Builtin when a database is set, trivy otherwise
+	Strategy   string                `yaml:"strategy"` // prefer: results of the first scanner succeeding, merge: union of the results of every scanner
+	Trivy      VulnScannerOptions    `yaml:"trivy"`
+	Grype      VulnScannerOptions    `yaml:"grype"`
+	Enrichment VulnEnrichmentOptions `yaml:"enrichment"`
+	Policy     RiskPolicyOptions     `yaml:"policy"`
+}
+
+// VulnScannerOptions contains the options of an external vulnerability scanner
+type VulnScannerOptions struct {
+	Binary   string   `yaml:"binary"`   // Path of the scanner, looked up in PATH by default
+	SkipDirs []string `yaml:"skipDirs"` // Directories not scanned, /run when empty
+	Timeout  int      `yaml:"timeout"`  // Timeout of the scan in seconds, 0 for none
+	DbPath   string   `yaml:"dbPath"`   // Directory of the scanner database, the scanner default when empty
+	Offline  bool     `yaml:"offline"`  // Do not update the scanner database
+	Args     []string `yaml:"args"`     // Additional arguments passed to the scanner
+}
+
+// VulnEnrichmentOptions contains the local feeds enriching the vulnerabilities, each one is optional
+type VulnEnrichmentOptions struct {
+	Nvd  string `yaml:"nvd"`  // NVD CVE API 2.0 JSON file, or directory of them, optionally gzipped, for CVSS scores and published dates
+	Epss string `yaml:"epss"` // FIRST EPSS scores CSV file, optionally gzipped
+	Kev  string `yaml:"kev"`  // CISA Known Exploited Vulnerabilities catalog JSON file
+}
+
+// RiskPolicyOptions contains the policy rating the risk of the vulnerabilities and of the host.
+// A vulnerability is rated at the highest level it meets, the default levels are used when none is set.
+type RiskPolicyOptions struct {
+	Critical RiskLevelOptions `yaml:"critical"`
+	High     RiskLevelOptions `yaml:"high"`
+	Medium   RiskLevelOptions `yaml:"medium"`
+	Low      RiskLevelOptions `yaml:"low"`
+	FailOn   string           `yaml:"failOn"` // Risk rating from which `facter run` exits non-zero: low, medium, high or critical, never when empty
+}
+
+// RiskLevelOptions contains the conditions of a risk level, any condition set is enough
+type RiskLevelOptions struct {
+	Cvss float64 `yaml:"cvss"` // Minimum CVSS base score, v4 when known, v3 otherwise
+	Epss float64 `yaml:"epss"` // Minimum EPSS probability
+	Kev  bool    `yaml:"kev"`  // Known exploited vulnerabilities
 }
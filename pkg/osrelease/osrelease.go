@@ -0,0 +1,41 @@
+// Package osrelease reads the os-release file identifying the operating system installed under a root.
+package osrelease
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// files are the locations of os-release, /etc/os-release takes precedence.
+var files = []string{"etc/os-release", "usr/lib/os-release"}
+
+// Read returns the values of the os-release of the system installed under root, nil when there is none.
+func Read(root string) map[string]string {
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			continue
+		}
+		return Parse(data)
+	}
+	return nil
+}
+
+// Parse returns the values of the KEY=value lines of an os-release file, without their quotes.
+func Parse(data []byte) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found || strings.HasPrefix(key, "#") {
+			continue
+		}
+		values[key] = strings.Trim(value, `"'`)
+	}
+	return values
+}
+
+// IDs returns the ID of the operating system followed by the IDs of the systems it is like, from ID_LIKE.
+func IDs(values map[string]string) []string {
+	return append(strings.Fields(values["ID"]), strings.Fields(values["ID_LIKE"])...)
+}
@@ -0,0 +1,34 @@
+package osrelease
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	values := Parse([]byte(`# Rocky Linux
+NAME="Rocky Linux"
+ID="rocky"
+ID_LIKE='rhel centos fedora'
+VERSION_ID="9.3"
+`))
+	assert.Equal(t, map[string]string{"NAME": "Rocky Linux", "ID": "rocky", "ID_LIKE": "rhel centos fedora", "VERSION_ID": "9.3"}, values)
+	assert.Equal(t, []string{"rocky", "rhel", "centos", "fedora"}, IDs(values))
+	assert.Empty(t, IDs(nil))
+}
+
+func TestRead(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, Read(root))
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "usr", "lib"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "usr", "lib", "os-release"), []byte("ID=debian\n"), 0o644))
+	assert.Equal(t, "debian", Read(root)["ID"])
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "etc"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "etc", "os-release"), []byte("ID=ubuntu\nID_LIKE=debian\n"), 0o644))
+	assert.Equal(t, []string{"ubuntu", "debian"}, IDs(Read(root)))
+}
@@ -0,0 +1,116 @@
+package versions
+
+import (
+	"strconv"
+	"strings"
+)
+
+// apkSuffixOrder ranks version suffixes, pre-release suffixes sort before a version without suffix.
+var apkSuffixOrder = map[string]int{
+	"alpha": -4,
+	"beta":  -3,
+	"pre":   -2,
+	"rc":    -1,
+	"":      0,
+	"cvs":   1,
+	"svn":   2,
+	"git":   3,
+	"hg":    4,
+	"p":     5,
+}
+
+type apkVersion struct {
+	numbers  []string
+	letter   byte
+	suffixes []apkSuffix
+	revision int64
+}
+
+type apkSuffix struct {
+	rank   int
+	number int64
+}
+
+// parseApkVersion splits a version like "1.2.3b_rc1_p2-r4" into its components.
+func parseApkVersion(v string) apkVersion {
+	var parsed apkVersion
+	if i := strings.LastIndex(v, "-r"); i >= 0 {
+		if rev, err := strconv.ParseInt(v[i+2:], 10, 64); err == nil {
+			parsed.revision = rev
+			v = v[:i]
+		}
+	}
+	// Commit hash suffix (e.g. "~a1b2c3") does not participate in ordering
+	v, _, _ = strings.Cut(v, "~")
+
+	main, suffixes, _ := strings.Cut(v, "_")
+	if n := len(main); n > 0 && main[n-1] >= 'a' && main[n-1] <= 'z' {
+		parsed.letter = main[n-1]
+		main = main[:n-1]
+	}
+	parsed.numbers = strings.Split(main, ".")
+
+	if suffixes != "" {
+		for _, s := range strings.Split(suffixes, "_") {
+			name := strings.TrimRight(s, "0123456789")
+			number, _ := strconv.ParseInt(s[len(name):], 10, 64)
+			rank, ok := apkSuffixOrder[name]
+			if !ok {
+				rank = 0
+			}
+			parsed.suffixes = append(parsed.suffixes, apkSuffix{rank: rank, number: number})
+		}
+	}
+	return parsed
+}
+
+// CompareApk returns -1, 0 or 1 when a is older, equal or newer than b, following apk-tools ordering.
+func CompareApk(a, b string) int {
+	va, vb := parseApkVersion(a), parseApkVersion(b)
+
+	for i := 0; i < len(va.numbers) && i < len(vb.numbers); i++ {
+		if c := compareNumeric(va.numbers[i], vb.numbers[i]); c != 0 {
+			return c
+		}
+	}
+	if len(va.numbers) != len(vb.numbers) {
+		if len(va.numbers) < len(vb.numbers) {
+			return -1
+		}
+		return 1
+	}
+	if va.letter != vb.letter {
+		if va.letter < vb.letter {
+			return -1
+		}
+		return 1
+	}
+	for i := 0; i < len(va.suffixes) || i < len(vb.suffixes); i++ {
+		sa, sb := apkSuffix{}, apkSuffix{}
+		if i < len(va.suffixes) {
+			sa = va.suffixes[i]
+		}
+		if i < len(vb.suffixes) {
+			sb = vb.suffixes[i]
+		}
+		if sa.rank != sb.rank {
+			if sa.rank < sb.rank {
+				return -1
+			}
+			return 1
+		}
+		if sa.number != sb.number {
+			if sa.number < sb.number {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case va.revision < vb.revision:
+		return -1
+	case va.revision > vb.revision:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package versions
+
+import "strings"
+
+// CompareDpkg returns -1, 0 or 1 when a is older, equal or newer than b, following dpkg ordering
+// of "[epoch:]upstream_version[-debian_revision]" versions, see deb-version(7).
+func CompareDpkg(a, b string) int {
+	epochA, restA := splitEpoch(a)
+	epochB, restB := splitEpoch(b)
+	if c := compareNumeric(epochA, epochB); c != 0 {
+		return c
+	}
+	upstreamA, revisionA := splitDpkgRevision(restA)
+	upstreamB, revisionB := splitDpkgRevision(restB)
+	if c := dpkgVerrevcmp(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return dpkgVerrevcmp(revisionA, revisionB)
+}
+
+// splitDpkgRevision splits the debian revision, after the last hyphen.
+func splitDpkgRevision(v string) (string, string) {
+	if i := strings.LastIndexByte(v, '-'); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
+
+// dpkgOrder ranks a character of a non digit part: the tilde sorts before anything, even the
+// end of the part, then letters, then other characters.
+func dpkgOrder(c byte) int {
+	switch {
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	case c == '~':
+		return -1
+	case c != 0:
+		return int(c) + 256
+	}
+	return 0
+}
+
+// dpkgVerrevcmp compares alternating non digit and digit parts, as verrevcmp in dpkg sources.
+func dpkgVerrevcmp(a, b string) int {
+	at := func(s string, i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return 0
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := dpkgOrder(at(a, i)), dpkgOrder(at(b, j))
+			if ac != bc {
+				if ac < bc {
+					return -1
+				}
+				return 1
+			}
+			i++
+			j++
+		}
+		startA := min(i, len(a))
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		startB := min(j, len(b))
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		if c := compareNumeric(a[startA:min(i, len(a))], b[startB:min(j, len(b))]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
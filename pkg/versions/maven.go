@@ -0,0 +1,189 @@
+package versions
+
+import "strings"
+
+// mavenQualifiers are the well-known qualifiers in their order, the empty qualifier is the release. Unknown
+// qualifiers come after them, in lexical order.
+var mavenQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+// mavenAliases are the alternative spellings of the qualifiers.
+var mavenAliases = map[string]string{"ga": "", "final": "", "release": "", "cr": "rc"}
+
+// mavenItem is an item of a maven version: a number, a qualifier or a sub list started by a hyphen or a
+// transition between digits and letters. other is nil when the other version has no more items.
+type mavenItem interface {
+	compare(other mavenItem) int
+	isNull() bool
+}
+
+// mavenInt is a number without its leading zeros, empty for zero.
+type mavenInt string
+
+type mavenString string
+
+type mavenList []mavenItem
+
+// CompareMaven returns -1, 0 or 1 when a is older, equal or newer than b, following the ComparableVersion
+// ordering of maven: 1.0-alpha1 < 1.0-beta < 1.0-rc1 < 1.0-SNAPSHOT < 1.0 = 1.0.0 = 1.0-final < 1.0-sp1.
+func CompareMaven(a, b string) int {
+	return parseMaven(a).compare(parseMaven(b))
+}
+
+func parseMaven(v string) *mavenList {
+	v = strings.ToLower(strings.TrimSpace(v))
+	root := &mavenList{}
+	list := root
+	stack := []*mavenList{root}
+	// sub starts a new list nested in the current one
+	sub := func() {
+		next := &mavenList{}
+		*list = append(*list, next)
+		list = next
+		stack = append(stack, next)
+	}
+	digits, start := false, 0
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == '.' || c == '-':
+			if i == start {
+				*list = append(*list, mavenInt(""))
+			} else {
+				*list = append(*list, mavenParseItem(digits, v[start:i], false))
+			}
+			start = i + 1
+			if c == '-' {
+				sub()
+			}
+		case isDigit(c):
+			if !digits && i > start {
+				*list = append(*list, mavenParseItem(false, v[start:i], true))
+				start = i
+				sub()
+			}
+			digits = true
+		default:
+			if digits && i > start {
+				*list = append(*list, mavenParseItem(true, v[start:i], false))
+				start = i
+				sub()
+			}
+			digits = false
+		}
+	}
+	if len(v) > start {
+		*list = append(*list, mavenParseItem(digits, v[start:], false))
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		stack[i].normalize()
+	}
+	return root
+}
+
+// mavenParseItem returns a number or a qualifier, a single letter followed by a digit is a short qualifier,
+// as in 1.0a1 for 1.0-alpha-1.
+func mavenParseItem(digits bool, value string, followedByDigit bool) mavenItem {
+	if digits {
+		return mavenInt(strings.TrimLeft(value, "0"))
+	}
+	if followedByDigit && len(value) == 1 {
+		switch value {
+		case "a":
+			value = "alpha"
+		case "b":
+			value = "beta"
+		case "m":
+			value = "milestone"
+		}
+	}
+	if alias, ok := mavenAliases[value]; ok {
+		value = alias
+	}
+	return mavenString(value)
+}
+
+func (i mavenInt) isNull() bool { return i == "" }
+
+func (i mavenInt) compare(other mavenItem) int {
+	switch other := other.(type) {
+	case nil:
+		if i.isNull() {
+			return 0
+		}
+		return 1
+	case mavenInt:
+		return compareNumeric(string(i), string(other))
+	}
+	// A number is newer than a qualifier or a sub list
+	return 1
+}
+
+func (s mavenString) isNull() bool { return s == "" }
+
+// comparable returns the rank of the qualifier as a string, to be compared lexically with the unknown ones.
+func (s mavenString) comparable() string {
+	for i, qualifier := range mavenQualifiers {
+		if string(s) == qualifier {
+			return string(rune('0' + i))
+		}
+	}
+	return string(rune('0'+len(mavenQualifiers))) + "-" + string(s)
+}
+
+func (s mavenString) compare(other mavenItem) int {
+	switch other := other.(type) {
+	case nil:
+		// Compared with the release
+		return strings.Compare(s.comparable(), mavenString("").comparable())
+	case mavenString:
+		return strings.Compare(s.comparable(), other.comparable())
+	}
+	return -1
+}
+
+func (l *mavenList) isNull() bool { return len(*l) == 0 }
+
+// normalize removes the trailing null items, 1.0.0 is 1.
+func (l *mavenList) normalize() {
+	for i := len(*l) - 1; i >= 0; i-- {
+		if (*l)[i].isNull() {
+			*l = append((*l)[:i], (*l)[i+1:]...)
+		} else if _, ok := (*l)[i].(*mavenList); !ok {
+			break
+		}
+	}
+}
+
+func (l *mavenList) compare(other mavenItem) int {
+	switch other := other.(type) {
+	case nil:
+		if len(*l) == 0 {
+			return 0
+		}
+		return (*l)[0].compare(nil)
+	case mavenInt:
+		return -1
+	case mavenString:
+		return 1
+	case *mavenList:
+		for i := 0; i < len(*l) || i < len(*other); i++ {
+			var left, right mavenItem
+			if i < len(*l) {
+				left = (*l)[i]
+			}
+			if i < len(*other) {
+				right = (*other)[i]
+			}
+			var c int
+			if left == nil {
+				c = -right.compare(nil)
+			} else {
+				c = left.compare(right)
+			}
+			if c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package versions
+
+import "strings"
+
+// ComparePacman returns -1, 0 or 1 when a is older, equal or newer than b, following pacman
+// vercmp ordering of "[epoch:]pkgver[-pkgrel]" versions.
+func ComparePacman(a, b string) int {
+	if a == b {
+		return 0
+	}
+	epochA, restA := splitEpoch(a)
+	epochB, restB := splitEpoch(b)
+	if c := alpmvercmp(epochA, epochB); c != 0 {
+		return c
+	}
+	versionA, releaseA, hasReleaseA := splitRelease(restA)
+	versionB, releaseB, hasReleaseB := splitRelease(restB)
+	if c := alpmvercmp(versionA, versionB); c != 0 || !hasReleaseA || !hasReleaseB {
+		return c
+	}
+	return alpmvercmp(releaseA, releaseB)
+}
+
+// alpmvercmp is the rpmvercmp variant of libalpm: there is no tilde nor caret, more separators
+// make a version newer and a trailing alphabetic segment never beats the end of the version.
+func alpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	for a != "" && b != "" {
+		var sepA, sepB string
+		sepA, a = cutWhile(a, func(c byte) bool { return !isAlnum(c) })
+		sepB, b = cutWhile(b, func(c byte) bool { return !isAlnum(c) })
+		if a == "" || b == "" {
+			break
+		}
+		if len(sepA) != len(sepB) {
+			if len(sepA) < len(sepB) {
+				return -1
+			}
+			return 1
+		}
+
+		isNum := isDigit(a[0])
+		segA, segB := "", ""
+		if isNum {
+			segA, a = cutWhile(a, isDigit)
+			segB, b = cutWhile(b, isDigit)
+		} else {
+			segA, a = cutWhile(a, isAlpha)
+			segB, b = cutWhile(b, isAlpha)
+		}
+		if segB == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+		var c int
+		if isNum {
+			c = compareNumeric(segA, segB)
+		} else {
+			c = strings.Compare(segA, segB)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case (a == "" && (b == "" || !isAlpha(b[0]))) || (a != "" && isAlpha(a[0])):
+		return -1
+	}
+	return 1
+}
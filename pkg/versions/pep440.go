@@ -0,0 +1,151 @@
+package versions
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pep440RE matches a PEP 440 version with its alternative spellings: the separators of the pre, post and
+// development segments are optional, "1.0-1" is a post release and the segment numbers default to zero.
+var pep440RE = regexp.MustCompile(`^v?(?:(\d+)!)?(\d+(?:\.\d+)*)` +
+	`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d*))?` +
+	`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d*))?` +
+	`(?:[-_.]?(dev)[-_.]?(\d*))?` +
+	`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+// pep440PreReleases ranks the pre-release phases, a final release comes after them.
+var pep440PreReleases = map[string]int{"a": 1, "alpha": 1, "b": 2, "beta": 2, "c": 3, "rc": 3, "pre": 3, "preview": 3}
+
+const pep440Final = 4
+
+type pep440Version struct {
+	epoch   string
+	release []string
+	// preRank is the rank of the pre-release phase, pep440Final without pre-release and 0 for a development
+	// release of the final release, which comes before its pre-releases.
+	preRank          int
+	preNumber        string
+	hasPost, hasDev  bool
+	post, dev, local string
+}
+
+// ComparePep440 returns -1, 0 or 1 when a is older, equal or newer than b, following the PEP 440 ordering
+// of python packages: 1.0.dev1 < 1.0a1 < 1.0rc1 < 1.0 < 1.0.post1. Versions which are not PEP 440 compliant
+// are compared segment by segment.
+func ComparePep440(a, b string) int {
+	va, okA := parsePep440(a)
+	vb, okB := parsePep440(b)
+	if !okA || !okB {
+		return rpmvercmp(a, b)
+	}
+	if c := compareNumeric(va.epoch, vb.epoch); c != 0 {
+		return c
+	}
+	// Missing release numbers are zero, 1.0 equals 1.0.0
+	for i := 0; i < len(va.release) || i < len(vb.release); i++ {
+		partA, partB := "0", "0"
+		if i < len(va.release) {
+			partA = va.release[i]
+		}
+		if i < len(vb.release) {
+			partB = vb.release[i]
+		}
+		if c := compareNumeric(partA, partB); c != 0 {
+			return c
+		}
+	}
+	if va.preRank != vb.preRank {
+		return compareInt(va.preRank, vb.preRank)
+	}
+	if c := compareNumeric(va.preNumber, vb.preNumber); c != 0 {
+		return c
+	}
+	if va.hasPost != vb.hasPost {
+		return boolOrder(va.hasPost)
+	}
+	if c := compareNumeric(va.post, vb.post); c != 0 {
+		return c
+	}
+	// A development release comes before the release it leads to
+	if va.hasDev != vb.hasDev {
+		return -boolOrder(va.hasDev)
+	}
+	if c := compareNumeric(va.dev, vb.dev); c != 0 {
+		return c
+	}
+	return comparePep440Local(va.local, vb.local)
+}
+
+func parsePep440(v string) (*pep440Version, bool) {
+	match := pep440RE.FindStringSubmatch(strings.ToLower(strings.TrimSpace(v)))
+	if match == nil {
+		return nil, false
+	}
+	version := &pep440Version{
+		epoch:   match[1],
+		release: strings.Split(match[2], "."),
+		preRank: pep440Final,
+		hasPost: match[5] != "" || match[6] != "",
+		post:    match[5] + match[7],
+		hasDev:  match[8] != "",
+		dev:     match[9],
+		local:   match[10],
+	}
+	if match[3] != "" {
+		version.preRank = pep440PreReleases[match[3]]
+		version.preNumber = match[4]
+	} else if version.hasDev && !version.hasPost {
+		version.preRank = 0
+	}
+	return version, true
+}
+
+// comparePep440Local compares the local version labels, a version without label comes first. The labels are
+// compared segment by segment, a numeric segment is newer than an alphanumeric one and more segments are newer.
+func comparePep440Local(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	case b == "":
+		return 1
+	}
+	split := func(r rune) bool { return r == '.' || r == '-' || r == '_' }
+	segmentsA, segmentsB := strings.FieldsFunc(a, split), strings.FieldsFunc(b, split)
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		numA := strings.Trim(segmentsA[i], "0123456789") == ""
+		numB := strings.Trim(segmentsB[i], "0123456789") == ""
+		var c int
+		switch {
+		case numA && numB:
+			c = compareNumeric(segmentsA[i], segmentsB[i])
+		case numA != numB:
+			c = boolOrder(numA)
+		default:
+			c = strings.Compare(segmentsA[i], segmentsB[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(segmentsA), len(segmentsB))
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// boolOrder returns 1 when set, -1 otherwise.
+func boolOrder(set bool) int {
+	if set {
+		return 1
+	}
+	return -1
+}
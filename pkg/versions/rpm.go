@@ -0,0 +1,115 @@
+package versions
+
+import "strings"
+
+// CompareRpm returns -1, 0 or 1 when a is older, equal or newer than b, following rpm ordering
+// of "[epoch:]version[-release]" versions. The release is only compared when both versions have one.
+func CompareRpm(a, b string) int {
+	epochA, restA := splitEpoch(a)
+	epochB, restB := splitEpoch(b)
+	if c := compareNumeric(epochA, epochB); c != 0 {
+		return c
+	}
+	versionA, releaseA, hasReleaseA := splitRelease(restA)
+	versionB, releaseB, hasReleaseB := splitRelease(restB)
+	if c := rpmvercmp(versionA, versionB); c != 0 || !hasReleaseA || !hasReleaseB {
+		return c
+	}
+	return rpmvercmp(releaseA, releaseB)
+}
+
+// splitRelease splits the release, after the last hyphen.
+func splitRelease(v string) (string, string, bool) {
+	if i := strings.LastIndexByte(v, '-'); i >= 0 {
+		return v[:i], v[i+1:], true
+	}
+	return v, "", false
+}
+
+// rpmvercmp compares alternating alphabetic and numeric segments, as rpmvercmp in rpm sources.
+// A tilde sorts before anything, a caret sorts after the end of the version but before anything else.
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	for len(a) > 0 || len(b) > 0 {
+		a = strings.TrimLeftFunc(a, isRpmSeparator)
+		b = strings.TrimLeftFunc(b, isRpmSeparator)
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		isNum := isDigit(a[0])
+		segA, segB := "", ""
+		if isNum {
+			segA, a = cutWhile(a, isDigit)
+			segB, b = cutWhile(b, isDigit)
+		} else {
+			segA, a = cutWhile(a, isAlpha)
+			segB, b = cutWhile(b, isAlpha)
+		}
+		// Segments of different types, numbers are newer
+		if segB == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+		var c int
+		if isNum {
+			c = compareNumeric(segA, segB)
+		} else {
+			c = strings.Compare(segA, segB)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+func isRpmSeparator(r rune) bool {
+	return r < 128 && !isAlnum(byte(r)) && r != '~' && r != '^'
+}
+
+// cutWhile splits s after its longest prefix of characters matching fn.
+func cutWhile(s string, fn func(byte) bool) (string, string) {
+	i := 0
+	for i < len(s) && fn(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
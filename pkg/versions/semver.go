@@ -0,0 +1,71 @@
+package versions
+
+import "strings"
+
+// CompareSemver returns -1, 0 or 1 when a is older, equal or newer than b, following semantic
+// versioning precedence. A "v" prefix is accepted and missing minor or patch numbers are zero.
+// Build metadata does not participate in ordering.
+func CompareSemver(a, b string) int {
+	coreA, preA := parseSemver(a)
+	coreB, preB := parseSemver(b)
+	for i := 0; i < len(coreA) || i < len(coreB); i++ {
+		partA, partB := "0", "0"
+		if i < len(coreA) {
+			partA = coreA[i]
+		}
+		if i < len(coreB) {
+			partB = coreB[i]
+		}
+		if c := compareIdentifier(partA, partB); c != 0 {
+			return c
+		}
+	}
+	// A pre-release has a lower precedence than the release
+	switch {
+	case preA == nil && preB == nil:
+		return 0
+	case preA == nil:
+		return 1
+	case preB == nil:
+		return -1
+	}
+	for i := 0; i < len(preA) && i < len(preB); i++ {
+		if c := compareIdentifier(preA[i], preB[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(preA) < len(preB):
+		return -1
+	case len(preA) > len(preB):
+		return 1
+	}
+	return 0
+}
+
+// parseSemver splits a version into its core numbers and its pre-release identifiers.
+func parseSemver(v string) ([]string, []string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+")
+	core, pre, hasPre := strings.Cut(v, "-")
+	if !hasPre {
+		return strings.Split(core, "."), nil
+	}
+	return strings.Split(core, "."), strings.Split(pre, ".")
+}
+
+// compareIdentifier compares numeric identifiers numerically, they have a lower precedence
+// than alphanumeric identifiers which are compared lexically.
+func compareIdentifier(a, b string) int {
+	numA := a != "" && strings.Trim(a, "0123456789") == ""
+	numB := b != "" && strings.Trim(b, "0123456789") == ""
+	switch {
+	case numA && numB:
+		return compareNumeric(a, b)
+	case numA:
+		return -1
+	case numB:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
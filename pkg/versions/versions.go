@@ -0,0 +1,89 @@
+// Package versions orders package versions following the rules of each package ecosystem.
+package versions
+
+import (
+	"strings"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+)
+
+// Compare returns -1, 0 or 1 when a is older, equal or newer than b, following the ordering of the ecosystem.
+// Versions of ecosystems without a dedicated ordering are compared segment by segment.
+func Compare(ecosystem, a, b string) int {
+	switch ecosystem {
+	case models.EcosystemDeb:
+		return CompareDpkg(a, b)
+	case models.EcosystemRpm:
+		return CompareRpm(a, b)
+	case models.EcosystemAlpm:
+		return ComparePacman(a, b)
+	case models.EcosystemApk:
+		return CompareApk(a, b)
+	case models.EcosystemNpm, models.EcosystemGolang:
+		return CompareSemver(a, b)
+	case models.EcosystemPypi:
+		return ComparePep440(a, b)
+	case models.EcosystemMaven:
+		return CompareMaven(a, b)
+	}
+	return rpmvercmp(a, b)
+}
+
+// LessThan reports whether a is older than b.
+func LessThan(ecosystem, a, b string) bool {
+	return Compare(ecosystem, a, b) < 0
+}
+
+// Below reports whether installed is older than the fixed version of a vulnerability.
+// Scanners list one fixed version per maintained branch ("1.2.5, 1.3.2"), installed is only
+// reported below when it is older than all of them, so that a newer branch is never flagged.
+func Below(ecosystem, installed, fixed string) bool {
+	below := false
+	for _, version := range strings.Split(fixed, ",") {
+		version = strings.TrimSpace(version)
+		if version == "" {
+			continue
+		}
+		if !LessThan(ecosystem, installed, version) {
+			return false
+		}
+		below = true
+	}
+	return below
+}
+
+// compareNumeric compares two digit strings as numbers, without overflow on long ones.
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || isAlpha(c)
+}
+
+// splitEpoch splits "[epoch:]rest", the epoch defaults to "0".
+func splitEpoch(v string) (string, string) {
+	if i := strings.IndexByte(v, ':'); i > 0 {
+		epoch := v[:i]
+		if strings.Trim(epoch, "0123456789") == "" {
+			return epoch, v[i+1:]
+		}
+	}
+	return "0", v
+}
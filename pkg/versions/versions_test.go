@@ -0,0 +1,172 @@
+package versions
+
+import (
+	"testing"
+
+	"github.com/klamhq/facter-oss/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type versionTest struct {
+	a, b     string
+	expected int
+}
+
+func checkOrdering(t *testing.T, compare func(a, b string) int, tests []versionTest) {
+	t.Helper()
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, compare(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+		assert.Equal(t, -tt.expected, compare(tt.b, tt.a), "%s vs %s", tt.b, tt.a)
+	}
+}
+
+func TestCompareDpkg(t *testing.T) {
+	checkOrdering(t, CompareDpkg, []versionTest{
+		{"1.0", "1.0", 0},
+		{"1.0-1", "1.0-2", -1},
+		{"1:1.0", "2.0", 1},
+		{"0:1.0", "1.0", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0~", "1.0", -1},
+		{"1.0", "1.0+b1", -1},
+		{"1.0a", "1.0+", -1},
+		{"1.10", "1.9", 1},
+		{"7.88.1-10+deb12u4", "7.88.1-10+deb12u5", -1},
+		{"2.36-9+deb12u4", "2.36-9", 1},
+		{"1.2.3-0ubuntu1~22.04.1", "1.2.3-0ubuntu1", -1},
+		{"1.0-1-1", "1.0-1-2", -1},
+		{"001.0", "1.0", 0},
+	})
+}
+
+func TestCompareRpm(t *testing.T) {
+	checkOrdering(t, CompareRpm, []versionTest{
+		{"1.0", "1.0", 0},
+		{"1.0-1.el9", "1.0-2.el9", -1},
+		{"1:2.02-0.86.el7", "2.03-1.el7", 1},
+		{"1.0", "1.0-5.el9", 0},
+		{"1.0a", "1.0", 1},
+		{"1.0.a", "1.0.1", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0^git1", "1.0", 1},
+		{"1.0^git1", "1.0.1", -1},
+		{"1.0^", "1.0~", 1},
+		{"5.1.8-6.el9", "5.1.8-6.el9_2", -1},
+		{"1_0", "1.0", 0},
+		{"2.4.57-5.el9", "2.4.57-11.el9", -1},
+	})
+}
+
+func TestComparePacman(t *testing.T) {
+	checkOrdering(t, ComparePacman, []versionTest{
+		{"1.0", "1.0", 0},
+		{"1.0-1", "1.0-2", -1},
+		{"1:1.0-1", "2.0-1", 1},
+		{"1.0a", "1.0", -1},
+		{"1.0", "1.0.1", -1},
+		{"1.0.a", "1.0.1", -1},
+		{"1.0alpha", "1.0", -1},
+		{"1.0", "1.0", 0},
+		{"9.6p1-1", "9.7p1-1", -1},
+		{"1.0", "1.0-1", 0},
+		{"1..0", "1.0", 1},
+	})
+}
+
+func TestCompareApk(t *testing.T) {
+	checkOrdering(t, CompareApk, []versionTest{
+		{"1.0", "1.0", 0},
+		{"1.0-r1", "1.0-r0", 1},
+		{"1.0", "1.0.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0a", "1.0", 1},
+		{"1.0_rc1", "1.0", -1},
+		{"1.0_alpha2", "1.0_beta1", -1},
+		{"1.0_p1", "1.0", 1},
+		{"1.0_git20230101", "1.0_rc1", 1},
+		{"3.1.4_rc1-r0", "3.1.4-r5", -1},
+		{"2.0~a1b2c3", "2.0", 0},
+		{"20240101000000000000", "9", 1},
+	})
+}
+
+func TestCompareSemver(t *testing.T) {
+	checkOrdering(t, CompareSemver, []versionTest{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.0.0+build.1", "1.0.0", 0},
+		{"1.9.0", "1.10.0", -1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"v0.0.0-20230101000000-abcdef123456", "v0.1.0", -1},
+	})
+}
+
+func TestComparePep440(t *testing.T) {
+	checkOrdering(t, ComparePep440, []versionTest{
+		{"1.0", "1.0.0", 0},
+		{"1.0rc1", "1.0", -1},
+		{"1.0.dev1", "1.0a1", -1},
+		{"1.0a1", "1.0b1", -1},
+		{"1.0b2", "1.0rc1", -1},
+		{"1.0", "1.0.post1", -1},
+		{"1.0-1", "1.0.post1", 0},
+		{"1.0.post1.dev1", "1.0.post1", -1},
+		{"1.0a1.dev1", "1.0a1", -1},
+		{"1.0", "1.0+local.1", -1},
+		{"1.0+abc", "1.0+1", -1},
+		{"1.0.0-Alpha_2", "1.0a2", 0},
+		{"1!0.1", "2.0", 1},
+		{"1.9", "1.10", -1},
+		{"2.31.0", "2.31.0rc2", 1},
+	})
+}
+
+func TestCompareMaven(t *testing.T) {
+	checkOrdering(t, CompareMaven, []versionTest{
+		{"1.0", "1.0.0", 0},
+		{"1.0", "1-0", 0},
+		{"1.0", "1.0-final", 0},
+		{"2.0.0.RELEASE", "2.0.0", 0},
+		{"1.0rc1", "1.0", -1},
+		{"1.0-alpha-1", "1.0-beta-1", -1},
+		{"1.0a1", "1.0-alpha-1", 0},
+		{"1.0-beta-2", "1.0-rc1", -1},
+		{"1.0-rc1", "1.0-SNAPSHOT", -1},
+		{"1.0-SNAPSHOT", "1.0", -1},
+		{"1.0", "1.0-sp1", -1},
+		{"1.0-cr1", "1.0-rc1", 0},
+		{"1.0", "1.0-foo", -1},
+		{"1.0-foo", "1.0.1", -1},
+		{"1.9", "1.10", -1},
+		{"2.14.1", "2.17.0", -1},
+	})
+}
+
+func TestCompare_Ecosystem(t *testing.T) {
+	// "~" is a pre-release for dpkg, a plain separator for semver
+	assert.Equal(t, -1, Compare(models.EcosystemDeb, "1.0~rc1", "1.0"))
+	assert.Equal(t, 1, Compare(models.EcosystemAlpm, "1.0", "1.0a"))
+	assert.Equal(t, -1, Compare(models.EcosystemRpm, "1.0a", "1:0.1"))
+	assert.Equal(t, -1, Compare(models.EcosystemApk, "1.0_rc1", "1.0"))
+	assert.Equal(t, -1, Compare(models.EcosystemNpm, "4.17.20", "4.17.21"))
+	assert.Equal(t, 1, Compare(models.EcosystemHomebrew, "8.5.0_1", "8.5.0"))
+	assert.True(t, LessThan(models.EcosystemGolang, "v1.21.5", "v1.21.6"))
+	// A release candidate is older than the release for python and maven, not for rpmvercmp
+	assert.Equal(t, -1, Compare(models.EcosystemPypi, "1.0rc1", "1.0"))
+	assert.Equal(t, -1, Compare(models.EcosystemMaven, "1.0-RC1", "1.0"))
+}
+
+func TestBelow(t *testing.T) {
+	assert.True(t, Below(models.EcosystemDeb, "7.88.1-10+deb12u4", "7.88.1-10+deb12u5"))
+	assert.False(t, Below(models.EcosystemDeb, "7.88.1-10+deb12u5", "7.88.1-10+deb12u5"))
+	assert.False(t, Below(models.EcosystemDeb, "7.88.1-10+deb12u4", ""))
+	// One fixed version per branch
+	assert.True(t, Below(models.EcosystemNpm, "2.14.0", "2.15.1, 3.0.2"))
+	assert.False(t, Below(models.EcosystemNpm, "3.0.0", "2.15.1, 3.0.2"))
+}